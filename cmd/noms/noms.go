@@ -37,6 +37,9 @@ var commands = []*util.Command{
 
 var kingpinCommands = []util.KingpinCommand{
 	nomsBlob,
+	nomsCodegen,
+	nomsPatch,
+	nomsSchema,
 	splore.Cmd,
 }
 
@@ -144,19 +147,28 @@ If absolute-path is not provided, then it is read from stdin. See Spelling Objec
 	noms.Command("config", "Prints the active configuration if a .nomsconfig file is present")
 
 	// diff
-	diff := noms.Command("diff", `Shows the difference between two objects
+	diff := noms.Command("diff", `Shows the difference between two objects, or across a range of commits given as <dataset>@<from>..<to>
 See Spelling Objects at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the object arguments.
+<from> and <to> are each a commit hash, a number of commits before the head of the dataset, or a date; an omitted <to> is the head.
 `)
 	diff.Flag("stat", "Writes a summary of the changes instead").Short('s').Bool()
-	diff.Arg("object1", "").Required().String()
-	diff.Arg("object2", "").Required().String()
+	diff.Flag("format", "the format of the differences: text, json (one JSON object per difference) or jsonpatch (RFC 6902)").Default("text").Enum("text", "json", "jsonpatch")
+	diff.Flag("include", "only consider paths matched by this path glob, e.g. .rows[*].price, and what's beneath them (may be repeated)").Strings()
+	diff.Flag("exclude", "don't consider paths matched by this path glob, or what's beneath them (may be repeated)").Strings()
+	diff.Flag("max-depth", "don't descend into values deeper than this many path parts, reporting changes to them whole (0 for no limit)").Default("0").Int()
+	diff.Flag("per-commit", "with a range of commits, also show the diff made by each commit in the range").Bool()
+	diff.Flag("out-patch", "commit the differences as a patch to this dataset, for noms patch apply, instead of writing them").String()
+	diff.Arg("object1", "the first object, or a range of commits").Required().String()
+	diff.Arg("object2", "the second object, if a range of commits isn't given").String()
 
 	// ds
 	ds := noms.Command("ds", `Noms dataset management
 See Spelling Objects at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the database argument.
 `)
 	ds.Flag("delete", "dataset to delete").Short('d').String()
+	ds.Flag("merge-strategies", "dataset whose merge strategies to show or, if any <pattern>=<strategy> arguments are given, replace").String()
 	ds.Arg("database", "a noms database path").String()
+	ds.Arg("strategies", "<pattern>=<strategy> merge strategies, with --merge-strategies").Strings()
 
 	// log
 	log := noms.Command("log", `Displays the history of a path
@@ -169,18 +181,31 @@ See Spelling Values at https://github.com/attic-labs/noms/blob/master/doc/spelli
 	log.Flag("graph", "show ascii-based commit hierarchy on left side of output").Bool()
 	log.Flag("show-value", "show commit value rather than diff information").Bool()
 	log.Flag("tz", "display formatted date comments in specified timezone, must be: local or utc").Enum("local", "utc")
+	log.Flag("include", "only consider paths matched by this path glob, e.g. .rows[*].price, and what's beneath them (may be repeated)").Strings()
+	log.Flag("exclude", "don't consider paths matched by this path glob, or what's beneath them (may be repeated)").Strings()
+	log.Flag("max-depth", "don't descend into values deeper than this many path parts, reporting changes to them whole (0 for no limit)").Default("0").Int()
+	log.Flag("author", "only show commits whose author meta field matches this regular expression").String()
+	log.Flag("grep", "only show commits whose message meta field matches this regular expression").String()
+	log.Flag("since", "only show commits whose date meta field is at or after this date, e.g. 2017-06-30 or 2017-06-30T17:00:00Z").String()
+	log.Flag("until", "only show commits whose date meta field is at or before this date").String()
+	log.Flag("path", "only show commits that changed the value at this path, relative to <path-spec>").String()
+	log.Flag("format", "write each commit using this Go template, e.g. '{{.Hash}} {{.Meta.message}}', over the fields Hash, Parents and Meta").String()
+	log.Flag("json", "write each commit as a JSON object on its own line, with the fields hash, parents and meta").Bool()
 	log.Arg("path-spec", "").Required().String()
 
 	// merge
-	merge := noms.Command("merge", `Merges and commits the head values of two named datasets
+	merge := noms.Command("merge", `Merges and commits the head values of two or more named datasets
 See Spelling Objects at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the database argument.
-You must provide a working database and the names of two Datasets you want to merge. The values at the heads of these Datasets will be merged, put into a new Commit object, and set as the Head of the third provided Dataset name.
+You must provide a working database and the names of two or more Datasets you want to merge. The values at the heads of these Datasets will be merged, put into a new Commit object whose parents are all of their heads, and set as the Head of the last provided Dataset name.
+With --all-conflicts, every conflict is reported and the merge is saved in the database until it's finished with --continue or abandoned with --abort, which, like --resolve, take only <database> and <output-dataset-name>.
 `)
-	merge.Flag("policy", "conflict resolution policy for merging. Defaults to 'n', which means no resolution strategy will be applied. Supported values are 'l' (left), 'r' (right) and 'p' (prompt). 'prompt' will bring up a simple command-line prompt allowing you to resolve conflicts by choosing between 'l' or 'r' on a case-by-case basis.").Default("n").Enum("n", "r", "l", "p")
+	merge.Flag("policy", "conflict resolution policy for merging. Defaults to 'n', which means no resolution strategy will be applied. Supported values are 'l' (left), 'r' (right), 'p' (prompt) and 's' (strategies). 'prompt' will bring up a simple command-line prompt allowing you to resolve conflicts by choosing between 'l' or 'r' on a case-by-case basis. 'strategies' merges using the merge strategies stored for the output dataset (see noms ds --merge-strategies).").Default("n").Enum("n", "r", "l", "p", "s")
+	merge.Flag("all-conflicts", "report every conflict, rather than stopping at the first, and save the merge so that they can be resolved").Bool()
+	merge.Flag("continue", "commit a saved merge whose conflicts have all been resolved").Bool()
+	merge.Flag("abort", "abandon a saved merge").Bool()
+	merge.Flag("resolve", "resolve a conflict in a saved merge, given as <path>=<ours|theirs|ancestor|remove>").Strings()
 	addDatabaseArg(merge)
-	merge.Arg("left-dataset-name", "a dataset").Required().String()
-	merge.Arg("right-dataset-name", "a dataset").Required().String()
-	merge.Arg("output-dataset-name", "a dataset").Required().String()
+	merge.Arg("dataset-names", "the datasets to merge, followed by the output dataset").Strings()
 
 	// root
 	root := noms.Command("root", `Get or set the current root hash of the entire database
@@ -194,6 +219,7 @@ See Spelling Objects at https://github.com/attic-labs/noms/blob/master/doc/spell
 See Spelling Objects at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the database argument.
 `)
 	serve.Flag("port", "port to listen on for HTTP requests").Default("8000").Int()
+	serve.Flag("replica", "a database to mirror everything committed to the served database to; may be repeated").Strings()
 	addDatabaseArg(serve)
 
 	// show
@@ -203,6 +229,8 @@ See Spelling Objects at https://github.com/attic-labs/noms/blob/master/doc/spell
 	show.Flag("raw", "If true, dumps the raw binary version of the data").Bool()
 	show.Flag("stats", "If true, reports statistics related to the value").Bool()
 	show.Flag("tz", "display formatted date comments in specified timezone, must be: local or utc").Enum("local", "utc")
+	show.Flag("include", "only consider paths matched by this path glob, e.g. .rows[*].price, and what's beneath them (may be repeated)").Strings()
+	show.Flag("exclude", "don't consider paths matched by this path glob, or what's beneath them (may be repeated)").Strings()
 	show.Arg("object", "a noms object").Required().String()
 
 	// sync
@@ -0,0 +1,66 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/attic-labs/noms/cmd/util"
+	"github.com/attic-labs/noms/go/config"
+	"github.com/attic-labs/noms/go/d"
+	"github.com/attic-labs/noms/go/marshal/codegen"
+	"github.com/attic-labs/noms/go/nomdl"
+	"github.com/attic-labs/noms/go/types"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func nomsCodegen(noms *kingpin.Application) (*kingpin.CmdClause, util.KingpinHandler) {
+	codegen := noms.Command("codegen", `generates Go types for the type of a value, or a type in a nomdl file
+The generated types marshal to and unmarshal from Noms values of the type with github.com/attic-labs/noms/go/marshal. Use <dataset>.value for the type of the head value of a dataset, rather than of its commit.
+See Spelling Values at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the path-spec argument.`)
+	pkg := codegen.Flag("package", "the package of the generated Go file").Default("main").String()
+	name := codegen.Flag("name", "the name of the Go type for the type itself, unless it's a named struct").Default("Root").String()
+	out := codegen.Flag("out", "the file to write the Go source to, rather than stdout").Short('o').String()
+	source := codegen.Arg("path-spec|nomdl-file", "the value whose type to generate Go types for, or a file with the type in nomdl").Required().String()
+
+	return codegen, func(input string) int {
+		return runCodegen(*source, *pkg, *name, *out)
+	}
+}
+
+func runCodegen(source, pkg, name, out string) int {
+	if !types.IsValidStructFieldName(name) {
+		d.CheckErrorNoUsage(fmt.Errorf("Invalid name: %s", name))
+	}
+
+	var t *types.Type
+	if fi, err := os.Stat(source); err == nil && !fi.IsDir() {
+		code, err := ioutil.ReadFile(source)
+		d.CheckErrorNoUsage(err)
+		t, err = nomdl.ParseType(string(code))
+		d.CheckErrorNoUsage(err)
+	} else {
+		cfg := config.NewResolver()
+		db, value, err := cfg.GetPath(source)
+		d.CheckErrorNoUsage(err)
+		defer db.Close()
+		if value == nil {
+			d.CheckErrorNoUsage(fmt.Errorf("Object not found: %s", source))
+		}
+		t = types.TypeOf(value)
+	}
+
+	src, err := codegen.Generate(pkg, name, t)
+	d.CheckErrorNoUsage(err)
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+	} else {
+		err = ioutil.WriteFile(out, src, 0644)
+	}
+	d.CheckErrorNoUsage(err)
+	return 0
+}
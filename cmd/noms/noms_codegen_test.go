@@ -0,0 +1,61 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/attic-labs/noms/go/spec"
+	"github.com/attic-labs/noms/go/types"
+	"github.com/attic-labs/noms/go/util/clienttest"
+	"github.com/stretchr/testify/suite"
+)
+
+type nomsCodegenTestSuite struct {
+	clienttest.ClientTestSuite
+}
+
+func TestNomsCodegen(t *testing.T) {
+	suite.Run(t, &nomsCodegenTestSuite{})
+}
+
+func (s *nomsCodegenTestSuite) TestCodegenFromValue() {
+	sp, err := spec.ForDataset(spec.CreateValueSpecString("nbs", s.DBDir, "people"))
+	s.NoError(err)
+	db := sp.GetDatabase()
+	_, err = db.CommitValue(sp.GetDataset(), types.NewList(db,
+		types.NewStruct("Person", types.StructData{
+			"name": types.String("alice"),
+			"tags": types.NewSet(db, types.String("a")),
+		}),
+	))
+	s.NoError(err)
+	sp.Close()
+
+	stdout, _ := s.MustRun(main, []string{"codegen", "--package", "people", "--name", "people", spec.CreateValueSpecString("nbs", s.DBDir, "people.value")})
+	s.Contains(stdout, "package people\n")
+	s.Contains(stdout, "type People []Person\n")
+	s.Contains(stdout, "type Person struct {\n\tName string              `noms:\"name\"`\n\tTags map[string]struct{} `noms:\"tags,set\"`\n}\n")
+}
+
+func (s *nomsCodegenTestSuite) TestCodegenFromFile() {
+	dir := s.TempDir
+	in, out := filepath.Join(dir, "point.noms"), filepath.Join(dir, "point.go")
+	s.NoError(ioutil.WriteFile(in, []byte("Struct Point { x: Number, y: Number }"), 0644))
+
+	stdout, _ := s.MustRun(main, []string{"codegen", "-o", out, in})
+	s.Empty(stdout)
+	src, err := ioutil.ReadFile(out)
+	s.NoError(err)
+	s.Contains(string(src), "package main\n")
+	s.Contains(string(src), "type Point struct {\n\tX float64 `noms:\"x\"`\n\tY float64 `noms:\"y\"`\n}\n")
+
+	s.NoError(ioutil.WriteFile(in, []byte("Struct Point {"), 0644))
+	_, stderr, exitErr := s.Run(main, []string{"codegen", in})
+	s.NotNil(exitErr)
+	s.Contains(stderr, "Unexpected token EOF")
+}
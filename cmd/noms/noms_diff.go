@@ -6,30 +6,47 @@ package main
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/attic-labs/noms/cmd/util"
 	"github.com/attic-labs/noms/go/config"
 	"github.com/attic-labs/noms/go/d"
+	"github.com/attic-labs/noms/go/datas"
 	"github.com/attic-labs/noms/go/diff"
+	"github.com/attic-labs/noms/go/types"
 	"github.com/attic-labs/noms/go/util/outputpager"
 	"github.com/attic-labs/noms/go/util/verbose"
 	flag "github.com/juju/gnuflag"
 )
 
-var stat bool
+var (
+	stat       bool
+	diffFormat string
+	outPatch   string
+	perCommit  bool
+)
 
 var nomsDiff = &util.Command{
 	Run:       runDiff,
-	UsageLine: "diff [--stat] <object1> <object2>",
+	UsageLine: "diff [--stat] [--format=text|json|jsonpatch] [--include <glob>] [--exclude <glob>] [--max-depth <n>] [--out-patch <dataset>] <object1> <object2> | <dataset>@<from>..<to>",
 	Short:     "Shows the difference between two objects",
-	Long:      "See Spelling Objects at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the object arguments.",
-	Flags:     setupDiffFlags,
-	Nargs:     2,
+	Long: "See Spelling Objects at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the object arguments." +
+		"\n\nInstead of two objects, a range of commits in a dataset can be given as <dataset>@<from>..<to>, to show what changed in the dataset's value between them. <from> and <to> are each a commit hash, a number of commits before the head of the dataset, or a date (e.g. 2017-06-30 or 2017-06-30T17:00:00Z), which stands for the latest commit made at or before it. If <to> is omitted, it's the head. Commits are followed back through the parent with the longest history. With --per-commit, the diff made by each commit in the range is shown as well, and with --stat, the changes are counted under each top-level field or key." +
+		"\n\nWith --format=json, each difference is written on its own line as a JSON object with the path, the change (added, removed or modified) and the old and new values. With --format=jsonpatch, the differences are written as a JSON Patch (RFC 6902), which requires that the objects can be represented as JSON." +
+		"\n\nWith --include and --exclude, only the differences at paths matched by the given path globs are shown, e.g. --include .rows[*].price. In a path glob, .* matches any field and [*] matches any index or key. With --max-depth, changes deeper than the given number of path parts are shown as changes to their ancestor at that depth. Values that aren't included are never read." +
+		"\n\nWith --out-patch, the differences are instead committed to <dataset> as a patch, which noms patch apply can apply to another dataset. <dataset> must be in the same database as <object1> and <object2>.",
+	Flags: setupDiffFlags,
+	Nargs: 1,
 }
 
 func setupDiffFlags() *flag.FlagSet {
 	diffFlagSet := flag.NewFlagSet("diff", flag.ExitOnError)
 	diffFlagSet.BoolVar(&stat, "stat", false, "Writes a summary of the changes instead")
+	diffFlagSet.StringVar(&diffFormat, "format", "text", "the format of the differences: text, json (one JSON object per difference) or jsonpatch (RFC 6902)")
+	registerPathFilterFlags(diffFlagSet)
+	registerMaxDepthFlag(diffFlagSet)
+	diffFlagSet.BoolVar(&perCommit, "per-commit", false, "with a range of commits, also show the diff made by each commit in the range")
+	diffFlagSet.StringVar(&outPatch, "out-patch", "", "commit the differences as a patch to this dataset, for noms patch apply, instead of writing them")
 	outputpager.RegisterOutputpagerFlags(diffFlagSet)
 	verbose.RegisterVerboseFlags(diffFlagSet)
 
@@ -37,29 +54,94 @@ func setupDiffFlags() *flag.FlagSet {
 }
 
 func runDiff(args []string) int {
-	cfg := config.NewResolver()
-	db1, value1, err := cfg.GetPath(args[0])
-	d.CheckErrorNoUsage(err)
-	if value1 == nil {
-		d.CheckErrorNoUsage(fmt.Errorf("Object not found: %s", args[0]))
+	var write func(w io.Writer, v1, v2 types.Value, leftRight bool, filter diff.Filter) error
+	switch diffFormat {
+	case "text":
+		write = diff.PrintDiffWithFilter
+	case "json":
+		write = diff.WriteJSON
+	case "jsonpatch":
+		write = diff.WriteJSONPatch
+	default:
+		d.CheckErrorNoUsage(fmt.Errorf("Unsupported diff format: %s. Choices are text, json and jsonpatch.", diffFormat))
 	}
-	defer db1.Close()
 
-	db2, value2, err := cfg.GetPath(args[1])
-	d.CheckErrorNoUsage(err)
-	if value2 == nil {
-		d.CheckErrorNoUsage(fmt.Errorf("Object not found: %s", args[1]))
+	if perCommit && diffFormat != "text" {
+		d.CheckErrorNoUsage(fmt.Errorf("--per-commit can only be used with --format=text"))
+	}
+
+	cfg := config.NewResolver()
+	var value1, value2 types.Value
+	var commits []types.Struct
+	var db datas.Database
+	isRange := len(args) == 1 && isCommitRange(args[0])
+	switch {
+	case isRange:
+		var from, to types.Struct
+		var err error
+		db, from, to, err = resolveCommitRange(cfg, args[0])
+		d.CheckErrorNoUsage(err)
+		defer db.Close()
+		value1, value2 = from.Get(datas.ValueField), to.Get(datas.ValueField)
+		if perCommit {
+			commits, err = commitsBetween(from, to, db)
+			d.CheckErrorNoUsage(err)
+		}
+	case len(args) == 2:
+		if perCommit {
+			d.CheckErrorNoUsage(fmt.Errorf("--per-commit can only be used with a range of commits"))
+		}
+		db1, v1, err := cfg.GetPath(args[0])
+		d.CheckErrorNoUsage(err)
+		if v1 == nil {
+			d.CheckErrorNoUsage(fmt.Errorf("Object not found: %s", args[0]))
+		}
+		defer db1.Close()
+
+		db2, v2, err := cfg.GetPath(args[1])
+		d.CheckErrorNoUsage(err)
+		if v2 == nil {
+			d.CheckErrorNoUsage(fmt.Errorf("Object not found: %s", args[1]))
+		}
+		defer db2.Close()
+		value1, value2 = v1, v2
+	default:
+		d.CheckError(fmt.Errorf("Expected two objects or a range of commits: <dataset>@<from>..<to>"))
 	}
-	defer db2.Close()
 
 	if stat {
-		diff.Summary(value1, value2)
+		if !isRange {
+			diff.Summary(value1, value2)
+			return 0
+		}
+		diff.SummaryByKey(value1, value2)
+		for _, c := range commits {
+			fmt.Printf("\ncommit #%s\n", c.Hash())
+			parent, _ := mainParent(c, db)
+			diff.SummaryByKey(parent.Get(datas.ValueField), c.Get(datas.ValueField))
+		}
+		return 0
+	}
+
+	if outPatch != "" {
+		db, ds, err := cfg.GetDataset(outPatch)
+		d.CheckErrorNoUsage(err)
+		defer db.Close()
+		patch := diff.MakePatch(value1, value2, false, diffFilter())
+		d.CheckErrorNoUsage(diff.CheckRefs(patch, db))
+		_, err = db.CommitValue(ds, patch.Marshal(db))
+		d.CheckErrorNoUsage(err)
 		return 0
 	}
 
 	pgr := outputpager.Start()
 	defer pgr.Stop()
 
-	diff.PrintDiff(pgr.Writer, value1, value2, false)
+	d.CheckErrorNoUsage(write(pgr.Writer, value1, value2, false, diffFilter()))
+	for _, c := range commits {
+		fmt.Fprintf(pgr.Writer, "\ncommit #%s\n", c.Hash())
+		parent, _ := mainParent(c, db)
+		d.CheckErrorNoUsage(write(pgr.Writer, parent.Get(datas.ValueField), c.Get(datas.ValueField), false, diffFilter()))
+	}
 	return 0
 }
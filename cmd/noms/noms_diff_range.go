@@ -0,0 +1,149 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/attic-labs/noms/go/config"
+	"github.com/attic-labs/noms/go/datas"
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/attic-labs/noms/go/types"
+)
+
+// The date formats accepted in commit ranges, most specific first.
+var revisionDateFormats = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+// isCommitRange returns true if |str| is a <dataset>@<from>..<to> range
+// rather than an object.
+func isCommitRange(str string) bool {
+	at := strings.LastIndex(str, "@")
+	return at >= 0 && strings.Contains(str[at:], "..")
+}
+
+// resolveCommitRange resolves a <dataset>@<from>..<to> range to the commits
+// it begins and ends at. <from> and <to> are each a commit hash, a number of
+// commits before the head of the dataset, or a date, which stands for the
+// latest commit made at or before it. An empty <to> is the head.
+func resolveCommitRange(cfg *config.Resolver, str string) (db datas.Database, from, to types.Struct, err error) {
+	at := strings.LastIndex(str, "@")
+	revs := strings.SplitN(str[at+1:], "..", 2)
+	db, ds, err := cfg.GetDataset(str[:at])
+	if err != nil {
+		return
+	}
+	head, ok := ds.MaybeHead()
+	if !ok {
+		err = fmt.Errorf("Dataset %s has no commits", ds.ID())
+		return
+	}
+	if revs[0] == "" {
+		err = fmt.Errorf("Missing start of range: %s", str)
+		return
+	}
+	if from, err = resolveRevision(db, head, revs[0]); err != nil {
+		return
+	}
+	to = head
+	if revs[1] != "" {
+		to, err = resolveRevision(db, head, revs[1])
+	}
+	return
+}
+
+// resolveRevision resolves |rev| to a commit: a commit hash, with or without
+// a leading #, a number of commits before |head|, or a date.
+func resolveRevision(vr types.ValueReader, head types.Struct, rev string) (types.Struct, error) {
+	if h, ok := hash.MaybeParse(strings.TrimPrefix(rev, "#")); ok {
+		v := vr.ReadValue(h)
+		if v == nil || !datas.IsCommit(v) {
+			return types.Struct{}, fmt.Errorf("%s is not a commit", rev)
+		}
+		return v.(types.Struct), nil
+	}
+
+	if n, err := strconv.Atoi(rev); err == nil && n >= 0 {
+		c := head
+		for i := 0; i < n; i++ {
+			p, ok := mainParent(c, vr)
+			if !ok {
+				return types.Struct{}, fmt.Errorf("There are only %d commits before the head", i)
+			}
+			c = p
+		}
+		return c, nil
+	}
+
+	if t, ok := parseRevisionDate(rev); ok {
+		for c, ok := head, true; ok; c, ok = mainParent(c, vr) {
+			if date, ok := commitTime(c); ok && !date.After(t) {
+				return c, nil
+			}
+		}
+		return types.Struct{}, fmt.Errorf("No commit was made at or before %s", rev)
+	}
+	return types.Struct{}, fmt.Errorf("Invalid revision %s: expected a commit hash, a number of commits or a date", rev)
+}
+
+// parseRevisionDate parses |str| using the first of revisionDateFormats that
+// it's in.
+func parseRevisionDate(str string) (time.Time, bool) {
+	for _, format := range revisionDateFormats {
+		if t, err := time.Parse(format, str); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// commitsBetween returns the commits after |from|, up to and including |to|,
+// oldest first, following mainParent back from |to|. It's an error if |from|
+// isn't reached.
+func commitsBetween(from, to types.Struct, vr types.ValueReader) ([]types.Struct, error) {
+	commits := []types.Struct{}
+	for c, ok := to, true; ok; c, ok = mainParent(c, vr) {
+		if c.Equals(from) {
+			for i, j := 0, len(commits)-1; i < j; i, j = i+1, j-1 {
+				commits[i], commits[j] = commits[j], commits[i]
+			}
+			return commits, nil
+		}
+		commits = append(commits, c)
+	}
+	return nil, fmt.Errorf("#%s is not an ancestor of #%s", from.Hash(), to.Hash())
+}
+
+// mainParent returns the parent of |commit| with the longest history, and
+// false if it has no parents.
+func mainParent(commit types.Struct, vr types.ValueReader) (parent types.Struct, ok bool) {
+	var best types.Ref
+	commit.Get(datas.ParentsField).(types.Set).IterAll(func(v types.Value) {
+		if r := v.(types.Ref); !ok || r.Height() > best.Height() {
+			best, ok = r, true
+		}
+	})
+	if ok {
+		parent = best.TargetValue(vr).(types.Struct)
+	}
+	return
+}
+
+// commitTime returns the date in the meta of |commit|, if it has one.
+func commitTime(commit types.Struct) (t time.Time, ok bool) {
+	meta, isStruct := commit.Get(datas.MetaField).(types.Struct)
+	if !isStruct {
+		return
+	}
+	if date, found := meta.MaybeGet("date"); found {
+		if s, isString := date.(types.String); isString {
+			t, err := time.Parse(time.RFC3339, string(s))
+			return t, err == nil
+		}
+	}
+	return
+}
@@ -9,6 +9,7 @@ import (
 
 	"strings"
 
+	"github.com/attic-labs/noms/go/datas"
 	"github.com/attic-labs/noms/go/spec"
 	"github.com/attic-labs/noms/go/types"
 	"github.com/attic-labs/noms/go/util/clienttest"
@@ -73,3 +74,116 @@ func (s *nomsDiffTestSuite) TestNomsDiffStat() {
 	out, _ = s.MustRun(main, []string{"diff", "--stat", r3, r4})
 	s.Contains(out, "1 insertion (25.00%), 2 deletions (50.00%), 0 changes (0.00%), (4 values vs 3 values)")
 }
+
+func (s *nomsDiffTestSuite) TestNomsDiffFormat() {
+	sp, err := spec.ForDataset(spec.CreateValueSpecString("nbs", s.DBDir, "diffFormatTest"))
+	s.NoError(err)
+	defer sp.Close()
+
+	db := sp.GetDatabase()
+	ds, err := db.CommitValue(sp.GetDataset(), types.NewList(db, types.Number(1), types.String("two"), types.Number(3)))
+	s.NoError(err)
+	r1 := spec.CreateHashSpecString("nbs", s.DBDir, ds.HeadRef().TargetHash()) + ".value"
+
+	ds, err = db.CommitValue(ds, types.NewList(db, types.Number(1), types.Number(3), types.Bool(true)))
+	s.NoError(err)
+	r2 := spec.CreateHashSpecString("nbs", s.DBDir, ds.HeadRef().TargetHash()) + ".value"
+
+	out, _ := s.MustRun(main, []string{"diff", "--format=json", r1, r2})
+	s.Equal(`{"path":"[1]","change":"removed","old":"two"}
+{"path":"[2]","change":"added","new":true}
+`, out)
+
+	out, _ = s.MustRun(main, []string{"diff", "--format=jsonpatch", r1, r2})
+	s.Equal(`[
+{"op":"remove","path":"/1"},
+{"op":"add","path":"/2","value":true}
+]
+`, out)
+
+}
+
+func (s *nomsDiffTestSuite) TestNomsDiffFilter() {
+	sp, err := spec.ForDataset(spec.CreateValueSpecString("nbs", s.DBDir, "diffFilterTest"))
+	s.NoError(err)
+	defer sp.Close()
+
+	db := sp.GetDatabase()
+	row := func(name string, price float64) types.Struct {
+		return types.NewStruct("Row", types.StructData{"name": types.String(name), "price": types.Number(price)})
+	}
+	ds, err := db.CommitValue(sp.GetDataset(), types.NewStruct("", types.StructData{
+		"rows":  types.NewList(db, row("a", 1), row("b", 2)),
+		"title": types.String("before"),
+	}))
+	s.NoError(err)
+	r1 := spec.CreateHashSpecString("nbs", s.DBDir, ds.HeadRef().TargetHash()) + ".value"
+
+	ds, err = db.CommitValue(ds, types.NewStruct("", types.StructData{
+		"rows":  types.NewList(db, row("a", 10), row("bb", 2)),
+		"title": types.String("after"),
+	}))
+	s.NoError(err)
+	r2 := spec.CreateHashSpecString("nbs", s.DBDir, ds.HeadRef().TargetHash()) + ".value"
+
+	paths := func(args ...string) string {
+		out, _ := s.MustRun(main, append(append([]string{"diff", "--format=json"}, args...), r1, r2))
+		return out
+	}
+	out := paths("--include", ".rows[*].price")
+	s.Contains(out, `"path":".rows[0].price"`)
+	s.NotContains(out, ".name")
+	s.NotContains(out, ".title")
+
+	out = paths("--exclude", ".rows")
+	s.Equal(`{"path":".title","change":"modified","old":"before","new":"after"}
+`, out)
+
+	out = paths("--max-depth", "1", "--exclude", ".title")
+	s.Contains(out, `"path":".rows","change":"modified"`)
+}
+
+func (s *nomsDiffTestSuite) TestNomsDiffRange() {
+	sp, err := spec.ForDataset(spec.CreateValueSpecString("nbs", s.DBDir, "diffRangeTest"))
+	s.NoError(err)
+	defer sp.Close()
+
+	db := sp.GetDatabase()
+	ds := sp.GetDataset()
+	hashes := []string{}
+	for i, date := range []string{"2017-06-26T12:00:00Z", "2017-06-28T12:00:00Z", "2017-06-30T12:00:00Z"} {
+		meta := types.NewStruct("Meta", types.StructData{"date": types.String(date)})
+		v := types.NewStruct("", types.StructData{
+			"count": types.Number(i),
+			"tags":  types.NewMap(db, types.String("t"), types.Number(i)),
+		})
+		ds, err = db.Commit(ds, v, datas.CommitOptions{Meta: meta})
+		s.NoError(err)
+		hashes = append(hashes, ds.HeadRef().TargetHash().String())
+	}
+	dsSpec := spec.CreateValueSpecString("nbs", s.DBDir, "diffRangeTest")
+
+	for _, rng := range []string{"#" + hashes[0] + "..", hashes[0] + "..#" + hashes[2], "2..0", "2017-06-27..", "2017-06-26T12:00:00Z..2017-07-01"} {
+		out, _ := s.MustRun(main, []string{"diff", "--format=json", dsSpec + "@" + rng})
+		s.Equal(`{"path":".count","change":"modified","old":0,"new":2}
+{"path":".tags[\"t\"]","change":"modified","old":0,"new":2}
+`, out, rng)
+	}
+
+	out, _ := s.MustRun(main, []string{"diff", "--per-commit", dsSpec + "@2.."})
+	s.Contains(out, "commit #"+hashes[1])
+	s.Contains(out, "commit #"+hashes[2])
+	s.True(strings.Index(out, hashes[1]) < strings.Index(out, hashes[2]))
+	s.Equal(6, strings.Count(out, "-   "))
+
+	out, _ = s.MustRun(main, []string{"diff", "--stat", dsSpec + "@2.."})
+	s.Contains(out, ".count: 1 insertion, 1 deletion, 0 changes")
+	s.Contains(out, ".tags: 0 insertions, 0 deletions, 1 change")
+
+	for _, rng := range []string{"3..", "2017-01-01..", "..0", "nonsense.."} {
+		_, _, runErr := s.Run(main, []string{"diff", dsSpec + "@" + rng})
+		s.NotNil(runErr, rng)
+	}
+	_, _, runErr := s.Run(main, []string{"diff", "--per-commit", dsSpec + "@0..2"})
+	s.NotNil(runErr)
+}
@@ -6,22 +6,28 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/attic-labs/noms/cmd/util"
 	"github.com/attic-labs/noms/go/config"
 	"github.com/attic-labs/noms/go/d"
+	"github.com/attic-labs/noms/go/datas"
+	"github.com/attic-labs/noms/go/merge"
 	"github.com/attic-labs/noms/go/types"
 	"github.com/attic-labs/noms/go/util/verbose"
 	flag "github.com/juju/gnuflag"
 )
 
-var toDelete string
+var (
+	toDelete          string
+	strategiesDataset string
+)
 
 var nomsDs = &util.Command{
 	Run:       runDs,
-	UsageLine: "ds [<database> | -d <dataset>]",
+	UsageLine: "ds [<database> | -d <dataset> | --merge-strategies <dataset> [<pattern>=<strategy>...]]",
 	Short:     "Noms dataset management",
-	Long:      "See Spelling Objects at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the database and dataset arguments.",
+	Long:      "See Spelling Objects at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the database and dataset arguments.\n\nWith --merge-strategies, shows the merge strategies stored for a dataset or, if any are given, replaces them. Each strategy is written <pattern>=<strategy>, where <pattern> is a path in which .* matches any field and [*] any index or key, and <strategy> is one of counter, lww, max, min, union, ours or theirs. They're used by noms merge --policy=s.",
 	Flags:     setupDsFlags,
 	Nargs:     0,
 }
@@ -29,12 +35,36 @@ var nomsDs = &util.Command{
 func setupDsFlags() *flag.FlagSet {
 	dsFlagSet := flag.NewFlagSet("ds", flag.ExitOnError)
 	dsFlagSet.StringVar(&toDelete, "d", "", "dataset to delete")
+	dsFlagSet.StringVar(&strategiesDataset, "merge-strategies", "", "dataset whose merge strategies to show or replace")
 	verbose.RegisterVerboseFlags(dsFlagSet)
 	return dsFlagSet
 }
 
 func runDs(args []string) int {
 	cfg := config.NewResolver()
+	if strategiesDataset != "" {
+		db, ds, err := cfg.GetDataset(strategiesDataset)
+		d.CheckError(err)
+		defer db.Close()
+
+		if len(args) == 0 {
+			strategies, _, err := datas.GetMergeStrategies(ds)
+			d.CheckErrorNoUsage(err)
+			fmt.Print(strategies.String())
+			return 0
+		}
+		strategies := merge.Strategies{}
+		for _, arg := range args {
+			idx := strings.LastIndex(arg, "=")
+			if idx < 0 {
+				d.CheckErrorNoUsage(fmt.Errorf("Invalid merge strategy %s, must be <pattern>=<strategy>", arg))
+			}
+			strategies, err = strategies.Add(arg[:idx], arg[idx+1:])
+			d.CheckErrorNoUsage(err)
+		}
+		d.PanicIfError(datas.SetMergeStrategies(ds, strategies))
+		return 0
+	}
 	if toDelete != "" {
 		db, set, err := cfg.GetDataset(toDelete)
 		d.CheckError(err)
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
 	"os"
 	"strings"
@@ -46,7 +47,7 @@ var nomsLog = &util.Command{
 	Run:       runLog,
 	UsageLine: "log [options] <path-spec>",
 	Short:     "Displays the history of a path",
-	Long:      "Displays the history of a path. See Spelling Values at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the <path-spec> parameter.",
+	Long:      "Displays the history of a path. See Spelling Values at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the <path-spec> parameter.\n\n--author, --grep, --since, --until and --path only show the commits that match all of them. --author and --grep match the author and message fields of the commit meta, and --since and --until its date field, as written by noms commit. Commits without those fields don't match. With --path, a merge is only shown if the value at the path differs from that in each of its parents.\n\n--json and --format write each commit on a single line, without its diff, for scripts to read.\n\n--include, --exclude and --max-depth limit the diff shown for each commit, as they do for noms diff. --include and --exclude also limit the value shown with --show-value. Path globs are relative to <path-spec>.",
 	Flags:     setupLogFlags,
 	Nargs:     1,
 }
@@ -60,6 +61,15 @@ func setupLogFlags() *flag.FlagSet {
 	logFlagSet.BoolVar(&showGraph, "graph", false, "show ascii-based commit hierarchy on left side of output")
 	logFlagSet.BoolVar(&showValue, "show-value", false, "show commit value rather than diff information")
 	logFlagSet.StringVar(&tzName, "tz", "local", "display formatted date comments in specified timezone, must be: local or utc")
+	logFlagSet.StringVar(&logAuthor, "author", "", "only show commits whose author meta field matches this regular expression")
+	logFlagSet.StringVar(&logGrep, "grep", "", "only show commits whose message meta field matches this regular expression")
+	logFlagSet.StringVar(&logSince, "since", "", "only show commits whose date meta field is at or after this date, e.g. 2017-06-30 or 2017-06-30T17:00:00Z")
+	logFlagSet.StringVar(&logUntil, "until", "", "only show commits whose date meta field is at or before this date")
+	logFlagSet.StringVar(&logPath, "path", "", "only show commits that changed the value at this path, relative to <path-spec>")
+	logFlagSet.StringVar(&logFormat, "format", "", "write each commit using this Go template, e.g. '{{.Hash}} {{.Meta.message}}', over the fields Hash, Parents and Meta")
+	logFlagSet.BoolVar(&logJSON, "json", false, "write each commit as a JSON object on its own line, with the fields hash, parents and meta")
+	registerPathFilterFlags(logFlagSet)
+	registerMaxDepthFlag(logFlagSet)
 	outputpager.RegisterOutputpagerFlags(logFlagSet)
 	verbose.RegisterVerboseFlags(logFlagSet)
 	return logFlagSet
@@ -96,25 +106,45 @@ func runLog(args []string) int {
 		d.CheckError(fmt.Errorf("%s does not reference a Commit object", args[0]))
 	}
 
+	filter, err := newCommitFilter(path)
+	d.CheckErrorNoUsage(err)
+	writeCommit, err := newCommitWriter()
+	d.CheckErrorNoUsage(err)
+	if showGraph && !filter.isEmpty() {
+		d.CheckErrorNoUsage(errors.New("--graph can't be used with --author, --grep, --since, --until or --path"))
+	}
+	if writeCommit != nil {
+		// Catch errors in --format templates before writing anything.
+		d.CheckErrorNoUsage(writeCommit(ioutil.Discard, origCommit))
+	}
+
 	iter := NewCommitIterator(database, origCommit)
 	displayed := 0
 	if maxCommits <= 0 {
 		maxCommits = math.MaxInt32
 	}
 
-	bytesChan := make(chan chan []byte, parallelism)
+	bytesChan := make(chan chan commitOutput, parallelism)
 
 	var done = false
 
 	go func() {
 		for ln, ok := iter.Next(); !done && ok && displayed < maxCommits; ln, ok = iter.Next() {
-			ch := make(chan []byte)
+			if !filter.matches(ln.commit, database) {
+				continue
+			}
+			ch := make(chan commitOutput)
 			bytesChan <- ch
 
-			go func(ch chan []byte, node LogNode) {
+			go func(ch chan commitOutput, node LogNode) {
 				buff := &bytes.Buffer{}
-				printCommit(node, path, buff, database, tz)
-				ch <- buff.Bytes()
+				var err error
+				if writeCommit != nil {
+					err = writeCommit(buff, node.commit)
+				} else {
+					printCommit(node, path, buff, database, tz)
+				}
+				ch <- commitOutput{buff.Bytes(), err}
 			}(ch, ln)
 
 			displayed++
@@ -122,23 +152,38 @@ func runLog(args []string) int {
 		close(bytesChan)
 	}()
 
-	pgr := outputpager.Start()
-	defer pgr.Stop()
+	err = func() error {
+		pgr := outputpager.Start()
+		defer pgr.Stop()
 
-	for ch := range bytesChan {
-		commitBuff := <-ch
-		_, err := io.Copy(pgr.Writer, bytes.NewReader(commitBuff))
-		if err != nil {
+		for ch := range bytesChan {
+			out := <-ch
+			if out.err == nil {
+				_, err := io.Copy(pgr.Writer, bytes.NewReader(out.bytes))
+				if err == nil {
+					continue
+				}
+			}
 			done = true
 			for range bytesChan {
 				// drain the output
 			}
+			return out.err
 		}
-	}
+		return nil
+	}()
+	d.CheckErrorNoUsage(err)
 
 	return 0
 }
 
+// commitOutput is the output for one commit in the log, or the error that
+// writing it failed with.
+type commitOutput struct {
+	bytes []byte
+	err   error
+}
+
 // Prints the information for one commit in the log, including ascii graph on left side of commits if
 // -graph arg is true.
 func printCommit(node LogNode, path types.Path, w io.Writer, db datas.Database, tz *time.Location) (err error) {
@@ -291,6 +336,9 @@ func writeCommitLines(node LogNode, path types.Path, maxLines, lineno int, w io.
 	mlw := &writers.MaxLineWriter{Dest: w, MaxLines: uint32(maxLines), NumLines: uint32(lineno)}
 	pw := &writers.PrefixWriter{Dest: mlw, PrefixFunc: genPrefix, NeedsPrefix: true, NumLines: uint32(lineno)}
 	v := path.Resolve(node.commit, db)
+	if v != nil {
+		v = pathFilter().Project(v, db)
+	}
 	if v == nil {
 		pw.Write([]byte("<nil>\n"))
 	} else {
@@ -346,7 +394,7 @@ func writeDiffLines(node LogNode, path types.Path, db datas.Database, maxLines,
 	}
 
 	if old != nil && neu != nil {
-		err = diff.PrintDiff(pw, old, neu, true)
+		err = diff.PrintDiffWithFilter(pw, old, neu, true, diffFilter())
 		mlw.MaxLines = 0
 		if err != nil {
 			d.PanicIfNotType(err, writers.MaxLinesErr)
@@ -0,0 +1,178 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"text/template"
+	"time"
+
+	"github.com/attic-labs/noms/go/datas"
+	"github.com/attic-labs/noms/go/diff"
+	"github.com/attic-labs/noms/go/types"
+)
+
+var (
+	logAuthor string
+	logGrep   string
+	logSince  string
+	logUntil  string
+	logPath   string
+	logFormat string
+	logJSON   bool
+)
+
+// commitFilter selects the commits that noms log shows.
+type commitFilter struct {
+	author, grep *regexp.Regexp
+	since, until time.Time
+	// The values at path must differ between a commit and each of its
+	// parents, if path isn't nil.
+	path types.Path
+}
+
+// newCommitFilter returns the commitFilter described by the log flags.
+// |basePath| is the path of the value being logged, within each commit,
+// which --path is relative to.
+func newCommitFilter(basePath types.Path) (f commitFilter, err error) {
+	if logAuthor != "" {
+		if f.author, err = regexp.Compile(logAuthor); err != nil {
+			return
+		}
+	}
+	if logGrep != "" {
+		if f.grep, err = regexp.Compile(logGrep); err != nil {
+			return
+		}
+	}
+	for _, date := range []struct {
+		str string
+		t   *time.Time
+	}{{logSince, &f.since}, {logUntil, &f.until}} {
+		if date.str == "" {
+			continue
+		}
+		t, ok := parseRevisionDate(date.str)
+		if !ok {
+			return f, fmt.Errorf("Invalid date: %s", date.str)
+		}
+		*date.t = t
+	}
+	if logPath != "" {
+		p, err := types.ParsePath(logPath)
+		if err != nil {
+			return f, err
+		}
+		f.path = append(append(types.Path{}, basePath...), p...)
+	}
+	return
+}
+
+// isEmpty returns true if |f| selects every commit.
+func (f commitFilter) isEmpty() bool {
+	return f.author == nil && f.grep == nil && f.since.IsZero() && f.until.IsZero() && f.path == nil
+}
+
+// matches returns true if |f| selects |commit|.
+func (f commitFilter) matches(commit types.Struct, vr types.ValueReader) bool {
+	if f.author != nil && !f.author.MatchString(metaString(commit, "author")) {
+		return false
+	}
+	if f.grep != nil && !f.grep.MatchString(metaString(commit, "message")) {
+		return false
+	}
+	if !f.since.IsZero() || !f.until.IsZero() {
+		date, ok := commitTime(commit)
+		if !ok || (!f.since.IsZero() && date.Before(f.since)) || (!f.until.IsZero() && date.After(f.until)) {
+			return false
+		}
+	}
+	if f.path != nil {
+		// Like git, a merge is only shown if the value differs from that in
+		// every parent.
+		v := f.path.Resolve(commit, vr)
+		parents := commit.Get(datas.ParentsField).(types.Set)
+		unchanged := parents.Empty() && v == nil
+		parents.IterAll(func(p types.Value) {
+			pv := f.path.Resolve(p.(types.Ref).TargetValue(vr), vr)
+			unchanged = unchanged || v == nil && pv == nil || v != nil && pv != nil && v.Equals(pv)
+		})
+		if unchanged {
+			return false
+		}
+	}
+	return true
+}
+
+// metaString returns the String field |name| of the meta of |commit|, or ""
+// if there isn't one.
+func metaString(commit types.Struct, name string) string {
+	if meta, ok := commit.Get(datas.MetaField).(types.Struct); ok {
+		if s, ok := meta.MaybeGet(name); ok {
+			if s, ok := s.(types.String); ok {
+				return string(s)
+			}
+		}
+	}
+	return ""
+}
+
+// logEntry is the form in which noms log --json writes, and --format
+// templates see, a commit.
+type logEntry struct {
+	Hash    string                 `json:"hash"`
+	Parents []string               `json:"parents"`
+	Meta    map[string]interface{} `json:"meta"`
+}
+
+func newLogEntry(commit types.Struct) logEntry {
+	entry := logEntry{Hash: commit.Hash().String(), Parents: []string{}, Meta: map[string]interface{}{}}
+	for _, r := range commitRefsFromSet(commit.Get(datas.ParentsField).(types.Set)) {
+		entry.Parents = append(entry.Parents, r.TargetHash().String())
+	}
+	if meta, ok := commit.Get(datas.MetaField).(types.Struct); ok {
+		meta.IterFields(func(name string, v types.Value) {
+			if j, err := diff.ValueToJSON(v); err == nil {
+				entry.Meta[name] = j
+			} else {
+				entry.Meta[name] = types.EncodedValue(v)
+			}
+		})
+	}
+	return entry
+}
+
+// newCommitWriter returns a function that writes a commit as --json or
+// --format says to, or nil if neither was given.
+func newCommitWriter() (func(w io.Writer, commit types.Struct) error, error) {
+	if logJSON && logFormat != "" {
+		return nil, fmt.Errorf("--json and --format can't both be used")
+	}
+	if logJSON {
+		return func(w io.Writer, commit types.Struct) error {
+			return json.NewEncoder(w).Encode(newLogEntry(commit))
+		}, nil
+	}
+	if logFormat != "" {
+		tmpl, err := template.New("format").Parse(logFormat)
+		if err != nil {
+			return nil, err
+		}
+		return func(w io.Writer, commit types.Struct) error {
+			buff := &bytes.Buffer{}
+			if err := tmpl.Execute(buff, newLogEntry(commit)); err != nil {
+				return err
+			}
+			buff.WriteByte('\n')
+			_, err := io.Copy(w, buff)
+			return err
+		}, nil
+	}
+	return nil, nil
+}
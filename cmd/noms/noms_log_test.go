@@ -5,6 +5,7 @@
 package main
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/attic-labs/noms/go/datas"
@@ -368,3 +369,63 @@ const (
 
 	pathDiff = "oki4cv7vkh743rccese3r3omf6l6mao4\nParent: lca4vejkm0iqsk7ok5322pt61u4otn6q\n-   1\n+   2\n\nlca4vejkm0iqsk7ok5322pt61u4otn6q\nParent: u42pi8ukgkvpoi6n7d46cklske41oguf\n-   0\n+   1\n\nu42pi8ukgkvpoi6n7d46cklske41oguf\nParent: hgmlqmsnrb3sp9jqc6mas8kusa1trrs2\nold (#hgmlqmsnrb3sp9jqc6mas8kusa1trrs2.value.bar) not found\n\nhgmlqmsnrb3sp9jqc6mas8kusa1trrs2\nParent: hffiuecdpoq622tamm3nvungeca99ohl\nnew (#hgmlqmsnrb3sp9jqc6mas8kusa1trrs2.value.bar) not found\nold (#hffiuecdpoq622tamm3nvungeca99ohl.value.bar) not found\n\nhffiuecdpoq622tamm3nvungeca99ohl\nParent: None\n\n"
 )
+
+func (s *nomsLogTestSuite) TestNomsLogFilters() {
+	str := spec.CreateValueSpecString("nbs", s.DBDir, "filterTest")
+	sp, err := spec.ForDataset(str)
+	s.NoError(err)
+	defer sp.Close()
+
+	db := sp.GetDatabase()
+	ds := sp.GetDataset()
+	commits := []struct {
+		author, message, date string
+		a, b                  float64
+	}{
+		{"alice", "add data", "2017-06-26T12:00:00Z", 1, 1},
+		{"bob", "fix b", "2017-06-28T12:00:00Z", 1, 2},
+		{"alice", "fix a", "2017-06-30T12:00:00Z", 2, 2},
+	}
+	for _, c := range commits {
+		meta := types.NewStruct("Meta", types.StructData{
+			"author":  types.String(c.author),
+			"message": types.String(c.message),
+			"date":    types.String(c.date),
+		})
+		v := types.NewStruct("", types.StructData{"a": types.Number(c.a), "b": types.Number(c.b)})
+		ds, err = db.Commit(ds, v, datas.CommitOptions{Meta: meta})
+		s.NoError(err)
+	}
+
+	messages := func(args ...string) string {
+		out, _ := s.MustRun(main, append(append([]string{"log", "--format", "{{.Meta.message}}"}, args...), str))
+		return out
+	}
+	s.Equal("fix a\nfix b\nadd data\n", messages())
+	s.Equal("fix a\nadd data\n", messages("--author", "^al"))
+	s.Equal("fix a\nfix b\n", messages("--grep", "fix"))
+	s.Equal("fix b\n", messages("--since", "2017-06-27", "--until", "2017-06-29"))
+	s.Equal("fix a\nadd data\n", messages("--path", ".a"))
+	s.Equal("fix a\n", messages("--path", ".a", "-n", "1"))
+
+	out, _ := s.MustRun(main, []string{"log", "--json", "--grep", "fix b", str})
+	var entry struct {
+		Hash    string
+		Parents []string
+		Meta    map[string]string
+	}
+	s.NoError(json.Unmarshal([]byte(out), &entry))
+	s.Equal("bob", entry.Meta["author"])
+	s.Equal("2017-06-28T12:00:00Z", entry.Meta["date"])
+	s.Len(entry.Parents, 1)
+	out, _ = s.MustRun(main, []string{"log", "--format", "{{.Hash}} {{len .Parents}}", str})
+	s.Contains(out, entry.Hash+" 1\n")
+
+	// The first commit has no parents, so indexing them fails only once the
+	// log reaches it.
+	for _, args := range [][]string{{"--since", "yesterday"}, {"--grep", "("}, {"--json", "--format", "x"}, {"--graph", "--grep", "fix"}, {"--format", "{{"}, {"--format", "{{.Nope}}"}, {"--format", "{{index .Parents 0}}"}} {
+		_, stderr, runErr := s.Run(main, append(append([]string{"log"}, args...), str))
+		s.NotNil(runErr, "%v", args)
+		s.Contains(stderr, "error: ", "%v", args)
+	}
+}
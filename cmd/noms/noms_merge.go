@@ -9,6 +9,7 @@ import (
 	"io"
 	"os"
 	"regexp"
+	"strings"
 
 	"github.com/attic-labs/noms/cmd/util"
 	"github.com/attic-labs/noms/go/config"
@@ -22,22 +23,52 @@ import (
 )
 
 var (
-	resolver string
+	resolver         string
+	collectConflicts bool
+	continueMerge    bool
+	abortMerge       bool
+	resolutions      resolutionList
 
 	nomsMerge = &util.Command{
 		Run:       runMerge,
-		UsageLine: "merge [options] <database> <left-dataset-name> <right-dataset-name> <output-dataset-name>",
-		Short:     "Merges and commits the head values of two named datasets",
-		Long:      "See Spelling Objects at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the database argument.\nYu must provide a working database and the names of two Datasets you want to merge. The values at the heads of these Datasets will be merged, put into a new Commit object, and set as the Head of the third provided Dataset name.",
-		Flags:     setupMergeFlags,
-		Nargs:     1, // if absolute-path not present we read it from stdin
+		UsageLine: "merge [options] <database> <left-dataset-name> <right-dataset-name> [<dataset-name>...] <output-dataset-name>",
+		Short:     "Merges and commits the head values of two or more named datasets",
+		Long: "See Spelling Objects at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the database argument.\nYu must provide a working database and the names of two or more Datasets you want to merge. The values at the heads of these Datasets will be merged, put into a new Commit object whose parents are all of their heads, and set as the Head of the last provided Dataset name." +
+			"\n\nWhen more than two Datasets are given, each is merged in turn into the result of merging those before it. When histories criss-cross, so that there are several best common ancestors, they are first merged into a virtual ancestor." +
+			"\n\nWith --all-conflicts, the merge doesn't stop at the first conflict. Instead, every conflict is reported, and the merge is saved in the database, in the dataset " + mergeStatePrefix + "<output-dataset-name>, until it's finished or abandoned:" +
+			"\n\n  noms merge --resolve <path>=<ours|theirs|ancestor|remove> <database> <output-dataset-name>\n    resolves the conflict at <path> by choosing a value. --resolve may be repeated." +
+			"\n  noms merge --continue <database> <output-dataset-name>\n    commits the merge to the output dataset, once every conflict has been resolved." +
+			"\n  noms merge --abort <database> <output-dataset-name>\n    abandons the merge.",
+		Flags: setupMergeFlags,
+		Nargs: 1, // if absolute-path not present we read it from stdin
 	}
 	datasetRe = regexp.MustCompile("^" + datas.DatasetRe.String() + "$")
 )
 
+// mergeStatePrefix is prepended to the name of the output dataset of a merge
+// to name the dataset in which its merge.State is kept.
+const mergeStatePrefix = "_merge/"
+
+// resolutionList collects the values of the repeatable --resolve flag.
+type resolutionList []string
+
+func (rl *resolutionList) String() string {
+	return strings.Join(*rl, ",")
+}
+
+func (rl *resolutionList) Set(value string) error {
+	*rl = append(*rl, value)
+	return nil
+}
+
 func setupMergeFlags() *flag.FlagSet {
 	commitFlagSet := flag.NewFlagSet("merge", flag.ExitOnError)
-	commitFlagSet.StringVar(&resolver, "policy", "n", "conflict resolution policy for merging. Defaults to 'n', which means no resolution strategy will be applied. Supported values are 'l' (left), 'r' (right) and 'p' (prompt). 'prompt' will bring up a simple command-line prompt allowing you to resolve conflicts by choosing between 'l' or 'r' on a case-by-case basis.")
+	commitFlagSet.StringVar(&resolver, "policy", "n", "conflict resolution policy for merging. Defaults to 'n', which means no resolution strategy will be applied. Supported values are 'l' (left), 'r' (right), 'p' (prompt) and 's' (strategies). 'prompt' will bring up a simple command-line prompt allowing you to resolve conflicts by choosing between 'l' or 'r' on a case-by-case basis. 'strategies' merges using the merge strategies stored for the output dataset (see noms ds --merge-strategies).")
+	commitFlagSet.BoolVar(&collectConflicts, "all-conflicts", false, "report every conflict, rather than stopping at the first, and save the merge so that they can be resolved")
+	commitFlagSet.BoolVar(&continueMerge, "continue", false, "commit a saved merge whose conflicts have all been resolved")
+	commitFlagSet.BoolVar(&abortMerge, "abort", false, "abandon a saved merge")
+	resolutions = nil
+	commitFlagSet.Var(&resolutions, "resolve", "resolve a conflict in a saved merge, given as <path>=<ours|theirs|ancestor|remove>")
 	verbose.RegisterVerboseFlags(commitFlagSet)
 	return commitFlagSet
 }
@@ -51,67 +82,220 @@ func checkIfTrue(b bool, format string, args ...interface{}) {
 func runMerge(args []string) int {
 	cfg := config.NewResolver()
 
-	if len(args) != 4 {
+	if continueMerge || abortMerge || len(resolutions) > 0 {
+		return runSavedMerge(cfg, args)
+	}
+	if len(args) < 4 {
 		d.CheckErrorNoUsage(fmt.Errorf("Incorrect number of arguments"))
 	}
 	db, err := cfg.GetDatabase(args[0])
 	d.CheckError(err)
 	defer db.Close()
 
-	leftDS, rightDS, outDS := resolveDatasets(db, args[1], args[2], args[3])
-	left, right, ancestor := getMergeCandidates(db, leftDS, rightDS)
-	policy := decidePolicy(resolver)
+	inDSs, outDS := resolveDatasets(db, args[1:len(args)-1], args[len(args)-1])
+	heads := getMergeHeads(inDSs)
+	useStrategies := resolver == "s" || resolver == "S"
 	pc := newMergeProgressChan()
-	merged, err := policy(left, right, ancestor, db, pc)
+
+	if collectConflicts {
+		checkIfTrue(useStrategies, "--all-conflicts can't be used with --policy=s")
+		checkIfTrue(len(inDSs) > 2, "--all-conflicts can only merge two datasets")
+		leftDS, rightDS := inDSs[0], inDSs[1]
+		left, right, ancestor := getMergeCandidates(db, leftDS, rightDS)
+		stateDS := db.GetDataset(mergeStatePrefix + outDS.ID())
+		checkIfTrue(stateDS.HasHead(), "A merge into %s is already in progress. Use --continue or --abort to finish it.", outDS.ID())
+		merged, conflicts := merge.ThreeWayCollect(left, right, ancestor, db, decideResolveFunc(resolver), pc)
+		close(pc)
+		if len(conflicts) > 0 {
+			state := merge.NewState(leftDS.HeadRef(), rightDS.HeadRef(), merged, conflicts)
+			_, err = db.CommitValue(stateDS, state.Marshal(db))
+			d.PanicIfError(err)
+			printConflicts(os.Stdout, state)
+			fmt.Fprintf(os.Stdout, "\nResolve the conflicts with --resolve, then run noms merge --continue.\n")
+			return 1
+		}
+		commitMerge(db, outDS, merged, heads...)
+		return 0
+	}
+
+	var policy merge.Policy
+	if useStrategies {
+		checkIfTrue(len(inDSs) > 2, "--policy=s can only merge two datasets")
+		strategies, _, err := datas.GetMergeStrategies(outDS)
+		d.CheckErrorNoUsage(err)
+		policy = strategies.Policy(inDSs[0].Head().Get(datas.MetaField).(types.Struct), inDSs[1].Head().Get(datas.MetaField).(types.Struct), merge.None)
+	} else {
+		policy = decidePolicy(resolver)
+	}
+	merged, err := datas.MergeCommits(heads, db, policy, pc)
+	if err == datas.ErrNoCommonAncestor {
+		err = fmt.Errorf("Datasets %s have no common ancestor", datasetNames(inDSs))
+	}
 	d.CheckErrorNoUsage(err)
 	close(pc)
 
-	_, err = db.SetHead(outDS, db.WriteValue(datas.NewCommit(merged, types.NewSet(db, leftDS.HeadRef(), rightDS.HeadRef()), types.EmptyStruct)))
+	commitMerge(db, outDS, merged, heads...)
+	return 0
+}
+
+func commitMerge(db datas.Database, outDS datas.Dataset, merged types.Value, parents ...types.Ref) {
+	se := types.NewSet(db).Edit()
+	for _, p := range parents {
+		se.Insert(p)
+	}
+	_, err := db.SetHead(outDS, db.WriteValue(datas.NewCommit(merged, se.Set(), types.EmptyStruct)))
 	d.PanicIfError(err)
 	if !verbose.Quiet() {
 		status.Printf("Done")
 		status.Done()
 	}
+}
+
+// runSavedMerge handles --resolve, --continue and --abort, which all operate
+// on the merge.State saved by a previous merge with --all-conflicts.
+func runSavedMerge(cfg *config.Resolver, args []string) int {
+	checkIfTrue(continueMerge && abortMerge, "--continue and --abort can't be used together")
+	checkIfTrue(len(resolutions) > 0 && abortMerge, "--resolve and --abort can't be used together")
+	if len(args) != 2 {
+		d.CheckErrorNoUsage(fmt.Errorf("Incorrect number of arguments"))
+	}
+	db, err := cfg.GetDatabase(args[0])
+	d.CheckError(err)
+	defer db.Close()
+
+	outName := args[1]
+	if !datasetRe.MatchString(outName) {
+		d.CheckErrorNoUsage(fmt.Errorf("Invalid dataset %s, must match %s", outName, datas.DatasetRe.String()))
+	}
+	stateDS := db.GetDataset(mergeStatePrefix + outName)
+	checkIfTrue(!stateDS.HasHead(), "No merge into %s is in progress", outName)
+
+	if abortMerge {
+		_, err = db.Delete(stateDS)
+		d.PanicIfError(err)
+		return 0
+	}
+
+	state, err := merge.UnmarshalState(stateDS.HeadValue())
+	d.CheckErrorNoUsage(err)
+	if len(resolutions) > 0 {
+		for _, r := range resolutions {
+			state = resolveConflict(db, state, r)
+		}
+		stateDS, err = db.CommitValue(stateDS, state.Marshal(db))
+		d.PanicIfError(err)
+	}
+	if !continueMerge {
+		if !state.Done() {
+			printConflicts(os.Stdout, state)
+		}
+		return 0
+	}
+
+	if !state.Done() {
+		printConflicts(os.Stdout, state)
+		d.CheckErrorNoUsage(fmt.Errorf("%d conflicts remain unresolved", len(state.Conflicts)))
+	}
+	checkIfTrue(state.Merged == nil, "Nothing to commit: the merged value was removed")
+	commitMerge(db, db.GetDataset(outName), state.Merged, state.Ours, state.Theirs)
+	_, err = db.Delete(stateDS)
+	d.PanicIfError(err)
 	return 0
 }
 
-func resolveDatasets(db datas.Database, leftName, rightName, outName string) (leftDS, rightDS, outDS datas.Dataset) {
+// resolveConflict applies a single --resolve flag value, of the form
+// <path>=<ours|theirs|ancestor|remove>, to |state|.
+func resolveConflict(vrw types.ValueReadWriter, state merge.State, resolution string) merge.State {
+	idx := strings.LastIndex(resolution, "=")
+	checkIfTrue(idx < 0, "Invalid resolution %s, must be <path>=<ours|theirs|ancestor|remove>", resolution)
+	pathStr, choice := resolution[:idx], resolution[idx+1:]
+
+	path := types.Path{}
+	if pathStr != "" {
+		var err error
+		path, err = types.ParsePath(pathStr)
+		d.CheckErrorNoUsage(err)
+	}
+	c, ok := state.Conflict(path)
+	checkIfTrue(!ok, "No conflict at %s", pathStr)
+
+	var v types.Value
+	switch choice {
+	case "ours":
+		v = c.Ours
+	case "theirs":
+		v = c.Theirs
+	case "ancestor":
+		v = c.Ancestor
+	case "remove":
+	default:
+		d.CheckErrorNoUsage(fmt.Errorf("Invalid resolution %s, must be <path>=<ours|theirs|ancestor|remove>", resolution))
+	}
+	state, err := state.Resolve(path, v, vrw)
+	d.CheckErrorNoUsage(err)
+	return state
+}
+
+func printConflicts(w io.Writer, state merge.State) {
+	describe := func(v types.Value) string {
+		if v == nil {
+			return "(none)"
+		}
+		return types.EncodedValueMaxLines(v, 5)
+	}
+	for _, c := range state.Conflicts {
+		fmt.Fprintf(w, "Conflict at %s\n", c.Path.String())
+		fmt.Fprintf(w, "  ancestor: %s\n", describe(c.Ancestor))
+		fmt.Fprintf(w, "  ours:     %s\n", describe(c.Ours))
+		fmt.Fprintf(w, "  theirs:   %s\n", describe(c.Theirs))
+	}
+}
+
+func resolveDatasets(db datas.Database, inNames []string, outName string) (inDSs []datas.Dataset, outDS datas.Dataset) {
 	makeDS := func(dsName string) datas.Dataset {
 		if !datasetRe.MatchString(dsName) {
 			d.CheckErrorNoUsage(fmt.Errorf("Invalid dataset %s, must match %s", dsName, datas.DatasetRe.String()))
 		}
 		return db.GetDataset(dsName)
 	}
-	leftDS = makeDS(leftName)
-	rightDS = makeDS(rightName)
+	for _, name := range inNames {
+		inDSs = append(inDSs, makeDS(name))
+	}
 	outDS = makeDS(outName)
 	return
 }
 
-func getMergeCandidates(db datas.Database, leftDS, rightDS datas.Dataset) (left, right, ancestor types.Value) {
-	leftRef, ok := leftDS.MaybeHeadRef()
-	checkIfTrue(!ok, "Dataset %s has no data", leftDS.ID())
-	rightRef, ok := rightDS.MaybeHeadRef()
-	checkIfTrue(!ok, "Dataset %s has no data", rightDS.ID())
-	ancestorCommit, ok := getCommonAncestor(leftRef, rightRef, db)
-	checkIfTrue(!ok, "Datasets %s and %s have no common ancestor", leftDS.ID(), rightDS.ID())
+func getMergeHeads(dss []datas.Dataset) []types.Ref {
+	heads := make([]types.Ref, len(dss))
+	for i, ds := range dss {
+		head, ok := ds.MaybeHeadRef()
+		checkIfTrue(!ok, "Dataset %s has no data", ds.ID())
+		heads[i] = head
+	}
+	return heads
+}
 
-	return leftDS.HeadValue(), rightDS.HeadValue(), ancestorCommit.Get(datas.ValueField)
+// getMergeCandidates returns the values to three-way merge to merge |leftDS|
+// and |rightDS|. Where there are several best common ancestors, |ancestor| is
+// the virtual ancestor made by merging them without resolving any conflicts.
+func getMergeCandidates(db datas.Database, leftDS, rightDS datas.Dataset) (left, right, ancestor types.Value) {
+	ancestor, ok, err := datas.FindMergeBase(leftDS.HeadRef(), rightDS.HeadRef(), db, merge.NewThreeWay(merge.None))
+	d.CheckErrorNoUsage(err)
+	checkIfTrue(!ok, "Datasets %s have no common ancestor", datasetNames([]datas.Dataset{leftDS, rightDS}))
+	return leftDS.HeadValue(), rightDS.HeadValue(), ancestor
 }
 
-func getCommonAncestor(r1, r2 types.Ref, vr types.ValueReader) (a types.Struct, found bool) {
-	aRef, found := datas.FindCommonAncestor(r1, r2, vr)
-	if !found {
-		return
-	}
-	v := vr.ReadValue(aRef.TargetHash())
-	if v == nil {
-		panic(aRef.TargetHash().String() + " not found")
+// datasetNames lists the IDs of |dss| for use in a message, e.g. "a, b and c".
+func datasetNames(dss []datas.Dataset) string {
+	names := make([]string, len(dss))
+	for i, ds := range dss {
+		names[i] = ds.ID()
 	}
-	if !datas.IsCommit(v) {
-		panic("Not a commit: " + types.EncodedValueMaxLines(v, 10) + "  ...")
+	last := len(names) - 1
+	if last == 0 {
+		return names[0]
 	}
-	return v.(types.Struct), true
+	return strings.Join(names[:last], ", ") + " and " + names[last]
 }
 
 func newMergeProgressChan() chan struct{} {
@@ -129,7 +313,10 @@ func newMergeProgressChan() chan struct{} {
 }
 
 func decidePolicy(policy string) merge.Policy {
-	var resolve merge.ResolveFunc
+	return merge.NewThreeWay(decideResolveFunc(policy))
+}
+
+func decideResolveFunc(policy string) (resolve merge.ResolveFunc) {
 	switch policy {
 	case "n", "N":
 		resolve = merge.None
@@ -144,7 +331,7 @@ func decidePolicy(policy string) merge.Policy {
 	default:
 		d.CheckErrorNoUsage(fmt.Errorf("Unsupported merge policy: %s. Choices are n, l, r and a.", policy))
 	}
-	return merge.NewThreeWay(resolve)
+	return
 }
 
 func cliResolve(in io.Reader, out io.Writer, aType, bType types.DiffChangeType, a, b types.Value, path types.Path) (change types.DiffChangeType, merged types.Value, ok bool) {
@@ -180,6 +180,125 @@ func (s *nomsMergeTestSuite) TestNomsMerge_Conflict() {
 	s.Panics(func() { s.MustRun(main, []string{"merge", s.DBDir, left, right, "output"}) })
 }
 
+func (s *nomsMergeTestSuite) TestNomsMerge_AllConflicts() {
+	left, right := "left", "right"
+	parentSpec := s.spec("parent")
+	defer parentSpec.Close()
+	leftSpec := s.spec(left)
+	defer leftSpec.Close()
+	rightSpec := s.spec(right)
+	defer rightSpec.Close()
+
+	p := s.setupMergeDataset(parentSpec, types.StructData{"num": types.Number(42), "str": types.String("foo"), "other": types.Number(1)}, types.NewSet(parentSpec.GetDatabase()))
+	l := s.setupMergeDataset(leftSpec, types.StructData{"num": types.Number(43), "str": types.String("bar"), "other": types.Number(1)}, types.NewSet(leftSpec.GetDatabase(), p))
+	r := s.setupMergeDataset(rightSpec, types.StructData{"num": types.Number(44), "str": types.String("baz"), "other": types.Number(2)}, types.NewSet(rightSpec.GetDatabase(), p))
+
+	output := "output"
+	stdout, _, err := s.Run(main, []string{"merge", "--all-conflicts", s.DBDir, left, right, output})
+	s.NotNil(err)
+	s.Contains(stdout, "Conflict at .num\n  ancestor: 42\n  ours:     43\n  theirs:   44\n")
+	s.Contains(stdout, "Conflict at .str\n")
+
+	// A second merge into the same dataset has to wait for this one.
+	_, stderr, err := s.Run(main, []string{"merge", "--all-conflicts", s.DBDir, left, right, output})
+	s.NotNil(err)
+	s.Contains(stderr, "already in progress")
+
+	_, stderr, err = s.Run(main, []string{"merge", "--continue", s.DBDir, output})
+	s.NotNil(err)
+	s.Contains(stderr, "2 conflicts remain unresolved")
+
+	stdout, _ = s.MustRun(main, []string{"merge", "--resolve", ".num=theirs", s.DBDir, output})
+	s.NotContains(stdout, "Conflict at .num")
+	s.Contains(stdout, "Conflict at .str")
+	s.MustRun(main, []string{"merge", "--resolve", ".str=ours", "--continue", s.DBDir, output})
+
+	expected := types.NewStruct("", types.StructData{"num": types.Number(44), "str": types.String("bar"), "other": types.Number(2)})
+	s.validateDataset(output, expected, l, r)
+
+	_, stderr, err = s.Run(main, []string{"merge", "--abort", s.DBDir, output})
+	s.NotNil(err)
+	s.Contains(stderr, "No merge into output is in progress")
+}
+
+func (s *nomsMergeTestSuite) TestNomsMerge_Abort() {
+	left, right := "left", "right"
+	parentSpec := s.spec("parent")
+	defer parentSpec.Close()
+	leftSpec := s.spec(left)
+	defer leftSpec.Close()
+	rightSpec := s.spec(right)
+	defer rightSpec.Close()
+
+	p := s.setupMergeDataset(parentSpec, types.StructData{"num": types.Number(42)}, types.NewSet(parentSpec.GetDatabase()))
+	s.setupMergeDataset(leftSpec, types.StructData{"num": types.Number(43)}, types.NewSet(leftSpec.GetDatabase(), p))
+	s.setupMergeDataset(rightSpec, types.StructData{"num": types.Number(44)}, types.NewSet(rightSpec.GetDatabase(), p))
+
+	_, _, err := s.Run(main, []string{"merge", "--all-conflicts", s.DBDir, left, right, "output"})
+	s.NotNil(err)
+	_, stderr, err := s.Run(main, []string{"merge", "--resolve", ".nope=ours", s.DBDir, "output"})
+	s.NotNil(err)
+	s.Contains(stderr, "No conflict at .nope")
+	s.MustRun(main, []string{"merge", "--abort", s.DBDir, "output"})
+
+	sp := s.spec("output")
+	defer sp.Close()
+	s.False(sp.GetDataset().HasHead())
+	s.False(sp.GetDatabase().GetDataset(mergeStatePrefix + "output").HasHead())
+}
+
+func (s *nomsMergeTestSuite) TestNomsMerge_Strategies() {
+	left, right := "left", "right"
+	parentSpec := s.spec("parent")
+	defer parentSpec.Close()
+	leftSpec := s.spec(left)
+	defer leftSpec.Close()
+	rightSpec := s.spec(right)
+	defer rightSpec.Close()
+
+	p := s.setupMergeDataset(parentSpec, types.StructData{"num": types.Number(42), "str": types.String("foo")}, types.NewSet(parentSpec.GetDatabase()))
+	l := s.setupMergeDataset(leftSpec, types.StructData{"num": types.Number(43), "str": types.String("bar")}, types.NewSet(leftSpec.GetDatabase(), p))
+	r := s.setupMergeDataset(rightSpec, types.StructData{"num": types.Number(44), "str": types.String("baz")}, types.NewSet(rightSpec.GetDatabase(), p))
+
+	output := "output"
+	outSpec := spec.CreateValueSpecString("nbs", s.DBDir, output)
+	s.MustRun(main, []string{"ds", "--merge-strategies", outSpec, ".num=counter", ".str=max"})
+	stdout, _ := s.MustRun(main, []string{"ds", "--merge-strategies", outSpec})
+	s.Equal(".num=counter\n.str=max\n", stdout)
+
+	_, stderr, err := s.Run(main, []string{"ds", "--merge-strategies", outSpec, ".num=sum"})
+	s.NotNil(err)
+	s.Contains(stderr, "Unknown merge strategy: sum")
+
+	s.MustRun(main, []string{"merge", "--policy=s", s.DBDir, left, right, output})
+	expected := types.NewStruct("", types.StructData{"num": types.Number(45), "str": types.String("baz")})
+	s.validateDataset(output, expected, l, r)
+}
+
+func (s *nomsMergeTestSuite) TestNomsMerge_Octopus() {
+	parentSpec := s.spec("parent")
+	defer parentSpec.Close()
+	specs := map[string]spec.Spec{}
+	for _, name := range []string{"a", "b", "c"} {
+		specs[name] = s.spec(name)
+		defer specs[name].Close()
+	}
+
+	p := s.setupMergeDataset(parentSpec, types.StructData{"a": types.Number(1), "b": types.Number(1), "c": types.Number(1)}, types.NewSet(parentSpec.GetDatabase()))
+	a := s.setupMergeDataset(specs["a"], types.StructData{"a": types.Number(2), "b": types.Number(1), "c": types.Number(1)}, types.NewSet(specs["a"].GetDatabase(), p))
+	b := s.setupMergeDataset(specs["b"], types.StructData{"a": types.Number(1), "b": types.Number(2), "c": types.Number(1)}, types.NewSet(specs["b"].GetDatabase(), p))
+	c := s.setupMergeDataset(specs["c"], types.StructData{"a": types.Number(1), "b": types.Number(1), "c": types.Number(2)}, types.NewSet(specs["c"].GetDatabase(), p))
+
+	output := "output"
+	_, stderr, err := s.Run(main, []string{"merge", "--all-conflicts", s.DBDir, "a", "b", "c", output})
+	s.NotNil(err)
+	s.Contains(stderr, "--all-conflicts can only merge two datasets")
+
+	s.MustRun(main, []string{"merge", s.DBDir, "a", "b", "c", output})
+	expected := types.NewStruct("", types.StructData{"a": types.Number(2), "b": types.Number(2), "c": types.Number(2)})
+	s.validateDataset(output, expected, a, b, c)
+}
+
 func (s *nomsMergeTestSuite) TestBadInput() {
 	sp, err := spec.ForDatabase(spec.CreateDatabaseSpecString("nbs", s.DBDir))
 	s.NoError(err)
@@ -195,6 +314,7 @@ func (s *nomsMergeTestSuite) TestBadInput() {
 		{[]string{sp.String(), l + "2", r, o}, "error: Dataset " + l + "2 has no data\n"},
 		{[]string{sp.String(), l, r + "2", o}, "error: Dataset " + r + "2 has no data\n"},
 		{[]string{sp.String(), l, r, "!invalid"}, "error: Invalid dataset !invalid, must match [a-zA-Z0-9\\-_/]+\n"},
+		{[]string{sp.String(), l, r, "unrelated", o}, "error: Datasets " + l + ", " + r + " and unrelated have no common ancestor\n"},
 	}
 
 	db := sp.GetDatabase()
@@ -205,6 +325,7 @@ func (s *nomsMergeTestSuite) TestBadInput() {
 	}
 	prep(l)
 	prep(r)
+	db.CommitValue(db.GetDataset("unrelated"), types.String("unrelated"))
 
 	for _, c := range cases {
 		stdout, stderr, err := s.Run(main, append([]string{"merge"}, c.args...))
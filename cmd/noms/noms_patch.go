@@ -0,0 +1,71 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/attic-labs/noms/cmd/util"
+	"github.com/attic-labs/noms/go/config"
+	"github.com/attic-labs/noms/go/d"
+	"github.com/attic-labs/noms/go/datas"
+	"github.com/attic-labs/noms/go/diff"
+	"github.com/attic-labs/noms/go/types"
+	"github.com/attic-labs/noms/go/util/status"
+	"github.com/attic-labs/noms/go/util/verbose"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func nomsPatch(noms *kingpin.Application) (*kingpin.CmdClause, util.KingpinHandler) {
+	patch := noms.Command("patch", "interact with patches written by noms diff --out-patch")
+
+	patchApply := patch.Command("apply", `applies a patch to the head of a dataset, and commits the result
+The values changed by the patch must not have changed in the dataset since the patch was made; if they have, nothing is committed. The patch and the dataset must be in the same database.
+See Spelling Objects at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the patch and dataset arguments.`)
+	patchSpec := patchApply.Arg("patch", "the patch, or a dataset whose head is the patch").Required().String()
+	targetDs := patchApply.Arg("dataset", "the dataset to apply the patch to").Required().String()
+
+	return patch, func(input string) int {
+		switch input {
+		case patchApply.FullCommand():
+			return nomsPatchApply(*patchSpec, *targetDs)
+		}
+		d.Panic("notreached")
+		return 1
+	}
+}
+
+func nomsPatchApply(patchSpec, targetDs string) int {
+	cfg := config.NewResolver()
+	patchDB, patchVal, err := cfg.GetPath(patchSpec)
+	d.CheckErrorNoUsage(err)
+	if patchVal == nil {
+		d.CheckErrorNoUsage(fmt.Errorf("No value at %s", patchSpec))
+	}
+	defer patchDB.Close()
+	if datas.IsCommit(patchVal) {
+		patchVal = patchVal.(types.Struct).Get(datas.ValueField)
+	}
+	patch, err := diff.UnmarshalPatch(patchVal)
+	d.CheckErrorNoUsage(err)
+
+	db, ds, err := cfg.GetDataset(targetDs)
+	d.CheckErrorNoUsage(err)
+	defer db.Close()
+	root, ok := ds.MaybeHeadValue()
+	if !ok {
+		d.CheckErrorNoUsage(fmt.Errorf("Dataset %s has no data", ds.ID()))
+	}
+
+	d.CheckErrorNoUsage(diff.Validate(root, patch, db))
+	d.CheckErrorNoUsage(diff.CheckRefs(patch, db))
+	_, err = db.CommitValue(ds, diff.Apply(root, patch))
+	d.CheckErrorNoUsage(err)
+	if !verbose.Quiet() {
+		status.Printf("Applied %d changes", len(patch))
+		status.Done()
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/attic-labs/noms/go/spec"
+	"github.com/attic-labs/noms/go/types"
+	"github.com/attic-labs/noms/go/util/clienttest"
+	"github.com/stretchr/testify/suite"
+)
+
+type nomsPatchTestSuite struct {
+	clienttest.ClientTestSuite
+}
+
+func TestNomsPatch(t *testing.T) {
+	suite.Run(t, &nomsPatchTestSuite{})
+}
+
+func (s *nomsPatchTestSuite) TestNomsPatchApply() {
+	sp, err := spec.ForDatabase(spec.CreateDatabaseSpecString("nbs", s.DBDir))
+	s.NoError(err)
+	db := sp.GetDatabase()
+
+	v1 := types.NewStruct("", types.StructData{
+		"name": types.String("before"),
+		"tags": types.NewSet(db, types.String("a")),
+		"list": types.NewList(db, types.Number(1), types.Number(2)),
+	})
+	v2 := types.NewStruct("", types.StructData{
+		"name": types.String("after"),
+		"tags": types.NewSet(db, types.String("a"), types.String("b")),
+		"list": types.NewList(db, types.Number(2), types.Number(3)),
+	})
+	_, err = db.CommitValue(db.GetDataset("from"), v1)
+	s.NoError(err)
+	_, err = db.CommitValue(db.GetDataset("to"), v2)
+	s.NoError(err)
+	_, err = db.CommitValue(db.GetDataset("target"), v1)
+	s.NoError(err)
+	_, err = db.CommitValue(db.GetDataset("drifted"), v1.Set("name", types.String("other")))
+	s.NoError(err)
+	sp.Close()
+
+	dsSpec := func(name string) string {
+		return spec.CreateValueSpecString("nbs", s.DBDir, name)
+	}
+	stdout, _ := s.MustRun(main, []string{"diff", "--out-patch", dsSpec("patch"), dsSpec("from.value"), dsSpec("to.value")})
+	s.Empty(stdout)
+
+	s.MustRun(main, []string{"patch", "apply", dsSpec("patch"), dsSpec("target")})
+	sp, err = spec.ForDataset(dsSpec("target"))
+	s.NoError(err)
+	s.True(v2.Equals(sp.GetDataset().HeadValue()))
+	sp.Close()
+
+	_, stderr, exitErr := s.Run(main, []string{"patch", "apply", dsSpec("patch"), dsSpec("drifted")})
+	s.NotNil(exitErr)
+	s.Contains(stderr, "values have changed at:\n  .name")
+	sp, err = spec.ForDataset(dsSpec("drifted"))
+	s.NoError(err)
+	s.Equal("other", string(sp.GetDataset().HeadValue().(types.Struct).Get("name").(types.String)))
+	sp.Close()
+
+	// The patch and the dataset must be in the same database.
+	otherDir, err := ioutil.TempDir("", "")
+	s.NoError(err)
+	defer os.RemoveAll(otherDir)
+	otherSp, err := spec.ForDatabase(spec.CreateDatabaseSpecString("nbs", otherDir))
+	s.NoError(err)
+	otherDB := otherSp.GetDatabase()
+	_, err = otherDB.CommitValue(otherDB.GetDataset("from"), v1)
+	s.NoError(err)
+	_, err = otherDB.CommitValue(otherDB.GetDataset("to"), v1.Set("ref", otherDB.WriteValue(types.String("elsewhere"))))
+	s.NoError(err)
+	otherSp.Close()
+
+	otherSpec := func(name string) string {
+		return spec.CreateValueSpecString("nbs", otherDir, name)
+	}
+	_, stderr, exitErr = s.Run(main, []string{"diff", "--out-patch", dsSpec("patch2"), otherSpec("from.value"), otherSpec("to.value")})
+	s.NotNil(exitErr)
+	s.Contains(stderr, "The value added at .ref refers to")
+
+	s.MustRun(main, []string{"diff", "--out-patch", otherSpec("patch"), otherSpec("from.value"), otherSpec("to.value")})
+	_, stderr, exitErr = s.Run(main, []string{"patch", "apply", otherSpec("patch"), dsSpec("target")})
+	s.NotNil(exitErr)
+	s.Contains(stderr, "The value added at .ref refers to")
+	sp, err = spec.ForDataset(dsSpec("target"))
+	s.NoError(err)
+	s.True(v2.Equals(sp.GetDataset().HeadValue()))
+	sp.Close()
+}
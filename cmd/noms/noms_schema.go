@@ -0,0 +1,283 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/attic-labs/noms/cmd/util"
+	"github.com/attic-labs/noms/go/config"
+	"github.com/attic-labs/noms/go/d"
+	"github.com/attic-labs/noms/go/types"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+func nomsSchema(noms *kingpin.Application) (*kingpin.CmdClause, util.KingpinHandler) {
+	schema := noms.Command("schema", `reports the type of a value, and how often each part of it occurs
+Each union in the type is listed with the number of values of each of its types, largest unions first, and each optional struct field with the number of values it's present in. With --suggest, a simpler type that all the values still fit is suggested, in which unions with more than --max-union-types types are widened to Value.
+See Spelling Values at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the path-spec argument.`)
+	suggest := schema.Flag("suggest", "also suggest a simpler type for the value").Bool()
+	maxUnionTypes := schema.Flag("max-union-types", "the number of types above which a union is widened to Value in the suggested type").Default("3").Int()
+	path := schema.Arg("path-spec", "the value to report the type of").Required().String()
+
+	return schema, func(input string) int {
+		cfg := config.NewResolver()
+		db, value, err := cfg.GetPath(*path)
+		d.CheckErrorNoUsage(err)
+		defer db.Close()
+		if value == nil {
+			d.CheckErrorNoUsage(fmt.Errorf("Object not found: %s", *path))
+		}
+		maxTypes := -1
+		if *suggest {
+			maxTypes = *maxUnionTypes
+		}
+		writeSchemaReport(os.Stdout, value, maxTypes)
+		return 0
+	}
+}
+
+// writeSchemaReport writes the type of |v| to |w|, with how often its unions'
+// types and its optional fields occur. If |maxUnionTypes| isn't negative, it
+// also writes the type widened by widenType.
+func writeSchemaReport(w io.Writer, v types.Value, maxUnionTypes int) {
+	t := types.TypeOf(v)
+	fmt.Fprintf(w, "Type:\n%s\n", t.Describe())
+
+	c := newTypeStatsCollector()
+	c.walk(v, t, "")
+
+	unions := c.statsOfKind(types.UnionKind)
+	if len(unions) > 0 {
+		// The unions with the most types are the ones that make the type hard
+		// to read, so list them first.
+		sort.SliceStable(unions, func(i, j int) bool {
+			return len(unions[i].counts) > len(unions[j].counts)
+		})
+		fmt.Fprintf(w, "\nUnions:\n")
+		for _, ts := range unions {
+			elemTypes := ts.t.Desc.(types.CompoundDesc).ElemTypes
+			fmt.Fprintf(w, "%s has %d types, in %d values:\n", displayPath(ts.path), len(elemTypes), ts.count)
+			for i, et := range elemTypes {
+				fmt.Fprintf(w, "  %d\t%s\n", ts.counts[i], summarizeType(et))
+			}
+		}
+	}
+
+	optional := []string{}
+	for _, ts := range c.statsOfKind(types.StructKind) {
+		i := 0
+		ts.t.Desc.(types.StructDesc).IterFields(func(name string, t *types.Type, opt bool) {
+			if opt {
+				optional = append(optional, fmt.Sprintf("%s is present in %d of %d values", displayPath(ts.path+"."+name), ts.counts[i], ts.count))
+			}
+			i++
+		})
+	}
+	if len(optional) > 0 {
+		fmt.Fprintf(w, "\nOptional fields:\n%s\n", strings.Join(optional, "\n"))
+	}
+
+	if maxUnionTypes >= 0 {
+		fmt.Fprintf(w, "\nSuggested type:\n%s\n", widenType(t, maxUnionTypes).Describe())
+	}
+}
+
+// widenType returns a super type of |t|, in which the unions with more than
+// |maxUnionTypes| types are replaced by Value. The types are rebuilt with the
+// types.Make functions, so the result is simplified like any other type.
+func widenType(t *types.Type, maxUnionTypes int) *types.Type {
+	return widenTypeImpl(t, maxUnionTypes, map[*types.Type]bool{})
+}
+
+func widenTypeImpl(t *types.Type, maxUnionTypes int, parentStructs map[*types.Type]bool) *types.Type {
+	switch desc := t.Desc.(type) {
+	case types.CompoundDesc:
+		elemTypes := make([]*types.Type, len(desc.ElemTypes))
+		for i, et := range desc.ElemTypes {
+			elemTypes[i] = widenTypeImpl(et, maxUnionTypes, parentStructs)
+		}
+		switch t.TargetKind() {
+		case types.UnionKind:
+			if len(elemTypes) > maxUnionTypes {
+				return types.ValueType
+			}
+			return types.MakeUnionType(elemTypes...)
+		case types.ListKind:
+			return types.MakeListType(elemTypes[0])
+		case types.SetKind:
+			return types.MakeSetType(elemTypes[0])
+		case types.RefKind:
+			return types.MakeRefType(elemTypes[0])
+		case types.MapKind:
+			return types.MakeMapType(elemTypes[0], elemTypes[1])
+		}
+	case types.StructDesc:
+		if parentStructs[t] {
+			// Only named structs can be recursive.
+			return types.MakeCycleType(desc.Name)
+		}
+		parentStructs[t] = true
+		defer delete(parentStructs, t)
+		fields := make([]types.StructField, 0, desc.Len())
+		desc.IterFields(func(name string, ft *types.Type, optional bool) {
+			fields = append(fields, types.StructField{Name: name, Type: widenTypeImpl(ft, maxUnionTypes, parentStructs), Optional: optional})
+		})
+		return types.MakeStructType(desc.Name, fields...)
+	}
+	return t
+}
+
+// typeStats counts how often the parts of a type occur in a value, for each
+// union or struct type in it.
+type typeStats struct {
+	path  string
+	t     *types.Type
+	count uint64 // of values of |t|
+	// counts is the number of values of each type in a union, or the number of
+	// values with each field of a struct, in the order of the fields.
+	counts []uint64
+}
+
+type typeStatsCollector struct {
+	stats map[typeStatsKey]*typeStats
+	keys  []typeStatsKey // in the order they are first seen
+	// structPaths is the path each struct type is first seen at. Values of the
+	// same struct type are counted together, wherever they are, so that
+	// recursive types are counted once rather than at each level.
+	structPaths map[*types.Type]string
+}
+
+type typeStatsKey struct {
+	path string
+	t    *types.Type
+}
+
+func newTypeStatsCollector() *typeStatsCollector {
+	return &typeStatsCollector{map[typeStatsKey]*typeStats{}, nil, map[*types.Type]string{}}
+}
+
+func (c *typeStatsCollector) get(path string, t *types.Type, n int) *typeStats {
+	key := typeStatsKey{path, t}
+	ts, ok := c.stats[key]
+	if !ok {
+		ts = &typeStats{path, t, 0, make([]uint64, n)}
+		c.stats[key] = ts
+		c.keys = append(c.keys, key)
+	}
+	return ts
+}
+
+// walk counts the parts of |v|, which is of type |t|, at |path|. It doesn't
+// follow Refs.
+func (c *typeStatsCollector) walk(v types.Value, t *types.Type, path string) {
+	switch desc := t.Desc.(type) {
+	case types.CompoundDesc:
+		switch t.TargetKind() {
+		case types.UnionKind:
+			ts := c.get(path, t, len(desc.ElemTypes))
+			ts.count++
+			// Unions are folded, so there is only one type of each kind in
+			// them, except for structs, of which there is one for each name.
+			for i, et := range desc.ElemTypes {
+				if isValueOfUnionElemType(v, et) {
+					ts.counts[i]++
+					c.walk(v, et, path)
+					return
+				}
+			}
+		case types.ListKind:
+			v.(types.List).IterAll(func(v types.Value, _ uint64) {
+				c.walk(v, desc.ElemTypes[0], path+"[]")
+			})
+		case types.SetKind:
+			v.(types.Set).IterAll(func(v types.Value) {
+				c.walk(v, desc.ElemTypes[0], path+"[]")
+			})
+		case types.MapKind:
+			v.(types.Map).IterAll(func(k, v types.Value) {
+				c.walk(k, desc.ElemTypes[0], path+"[]@key")
+				c.walk(v, desc.ElemTypes[1], path+"[]")
+			})
+		}
+	case types.StructDesc:
+		if p, ok := c.structPaths[t]; ok {
+			path = p
+		} else {
+			c.structPaths[t] = path
+		}
+		s := v.(types.Struct)
+		ts := c.get(path, t, desc.Len())
+		ts.count++
+		i := 0
+		desc.IterFields(func(name string, ft *types.Type, optional bool) {
+			if fv, ok := s.MaybeGet(name); ok {
+				ts.counts[i]++
+				c.walk(fv, ft, path+"."+name)
+			}
+			i++
+		})
+	}
+}
+
+func (c *typeStatsCollector) statsOfKind(k types.NomsKind) []*typeStats {
+	r := []*typeStats{}
+	for _, key := range c.keys {
+		if key.t.TargetKind() == k {
+			r = append(r, c.stats[key])
+		}
+	}
+	return r
+}
+
+func isValueOfUnionElemType(v types.Value, t *types.Type) bool {
+	k := t.TargetKind()
+	if k == types.ValueKind {
+		return true
+	}
+	if v.Kind() != k {
+		return false
+	}
+	if k == types.StructKind {
+		return v.(types.Struct).Name() == t.Desc.(types.StructDesc).Name
+	}
+	return true
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "The value"
+	}
+	return path
+}
+
+// summarizeType describes |t| in a single line, listing just the field names
+// of structs and eliding the element types of collections.
+func summarizeType(t *types.Type) string {
+	switch desc := t.Desc.(type) {
+	case types.StructDesc:
+		names := []string{}
+		desc.IterFields(func(name string, t *types.Type, optional bool) {
+			if optional {
+				name += "?"
+			}
+			names = append(names, name)
+		})
+		s := "Struct "
+		if desc.Name != "" {
+			s += desc.Name + " "
+		}
+		return s + "{" + strings.Join(names, ", ") + "}"
+	case types.CompoundDesc:
+		if t.TargetKind() != types.UnionKind {
+			return t.TargetKind().String() + "<...>"
+		}
+	}
+	return t.Describe()
+}
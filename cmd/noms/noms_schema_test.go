@@ -0,0 +1,119 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"testing"
+
+	"github.com/attic-labs/noms/go/spec"
+	"github.com/attic-labs/noms/go/types"
+	"github.com/attic-labs/noms/go/util/clienttest"
+	"github.com/stretchr/testify/suite"
+)
+
+type nomsSchemaTestSuite struct {
+	clienttest.ClientTestSuite
+}
+
+func TestNomsSchema(t *testing.T) {
+	suite.Run(t, &nomsSchemaTestSuite{})
+}
+
+func (s *nomsSchemaTestSuite) TestSchema() {
+	sp, err := spec.ForDataset(spec.CreateValueSpecString("nbs", s.DBDir, "records"))
+	s.NoError(err)
+	db := sp.GetDatabase()
+	record := func(id, value types.Value, tags ...types.Value) types.Value {
+		data := types.StructData{"id": id, "value": value}
+		if len(tags) > 0 {
+			data["tags"] = types.NewSet(db, tags...)
+		}
+		return types.NewStruct("Record", data)
+	}
+	_, err = db.CommitValue(sp.GetDataset(), types.NewList(db,
+		record(types.Number(1), types.Number(1), types.String("a")),
+		record(types.Number(2), types.String("two")),
+		record(types.Number(3), types.Bool(true)),
+		record(types.Number(4), types.NewList(db, types.Number(4))),
+		record(types.String("5"), types.Number(5), types.String("b"), types.String("c")),
+		types.NewStruct("Note", types.StructData{"text": types.String("done")}),
+	))
+	s.NoError(err)
+	sp.Close()
+
+	stdout, _ := s.MustRun(main, []string{"schema", spec.CreateValueSpecString("nbs", s.DBDir, "records.value")})
+	s.Equal(`Type:
+List<Struct Note {
+  text: String,
+} | Struct Record {
+  id: Number | String,
+  tags?: Set<String>,
+  value: Bool | Number | String | List<Number>,
+}>
+
+Unions:
+[].value has 4 types, in 5 values:
+  1	Bool
+  2	Number
+  1	String
+  1	List<...>
+[] has 2 types, in 6 values:
+  1	Struct Note {text}
+  5	Struct Record {id, tags?, value}
+[].id has 2 types, in 5 values:
+  4	Number
+  1	String
+
+Optional fields:
+[].tags is present in 2 of 5 values
+`, stdout)
+
+	stdout, _ = s.MustRun(main, []string{"schema", "--suggest", spec.CreateValueSpecString("nbs", s.DBDir, "records.value")})
+	s.Contains(stdout, `
+Suggested type:
+List<Struct Note {
+  text: String,
+} | Struct Record {
+  id: Number | String,
+  tags?: Set<String>,
+  value: Value,
+}>
+`)
+
+	stdout, _ = s.MustRun(main, []string{"schema", "--suggest", "--max-union-types", "1", spec.CreateValueSpecString("nbs", s.DBDir, "records.value")})
+	s.Contains(stdout, "\nSuggested type:\nList<Value>\n")
+}
+
+func (s *nomsSchemaTestSuite) TestSchemaRecursive() {
+	sp, err := spec.ForDataset(spec.CreateValueSpecString("nbs", s.DBDir, "tree"))
+	s.NoError(err)
+	db := sp.GetDatabase()
+	node := func(value types.Value, children ...types.Value) types.Value {
+		return types.NewStruct("Node", types.StructData{"value": value, "children": types.NewList(db, children...)})
+	}
+	_, err = db.CommitValue(sp.GetDataset(), node(types.Number(1), node(types.String("2")), node(types.Number(3), node(types.Bool(true)))))
+	s.NoError(err)
+	sp.Close()
+
+	stdout, _ := s.MustRun(main, []string{"schema", "--suggest", "--max-union-types", "2", spec.CreateValueSpecString("nbs", s.DBDir, "tree.value")})
+	s.Equal(`Type:
+Struct Node {
+  children: List<Cycle<Node>>,
+  value: Bool | Number | String,
+}
+
+Unions:
+.value has 3 types, in 4 values:
+  1	Bool
+  2	Number
+  1	String
+
+Suggested type:
+Struct Node {
+  children: List<Cycle<Node>>,
+  value: Value,
+}
+`, stdout)
+}
@@ -7,26 +7,44 @@ package main
 import (
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/attic-labs/noms/cmd/util"
+	"github.com/attic-labs/noms/go/chunks"
 	"github.com/attic-labs/noms/go/config"
 	"github.com/attic-labs/noms/go/d"
 	"github.com/attic-labs/noms/go/datas"
+	"github.com/attic-labs/noms/go/spec"
 	"github.com/attic-labs/noms/go/util/profile"
 	"github.com/attic-labs/noms/go/util/verbose"
 	flag "github.com/juju/gnuflag"
 )
 
 var (
-	port int
+	port         int
+	replicas     replicaList
+	pollInterval time.Duration
 )
 
+// replicaList collects the values of the repeatable --replica flag.
+type replicaList []string
+
+func (rl *replicaList) String() string {
+	return strings.Join(*rl, ",")
+}
+
+func (rl *replicaList) Set(value string) error {
+	*rl = append(*rl, value)
+	return nil
+}
+
 var nomsServe = &util.Command{
 	Run:       runServe,
 	UsageLine: "serve [options] <database>",
 	Short:     "Serves a Noms database over HTTP",
-	Long:      "See Spelling Objects at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the database argument.",
+	Long:      "Serves a Noms database over HTTP. If one or more --replica databases are given, everything committed to the served database is mirrored to them in the background.\n\nGraphQL queries can be subscribed to over a WebSocket at /graphql/subscriptions, and are re-sent whenever their results change.\n\nRequest, commit conflict and storage metrics are served in the Prometheus text format at /metrics.\n\nSee Spelling Objects at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the database arguments.",
 	Flags:     setupServeFlags,
 	Nargs:     0,
 }
@@ -34,6 +52,8 @@ var nomsServe = &util.Command{
 func setupServeFlags() *flag.FlagSet {
 	serveFlagSet := flag.NewFlagSet("serve", flag.ExitOnError)
 	serveFlagSet.IntVar(&port, "port", 8000, "port to listen on for HTTP requests")
+	serveFlagSet.Var(&replicas, "replica", "database to mirror all commits to; may be given more than once")
+	serveFlagSet.DurationVar(&pollInterval, "poll", time.Second, "how often to check for commits made to the database by other processes, for GraphQL subscriptions; 0 disables checking")
 	verbose.RegisterVerboseFlags(serveFlagSet)
 	profile.RegisterProfileFlags(serveFlagSet)
 	return serveFlagSet
@@ -45,9 +65,22 @@ func runServe(args []string) int {
 	if len(args) > 0 {
 		db = args[0]
 	}
-	cs, err := cfg.GetChunkStore(db)
-	d.CheckError(err)
+	var cs chunks.ChunkStore
+	if len(replicas) == 0 {
+		var err error
+		cs, err = cfg.GetChunkStore(db)
+		d.CheckError(err)
+	} else {
+		dbSpecs := []string{cfg.ResolveDbSpec(db)}
+		for _, r := range replicas {
+			dbSpecs = append(dbSpecs, cfg.ResolveDbSpec(r))
+		}
+		sp, err := spec.ForDatabaseOpts("mirror:"+strings.Join(dbSpecs, "+"), cfg.ResolveDbOptions(db))
+		d.CheckError(err)
+		cs = sp.NewChunkStore()
+	}
 	server := datas.NewRemoteDatabaseServer(cs, port)
+	server.PollInterval = pollInterval
 
 	// Shutdown server gracefully so that profile may be written
 	c := make(chan os.Signal, 1)
@@ -24,7 +24,7 @@ var nomsShow = &util.Command{
 	Run:       runShow,
 	UsageLine: "show [flags] <object>",
 	Short:     "Shows a serialization of a Noms object",
-	Long:      "See Spelling Objects at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the object argument.",
+	Long:      "See Spelling Objects at https://github.com/attic-labs/noms/blob/master/doc/spelling.md for details on the object argument.\n\nWith --include and --exclude, only the parts of the object at paths matched by the given path globs are shown, e.g. --include .rows[*].price shows only the price field of each row. In a path glob, .* matches any field and [*] matches any index or key.",
 	Flags:     setupShowFlags,
 	Nargs:     1,
 }
@@ -42,6 +42,7 @@ func setupShowFlags() *flag.FlagSet {
 	showFlagSet.BoolVar(&showRaw, "raw", false, "If true, dumps the raw binary version of the data")
 	showFlagSet.BoolVar(&showStats, "stats", false, "If true, reports statistics related to the value")
 	showFlagSet.StringVar(&tzName, "tz", "local", "display formatted date comments in specified timezone, must be: local or utc")
+	registerPathFilterFlags(showFlagSet)
 	return showFlagSet
 }
 
@@ -77,6 +78,12 @@ func runShow(args []string) int {
 	tz, _ := locationFromTimezoneArg(tzName, nil)
 	datetime.RegisterHRSCommenter(tz)
 
+	value = pathFilter().Project(value, database)
+	if value == nil {
+		fmt.Fprintf(os.Stderr, "Nothing in %s matches the given paths\n", args[0])
+		return 0
+	}
+
 	pgr := outputpager.Start()
 	defer pgr.Stop()
 
@@ -126,3 +126,25 @@ func (s *nomsShowTestSuite) TestNomsShowRaw() {
 	s.True(numChildChunks > 0)
 	test(l)
 }
+
+func (s *nomsShowTestSuite) TestNomsShowFilter() {
+	str := spec.CreateValueSpecString("nbs", s.DBDir, "filterTest")
+	sp := s.spec(str)
+	defer sp.Close()
+
+	db := sp.GetDatabase()
+	row := func(name string, price float64) types.Struct {
+		return types.NewStruct("Row", types.StructData{"name": types.String(name), "price": types.Number(price)})
+	}
+	_, err := db.CommitValue(sp.GetDataset(), types.NewStruct("", types.StructData{
+		"rows":  types.NewList(db, row("a", 1), row("b", 2)),
+		"title": types.String("t"),
+	}))
+	s.NoError(err)
+
+	res, _ := s.MustRun(main, []string{"show", "--include", ".rows[*].price", str + ".value"})
+	s.Equal("struct {\n  rows: [\n    struct Row {\n      price: 1,\n    },\n    struct Row {\n      price: 2,\n    },\n  ],\n}\n", res)
+
+	res, _ = s.MustRun(main, []string{"show", "--exclude", ".rows", str + ".value"})
+	s.Equal("struct {\n  title: \"t\",\n}\n", res)
+}
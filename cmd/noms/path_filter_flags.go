@@ -0,0 +1,60 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package main
+
+import (
+	"strings"
+
+	"github.com/attic-labs/noms/go/diff"
+	"github.com/attic-labs/noms/go/types"
+	flag "github.com/juju/gnuflag"
+)
+
+var (
+	includePaths pathGlobList
+	excludePaths pathGlobList
+	maxDepth     int
+)
+
+// pathGlobList collects the values of a repeatable path glob flag.
+type pathGlobList []types.PathGlob
+
+func (gl *pathGlobList) String() string {
+	strs := make([]string, len(*gl))
+	for i, g := range *gl {
+		strs[i] = g.String()
+	}
+	return strings.Join(strs, ",")
+}
+
+func (gl *pathGlobList) Set(value string) error {
+	g, err := types.ParsePathGlob(value)
+	if err != nil {
+		return err
+	}
+	*gl = append(*gl, g)
+	return nil
+}
+
+// registerPathFilterFlags registers the --include and --exclude flags with
+// |fs|. Use pathFilter to get the filter they describe.
+func registerPathFilterFlags(fs *flag.FlagSet) {
+	includePaths, excludePaths = nil, nil
+	fs.Var(&includePaths, "include", "only consider paths matched by this path glob, e.g. .rows[*].price, and what's beneath them (may be repeated)")
+	fs.Var(&excludePaths, "exclude", "don't consider paths matched by this path glob, or what's beneath them (may be repeated)")
+}
+
+// registerMaxDepthFlag registers the --max-depth flag with |fs|.
+func registerMaxDepthFlag(fs *flag.FlagSet) {
+	fs.IntVar(&maxDepth, "max-depth", 0, "don't descend into values deeper than this many path parts, reporting changes to them whole (0 for no limit)")
+}
+
+func pathFilter() types.PathFilter {
+	return types.PathFilter{Include: includePaths, Exclude: excludePaths}
+}
+
+func diffFilter() diff.Filter {
+	return diff.Filter{PathFilter: pathFilter(), MaxDepth: maxDepth}
+}
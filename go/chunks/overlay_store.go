@@ -0,0 +1,106 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package chunks
+
+import (
+	"github.com/attic-labs/noms/go/hash"
+)
+
+// OverlayStore is a copy-on-write ChunkStore layered over a read-only base
+// ChunkStore. Reads are served by a scratch ChunkStore, falling through to the
+// base for anything the scratch store doesn't have. All writes, including
+// root updates, go only to the scratch store, so the base is never modified.
+//
+// Until something is committed to the scratch store, the root of an
+// OverlayStore is the root of the base. After that, it's the root of the
+// scratch store, and changes to the base's root are no longer visible.
+type OverlayStore struct {
+	base, scratch ChunkStore
+}
+
+// NewOverlayStore returns an OverlayStore that reads from |base| and
+// |scratch|, and writes only to |scratch|.
+func NewOverlayStore(base, scratch ChunkStore) *OverlayStore {
+	return &OverlayStore{base, scratch}
+}
+
+func (ovs *OverlayStore) Get(h hash.Hash) Chunk {
+	if c := ovs.scratch.Get(h); !c.IsEmpty() {
+		return c
+	}
+	return ovs.base.Get(h)
+}
+
+func (ovs *OverlayStore) GetMany(hashes hash.HashSet, foundChunks chan *Chunk) {
+	remaining := hash.HashSet{}
+	for h := range hashes {
+		remaining.Insert(h)
+	}
+
+	found := make(chan *Chunk)
+	go func() { defer close(found); ovs.scratch.GetMany(hashes, found) }()
+	for c := range found {
+		remaining.Remove(c.Hash())
+		foundChunks <- c
+	}
+	if len(remaining) > 0 {
+		ovs.base.GetMany(remaining, foundChunks)
+	}
+}
+
+func (ovs *OverlayStore) Has(h hash.Hash) bool {
+	return ovs.scratch.Has(h) || ovs.base.Has(h)
+}
+
+func (ovs *OverlayStore) HasMany(hashes hash.HashSet) hash.HashSet {
+	absent := ovs.scratch.HasMany(hashes)
+	if len(absent) == 0 {
+		return absent
+	}
+	return ovs.base.HasMany(absent)
+}
+
+// Put writes |c| to the scratch store only.
+func (ovs *OverlayStore) Put(c Chunk) {
+	ovs.scratch.Put(c)
+}
+
+func (ovs *OverlayStore) Version() string {
+	return ovs.base.Version()
+}
+
+func (ovs *OverlayStore) Rebase() {
+	ovs.scratch.Rebase()
+	ovs.base.Rebase()
+}
+
+func (ovs *OverlayStore) Root() hash.Hash {
+	if root := ovs.scratch.Root(); !root.IsEmpty() {
+		return root
+	}
+	return ovs.base.Root()
+}
+
+// Commit persists all novel chunks to the scratch store, and updates the root
+// of the scratch store to |current|, iff the root of the OverlayStore is
+// |last|.
+func (ovs *OverlayStore) Commit(current, last hash.Hash) bool {
+	if last != ovs.Root() {
+		return false
+	}
+	return ovs.scratch.Commit(current, ovs.scratch.Root())
+}
+
+func (ovs *OverlayStore) Stats() interface{} {
+	return ovs.scratch.Stats()
+}
+
+func (ovs *OverlayStore) Close() error {
+	err := ovs.scratch.Close()
+	if berr := ovs.base.Close(); err == nil {
+		err = berr
+	}
+	return err
+}
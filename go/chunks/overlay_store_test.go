@@ -0,0 +1,99 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package chunks
+
+import (
+	"testing"
+
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestOverlayStoreTestSuite(t *testing.T) {
+	suite.Run(t, &OverlayStoreTestSuite{})
+}
+
+type OverlayStoreTestSuite struct {
+	ChunkStoreTestSuite
+}
+
+func (suite *OverlayStoreTestSuite) SetupTest() {
+	suite.Factory = &overlayStoreFactory{NewMemoryStoreFactory(), NewMemoryStoreFactory()}
+}
+
+func (suite *OverlayStoreTestSuite) TearDownTest() {
+	suite.Factory.Shutter()
+}
+
+type overlayStoreFactory struct {
+	base, scratch Factory
+}
+
+func (f *overlayStoreFactory) CreateStore(ns string) ChunkStore {
+	return NewOverlayStore(f.base.CreateStore(ns), f.scratch.CreateStore(ns))
+}
+
+func (f *overlayStoreFactory) CreateStoreFromCache(ns string) ChunkStore {
+	return f.CreateStore(ns)
+}
+
+func (f *overlayStoreFactory) Shutter() {
+	f.base.Shutter()
+	f.scratch.Shutter()
+}
+
+func TestOverlayStoreLeavesBaseUntouched(t *testing.T) {
+	assert := assert.New(t)
+	base, scratch := &MemoryStorage{}, &MemoryStorage{}
+
+	baseChunk := NewChunk([]byte("base"))
+	func() {
+		view := base.NewView()
+		view.Put(baseChunk)
+		assert.True(view.Commit(baseChunk.Hash(), hash.Hash{}))
+	}()
+
+	overlay := NewOverlayStore(base.NewView(), scratch.NewView())
+	assert.Equal(baseChunk.Hash(), overlay.Root())
+	assertInputInStore("base", baseChunk.Hash(), overlay, assert)
+
+	novel := NewChunk([]byte("novel"))
+	overlay.Put(novel)
+	assert.False(overlay.Commit(novel.Hash(), hash.Hash{}))
+	assert.True(overlay.Commit(novel.Hash(), baseChunk.Hash()))
+	assert.Equal(novel.Hash(), overlay.Root())
+
+	assert.Equal(baseChunk.Hash(), base.Root())
+	assert.False(base.Has(novel.Hash()))
+	assert.Equal(novel.Hash(), scratch.Root())
+	assert.True(scratch.Has(novel.Hash()))
+	assert.False(scratch.Has(baseChunk.Hash()))
+
+	assert.True(overlay.Has(baseChunk.Hash()))
+	assert.Equal(hash.NewHashSet(), overlay.HasMany(hash.NewHashSet(baseChunk.Hash(), novel.Hash())))
+
+	found := make(chan *Chunk)
+	go func() {
+		defer close(found)
+		overlay.GetMany(hash.NewHashSet(baseChunk.Hash(), novel.Hash()), found)
+	}()
+	got := hash.HashSet{}
+	for c := range found {
+		got.Insert(c.Hash())
+	}
+	assert.Equal(hash.NewHashSet(baseChunk.Hash(), novel.Hash()), got)
+
+	// Once the scratch store has a root, the overlay no longer follows the base.
+	other := NewChunk([]byte("other"))
+	func() {
+		view := base.NewView()
+		view.Put(other)
+		assert.True(view.Commit(other.Hash(), baseChunk.Hash()))
+	}()
+	overlay.Rebase()
+	assert.Equal(novel.Hash(), overlay.Root())
+	assert.Equal(novel.Hash(), NewOverlayStore(base.NewView(), scratch.NewView()).Root())
+}
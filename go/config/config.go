@@ -23,6 +23,15 @@ type Config struct {
 
 type DbConfig struct {
 	Url string
+
+	// KeyFile is the path of the master key used to encrypt the database at
+	// rest. If empty, the database is not encrypted.
+	KeyFile string
+}
+
+// SpecOptions returns the spec.SpecOptions needed to open the database.
+func (dc DbConfig) SpecOptions() spec.SpecOptions {
+	return spec.SpecOptions{KeyFile: dc.KeyFile}
 }
 
 const (
@@ -108,6 +117,15 @@ func absDbSpec(configHome string, url string) string {
 	return "nbs:" + dbName
 }
 
+// Replace a relative file path with an absolute one. Assumes the path is
+// relative to the location of the config file
+func absFilePath(configHome string, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(configHome, path)
+}
+
 func qualifyPaths(configPath string, c *Config) (*Config, error) {
 	file, err := filepath.Abs(configPath)
 	if err != nil {
@@ -117,7 +135,7 @@ func qualifyPaths(configPath string, c *Config) (*Config, error) {
 	qc := *c
 	qc.File = file
 	for k, r := range c.Db {
-		qc.Db[k] = DbConfig{absDbSpec(dir, r.Url)}
+		qc.Db[k] = DbConfig{absDbSpec(dir, r.Url), absFilePath(dir, r.KeyFile)}
 	}
 	return &qc, nil
 }
@@ -136,6 +154,9 @@ func (c *Config) writeableString() string {
 	for k, r := range c.Db {
 		buffer.WriteString(fmt.Sprintf("[db.%s]\n", k))
 		buffer.WriteString(fmt.Sprintf("\t"+`url = "%s"`+"\n", r.Url))
+		if r.KeyFile != "" {
+			buffer.WriteString(fmt.Sprintf("\t"+`keyfile = "%s"`+"\n", r.KeyFile))
+		}
 	}
 	return buffer.String()
 }
@@ -29,32 +29,32 @@ var (
 	ldbConfig = &Config{
 		"",
 		map[string]DbConfig{
-			DefaultDbAlias: {nbsSpec},
-			remoteAlias:    {httpSpec},
+			DefaultDbAlias: {Url: nbsSpec},
+			remoteAlias:    {Url: httpSpec},
 		},
 	}
 
 	httpConfig = &Config{
 		"",
 		map[string]DbConfig{
-			DefaultDbAlias: {httpSpec},
-			remoteAlias:    {nbsSpec},
+			DefaultDbAlias: {Url: httpSpec},
+			remoteAlias:    {Url: nbsSpec},
 		},
 	}
 
 	memConfig = &Config{
 		"",
 		map[string]DbConfig{
-			DefaultDbAlias: {memSpec},
-			remoteAlias:    {httpSpec},
+			DefaultDbAlias: {Url: memSpec},
+			remoteAlias:    {Url: httpSpec},
 		},
 	}
 
 	ldbAbsConfig = &Config{
 		"",
 		map[string]DbConfig{
-			DefaultDbAlias: {nbsAbsSpec},
-			remoteAlias:    {httpSpec},
+			DefaultDbAlias: {Url: nbsAbsSpec},
+			remoteAlias:    {Url: httpSpec},
 		},
 	}
 )
@@ -189,6 +189,28 @@ func TestQualifyingPaths(t *testing.T) {
 	}
 }
 
+func TestKeyFile(t *testing.T) {
+	assert := assert.New(t)
+	path := getPaths(assert, "home.keyfile")
+	assert.NoError(os.MkdirAll(path.home, os.ModePerm))
+	assert.NoError(os.Chdir(path.home))
+
+	c := &Config{
+		"",
+		map[string]DbConfig{
+			DefaultDbAlias: {Url: nbsSpec, KeyFile: "keys/default.key"},
+			remoteAlias:    {Url: nbsAbsSpec, KeyFile: "/etc/noms/remote.key"},
+		},
+	}
+	writeConfig(assert, c, path.home)
+	ac, err := FindNomsConfig()
+	assert.NoError(err, path.config)
+	validateConfig(assert, path.config, c, ac)
+	assert.Equal(filepath.Join(path.home, "keys/default.key"), ac.Db[DefaultDbAlias].KeyFile)
+	assert.Equal("/etc/noms/remote.key", ac.Db[remoteAlias].KeyFile)
+	assert.Equal("/etc/noms/remote.key", ac.Db[remoteAlias].SpecOptions().KeyFile)
+}
+
 func TestCwd(t *testing.T) {
 	assert := assert.New(t)
 	cwd, err := os.Getwd()
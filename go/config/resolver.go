@@ -61,6 +61,36 @@ func (r *Resolver) ResolveDbSpec(str string) string {
 	return str
 }
 
+// Resolve string to the options needed to open its database. If config is
+// defined and the string is empty, a db alias or the url of a configured db,
+// the options of that db are returned.
+func (r *Resolver) ResolveDbOptions(str string) spec.SpecOptions {
+	if r.config != nil {
+		if str == "" {
+			str = DefaultDbAlias
+		}
+		if val, ok := r.config.Db[str]; ok {
+			return val.SpecOptions()
+		}
+		for _, val := range r.config.Db {
+			if val.Url == str {
+				return val.SpecOptions()
+			}
+		}
+	}
+	return spec.SpecOptions{}
+}
+
+// Returns the database part of a dataset or path string, which is empty if
+// the string doesn't specify one.
+func dbPart(str string) string {
+	split := strings.SplitN(str, spec.Separator, 2)
+	if len(split) > 1 {
+		return split[0]
+	}
+	return ""
+}
+
 // Resolve string to dataset or path name.
 //   - replace database name as described in ResolveDatabase
 //   - if this is the first call to ResolvePath, remember the
@@ -88,7 +118,7 @@ func (r *Resolver) ResolvePathSpec(str string) string {
 //   - resolve a db alias to its db spec
 //   - resolve "" to the default db spec
 func (r *Resolver) GetDatabase(str string) (datas.Database, error) {
-	sp, err := spec.ForDatabase(r.verbose(str, r.ResolveDbSpec(str)))
+	sp, err := spec.ForDatabaseOpts(r.verbose(str, r.ResolveDbSpec(str)), r.ResolveDbOptions(str))
 	if err != nil {
 		return nil, err
 	}
@@ -97,7 +127,7 @@ func (r *Resolver) GetDatabase(str string) (datas.Database, error) {
 
 // Resolve string to a chunkstore. Like ResolveDatabase, but returns the underlying ChunkStore
 func (r *Resolver) GetChunkStore(str string) (chunks.ChunkStore, error) {
-	sp, err := spec.ForDatabase(r.verbose(str, r.ResolveDbSpec(str)))
+	sp, err := spec.ForDatabaseOpts(r.verbose(str, r.ResolveDbSpec(str)), r.ResolveDbOptions(str))
 	if err != nil {
 		return nil, err
 	}
@@ -108,7 +138,7 @@ func (r *Resolver) GetChunkStore(str string) (chunks.ChunkStore, error) {
 //  - if no db prefix is present, assume the default db
 //  - if the db prefix is an alias, replace it
 func (r *Resolver) GetDataset(str string) (datas.Database, datas.Dataset, error) {
-	sp, err := spec.ForDataset(r.verbose(str, r.ResolvePathSpec(str)))
+	sp, err := spec.ForDatasetOpts(r.verbose(str, r.ResolvePathSpec(str)), r.ResolveDbOptions(dbPart(str)))
 	if err != nil {
 		return nil, datas.Dataset{}, err
 	}
@@ -119,7 +149,7 @@ func (r *Resolver) GetDataset(str string) (datas.Database, datas.Dataset, error)
 //  - if no db spec is present, assume the default db
 //  - if the db spec is an alias, replace it
 func (r *Resolver) GetPath(str string) (datas.Database, types.Value, error) {
-	sp, err := spec.ForPath(r.verbose(str, r.ResolvePathSpec(str)))
+	sp, err := spec.ForPathOpts(r.verbose(str, r.ResolvePathSpec(str)), r.ResolveDbOptions(dbPart(str)))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -32,8 +32,8 @@ var (
 	rtestConfig = &Config{
 		"",
 		map[string]DbConfig{
-			DefaultDbAlias: {localSpec},
-			remoteAlias:    {remoteSpec},
+			DefaultDbAlias: {Url: localSpec},
+			remoteAlias:    {Url: remoteSpec},
 		},
 	}
 
@@ -152,3 +152,29 @@ func TestResolveDestPathWithDot(t *testing.T) {
 	}
 
 }
+
+func TestResolveDbOptions(t *testing.T) {
+	assert := assert.New(t)
+	dir := filepath.Join(rtestRoot, "with-keyfile-config")
+	c := &Config{
+		"",
+		map[string]DbConfig{
+			DefaultDbAlias: {Url: localSpec, KeyFile: "/keys/local.key"},
+			remoteAlias:    {Url: remoteSpec},
+		},
+	}
+	_, err := c.WriteTo(dir)
+	assert.NoError(err, dir)
+	assert.NoError(os.Chdir(dir))
+	r := NewResolver()
+
+	assert.Equal("/keys/local.key", r.ResolveDbOptions("").KeyFile)
+	assert.Equal("/keys/local.key", r.ResolveDbOptions(DefaultDbAlias).KeyFile)
+	assert.Equal("/keys/local.key", r.ResolveDbOptions(r.ResolveDbSpec("")).KeyFile)
+	assert.Equal("", r.ResolveDbOptions(remoteAlias).KeyFile)
+	assert.Equal("", r.ResolveDbOptions("nbs:/some/other/db").KeyFile)
+	assert.Equal("/keys/local.key", r.ResolveDbOptions(dbPart(testDs)).KeyFile)
+	assert.Equal("", r.ResolveDbOptions(dbPart(remoteAlias+"::"+testDs)).KeyFile)
+
+	assert.Equal("", withoutConfig(t).ResolveDbOptions("").KeyFile)
+}
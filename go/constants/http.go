@@ -12,5 +12,7 @@ const (
 	WriteValuePath = "/writeValue/"
 	BasePath       = "/"
 
-	GraphQLPath = "/graphql/"
+	GraphQLPath              = "/graphql/"
+	GraphQLSubscriptionsPath = "/graphql/subscriptions"
+	MetricsPath              = "/metrics"
 )
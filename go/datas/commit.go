@@ -5,7 +5,7 @@
 package datas
 
 import (
-	"sort"
+	"container/heap"
 
 	"github.com/attic-labs/noms/go/d"
 	"github.com/attic-labs/noms/go/hash"
@@ -46,61 +46,109 @@ func NewCommit(value types.Value, parents types.Set, meta types.Struct) types.St
 
 // FindCommonAncestor returns the most recent common ancestor of c1 and c2, if
 // one exists, setting ok to true. If there is no common ancestor, ok is set
-// to false.
+// to false. If there are several best common ancestors, as there can be when
+// histories criss-cross, the tallest is returned; see FindCommonAncestors.
 func FindCommonAncestor(c1, c2 types.Ref, vr types.ValueReader) (a types.Ref, ok bool) {
+	if ancestors := FindCommonAncestors(c1, c2, vr); len(ancestors) > 0 {
+		return ancestors[0], true
+	}
+	return
+}
+
+// FindCommonAncestors returns all of the best common ancestors of c1 and c2,
+// i.e. every Commit that is an ancestor of both (or is one of them) and isn't
+// an ancestor of another such Commit. There's usually at most one, but there
+// can be several if c1 and c2 were each made by merging the same Commits.
+// The result is ordered by decreasing height, and is empty if c1 and c2 have
+// no common ancestor.
+func FindCommonAncestors(c1, c2 types.Ref, vr types.ValueReader) []types.Ref {
 	if !IsRefOfCommitType(types.TypeOf(c1)) {
-		d.Panic("FindCommonAncestor() called on %s", types.TypeOf(c1).Describe())
+		d.Panic("FindCommonAncestors() called on %s", types.TypeOf(c1).Describe())
 	}
 	if !IsRefOfCommitType(types.TypeOf(c2)) {
-		d.Panic("FindCommonAncestor() called on %s", types.TypeOf(c2).Describe())
+		d.Panic("FindCommonAncestors() called on %s", types.TypeOf(c2).Describe())
 	}
+	return findCommonAncestors([]types.Ref{c1}, []types.Ref{c2}, vr)
+}
+
+// Flags recorded for each Commit visited by findCommonAncestors.
+const (
+	reachableFromA uint8 = 1 << iota
+	reachableFromB
+	stale // an ancestor of a common ancestor that has already been found
+)
 
-	c1Q, c2Q := &types.RefByHeight{c1}, &types.RefByHeight{c2}
-	for !c1Q.Empty() && !c2Q.Empty() {
-		c1Ht, c2Ht := c1Q.MaxHeight(), c2Q.MaxHeight()
-		if c1Ht == c2Ht {
-			c1Parents, c2Parents := c1Q.PopRefsOfHeight(c1Ht), c2Q.PopRefsOfHeight(c2Ht)
-			if common, ok := findCommonRef(c1Parents, c2Parents); ok {
-				return common, true
+// findCommonAncestors returns the best common ancestors of any Commit in |a|
+// and any Commit in |b|. Commits are visited tallest first, so that by the
+// time a Commit is visited, every Commit it can be reached from has been, and
+// so its flags are final. The walk stops once every Commit left to visit is
+// stale.
+func findCommonAncestors(a, b []types.Ref, vr types.ValueReader) (ancestors []types.Ref) {
+	flags := map[hash.Hash]uint8{}
+	q := &refHeap{}
+	active := 0 // the number of Commits in |q| that aren't stale
+	mark := func(r types.Ref, f uint8) {
+		h := r.TargetHash()
+		old, queued := flags[h]
+		flags[h] = old | f
+		if !queued {
+			heap.Push(q, r)
+			if f&stale == 0 {
+				active++
 			}
-			parentsToQueue(c1Parents, c1Q, vr)
-			parentsToQueue(c2Parents, c2Q, vr)
-		} else if c1Ht > c2Ht {
-			parentsToQueue(c1Q.PopRefsOfHeight(c1Ht), c1Q, vr)
-		} else {
-			parentsToQueue(c2Q.PopRefsOfHeight(c2Ht), c2Q, vr)
+		} else if old&stale == 0 && f&stale != 0 {
+			active--
 		}
 	}
-	return
-}
+	for _, r := range a {
+		mark(r, reachableFromA)
+	}
+	for _, r := range b {
+		mark(r, reachableFromB)
+	}
 
-func parentsToQueue(refs types.RefSlice, q *types.RefByHeight, vr types.ValueReader) {
-	for _, r := range refs {
-		c := r.TargetValue(vr).(types.Struct)
-		p := c.Get(ParentsField).(types.Set)
-		p.IterAll(func(v types.Value) {
-			q.PushBack(v.(types.Ref))
+	for active > 0 {
+		r := heap.Pop(q).(types.Ref)
+		f := flags[r.TargetHash()]
+		if f&stale == 0 {
+			active--
+			if f&(reachableFromA|reachableFromB) == reachableFromA|reachableFromB {
+				ancestors = append(ancestors, r)
+				f |= stale
+			}
+		}
+		r.TargetValue(vr).(types.Struct).Get(ParentsField).(types.Set).IterAll(func(v types.Value) {
+			mark(v.(types.Ref), f)
 		})
 	}
-	sort.Sort(q)
+	return
 }
 
-func findCommonRef(a, b types.RefSlice) (types.Ref, bool) {
-	toRefSet := func(s types.RefSlice) map[hash.Hash]types.Ref {
-		out := map[hash.Hash]types.Ref{}
-		for _, r := range s {
-			out[r.TargetHash()] = r
-		}
-		return out
-	}
+// refHeap is a heap.Interface of Refs, which pops the tallest first, in
+// types.HeightOrder.
+type refHeap []types.Ref
 
-	aSet, bSet := toRefSet(a), toRefSet(b)
-	for s, r := range aSet {
-		if _, present := bSet[s]; present {
-			return r, true
-		}
-	}
-	return types.Ref{}, false
+func (h refHeap) Len() int {
+	return len(h)
+}
+
+func (h refHeap) Less(i, j int) bool {
+	return types.HeightOrder(h[i], h[j])
+}
+
+func (h refHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *refHeap) Push(r interface{}) {
+	*h = append(*h, r.(types.Ref))
+}
+
+func (h *refHeap) Pop() interface{} {
+	old := *h
+	r := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return r
 }
 
 func makeCommitStructType(metaType, parentsType, valueType *types.Type) *types.Type {
@@ -146,21 +146,26 @@ func (db *database) doCommit(datasetID string, commit types.Struct, mergePolicy
 			if hasHead {
 				head := r.(types.Ref).TargetValue(db)
 				currentHeadRef := types.NewRef(head)
-				ancestorRef, found := FindCommonAncestor(commitRef, currentHeadRef, db)
-				if !found {
+				ancestorRefs := FindCommonAncestors(commitRef, currentHeadRef, db)
+				if len(ancestorRefs) == 0 {
 					return ErrMergeNeeded
 				}
 
 				// This covers all cases where currentHeadRef is not an ancestor of commit, including the following edge cases:
 				//   - commit is a duplicate of currentHead.
 				//   - we hit an ErrOptimisticLockFailed and looped back around because some other process changed the Head out from under us.
-				if currentHeadRef.TargetHash() != ancestorRef.TargetHash() || currentHeadRef.TargetHash() == commitRef.TargetHash() {
+				//   - commit and currentHead have several best common ancestors, because history criss-crosses.
+				if len(ancestorRefs) > 1 || currentHeadRef.TargetHash() != ancestorRefs[0].TargetHash() || currentHeadRef.TargetHash() == commitRef.TargetHash() {
 					if mergePolicy == nil {
 						return ErrMergeNeeded
 					}
 
-					ancestor, currentHead := db.validateRefAsCommit(ancestorRef), db.validateRefAsCommit(currentHeadRef)
-					merged, err := mergePolicy(commit.Get(ValueField), currentHead.Get(ValueField), ancestor.Get(ValueField), db, nil)
+					ancestor, err := mergeAncestors(ancestorRefs, db, mergePolicy)
+					if err != nil {
+						return err
+					}
+					currentHead := db.validateRefAsCommit(currentHeadRef)
+					merged, err := mergePolicy(commit.Get(ValueField), currentHead.Get(ValueField), ancestor, db, nil)
 					if err != nil {
 						return err
 					}
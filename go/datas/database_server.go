@@ -10,6 +10,8 @@ import (
 	"net"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/attic-labs/noms/go/chunks"
 	"github.com/attic-labs/noms/go/constants"
@@ -28,8 +30,16 @@ type RemoteDatabaseServer struct {
 	l       *net.Listener
 	csChan  chan *connectionState
 	closing bool
+	// mu guards closing, so that nothing is sent on csChan once it's closed.
+	mu      sync.Mutex
+	metrics *serverMetrics
+	roots   *rootWatcher
 	// Called just before the server is started.
 	Ready func()
+	// How often to check for commits made to the ChunkStore by other
+	// processes, so that GraphQL subscriptions see them. Zero disables
+	// polling, leaving only commits made through the server to be seen.
+	PollInterval time.Duration
 }
 
 func NewRemoteDatabaseServer(cs chunks.ChunkStore, port int) *RemoteDatabaseServer {
@@ -38,7 +48,7 @@ func NewRemoteDatabaseServer(cs chunks.ChunkStore, port int) *RemoteDatabaseServ
 		d.Panic("SDK version %s is incompatible with data of version %s", constants.NomsVersion, dataVersion)
 	}
 	return &RemoteDatabaseServer{
-		cs, port, nil, make(chan *connectionState, 16), false, func() {},
+		cs, port, nil, make(chan *connectionState, 16), false, sync.Mutex{}, newServerMetrics(), newRootWatcher(cs), func() {}, time.Second,
 	}
 }
 
@@ -61,21 +71,24 @@ func (s *RemoteDatabaseServer) Run() {
 
 	router := httprouter.New()
 
-	router.POST(constants.GetRefsPath, s.corsHandle(s.makeHandle(HandleGetRefs)))
-	router.GET(constants.GetBlobPath, s.corsHandle(s.makeHandle(HandleGetBlob)))
+	router.POST(constants.GetRefsPath, s.corsHandle(s.makeHandle("getRefs", HandleGetRefs)))
+	router.GET(constants.GetBlobPath, s.corsHandle(s.makeHandle("getBlob", HandleGetBlob)))
 	router.OPTIONS(constants.GetRefsPath, s.corsHandle(noopHandle))
-	router.POST(constants.HasRefsPath, s.corsHandle(s.makeHandle(HandleHasRefs)))
+	router.POST(constants.HasRefsPath, s.corsHandle(s.makeHandle("hasRefs", HandleHasRefs)))
 	router.OPTIONS(constants.HasRefsPath, s.corsHandle(noopHandle))
-	router.GET(constants.RootPath, s.corsHandle(s.makeHandle(HandleRootGet)))
-	router.POST(constants.RootPath, s.corsHandle(s.makeHandle(HandleRootPost)))
+	router.GET(constants.RootPath, s.corsHandle(s.makeHandle("root", HandleRootGet)))
+	router.POST(constants.RootPath, s.corsHandle(s.makeHandle("root", s.checkRoot(HandleRootPost))))
 	router.OPTIONS(constants.RootPath, s.corsHandle(noopHandle))
-	router.POST(constants.WriteValuePath, s.corsHandle(s.makeHandle(HandleWriteValue)))
+	router.POST(constants.WriteValuePath, s.corsHandle(s.makeHandle("writeValue", HandleWriteValue)))
 	router.OPTIONS(constants.WriteValuePath, s.corsHandle(noopHandle))
-	router.GET(constants.BasePath, s.corsHandle(s.makeHandle(HandleBaseGet)))
+	router.GET(constants.BasePath, s.corsHandle(s.makeHandle("base", HandleBaseGet)))
 
-	router.GET(constants.GraphQLPath, s.corsHandle(s.makeHandle(HandleGraphQL)))
-	router.POST(constants.GraphQLPath, s.corsHandle(s.makeHandle(HandleGraphQL)))
+	router.GET(constants.GraphQLPath, s.corsHandle(s.makeHandle("graphql", HandleGraphQL)))
+	router.POST(constants.GraphQLPath, s.corsHandle(s.makeHandle("graphql", s.checkRoot(HandleGraphQL))))
 	router.OPTIONS(constants.GraphQLPath, s.corsHandle(noopHandle))
+	router.GET(constants.GraphQLSubscriptionsPath, s.corsHandle(s.metrics.instrument("graphqlSubscriptions", s.handleGraphQLSubscriptions)))
+
+	router.GET(constants.MetricsPath, s.handleMetrics)
 
 	srv := &http.Server{
 		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
@@ -99,16 +112,37 @@ func (s *RemoteDatabaseServer) Run() {
 		}
 	}()
 
+	if s.PollInterval > 0 {
+		go s.roots.poll(s.PollInterval)
+	}
+
 	go s.Ready()
 	srv.Serve(l)
 }
 
-func (s *RemoteDatabaseServer) makeHandle(hndlr Handler) httprouter.Handle {
-	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+// makeHandle returns an httprouter.Handle that calls |hndlr|, and whose
+// requests are recorded in the server's metrics under |name|.
+func (s *RemoteDatabaseServer) makeHandle(name string, hndlr Handler) httprouter.Handle {
+	return s.metrics.instrument(name, func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 		hndlr(w, req, ps, s.cs)
+	})
+}
+
+// checkRoot returns a Handler that calls |hndlr|, which may commit, and then
+// lets GraphQL subscriptions know if the root has changed.
+func (s *RemoteDatabaseServer) checkRoot(hndlr Handler) Handler {
+	return func(w http.ResponseWriter, req *http.Request, ps URLParams, cs chunks.ChunkStore) {
+		hndlr(w, req, ps, cs)
+		s.roots.check()
 	}
 }
 
+// handleMetrics serves the server's metrics, and the statistics of its
+// ChunkStore, in the Prometheus text format.
+func (s *RemoteDatabaseServer) handleMetrics(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	s.metrics.handleMetrics(w, req, s.cs)
+}
+
 func noopHandle(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 }
 
@@ -127,6 +161,8 @@ func (s *RemoteDatabaseServer) corsHandle(f httprouter.Handle) httprouter.Handle
 }
 
 func (s *RemoteDatabaseServer) connState(c net.Conn, cs http.ConnState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.closing {
 		d.PanicIfFalse(cs == http.StateClosed)
 		return
@@ -136,7 +172,10 @@ func (s *RemoteDatabaseServer) connState(c net.Conn, cs http.ConnState) {
 
 // Will cause the RemoteDatabaseServer to stop listening and an existing call to Run() to continue.
 func (s *RemoteDatabaseServer) Stop() {
+	s.mu.Lock()
 	s.closing = true
+	s.mu.Unlock()
+	s.roots.close()
 	(*s.l).Close()
 	(s.cs).Close()
 	close(s.csChan)
@@ -295,6 +295,52 @@ func (suite *DatabaseSuite) TestDatabaseCommitMerge() {
 	suite.True(types.Number(47).Equals(ours.HeadValue().(types.Map).Get(types.String("Friends"))))
 }
 
+func (suite *DatabaseSuite) TestDatabaseCommitMergeStrategies() {
+	ds := suite.db.GetDataset("ds1")
+	_, ok, err := GetMergeStrategies(ds)
+	suite.NoError(err)
+	suite.False(ok)
+
+	strategies, err := merge.Strategies{}.Add(".count", merge.CounterStrategy)
+	suite.NoError(err)
+	strategies, err = strategies.Add(".name", merge.LastWriterWinsStrategy)
+	suite.NoError(err)
+	suite.NoError(SetMergeStrategies(ds, strategies))
+	stored, ok, err := GetMergeStrategies(ds)
+	suite.NoError(err)
+	suite.True(ok)
+	suite.Equal(2, stored.Len())
+
+	meta := func(date string) types.Struct {
+		return types.NewStruct("Meta", types.StructData{"date": types.String(date)})
+	}
+	value := func(count float64, name string) types.Struct {
+		return types.NewStruct("", types.StructData{"count": types.Number(count), "name": types.String(name)})
+	}
+
+	ds, err = suite.db.Commit(ds, value(10, "first"), CommitOptions{Meta: meta("2017-01-01T00:00:00Z")})
+	suite.NoError(err)
+	first := ds
+
+	// Two writers start from |first|. The second to commit has the earlier date, so its name loses.
+	ds, err = suite.db.Commit(ds, value(12, "later"), CommitOptions{Meta: meta("2017-01-03T00:00:00Z")})
+	suite.NoError(err)
+	m := meta("2017-01-02T00:00:00Z")
+	ds, err = suite.db.Commit(first, value(15, "earlier"), CommitOptions{Meta: m, Policy: NewStrategyPolicy(first, m, nil)})
+	suite.NoError(err)
+	suite.True(value(17, "later").Equals(ds.HeadValue()), "%s", types.EncodedValue(ds.HeadValue()))
+
+	// Without strategies for it, a dataset merges as usual.
+	other := suite.db.GetDataset("ds2")
+	other, err = suite.db.CommitValue(other, value(1, "a"))
+	suite.NoError(err)
+	otherFirst := other
+	_, err = suite.db.CommitValue(other, value(2, "a"))
+	suite.NoError(err)
+	_, err = suite.db.Commit(otherFirst, value(3, "a"), CommitOptions{Policy: NewStrategyPolicy(otherFirst, types.EmptyStruct, nil)})
+	suite.IsType(&merge.ErrMergeConflict{}, err, "%s", err)
+}
+
 func newOptsWithMerge(vrw types.ValueReadWriter, policy merge.ResolveFunc, parents ...types.Value) CommitOptions {
 	return CommitOptions{Parents: types.NewSet(vrw, parents...), Policy: merge.NewThreeWay(policy)}
 }
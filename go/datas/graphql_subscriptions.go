@@ -0,0 +1,334 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package datas
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/attic-labs/noms/go/types"
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+)
+
+// The types of the messages sent over a GraphQL subscriptions WebSocket.
+// They follow the graphql-ws protocol of the Apollo subscriptions transport.
+const (
+	subscriptionConnectionInit = "connection_init"
+	subscriptionConnectionAck  = "connection_ack"
+	subscriptionStart          = "start"
+	subscriptionStop           = "stop"
+	subscriptionData           = "data"
+	subscriptionError          = "error"
+	subscriptionComplete       = "complete"
+)
+
+// subscriptionMessage is a message sent, in either direction, over a GraphQL
+// subscriptions WebSocket. |ID| identifies the subscription the message is
+// about.
+type subscriptionMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// subscriptionRequest is the payload of a start message. As with the graphql
+// endpoint, |DS| and |H| select the dataset or value to query, and the
+// database itself is queried if neither is given.
+type subscriptionRequest struct {
+	Query string `json:"query"`
+	DS    string `json:"ds"`
+	H     string `json:"h"`
+}
+
+// subscription is a started subscription, and what was last sent for it.
+type subscription struct {
+	subscriptionRequest
+	sent   bool
+	head   hash.Hash // the head of DS when the query was last run
+	result hash.Hash // the hash of the result last sent
+}
+
+var subscriptionUpgrader = websocket.Upgrader{
+	// Like every other endpoint, subscriptions are open to any origin.
+	CheckOrigin: func(req *http.Request) bool { return true },
+}
+
+// handleGraphQLSubscriptions upgrades |req| to a WebSocket over which a client
+// starts and stops subscriptions to GraphQL queries. The result of a query is
+// sent when its subscription starts, and again whenever the root of the
+// database changes and the result is no longer the one last sent. Queries of
+// a dataset are only re-run when the head of the dataset changes.
+func (s *RemoteDatabaseServer) handleGraphQLSubscriptions(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	conn, err := subscriptionUpgrader.Upgrade(w, req, nil)
+	if err != nil {
+		// Upgrade has already responded with an error.
+		return
+	}
+	defer conn.Close()
+
+	changes := s.roots.subscribe()
+	defer s.roots.unsubscribe(changes)
+
+	done := make(chan struct{})
+	defer close(done)
+	msgs := make(chan []byte)
+	go func() {
+		defer close(msgs)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			select {
+			case msgs <- data:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	sc := &subscriptionConn{conn, s.cs, map[string]*subscription{}}
+	for {
+		select {
+		case data, ok := <-msgs:
+			if !ok {
+				return
+			}
+			err = sc.handle(data)
+		case _, ok := <-changes:
+			if !ok {
+				return
+			}
+			err = sc.update()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// subscriptionConn holds the subscriptions started over one WebSocket. Its
+// methods return an error only if writing to the WebSocket fails.
+type subscriptionConn struct {
+	conn *websocket.Conn
+	cs   chunks.ChunkStore
+	subs map[string]*subscription
+}
+
+func (sc *subscriptionConn) handle(data []byte) error {
+	msg := subscriptionMessage{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return sc.sendError("", err)
+	}
+
+	switch msg.Type {
+	case subscriptionConnectionInit:
+		return sc.send(subscriptionMessage{Type: subscriptionConnectionAck})
+	case subscriptionStart:
+		sub := &subscription{}
+		if err := json.Unmarshal(msg.Payload, &sub.subscriptionRequest); err != nil {
+			return sc.sendError(msg.ID, err)
+		}
+		if sub.Query == "" {
+			return sc.sendError(msg.ID, errors.New("Expected query"))
+		}
+		if sub.DS != "" && sub.H != "" {
+			return sc.sendError(msg.ID, errors.New("Must specify at most one of ds (dataset) or h (hash)"))
+		}
+		if sub.DS != "" && !DatasetFullRe.MatchString(sub.DS) {
+			return sc.sendError(msg.ID, fmt.Errorf("Invalid dataset ID: %s", sub.DS))
+		}
+		if _, ok := hash.MaybeParse(sub.H); sub.H != "" && !ok {
+			return sc.sendError(msg.ID, fmt.Errorf("Invalid hash: %s", sub.H))
+		}
+		sc.subs[msg.ID] = sub
+		return sc.run(NewDatabase(sc.cs), msg.ID, sub)
+	case subscriptionStop:
+		delete(sc.subs, msg.ID)
+		return sc.send(subscriptionMessage{ID: msg.ID, Type: subscriptionComplete})
+	default:
+		return sc.sendError(msg.ID, fmt.Errorf("Unknown message type: %s", msg.Type))
+	}
+}
+
+// update re-runs the subscriptions that may have a new result now that the
+// root of the database has changed.
+func (sc *subscriptionConn) update() error {
+	// Note: we don't close this because the server closes |sc.cs|.
+	db := NewDatabase(sc.cs)
+	for id, sub := range sc.subs {
+		if err := sc.run(db, id, sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run runs the query of |sub| against |db|, and sends the result, or the error
+// the query failed with, unless it's the one last sent.
+func (sc *subscriptionConn) run(db Database, id string, sub *subscription) error {
+	var head hash.Hash
+	if sub.DS != "" {
+		if r, ok := db.Datasets().MaybeGet(types.String(sub.DS)); ok {
+			head = r.(types.Ref).TargetHash()
+		}
+	}
+	// Values never change, and neither do queries of a dataset whose head
+	// hasn't.
+	if sub.sent && (sub.H != "" || sub.DS != "" && head == sub.head) {
+		return nil
+	}
+	sub.head = head
+
+	buf := &bytes.Buffer{}
+	msg := subscriptionMessage{ID: id, Type: subscriptionData}
+	if err := subscriptionQuery(db, sub.subscriptionRequest, buf); err != nil {
+		msg.Type, msg.Payload = subscriptionError, errorPayload(err)
+	} else {
+		msg.Payload = buf.Bytes()
+	}
+	result := hash.Of(append([]byte(msg.Type), msg.Payload...))
+	if sub.sent && result == sub.result {
+		return nil
+	}
+	sub.sent, sub.result = true, result
+	return sc.send(msg)
+}
+
+// subscriptionQuery runs the query of |req|, which can't mutate the dataset,
+// and writes the result to |w|. A query that makes the GraphQL library panic,
+// like one with an operation type that isn't supported, is an error rather
+// than the end of the connection.
+func subscriptionQuery(db Database, req subscriptionRequest, w *bytes.Buffer) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Query failed: %v", r)
+		}
+	}()
+	queryGraphQL(db, req.DS, req.H, req.Query, true, w)
+	return nil
+}
+
+func (sc *subscriptionConn) send(msg subscriptionMessage) error {
+	return sc.conn.WriteJSON(msg)
+}
+
+func (sc *subscriptionConn) sendError(id string, err error) error {
+	return sc.send(subscriptionMessage{ID: id, Type: subscriptionError, Payload: errorPayload(err)})
+}
+
+func errorPayload(err error) json.RawMessage {
+	payload, _ := json.Marshal(map[string]string{"message": err.Error()})
+	return payload
+}
+
+// rootWatcher notifies its subscribers when the root of a ChunkStore changes.
+// Commits made through the server are noticed as soon as they're made; while
+// polling, so are commits made to the underlying storage by other processes.
+type rootWatcher struct {
+	cs     chunks.ChunkStore
+	mu     sync.Mutex
+	root   hash.Hash
+	subs   map[chan struct{}]bool
+	closed bool
+	// rebaseMu is held while rebasing, instead of mu, so that subscribers
+	// aren't held up by it. close waits for it, so that the ChunkStore isn't
+	// rebased once the watcher is closed.
+	rebaseMu sync.Mutex
+}
+
+func newRootWatcher(cs chunks.ChunkStore) *rootWatcher {
+	return &rootWatcher{cs: cs, root: cs.Root(), subs: map[chan struct{}]bool{}}
+}
+
+// subscribe returns a channel that receives whenever the root changes.
+// Changes made while a notification is pending are coalesced into it. The
+// channel is closed when the watcher is.
+func (rw *rootWatcher) subscribe() chan struct{} {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	ch := make(chan struct{}, 1)
+	if rw.closed {
+		close(ch)
+		return ch
+	}
+	rw.subs[ch] = true
+	return ch
+}
+
+func (rw *rootWatcher) unsubscribe(ch chan struct{}) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if rw.subs[ch] {
+		delete(rw.subs, ch)
+		close(ch)
+	}
+}
+
+// check notifies the subscribers if the root has changed since it was last
+// checked.
+func (rw *rootWatcher) check() {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if rw.closed {
+		return
+	}
+	root := rw.cs.Root()
+	if root == rw.root {
+		return
+	}
+	rw.root = root
+	for ch := range rw.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// poll rebases the ChunkStore every |interval|, while there are subscribers,
+// and checks whether that changed the root. It returns once the watcher is
+// closed.
+func (rw *rootWatcher) poll(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for range t.C {
+		rw.rebaseMu.Lock()
+		rw.mu.Lock()
+		closed, rebase := rw.closed, len(rw.subs) > 0
+		rw.mu.Unlock()
+		if rebase && !closed {
+			rw.cs.Rebase()
+		}
+		rw.rebaseMu.Unlock()
+		if closed {
+			return
+		}
+		if rebase {
+			rw.check()
+		}
+	}
+}
+
+// close closes the channels of all the subscribers, and stops polling.
+func (rw *rootWatcher) close() {
+	rw.rebaseMu.Lock()
+	defer rw.rebaseMu.Unlock()
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	rw.closed = true
+	for ch := range rw.subs {
+		close(ch)
+	}
+	rw.subs = map[chan struct{}]bool{}
+}
@@ -0,0 +1,122 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package datas
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/constants"
+	"github.com/attic-labs/noms/go/types"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// startSubscriptionServer serves |cs| and connects to its GraphQL
+// subscriptions endpoint.
+func startSubscriptionServer(t *testing.T, cs chunks.ChunkStore, poll time.Duration) (*RemoteDatabaseServer, *websocket.Conn) {
+	server := NewRemoteDatabaseServer(cs, 0)
+	server.PollInterval = poll
+	portChan := make(chan int)
+	server.Ready = func() { portChan <- server.Port() }
+	go server.Run()
+	port := <-portChan
+
+	conn, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("ws://localhost:%d%s", port, constants.GraphQLSubscriptionsPath), nil)
+	assert.NoError(t, err)
+	return server, conn
+}
+
+func sendSubscriptionMessage(t *testing.T, conn *websocket.Conn, id, typ string, payload interface{}) {
+	msg := subscriptionMessage{ID: id, Type: typ}
+	if payload != nil {
+		msg.Payload, _ = json.Marshal(payload)
+	}
+	assert.NoError(t, conn.WriteJSON(msg))
+}
+
+func assertSubscriptionMessage(t *testing.T, conn *websocket.Conn, id, typ, payload string) {
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	msg := subscriptionMessage{}
+	assert.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, id, msg.ID)
+	assert.Equal(t, typ, msg.Type)
+	if payload != "" {
+		assert.JSONEq(t, payload, string(msg.Payload))
+	}
+}
+
+func TestGraphQLSubscriptions(t *testing.T) {
+	assert := assert.New(t)
+	server, conn := startSubscriptionServer(t, (&chunks.MemoryStorage{}).NewView(), 0)
+	defer server.Stop()
+	defer conn.Close()
+
+	// Commit through the server, as a remote client would.
+	db := NewDatabase(NewHTTPChunkStore(fmt.Sprintf("http://localhost:%d", server.Port()), ""))
+	commit := func(ds string, v types.Value) {
+		_, err := db.CommitValue(db.GetDataset(ds), v)
+		assert.NoError(err)
+	}
+	commit("foo", types.Number(1))
+
+	sendSubscriptionMessage(t, conn, "", subscriptionConnectionInit, nil)
+	assertSubscriptionMessage(t, conn, "", subscriptionConnectionAck, "")
+
+	sendSubscriptionMessage(t, conn, "1", subscriptionStart, subscriptionRequest{Query: "{root{value}}", DS: "foo"})
+	assertSubscriptionMessage(t, conn, "1", subscriptionData, `{"data":{"root":{"value":1}}}`)
+	sendSubscriptionMessage(t, conn, "2", subscriptionStart, subscriptionRequest{Query: "{datasets{name}}"})
+	assertSubscriptionMessage(t, conn, "2", subscriptionData, `{"data":{"datasets":[{"name":"foo"}]}}`)
+
+	// The head of foo doesn't change, so only 2 is re-sent.
+	commit("bar", types.Number(1))
+	assertSubscriptionMessage(t, conn, "2", subscriptionData, `{"data":{"datasets":[{"name":"bar"},{"name":"foo"}]}}`)
+
+	// Neither result changes, so nothing is sent until the value of foo does.
+	commit("foo", types.Number(1))
+	commit("foo", types.Number(2))
+	assertSubscriptionMessage(t, conn, "1", subscriptionData, `{"data":{"root":{"value":2}}}`)
+
+	sendSubscriptionMessage(t, conn, "1", subscriptionStop, nil)
+	assertSubscriptionMessage(t, conn, "1", subscriptionComplete, "")
+	commit("foo", types.Number(3))
+	commit("baz", types.Number(1))
+	assertSubscriptionMessage(t, conn, "2", subscriptionData, `{"data":{"datasets":[{"name":"bar"},{"name":"baz"},{"name":"foo"}]}}`)
+
+	sendSubscriptionMessage(t, conn, "3", subscriptionStart, subscriptionRequest{DS: "foo"})
+	assertSubscriptionMessage(t, conn, "3", subscriptionError, `{"message":"Expected query"}`)
+	sendSubscriptionMessage(t, conn, "4", subscriptionStart, subscriptionRequest{Query: "{root{value}}", DS: "nope"})
+	assertSubscriptionMessage(t, conn, "4", subscriptionData, `{"data":null,"errors":[{"message":"Dataset nope not found","locations":null}]}`)
+	sendSubscriptionMessage(t, conn, "5", "bogus", nil)
+	assertSubscriptionMessage(t, conn, "5", subscriptionError, `{"message":"Unknown message type: bogus"}`)
+
+	// A query that fails isn't re-sent until its error changes.
+	sendSubscriptionMessage(t, conn, "6", subscriptionStart, subscriptionRequest{Query: "subscription {datasets{name}}"})
+	assertSubscriptionMessage(t, conn, "6", subscriptionError, "")
+	commit("qux", types.Number(1))
+	assertSubscriptionMessage(t, conn, "2", subscriptionData, `{"data":{"datasets":[{"name":"bar"},{"name":"baz"},{"name":"foo"},{"name":"qux"}]}}`)
+	sendSubscriptionMessage(t, conn, "2", subscriptionStop, nil)
+	assertSubscriptionMessage(t, conn, "2", subscriptionComplete, "")
+}
+
+func TestGraphQLSubscriptionsPolling(t *testing.T) {
+	assert := assert.New(t)
+	storage := &chunks.MemoryStorage{}
+	server, conn := startSubscriptionServer(t, storage.NewView(), 10*time.Millisecond)
+	defer server.Stop()
+	defer conn.Close()
+
+	sendSubscriptionMessage(t, conn, "1", subscriptionStart, subscriptionRequest{Query: "{datasets{name}}"})
+	assertSubscriptionMessage(t, conn, "1", subscriptionData, `{"data":{"datasets":[]}}`)
+
+	// Commit directly to the storage, as another process would.
+	db := NewDatabase(storage.NewView())
+	_, err := db.CommitValue(db.GetDataset("foo"), types.Number(1))
+	assert.NoError(err)
+	assertSubscriptionMessage(t, conn, "1", subscriptionData, `{"data":{"datasets":[{"name":"foo"}]}}`)
+}
@@ -0,0 +1,92 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package datas
+
+import (
+	"errors"
+
+	"github.com/attic-labs/noms/go/d"
+	"github.com/attic-labs/noms/go/merge"
+	"github.com/attic-labs/noms/go/types"
+)
+
+// ErrNoCommonAncestor is returned by MergeCommits if one of the Commits it's
+// given has no common ancestor with those that come before it.
+var ErrNoCommonAncestor = errors.New("Commits have no common ancestor")
+
+// FindMergeBase returns the value to use as the parent when three-way merging
+// the values of the Commits c1 and c2, setting ok to false if they have no
+// common ancestor. If they have a single best common ancestor, this is its
+// value. If they have several, as they can when histories criss-cross, they
+// are merged using |policy| into a virtual ancestor, which is never committed.
+// This is done recursively: each is merged into the others with the merge
+// base of those Commits as the parent. Should that merge conflict, the older
+// merge base is used in its place, since it's still a common ancestor.
+func FindMergeBase(c1, c2 types.Ref, vrw types.ValueReadWriter, policy merge.Policy) (base types.Value, ok bool, err error) {
+	if !IsRefOfCommitType(types.TypeOf(c1)) {
+		d.Panic("FindMergeBase() called on %s", types.TypeOf(c1).Describe())
+	}
+	if !IsRefOfCommitType(types.TypeOf(c2)) {
+		d.Panic("FindMergeBase() called on %s", types.TypeOf(c2).Describe())
+	}
+	return findMergeBase([]types.Ref{c1}, []types.Ref{c2}, vrw, policy)
+}
+
+// MergeCommits merges the values of all of |commits| using |policy|, for a
+// Commit that will have all of them as parents. Commits are merged in order,
+// each into the result of merging those before it, using the merge base (see
+// FindMergeBase) of it and any of those before it as the parent.
+func MergeCommits(commits []types.Ref, vrw types.ValueReadWriter, policy merge.Policy, progress chan struct{}) (types.Value, error) {
+	d.PanicIfFalse(len(commits) > 0)
+	merged := commitValue(commits[0], vrw)
+	for i := 1; i < len(commits); i++ {
+		parent, ok, err := findMergeBase(commits[:i], commits[i:i+1], vrw, policy)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrNoCommonAncestor
+		}
+		merged, err = policy(merged, commitValue(commits[i], vrw), parent, vrw, progress)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
+
+func findMergeBase(a, b []types.Ref, vrw types.ValueReadWriter, policy merge.Policy) (base types.Value, ok bool, err error) {
+	ancestors := findCommonAncestors(a, b, vrw)
+	if len(ancestors) == 0 {
+		return nil, false, nil
+	}
+	base, err = mergeAncestors(ancestors, vrw, policy)
+	return base, err == nil, err
+}
+
+// mergeAncestors merges the values of the best common ancestors |ancestors|
+// into a single virtual ancestor.
+func mergeAncestors(ancestors []types.Ref, vrw types.ValueReadWriter, policy merge.Policy) (types.Value, error) {
+	merged := commitValue(ancestors[0], vrw)
+	for i := 1; i < len(ancestors); i++ {
+		parent, ok, err := findMergeBase(ancestors[:i], ancestors[i:i+1], vrw, policy)
+		if err != nil {
+			return nil, err
+		}
+		m, err := policy(merged, commitValue(ancestors[i], vrw), parent, vrw, nil)
+		if err != nil {
+			if _, conflict := err.(*merge.ErrMergeConflict); !conflict || !ok {
+				return nil, err
+			}
+			m = parent
+		}
+		merged = m
+	}
+	return merged, nil
+}
+
+func commitValue(r types.Ref, vr types.ValueReader) types.Value {
+	return r.TargetValue(vr).(types.Struct).Get(ValueField)
+}
@@ -0,0 +1,104 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package datas
+
+import (
+	"testing"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/merge"
+	"github.com/attic-labs/noms/go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeBaseCrissCross(t *testing.T) {
+	assert := assert.New(t)
+	storage := &chunks.TestStorage{}
+	db := NewDatabase(storage.NewView())
+	defer db.Close()
+
+	value := func(x, y, z float64) types.Struct {
+		return types.NewStruct("", types.StructData{"x": types.Number(x), "y": types.Number(y), "z": types.Number(z)})
+	}
+	// a2 and b2 have the same value and parents, so give each Commit a meta
+	// that tells them apart.
+	addCommit := func(datasetID string, v types.Value, parents ...types.Struct) types.Struct {
+		meta := types.NewStruct("", types.StructData{"dataset": types.String(datasetID)})
+		ds, err := db.Commit(db.GetDataset(datasetID), v, CommitOptions{Parents: toRefSet(db, parents...), Meta: meta})
+		assert.NoError(err)
+		return ds.Head()
+	}
+
+	// ds-a: p<-a1<-a2<-a3
+	//        \   \ /
+	//         \   X
+	//          \ / \
+	// ds-b:     b1<-b2<-b3
+	p := addCommit("ds-a", value(1, 1, 1))
+	a1 := addCommit("ds-a", value(2, 1, 1), p)
+	b1 := addCommit("ds-b", value(1, 2, 1), p)
+	a2 := addCommit("ds-a", value(2, 2, 1), a1, b1)
+	b2 := addCommit("ds-b", value(2, 2, 1), b1, a1)
+	a3 := addCommit("ds-a", value(2, 2, 3), a2)
+	b3 := addCommit("ds-b", value(2, 1, 1), b2)
+
+	ancestors := FindCommonAncestors(types.NewRef(a3), types.NewRef(b3), db)
+	if assert.Len(ancestors, 2) {
+		assert.True(toRefSet(db, a1, b1).Equals(types.NewSet(db, ancestors[0], ancestors[1])))
+	}
+
+	// Neither a1 nor b1 alone is a good merge base: each would make a3 or b3
+	// look like it changed y. The virtual ancestor that merges them is.
+	policy := merge.NewThreeWay(merge.None)
+	base, ok, err := FindMergeBase(types.NewRef(a3), types.NewRef(b3), db, policy)
+	assert.NoError(err)
+	assert.True(ok)
+	assert.True(value(2, 2, 1).Equals(base))
+
+	merged, err := MergeCommits([]types.Ref{types.NewRef(a3), types.NewRef(b3)}, db, policy, nil)
+	assert.NoError(err)
+	assert.True(value(2, 1, 3).Equals(merged))
+
+	// Committing with a merge Policy uses the virtual ancestor too.
+	ds, err := db.Commit(db.GetDataset("ds-b"), a3.Get(ValueField), CommitOptions{Parents: toRefSet(db, a3), Policy: policy})
+	assert.NoError(err)
+	assert.True(value(2, 1, 3).Equals(ds.HeadValue()))
+
+	// With one common ancestor, that's the merge base.
+	base, ok, err = FindMergeBase(types.NewRef(a3), types.NewRef(a1), db, policy)
+	assert.NoError(err)
+	assert.True(ok)
+	assert.True(a1.Get(ValueField).Equals(base))
+}
+
+func TestMergeCommitsOctopus(t *testing.T) {
+	assert := assert.New(t)
+	storage := &chunks.TestStorage{}
+	db := NewDatabase(storage.NewView())
+	defer db.Close()
+
+	addCommit := func(datasetID string, data types.StructData, parents ...types.Struct) types.Struct {
+		ds, err := db.Commit(db.GetDataset(datasetID), types.NewStruct("", data), CommitOptions{Parents: toRefSet(db, parents...)})
+		assert.NoError(err)
+		return ds.Head()
+	}
+
+	p := addCommit("ds-p", types.StructData{"x": types.Number(1), "y": types.Number(1), "z": types.Number(1)})
+	a := addCommit("ds-a", types.StructData{"x": types.Number(2), "y": types.Number(1), "z": types.Number(1)}, p)
+	b := addCommit("ds-b", types.StructData{"x": types.Number(1), "y": types.Number(2), "z": types.Number(1)}, p)
+	c := addCommit("ds-c", types.StructData{"x": types.Number(1), "y": types.Number(1)}, p)
+	other := addCommit("ds-other", types.StructData{"x": types.Number(3)})
+
+	policy := merge.NewThreeWay(merge.None)
+	merged, err := MergeCommits([]types.Ref{types.NewRef(a), types.NewRef(b), types.NewRef(c)}, db, policy, nil)
+	assert.NoError(err)
+	assert.True(types.NewStruct("", types.StructData{"x": types.Number(2), "y": types.Number(2)}).Equals(merged))
+
+	_, err = MergeCommits([]types.Ref{types.NewRef(a), types.NewRef(b), types.NewRef(other)}, db, policy, nil)
+	assert.Equal(ErrNoCommonAncestor, err)
+
+	_, err = MergeCommits([]types.Ref{types.NewRef(a), types.NewRef(b), types.NewRef(addCommit("ds-d", types.StructData{"x": types.Number(4)}, p))}, db, policy, nil)
+	assert.IsType(&merge.ErrMergeConflict{}, err)
+}
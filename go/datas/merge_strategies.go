@@ -0,0 +1,52 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package datas
+
+import (
+	"github.com/attic-labs/noms/go/merge"
+	"github.com/attic-labs/noms/go/types"
+)
+
+// MergeStrategiesPrefix is prepended to the ID of a Dataset to get the ID of
+// the Dataset in which its merge.Strategies are stored.
+const MergeStrategiesPrefix = "_merge-strategies/"
+
+// SetMergeStrategies stores |s| as the merge.Strategies of |ds|, replacing
+// any that were there before.
+func SetMergeStrategies(ds Dataset, s merge.Strategies) error {
+	db := ds.Database()
+	_, err := db.CommitValue(db.GetDataset(MergeStrategiesPrefix+ds.ID()), s.Marshal(db))
+	return err
+}
+
+// GetMergeStrategies returns the merge.Strategies stored for |ds|, if any.
+func GetMergeStrategies(ds Dataset) (s merge.Strategies, ok bool, err error) {
+	v, ok := ds.Database().GetDataset(MergeStrategiesPrefix + ds.ID()).MaybeHeadValue()
+	if !ok {
+		return
+	}
+	s, err = merge.UnmarshalStrategies(v)
+	return s, err == nil, err
+}
+
+// NewStrategyPolicy returns a merge.Policy, suitable for use as
+// CommitOptions.Policy when committing to |ds|, that merges using the
+// merge.Strategies stored for |ds| at the time of the merge, falling back to
+// |resolve| everywhere else. |meta| is the Meta of the Commit being made, and
+// is merged against that of the current Head of |ds|.
+func NewStrategyPolicy(ds Dataset, meta types.Struct, resolve merge.ResolveFunc) merge.Policy {
+	return func(a, b, parent types.Value, vrw types.ValueReadWriter, progress chan struct{}) (types.Value, error) {
+		current := ds.Database().GetDataset(ds.ID())
+		s, _, err := GetMergeStrategies(current)
+		if err != nil {
+			return nil, err
+		}
+		var headMeta types.Struct
+		if head, ok := current.MaybeHead(); ok {
+			headMeta, _ = head.Get(MetaField).(types.Struct)
+		}
+		return s.Policy(meta, headMeta, resolve)(a, b, parent, vrw, progress)
+	}
+}
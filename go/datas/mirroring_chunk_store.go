@@ -0,0 +1,355 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package datas
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/d"
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/attic-labs/noms/go/types"
+)
+
+// MirroringChunkStore is a ChunkStore that writes to a primary ChunkStore and
+// keeps one or more replica ChunkStores up to date with it.
+//
+// Put() is fanned out to the primary and to every replica that's in sync with
+// it. Commit() succeeds iff the primary commit succeeds; replicas are then
+// caught up to the new root asynchronously, by pulling anything they're
+// missing from the primary (as Pull() does) and committing the same root.
+// Replicas that fall behind, because they failed or because they started out
+// with a different root, stop receiving Puts until they've been caught up.
+// Replica failures are logged, and never cause a Put() or Commit() to fail.
+//
+// Reads are served by the primary, falling back to the replicas, in order,
+// for chunks the primary doesn't have.
+//
+// Replicas are mirrors: a replica's root is overwritten with the primary's,
+// regardless of what has been committed to the replica by anyone else.
+type MirroringChunkStore struct {
+	primary  chunks.ChunkStore
+	replicas []*replica
+
+	// mu is held for reading by Put() and for writing by Commit(), so that
+	// replicas only come back in sync between commits.
+	mu              sync.RWMutex
+	root            hash.Hash
+	putsSinceCommit uint64 // accessed atomically
+}
+
+// NewMirroringChunkStore returns a MirroringChunkStore that mirrors |primary|
+// to |replicas|. Any replicas whose root differs from the primary's begin to
+// catch up immediately.
+func NewMirroringChunkStore(primary chunks.ChunkStore, replicas ...chunks.ChunkStore) *MirroringChunkStore {
+	d.PanicIfTrue(len(replicas) == 0)
+	mcs := &MirroringChunkStore{primary: primary, root: primary.Root()}
+	for _, cs := range replicas {
+		r := newReplica(cs, mcs.root)
+		mcs.replicas = append(mcs.replicas, r)
+		go r.run(mcs)
+	}
+	return mcs
+}
+
+func (mcs *MirroringChunkStore) Get(h hash.Hash) chunks.Chunk {
+	if c := mcs.primary.Get(h); !c.IsEmpty() {
+		return c
+	}
+	for _, r := range mcs.replicas {
+		if c := r.cs.Get(h); !c.IsEmpty() {
+			return c
+		}
+	}
+	return chunks.EmptyChunk
+}
+
+func (mcs *MirroringChunkStore) GetMany(hashes hash.HashSet, foundChunks chan *chunks.Chunk) {
+	remaining := hash.HashSet{}
+	for h := range hashes {
+		remaining.Insert(h)
+	}
+	getFrom := func(cs chunks.ChunkStore) {
+		// |remaining| is modified below as chunks are found, so |cs| needs its own copy.
+		wanted := hash.HashSet{}
+		for h := range remaining {
+			wanted.Insert(h)
+		}
+		found := make(chan *chunks.Chunk)
+		go func() { defer close(found); cs.GetMany(wanted, found) }()
+		for c := range found {
+			remaining.Remove(c.Hash())
+			foundChunks <- c
+		}
+	}
+
+	getFrom(mcs.primary)
+	for _, r := range mcs.replicas {
+		if len(remaining) == 0 {
+			return
+		}
+		getFrom(r.cs)
+	}
+}
+
+func (mcs *MirroringChunkStore) Has(h hash.Hash) bool {
+	if mcs.primary.Has(h) {
+		return true
+	}
+	for _, r := range mcs.replicas {
+		if r.cs.Has(h) {
+			return true
+		}
+	}
+	return false
+}
+
+func (mcs *MirroringChunkStore) HasMany(hashes hash.HashSet) (absent hash.HashSet) {
+	absent = mcs.primary.HasMany(hashes)
+	for _, r := range mcs.replicas {
+		if len(absent) == 0 {
+			break
+		}
+		absent = r.cs.HasMany(absent)
+	}
+	return absent
+}
+
+func (mcs *MirroringChunkStore) Put(c chunks.Chunk) {
+	mcs.mu.RLock()
+	defer mcs.mu.RUnlock()
+	mcs.primary.Put(c)
+	atomic.AddUint64(&mcs.putsSinceCommit, 1)
+	for _, r := range mcs.replicas {
+		if r.inSync() {
+			r.put(c)
+		}
+	}
+}
+
+func (mcs *MirroringChunkStore) Version() string {
+	return mcs.primary.Version()
+}
+
+// Rebase rebases the primary. If its root has been changed by someone else,
+// replicas are caught up to the new root.
+func (mcs *MirroringChunkStore) Rebase() {
+	mcs.mu.Lock()
+	defer mcs.mu.Unlock()
+	mcs.primary.Rebase()
+	mcs.setRoot(mcs.primary.Root())
+}
+
+func (mcs *MirroringChunkStore) Root() hash.Hash {
+	return mcs.primary.Root()
+}
+
+// Commit commits to the primary. If that succeeds, all replicas will be
+// caught up to |current| in the background.
+func (mcs *MirroringChunkStore) Commit(current, last hash.Hash) bool {
+	mcs.mu.Lock()
+	defer mcs.mu.Unlock()
+	if !mcs.primary.Commit(current, last) {
+		return false
+	}
+	atomic.StoreUint64(&mcs.putsSinceCommit, 0)
+	mcs.setRoot(current)
+	return true
+}
+
+// setRoot must be called with mcs.mu held for writing.
+func (mcs *MirroringChunkStore) setRoot(root hash.Hash) {
+	mcs.root = root
+	for _, r := range mcs.replicas {
+		r.catchUpTo(root)
+	}
+}
+
+// markInSync starts fanning Puts out to |r| again, as long as it has been
+// caught up to |root| and |root| is still the root of the primary with no
+// Puts since. Otherwise, |r| might have missed Puts that will be needed by
+// the next commit.
+func (mcs *MirroringChunkStore) markInSync(r *replica, root hash.Hash) {
+	mcs.mu.Lock()
+	defer mcs.mu.Unlock()
+	if root == mcs.root && atomic.LoadUint64(&mcs.putsSinceCommit) == 0 {
+		r.setInSync(true)
+	}
+}
+
+// WaitForReplicas blocks until every replica has either been caught up to
+// the most recently committed root, or has failed to catch up to it.
+func (mcs *MirroringChunkStore) WaitForReplicas() {
+	for _, r := range mcs.replicas {
+		r.wait()
+	}
+}
+
+// ReplicaErrors returns the most recent error encountered while catching up
+// each replica, in the order the replicas were given. The error for a
+// replica is cleared once it's successfully caught up.
+func (mcs *MirroringChunkStore) ReplicaErrors() []error {
+	errs := make([]error, len(mcs.replicas))
+	for i, r := range mcs.replicas {
+		errs[i] = r.error()
+	}
+	return errs
+}
+
+func (mcs *MirroringChunkStore) Stats() interface{} {
+	return mcs.primary.Stats()
+}
+
+// Close waits for all replicas to catch up, then closes the primary and all
+// the replicas.
+func (mcs *MirroringChunkStore) Close() (err error) {
+	for _, r := range mcs.replicas {
+		r.stop()
+	}
+	err = mcs.primary.Close()
+	for _, r := range mcs.replicas {
+		if rerr := r.cs.Close(); err == nil {
+			err = rerr
+		}
+	}
+	return
+}
+
+// replica tracks the state of a single replica of a MirroringChunkStore, and
+// runs the goroutine which catches it up.
+type replica struct {
+	cs     chunks.ChunkStore
+	synced int32 // accessed atomically; 1 iff Puts should be sent to |cs|
+
+	mu      *sync.Mutex
+	cond    *sync.Cond
+	current hash.Hash // the primary root that |cs| was last caught up to
+	target  hash.Hash // the primary root that |cs| should be caught up to
+	failed  hash.Hash // the target that |cs| most recently failed to catch up to, if err is non-nil
+	err     error
+	stopped bool
+}
+
+func newReplica(cs chunks.ChunkStore, root hash.Hash) *replica {
+	mu := &sync.Mutex{}
+	r := &replica{cs: cs, mu: mu, cond: sync.NewCond(mu), current: cs.Root(), target: root}
+	r.setInSync(r.current == root)
+	return r
+}
+
+func (r *replica) inSync() bool {
+	return atomic.LoadInt32(&r.synced) == 1
+}
+
+func (r *replica) setInSync(synced bool) {
+	if synced {
+		atomic.StoreInt32(&r.synced, 1)
+	} else {
+		atomic.StoreInt32(&r.synced, 0)
+	}
+}
+
+func (r *replica) put(c chunks.Chunk) {
+	defer func() {
+		if re := recover(); re != nil {
+			r.setInSync(false)
+			log.Printf("Mirroring: failed to write chunk %s to replica, will catch up later: %v", c.Hash(), re)
+		}
+	}()
+	r.cs.Put(c)
+}
+
+func (r *replica) catchUpTo(root hash.Hash) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.target = root
+	r.cond.Broadcast()
+}
+
+// idle must be called with r.mu held.
+func (r *replica) idle() bool {
+	return r.target == r.current || (r.err != nil && r.target == r.failed)
+}
+
+func (r *replica) wait() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for !r.idle() {
+		r.cond.Wait()
+	}
+}
+
+func (r *replica) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = true
+	r.cond.Broadcast()
+	for !r.idle() {
+		r.cond.Wait()
+	}
+}
+
+func (r *replica) error() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *replica) run(mcs *MirroringChunkStore) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		for r.idle() && !r.stopped {
+			r.cond.Wait()
+		}
+		if r.idle() {
+			return
+		}
+
+		target := r.target
+		r.mu.Unlock()
+		err := r.catchUp(mcs.primary, target)
+		if err == nil {
+			mcs.markInSync(r, target)
+		} else {
+			log.Printf("Mirroring: failed to catch replica up to %s: %v", target, err)
+		}
+		r.mu.Lock()
+
+		if err == nil {
+			r.current, r.err = target, nil
+		} else {
+			r.failed, r.err = target, err
+		}
+		r.cond.Broadcast()
+	}
+}
+
+// catchUp pulls everything reachable from |root| that r.cs is missing out of
+// |primary|, then sets r.cs's root to |root|.
+func (r *replica) catchUp(primary chunks.ChunkStore, root hash.Hash) (err error) {
+	defer func() {
+		if re := recover(); re != nil {
+			r.setInSync(false)
+			err = fmt.Errorf("%v", re)
+		}
+	}()
+
+	r.cs.Rebase()
+	if r.cs.Root() == root {
+		return nil
+	}
+	if !root.IsEmpty() {
+		srcDB, sinkDB := newDatabase(primary), newDatabase(r.cs)
+		Pull(srcDB, sinkDB, types.NewRef(srcDB.ReadValue(root)), nil)
+	}
+	for !r.cs.Commit(root, r.cs.Root()) {
+		r.cs.Rebase()
+	}
+	return nil
+}
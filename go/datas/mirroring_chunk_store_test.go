@@ -0,0 +1,150 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package datas
+
+import (
+	"testing"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/attic-labs/noms/go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingChunkStore panics on every write, as a broken replica might.
+type failingChunkStore struct {
+	chunks.ChunkStore
+}
+
+func (fcs failingChunkStore) Put(c chunks.Chunk) {
+	panic("Put failed")
+}
+
+func (fcs failingChunkStore) Commit(current, last hash.Hash) bool {
+	panic("Commit failed")
+}
+
+func assertMirrored(assert *assert.Assertions, storage *chunks.MemoryStorage, ds string, expected types.Value) {
+	db := NewDatabase(storage.NewView())
+	defer db.Close()
+	head, ok := db.GetDataset(ds).MaybeHeadValue()
+	if assert.True(ok) {
+		assert.True(expected.Equals(head))
+	}
+}
+
+func TestMirroringChunkStoreCommit(t *testing.T) {
+	assert := assert.New(t)
+	primary, replica1, replica2 := &chunks.MemoryStorage{}, &chunks.MemoryStorage{}, &chunks.MemoryStorage{}
+	mcs := NewMirroringChunkStore(primary.NewView(), replica1.NewView(), replica2.NewView())
+	db := NewDatabase(mcs)
+	defer db.Close()
+
+	l := types.NewList(db, types.Number(1), types.String("two"), types.Bool(true))
+	_, err := db.CommitValue(db.GetDataset("ds"), l)
+	assert.NoError(err)
+	mcs.WaitForReplicas()
+
+	assert.Equal(primary.Root(), replica1.Root())
+	assert.Equal(primary.Root(), replica2.Root())
+	assertMirrored(assert, replica1, "ds", l)
+	assertMirrored(assert, replica2, "ds", l)
+	assert.Equal([]error{nil, nil}, mcs.ReplicaErrors())
+
+	// A commit that fails on the primary must not touch the replicas.
+	root := replica1.Root()
+	c := chunks.NewChunk([]byte("abc"))
+	mcs.Put(c)
+	assert.False(mcs.Commit(c.Hash(), hash.Hash{}))
+	mcs.WaitForReplicas()
+	assert.Equal(root, replica1.Root())
+}
+
+func TestMirroringChunkStoreCatchesUpStaleReplica(t *testing.T) {
+	assert := assert.New(t)
+	primary, replica := &chunks.MemoryStorage{}, &chunks.MemoryStorage{}
+
+	// Build up some history in the primary before it's mirrored.
+	func() {
+		db := NewDatabase(primary.NewView())
+		defer db.Close()
+		_, err := db.CommitValue(db.GetDataset("ds"), types.NewMap(db, types.String("a"), types.Number(1)))
+		assert.NoError(err)
+	}()
+
+	mcs := NewMirroringChunkStore(primary.NewView(), replica.NewView())
+	mcs.WaitForReplicas()
+	assert.Equal(primary.Root(), replica.Root())
+
+	db := NewDatabase(mcs)
+	defer db.Close()
+	ds := db.GetDataset("ds")
+	m := ds.HeadValue().(types.Map).Edit().Set(types.String("b"), types.Number(2)).Map()
+	_, err := db.CommitValue(ds, m)
+	assert.NoError(err)
+	mcs.WaitForReplicas()
+
+	assert.Equal(primary.Root(), replica.Root())
+	assertMirrored(assert, replica, "ds", m)
+	replicaDB := NewDatabase(replica.NewView())
+	defer replicaDB.Close()
+	assert.Equal(uint64(2), replicaDB.GetDataset("ds").HeadValue().(types.Map).Len())
+	assert.True(replicaDB.GetDataset("ds").Head().Get(ParentsField).(types.Set).Len() == 1)
+}
+
+func TestMirroringChunkStoreFailingReplica(t *testing.T) {
+	assert := assert.New(t)
+	primary, replica := &chunks.MemoryStorage{}, &chunks.MemoryStorage{}
+	mcs := NewMirroringChunkStore(primary.NewView(), failingChunkStore{(&chunks.MemoryStorage{}).NewView()}, replica.NewView())
+	db := NewDatabase(mcs)
+	defer db.Close()
+
+	s := types.String("still works")
+	_, err := db.CommitValue(db.GetDataset("ds"), s)
+	assert.NoError(err)
+	mcs.WaitForReplicas()
+
+	assertMirrored(assert, primary, "ds", s)
+	assertMirrored(assert, replica, "ds", s)
+	errs := mcs.ReplicaErrors()
+	assert.Error(errs[0])
+	assert.NoError(errs[1])
+}
+
+func TestMirroringChunkStoreReadFallback(t *testing.T) {
+	assert := assert.New(t)
+	primary, replica := &chunks.MemoryStorage{}, &chunks.MemoryStorage{}
+	mcs := NewMirroringChunkStore(primary.NewView(), replica.NewView())
+	defer mcs.Close()
+
+	inBoth := chunks.NewChunk([]byte("both"))
+	onlyReplica := chunks.NewChunk([]byte("replica"))
+	missing := chunks.NewChunk([]byte("missing"))
+	mcs.Put(inBoth)
+	replicaView := replica.NewView()
+	replicaView.Put(onlyReplica)
+	assert.True(replicaView.Commit(replicaView.Root(), replicaView.Root()))
+	mcs.replicas[0].cs.Rebase()
+
+	assert.Equal(inBoth.Data(), mcs.Get(inBoth.Hash()).Data())
+	assert.Equal(onlyReplica.Data(), mcs.Get(onlyReplica.Hash()).Data())
+	assert.True(mcs.Get(missing.Hash()).IsEmpty())
+
+	assert.True(mcs.Has(onlyReplica.Hash()))
+	assert.False(mcs.Has(missing.Hash()))
+	absent := mcs.HasMany(hash.NewHashSet(inBoth.Hash(), onlyReplica.Hash(), missing.Hash()))
+	assert.Equal(hash.NewHashSet(missing.Hash()), absent)
+
+	found := make(chan *chunks.Chunk)
+	go func() {
+		defer close(found)
+		mcs.GetMany(hash.NewHashSet(inBoth.Hash(), onlyReplica.Hash(), missing.Hash()), found)
+	}()
+	got := hash.HashSet{}
+	for c := range found {
+		got.Insert(c.Hash())
+	}
+	assert.Equal(hash.NewHashSet(inBoth.Hash(), onlyReplica.Hash()), got)
+}
@@ -409,12 +409,19 @@ func handleRootPost(w http.ResponseWriter, req *http.Request, ps URLParams, cs c
 		// traverse the Ref<Commit>s stored in the maps, though, just
 		// basically merge the maps together as long the changes to rootMap
 		// and proposedMap were in different Datasets.
+		sm := serverMetricsFromRequest(req)
 		merged, err := mergeDatasetMaps(proposedMap, rootMap, lastMap, vs)
 		if err != nil {
 			verbose.Log("Attempted root map auto-merge failed: %s", err)
+			if sm != nil {
+				sm.commitConflict(conflictRejected)
+			}
 			w.WriteHeader(http.StatusConflict)
 			break
 		}
+		if sm != nil {
+			sm.commitConflict(conflictMerged)
+		}
 		to, from = vs.WriteValue(merged).TargetHash(), root
 	}
 
@@ -561,8 +568,8 @@ func handleGraphQL(w http.ResponseWriter, req *http.Request, ps URLParams, cs ch
 	ds := req.FormValue("ds")
 	h := req.FormValue("h")
 
-	if (ds == "") == (h == "") {
-		d.Panic("Must specify one (and only one) of ds (dataset) or h (hash)")
+	if ds != "" && h != "" {
+		d.Panic("Must specify at most one of ds (dataset) or h (hash)")
 	}
 
 	query := req.FormValue("query")
@@ -573,31 +580,63 @@ func handleGraphQL(w http.ResponseWriter, req *http.Request, ps URLParams, cs ch
 	// Note: we don't close this becaues |cs| will be closed by the generic endpoint handler
 	db := NewDatabase(cs)
 
-	var rootValue types.Value
-	var err error
-	if ds != "" {
+	w.Header().Add("Content-Type", "application/json")
+	writer := respWriter(req, w)
+	defer writer.Close()
+
+	// Only POST requests may mutate the dataset.
+	queryGraphQL(db, ds, h, query, req.Method != http.MethodPost, writer)
+}
+
+// queryGraphQL runs |query| against the head of the dataset |ds|, the value
+// whose hash is |h| or, if neither is given, |db| itself, and writes the
+// result to |w|. Mutations of |ds| fail if |readOnly|.
+func queryGraphQL(db Database, ds, h, query string, readOnly bool, w io.Writer) {
+	switch {
+	case ds != "":
 		dataset := db.GetDataset(ds)
-		var ok bool
-		rootValue, ok = dataset.MaybeHead()
-		if !ok {
-			err = fmt.Errorf("Dataset %s not found", ds)
+		if !dataset.HasHead() {
+			ngql.Error(fmt.Errorf("Dataset %s not found", ds), w)
+			return
 		}
-	} else {
-		rootValue = db.ReadValue(hash.Parse(h))
+		ngql.QueryWithMutations(&datasetCommitter{db, dataset, readOnly}, query, db, w)
+	case h != "":
+		rootValue := db.ReadValue(hash.Parse(h))
 		if rootValue == nil {
-			err = errors.New("Root value not found")
+			ngql.Error(errors.New("Root value not found"), w)
+			return
 		}
+		ngql.Query(rootValue, query, db, w)
+	default:
+		ngql.QueryDatabase(db, query, w)
 	}
+}
 
-	w.Header().Add("Content-Type", "application/json")
-	writer := respWriter(req, w)
-	defer writer.Close()
+// datasetCommitter is the ngql.Committer for GraphQL mutations of a dataset.
+type datasetCommitter struct {
+	db       Database
+	ds       Dataset
+	readOnly bool
+}
 
+func (c *datasetCommitter) Head() types.Struct {
+	return c.ds.Head()
+}
+
+func (c *datasetCommitter) Commit(value types.Value, message string) (types.Struct, error) {
+	if c.readOnly {
+		return types.Struct{}, errors.New("Mutations must be sent with POST")
+	}
+	meta := types.StructData{"date": types.String(time.Now().UTC().Format(time.RFC3339))}
+	if message != "" {
+		meta["message"] = types.String(message)
+	}
+	ds, err := c.db.Commit(c.ds, value, CommitOptions{Meta: types.NewStruct("", meta)})
 	if err != nil {
-		ngql.Error(err, writer)
-	} else {
-		ngql.Query(rootValue, query, db, writer)
+		return types.Struct{}, err
 	}
+	c.ds = ds
+	return ds.Head(), nil
 }
 
 func handleBaseGet(w http.ResponseWriter, req *http.Request, ps URLParams, rt chunks.ChunkStore) {
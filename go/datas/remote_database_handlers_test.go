@@ -428,3 +428,54 @@ type params map[string]string
 func (p params) ByName(k string) string {
 	return p[k]
 }
+
+func TestHandleGraphQLMutation(t *testing.T) {
+	assert := assert.New(t)
+	storage := &chunks.MemoryStorage{}
+	db := NewDatabase(storage.NewView())
+	ds, err := db.CommitValue(db.GetDataset("foo"), types.NewMap(db, types.String("a"), types.Number(1)))
+	assert.NoError(err)
+	head := ds.Head()
+
+	graphQL := func(method, query string) string {
+		u := "/graphql/?" + url.Values{"ds": {"foo"}, "query": {query}}.Encode()
+		w := httptest.NewRecorder()
+		HandleGraphQL(w, newRequest(method, "", u, strings.NewReader(""), http.Header{}), params{}, storage.NewView())
+		assert.Equal(http.StatusOK, w.Code, "Handler error:\n%s", string(w.Body.Bytes()))
+		return w.Body.String()
+	}
+
+	// GET requests can't mutate.
+	mutation := `mutation {setEntry(key: "b", value: 2, message: "add b") {hash}}`
+	assert.Contains(graphQL("GET", mutation), "Mutations must be sent with POST")
+
+	assert.Contains(graphQL("POST", mutation), `"setEntry":{"hash":`)
+	db.Rebase()
+	ds = db.GetDataset("foo")
+	assert.True(types.NewMap(db, types.String("a"), types.Number(1), types.String("b"), types.Number(2)).Equals(ds.HeadValue()))
+	assert.True(ds.Head().Get(ParentsField).(types.Set).Has(types.NewRef(head)))
+	assert.Equal(types.String("add b"), ds.Head().Get(MetaField).(types.Struct).Get("message"))
+
+	// The head has moved on from |head|.
+	res := graphQL("POST", fmt.Sprintf(`mutation {deleteEntry(key: "a", expectedHead: "%s") {hash}}`, head.Hash()))
+	assert.Contains(res, "errors")
+	db.Rebase()
+	assert.True(ds.Head().Equals(db.GetDataset("foo").Head()))
+}
+
+func TestHandleGraphQLDatabase(t *testing.T) {
+	assert := assert.New(t)
+	storage := &chunks.MemoryStorage{}
+	db := NewDatabase(storage.NewView())
+	ds, err := db.CommitValue(db.GetDataset("foo"), types.Number(1))
+	assert.NoError(err)
+	ds, err = db.CommitValue(ds, types.Number(2))
+	assert.NoError(err)
+
+	u := "/graphql/?" + url.Values{"query": {`{datasets{name history{edges{node{value}}}} diff(from: "` +
+		ds.Head().Hash().String() + `", to: "` + ds.Head().Hash().String() + `"){path}}`}}.Encode()
+	w := httptest.NewRecorder()
+	HandleGraphQL(w, newRequest("GET", "", u, strings.NewReader(""), http.Header{}), params{}, storage.NewView())
+	assert.Equal(http.StatusOK, w.Code, "Handler error:\n%s", string(w.Body.Bytes()))
+	assert.JSONEq(`{"data":{"datasets":[{"name":"foo","history":{"edges":[{"node":{"value":2}},{"node":{"value":1}}]}}],"diff":[]}}`, w.Body.String())
+}
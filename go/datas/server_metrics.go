@@ -0,0 +1,218 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package datas
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/metrics"
+	"github.com/attic-labs/noms/go/ngql"
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	conflictMerged   = "merged"
+	conflictRejected = "rejected"
+)
+
+// serverMetrics collects the request and commit statistics of a
+// RemoteDatabaseServer, and serves them, along with the statistics of its
+// ChunkStore, in the Prometheus text format.
+type serverMetrics struct {
+	mu        sync.Mutex
+	handlers  map[handlerKey]*handlerMetrics
+	conflicts map[string]uint64 // keyed by conflictMerged or conflictRejected
+}
+
+type handlerKey struct {
+	name, method string
+}
+
+type handlerMetrics struct {
+	requests, errors            uint64
+	requestBytes, responseBytes uint64
+	latency                     metrics.Histogram
+}
+
+type serverMetricsKey struct{}
+
+func newServerMetrics() *serverMetrics {
+	return &serverMetrics{
+		handlers:  map[handlerKey]*handlerMetrics{},
+		conflicts: map[string]uint64{conflictMerged: 0, conflictRejected: 0},
+	}
+}
+
+// serverMetricsFromRequest returns the serverMetrics of the server handling
+// |req|, or nil if it's not being instrumented.
+func serverMetricsFromRequest(req *http.Request) *serverMetrics {
+	sm, _ := req.Context().Value(serverMetricsKey{}).(*serverMetrics)
+	return sm
+}
+
+// instrument wraps |f|, recording the count, latency, errors and bytes
+// transferred of every request it handles under |name|.
+func (sm *serverMetrics) instrument(name string, f httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		t1 := time.Now()
+		body := &countingReadCloser{ReadCloser: req.Body}
+		req.Body = body
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		defer func() {
+			sm.mu.Lock()
+			defer sm.mu.Unlock()
+			key := handlerKey{name, req.Method}
+			hm, ok := sm.handlers[key]
+			if !ok {
+				hm = &handlerMetrics{latency: metrics.NewTimeHistogram()}
+				sm.handlers[key] = hm
+			}
+			hm.requests++
+			if rec.status >= http.StatusBadRequest {
+				hm.errors++
+			}
+			hm.requestBytes += body.n
+			hm.responseBytes += rec.n
+			hm.latency.SampleTimeSince(t1)
+		}()
+
+		f(rec, req.WithContext(context.WithValue(req.Context(), serverMetricsKey{}, sm)), ps)
+	}
+}
+
+// commitConflict records that a commit conflicted with another, and was
+// either merged automatically or rejected.
+func (sm *serverMetrics) commitConflict(resolution string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.conflicts[resolution]++
+}
+
+// write writes all collected metrics, then the statistics of GraphQL queries,
+// followed by the histograms in the Stats() of |cs|, if any, to |w|.
+func (sm *serverMetrics) write(w io.Writer, cs chunks.ChunkStore) error {
+	pw := metrics.NewPrometheusWriter(w)
+
+	sm.mu.Lock()
+	keys := make([]handlerKey, 0, len(sm.handlers))
+	handlers := make(map[handlerKey]handlerMetrics, len(sm.handlers))
+	for key, hm := range sm.handlers {
+		keys = append(keys, key)
+		handlers[key] = *hm
+	}
+	conflicts := map[string]uint64{}
+	for resolution, n := range sm.conflicts {
+		conflicts[resolution] = n
+	}
+	sm.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+		return keys[i].method < keys[j].method
+	})
+	labels := func(key handlerKey) metrics.Labels {
+		return metrics.Labels{"handler": key.name, "method": key.method}
+	}
+	for _, key := range keys {
+		pw.Counter("noms_http_requests_total", "Requests handled, by handler and HTTP method.", labels(key), handlers[key].requests)
+	}
+	for _, key := range keys {
+		pw.Counter("noms_http_request_errors_total", "Requests that resulted in an HTTP error status, by handler and HTTP method.", labels(key), handlers[key].errors)
+	}
+	for _, key := range keys {
+		pw.Histogram("noms_http_request_duration_seconds", "Time taken to handle requests, by handler and HTTP method.", labels(key), handlers[key].latency, 1e-9)
+	}
+	for _, key := range keys {
+		pw.Counter("noms_http_request_bytes_total", "Bytes of request bodies read, by handler and HTTP method.", labels(key), handlers[key].requestBytes)
+	}
+	for _, key := range keys {
+		pw.Counter("noms_http_response_bytes_total", "Bytes of response bodies written, by handler and HTTP method.", labels(key), handlers[key].responseBytes)
+	}
+	for _, resolution := range []string{conflictMerged, conflictRejected} {
+		pw.Counter("noms_commit_conflicts_total", "Commits that conflicted with a concurrent commit, by whether the conflict was merged automatically or rejected.", metrics.Labels{"resolution": resolution}, conflicts[resolution])
+	}
+
+	qs := ngql.Stats()
+	pw.Counter("noms_graphql_queries_total", "GraphQL queries run, including those answered from the result cache.", nil, qs.Queries)
+	pw.Counter("noms_graphql_result_cache_hits_total", "GraphQL queries answered from the result cache.", nil, qs.ResultCacheHits)
+	pw.Counter("noms_graphql_values_read_total", "Values read one at a time by GraphQL queries.", nil, qs.ValuesRead)
+	pw.Counter("noms_graphql_batched_reads_total", "Batches of Values read together by GraphQL queries.", nil, qs.BatchedReads)
+	pw.Counter("noms_graphql_batched_values_total", "Values read in batches by GraphQL queries.", nil, qs.BatchedValues)
+	pw.Counter("noms_graphql_loader_cache_hits_total", "Reads of Values that a GraphQL query had already read.", nil, qs.LoaderCacheHits)
+
+	pw.StructHistograms("noms_store_", nil, cs.Stats())
+	return pw.Err()
+}
+
+// handleMetrics serves the metrics collected by |sm|, and the statistics of
+// |cs|.
+func (sm *serverMetrics) handleMetrics(w http.ResponseWriter, req *http.Request, cs chunks.ChunkStore) {
+	buff := &bytes.Buffer{}
+	if err := sm.write(buff, cs); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", metrics.PrometheusContentType)
+	io.Copy(w, buff)
+}
+
+type countingReadCloser struct {
+	io.ReadCloser
+	n uint64
+}
+
+func (crc *countingReadCloser) Read(p []byte) (n int, err error) {
+	n, err = crc.ReadCloser.Read(p)
+	crc.n += uint64(n)
+	return
+}
+
+// responseRecorder records the status code and number of bytes of the
+// response written through it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	n      uint64
+}
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	rr.status = status
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *responseRecorder) Write(p []byte) (n int, err error) {
+	n, err = rr.ResponseWriter.Write(p)
+	rr.n += uint64(n)
+	return
+}
+
+func (rr *responseRecorder) Flush() {
+	if f, ok := rr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets WebSocket handlers take over the connection.
+func (rr *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("ResponseWriter is not a Hijacker")
+	}
+	rr.status = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
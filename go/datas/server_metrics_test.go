@@ -0,0 +1,84 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package datas
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/attic-labs/noms/go/metrics"
+	"github.com/attic-labs/noms/go/types"
+	"github.com/julienschmidt/httprouter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerMetrics(t *testing.T) {
+	assert := assert.New(t)
+	storage := &chunks.MemoryStorage{}
+	vs := types.NewValueStore(storage.NewView())
+	sm := newServerMetrics()
+
+	handle := func(name string, hndlr Handler) httprouter.Handle {
+		return sm.instrument(name, func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+			hndlr(w, req, ps, storage.NewView())
+		})
+	}
+
+	w := httptest.NewRecorder()
+	handle("root", HandleRootGet)(w, newRequest("GET", "", "", nil, nil), nil)
+	assert.Equal(http.StatusOK, w.Code)
+
+	// A post whose 'last' doesn't match, and whose changes can't be merged, is
+	// rejected.
+	commit := buildTestCommit(vs, types.String("head"))
+	commitRef := vs.WriteValue(commit)
+	firstHeadRef := vs.WriteValue(types.NewMap(vs, types.String("dataset1"), types.ToRefOfValue(commitRef)))
+	commit = buildTestCommit(vs, types.String("second"), commitRef)
+	newHeadRef := vs.WriteValue(types.NewMap(vs, types.String("dataset1"), types.ToRefOfValue(vs.WriteValue(commit))))
+	vs.Commit(vs.Root(), vs.Root())
+
+	w = httptest.NewRecorder()
+	handle("root", HandleRootPost)(w, newRequest("POST", "", buildPostRootURL(newHeadRef.TargetHash(), firstHeadRef.TargetHash()), nil, nil), nil)
+	assert.Equal(http.StatusConflict, w.Code)
+
+	body, err := ioutil.ReadAll(buildHashesRequest(chunks.ReadBatch{commitRef.TargetHash(): nil}))
+	assert.NoError(err)
+	w = httptest.NewRecorder()
+	handle("hasRefs", HandleHasRefs)(w, newRequest("POST", "", "", bytes.NewReader(body), http.Header{"Content-Type": {"application/octet-stream"}}), nil)
+	assert.Equal(http.StatusOK, w.Code)
+
+	buff := &bytes.Buffer{}
+	assert.NoError(sm.write(buff, storage.NewView()))
+	out := buff.String()
+	assert.Contains(out, "# TYPE noms_http_requests_total counter\n")
+	assert.Contains(out, `noms_http_requests_total{handler="root",method="GET"} 1`+"\n")
+	assert.Contains(out, `noms_http_requests_total{handler="root",method="POST"} 1`+"\n")
+	assert.Contains(out, `noms_http_requests_total{handler="hasRefs",method="POST"} 1`+"\n")
+	assert.Contains(out, `noms_http_request_errors_total{handler="root",method="GET"} 0`+"\n")
+	assert.Contains(out, `noms_http_request_errors_total{handler="root",method="POST"} 1`+"\n")
+	assert.Contains(out, `noms_http_request_bytes_total{handler="hasRefs",method="POST"} `+strconv.Itoa(len(body))+"\n")
+	assert.Contains(out, `noms_http_request_duration_seconds_count{handler="root",method="GET"} 1`+"\n")
+	assert.Contains(out, `noms_http_response_bytes_total{handler="root",method="GET"} `+strconv.Itoa(hash.StringLen)+"\n")
+	assert.Contains(out, `noms_commit_conflicts_total{resolution="merged"} 0`+"\n")
+	assert.Contains(out, `noms_commit_conflicts_total{resolution="rejected"} 1`+"\n")
+	assert.Contains(out, "# TYPE noms_graphql_queries_total counter\n")
+}
+
+func TestServerMetricsHandler(t *testing.T) {
+	assert := assert.New(t)
+	sm := newServerMetrics()
+	w := httptest.NewRecorder()
+	sm.handleMetrics(w, newRequest("GET", "", "", nil, nil), (&chunks.TestStorage{}).NewView())
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal(metrics.PrometheusContentType, w.Header().Get("Content-Type"))
+	assert.True(strings.Contains(w.Body.String(), "noms_commit_conflicts_total"))
+}
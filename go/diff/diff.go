@@ -41,6 +41,8 @@ type differ struct {
 	stopChan chan struct{}
 	// Use LeftRight diff as opposed to TopDown
 	leftRight bool
+	// Limits which parts of the graphs are traversed
+	filter Filter
 }
 
 // Diff traverses two graphs simultaneously looking for differences. It returns
@@ -75,14 +77,31 @@ type differ struct {
 //        <some code>
 //    }
 func Diff(v1, v2 types.Value, dChan chan<- Difference, stopChan chan struct{}, leftRight bool) {
-	d := differ{diffChan: dChan, stopChan: stopChan, leftRight: leftRight}
+	DiffWithFilter(v1, v2, dChan, stopChan, leftRight, Filter{})
+}
+
+// DiffWithFilter is like Diff, but only traverses the parts of |v1| and |v2|
+// that |filter| selects, and descends no deeper than its MaxDepth. Pruning
+// happens during the traversal, so the values beneath Paths that aren't
+// selected are never loaded.
+func DiffWithFilter(v1, v2 types.Value, dChan chan<- Difference, stopChan chan struct{}, leftRight bool, filter Filter) {
+	d := differ{diffChan: dChan, stopChan: stopChan, leftRight: leftRight, filter: filter}
 	if !v1.Equals(v2) {
-		if !shouldDescend(v1, v2) {
-			d.sendDiff(Difference{Path: nil, ChangeType: types.DiffChangeModified, OldValue: v1, NewValue: v2})
-		} else {
-			d.diff(nil, v1, v2)
-		}
+		d.descend(nil, v1, v2)
+	}
+}
+
+// descend diffs |v1| and |v2|, the differing values at |p|, either by
+// recursing into them or by sending a single modification if they can't, or
+// mustn't, be descended into. Nothing is done if |p| isn't selected.
+func (d differ) descend(p types.Path, v1, v2 types.Value) bool {
+	if !d.filter.Selects(p) {
+		return false
 	}
+	if shouldDescend(v1, v2) && !d.filter.atMaxDepth(p) {
+		return d.diff(p, v1, v2)
+	}
+	return !d.sendDiff(Difference{Path: p, ChangeType: types.DiffChangeModified, OldValue: v1, NewValue: v2})
 }
 
 func (d differ) diff(p types.Path, v1, v2 types.Value) bool {
@@ -115,17 +134,12 @@ func (d differ) diffLists(p types.Path, v1, v2 types.List) (stop bool) {
 		}
 		if splice.SpRemoved == splice.SpAdded {
 			// Heuristic: list only has modifications.
-			for i := uint64(0); i < splice.SpRemoved; i++ {
-				lastEl := v1.Get(splice.SpAt + i)
-				newEl := v2.Get(splice.SpFrom + i)
-				if shouldDescend(lastEl, newEl) {
-					idx := types.Number(splice.SpAt + i)
-					stop = d.diff(append(p, types.NewIndexPath(idx)), lastEl, newEl)
-				} else {
-					p1 := p.Append(types.NewIndexPath(types.Number(splice.SpAt + i)))
-					dif := Difference{p1, types.DiffChangeModified, v1.Get(splice.SpAt + i), v2.Get(splice.SpFrom + i), nil}
-					stop = !d.sendDiff(dif)
+			for i := uint64(0); i < splice.SpRemoved && !stop; i++ {
+				p1 := p.Append(types.NewIndexPath(types.Number(splice.SpAt + i)))
+				if !d.filter.Selects(p1) {
+					continue
 				}
+				stop = d.descend(p1, v1.Get(splice.SpAt+i), v2.Get(splice.SpFrom+i))
 			}
 			continue
 		}
@@ -133,11 +147,17 @@ func (d differ) diffLists(p types.Path, v1, v2 types.List) (stop bool) {
 		// Heuristic: list only has additions/removals.
 		for i := uint64(0); i < splice.SpRemoved && !stop; i++ {
 			p1 := p.Append(types.NewIndexPath(types.Number(splice.SpAt + i)))
+			if !d.filter.Selects(p1) {
+				continue
+			}
 			dif := Difference{Path: p1, ChangeType: types.DiffChangeRemoved, OldValue: v1.Get(splice.SpAt + i), NewValue: nil}
 			stop = !d.sendDiff(dif)
 		}
 		for i := uint64(0); i < splice.SpAdded && !stop; i++ {
 			p1 := p.Append(types.NewIndexPath(types.Number(splice.SpFrom + i)))
+			if !d.filter.Selects(p1) {
+				continue
+			}
 			dif := Difference{Path: p1, ChangeType: types.DiffChangeAdded, OldValue: nil, NewValue: v2.Get(splice.SpFrom + i)}
 			stop = !d.sendDiff(dif)
 		}
@@ -226,6 +246,9 @@ func (d differ) diffOrdered(p types.Path, ppf pathPartFunc, df diffFunc, kf, v1,
 
 		k := kf(change.Key)
 		p1 := p.Append(ppf(k))
+		if !d.filter.Selects(p1) {
+			continue
+		}
 
 		switch change.ChangeType {
 		case types.DiffChangeAdded:
@@ -235,13 +258,7 @@ func (d differ) diffOrdered(p types.Path, ppf pathPartFunc, df diffFunc, kf, v1,
 			dif := Difference{Path: p1, ChangeType: types.DiffChangeRemoved, OldValue: v1(change.Key), NewValue: nil}
 			stop = !d.sendDiff(dif)
 		case types.DiffChangeModified:
-			c1, c2 := v1(change.Key), v2(change.Key)
-			if shouldDescend(c1, c2) {
-				stop = d.diff(p1, c1, c2)
-			} else {
-				dif := Difference{Path: p1, ChangeType: types.DiffChangeModified, OldValue: c1, NewValue: c2}
-				stop = !d.sendDiff(dif)
-			}
+			stop = d.descend(p1, v1(change.Key), v2(change.Key))
 		default:
 			panic("unknown change type")
 		}
@@ -493,3 +493,19 @@ func TestNomsDiffPrintRef(t *testing.T) {
 	tf(true)
 	tf(false)
 }
+
+func TestIsCommit(t *testing.T) {
+	assert := assert.New(t)
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	commit := types.NewStruct("Commit", types.StructData{
+		"meta":    types.NewStruct("", types.StructData{"date": types.String("today")}),
+		"parents": types.NewSet(vs),
+		"value":   types.Number(1),
+	})
+	assert.True(isCommit(commit))
+	assert.False(isCommit(commit.Delete("parents")))
+	assert.False(isCommit(types.NewStruct("NotCommit", types.StructData{})))
+	assert.False(isCommit(types.Number(1)))
+}
@@ -0,0 +1,27 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package diff
+
+import (
+	"github.com/attic-labs/noms/go/types"
+)
+
+// Filter limits the parts of two graphs that DiffWithFilter traverses. The
+// zero Filter doesn't limit anything.
+type Filter struct {
+	// PathFilter selects the Paths that are diffed. Values at Paths that
+	// aren't selected, and that contain nothing that is, aren't visited.
+	// Values that were added or removed are reported whole.
+	types.PathFilter
+	// MaxDepth, if greater than 0, is the length of the longest Path that is
+	// diffed. A changed collection or struct at that depth is reported as a
+	// single modification instead of being descended into.
+	MaxDepth int
+}
+
+// atMaxDepth returns true if values at |p| mustn't be descended into.
+func (f Filter) atMaxDepth(p types.Path) bool {
+	return f.MaxDepth > 0 && len(p) >= f.MaxDepth
+}
@@ -0,0 +1,53 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package diff
+
+import (
+	"testing"
+
+	"github.com/attic-labs/noms/go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffWithFilter(t *testing.T) {
+	assert := assert.New(t)
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	row := func(name string, price float64) types.Struct {
+		return types.NewStruct("Row", types.StructData{"name": types.String(name), "price": types.Number(price)})
+	}
+	v1 := types.NewStruct("", types.StructData{
+		"rows":  types.NewList(vs, row("a", 1), row("b", 2)),
+		"title": types.String("before"),
+	})
+	v2 := types.NewStruct("", types.StructData{
+		"rows":  types.NewList(vs, row("a", 10), row("bb", 2)),
+		"title": types.String("after"),
+	})
+
+	paths := func(filter Filter) []string {
+		strs := []string{}
+		streamDiff(v1, v2, false, filter, func(dif Difference) error {
+			strs = append(strs, dif.Path.String())
+			return nil
+		})
+		return strs
+	}
+	globs := func(strs ...string) []types.PathGlob {
+		gs := make([]types.PathGlob, len(strs))
+		for i, str := range strs {
+			gs[i] = types.MustParsePathGlob(str)
+		}
+		return gs
+	}
+
+	assert.Equal([]string{".rows[0].price", ".rows[1].name", ".title"}, paths(Filter{}))
+	assert.Equal([]string{".rows[0].price"}, paths(Filter{PathFilter: types.PathFilter{Include: globs(".rows[*].price")}}))
+	assert.Equal([]string{".rows[0].price", ".rows[1].name"}, paths(Filter{PathFilter: types.PathFilter{Include: globs(".rows")}}))
+	assert.Equal([]string{".rows[1].name", ".title"}, paths(Filter{PathFilter: types.PathFilter{Exclude: globs(".rows[*].price")}}))
+	assert.Equal([]string{".rows", ".title"}, paths(Filter{MaxDepth: 1}))
+	assert.Equal([]string{".rows[0]", ".rows[1]"}, paths(Filter{PathFilter: types.PathFilter{Include: globs(".rows")}, MaxDepth: 2}))
+}
@@ -0,0 +1,282 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/attic-labs/noms/go/types"
+)
+
+var changeTypeNames = map[types.DiffChangeType]string{
+	types.DiffChangeAdded:    "added",
+	types.DiffChangeRemoved:  "removed",
+	types.DiffChangeModified: "modified",
+}
+
+// jsonDifference is the form in which WriteJSON writes a Difference.
+type jsonDifference struct {
+	Path       string      `json:"path"`
+	ChangeType string      `json:"change"`
+	Old        interface{} `json:"old,omitempty"`
+	New        interface{} `json:"new,omitempty"`
+	OldEncoded string      `json:"oldEncoded,omitempty"`
+	NewEncoded string      `json:"newEncoded,omitempty"`
+}
+
+// jsonPatchOp is a single RFC 6902 operation.
+type jsonPatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// WriteJSON writes the diff from |v1| to |v2| to |w| as a stream of JSON
+// objects, one per line, each describing a Difference:
+//
+//	{"path": ".foo[1]", "change": "added|removed|modified", "old": ..., "new": ...}
+//
+// "path" is the Difference's Path as a string, which is empty for the root.
+// "old" and "new" are the OldValue and NewValue as JSON (see ValueToJSON), and
+// are omitted if there isn't one. Values that can't be represented as JSON
+// are given as their Noms encoding in "oldEncoded" and "newEncoded" instead.
+// If |leftRight| is true then the left-right diff is used for ordered
+// sequences - see Diff vs DiffLeftRight in Set and Map. Only the differences
+// that DiffWithFilter finds using |filter| are written.
+func WriteJSON(w io.Writer, v1, v2 types.Value, leftRight bool, filter Filter) error {
+	enc := json.NewEncoder(w)
+	return streamDiff(v1, v2, leftRight, filter, func(dif Difference) error {
+		jd := jsonDifference{Path: dif.Path.String(), ChangeType: changeTypeNames[dif.ChangeType]}
+		jd.Old, jd.OldEncoded = jsonOrEncoded(dif.OldValue)
+		jd.New, jd.NewEncoded = jsonOrEncoded(dif.NewValue)
+		return enc.Encode(jd)
+	})
+}
+
+// WriteJSONPatch writes the diff from |v1| to |v2| to |w| as a JSON Patch
+// (RFC 6902): an array of operations that, applied in order to the JSON
+// representation of |v1|, give that of |v2|. This can only be done if every
+// changed value, and every container on the path to it, can be represented
+// as JSON (see ValueToJSON); if not, an error is returned, possibly after
+// part of the patch has been written. Only the differences that
+// DiffWithFilter finds using |filter| are included, so a filtered patch
+// describes only the selected parts of the values.
+func WriteJSONPatch(w io.Writer, v1, v2 types.Value, leftRight bool, filter Filter) error {
+	if err := write(w, []byte("[")); err != nil {
+		return err
+	}
+	pw := &jsonPatchWriter{root: v1, listOffsets: map[string]int64{}}
+	sep := "\n"
+	err := streamDiff(v1, v2, leftRight, filter, func(dif Difference) error {
+		op, err := pw.op(dif)
+		if err != nil {
+			return err
+		}
+		b, err := json.Marshal(op)
+		if err != nil {
+			return err
+		}
+		if err = write(w, []byte(sep)); err != nil {
+			return err
+		}
+		sep = ",\n"
+		return write(w, b)
+	})
+	if err != nil {
+		return err
+	}
+	return write(w, []byte("\n]\n"))
+}
+
+// ValueToJSON returns |v| in the form encoding/json uses for JSON values.
+// Bools, Numbers and Strings become JSON scalars, Lists become arrays, and
+// Structs and Maps whose keys are all Strings become objects. Other Values
+// can't be represented as JSON, and an error is returned for them.
+func ValueToJSON(v types.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case types.Bool:
+		return bool(v), nil
+	case types.Number:
+		return float64(v), nil
+	case types.String:
+		return string(v), nil
+	case types.List:
+		var err error
+		arr := make([]interface{}, 0, v.Len())
+		v.IterAll(func(el types.Value, idx uint64) {
+			if err == nil {
+				var j interface{}
+				j, err = ValueToJSON(el)
+				arr = append(arr, j)
+			}
+		})
+		return arr, err
+	case types.Map:
+		var err error
+		obj := make(map[string]interface{}, v.Len())
+		v.Iter(func(k, el types.Value) (stop bool) {
+			s, ok := k.(types.String)
+			if !ok {
+				err = fmt.Errorf("Map key %s can't be represented as JSON", types.EncodedValueMaxLines(k, 1))
+				return true
+			}
+			obj[string(s)], err = ValueToJSON(el)
+			return err != nil
+		})
+		return obj, err
+	case types.Struct:
+		var err error
+		obj := map[string]interface{}{}
+		v.IterFields(func(name string, el types.Value) {
+			if err == nil {
+				obj[name], err = ValueToJSON(el)
+			}
+		})
+		return obj, err
+	}
+	return nil, fmt.Errorf("%s can't be represented as JSON", types.TypeOf(v).Describe())
+}
+
+// jsonOrEncoded returns |v| as JSON if possible, or as its Noms encoding if
+// not. Both are empty if |v| is nil.
+func jsonOrEncoded(v types.Value) (interface{}, string) {
+	if v == nil {
+		return nil, ""
+	}
+	if j, err := ValueToJSON(v); err == nil {
+		return j, ""
+	}
+	buff := &bytes.Buffer{}
+	writeEncodedValue(buff, v)
+	return nil, buff.String()
+}
+
+// jsonPatchWriter turns Differences into JSON Patch operations.
+type jsonPatchWriter struct {
+	root types.Value
+	// The operations in a JSON Patch are applied one after another, so an
+	// element added to or removed from a List moves those after it. Diff
+	// gives removed and modified elements their index in the old List, and
+	// added ones their index in the new List. listOffsets records, for each
+	// List (by Path), how far the old indexes have moved so far.
+	listOffsets map[string]int64
+}
+
+func (pw *jsonPatchWriter) op(dif Difference) (op jsonPatchOp, err error) {
+	ptr, err := pw.pointer(dif)
+	if err != nil {
+		return
+	}
+	switch dif.ChangeType {
+	case types.DiffChangeAdded:
+		op.Op = "add"
+	case types.DiffChangeRemoved:
+		op.Op = "remove"
+	case types.DiffChangeModified:
+		op.Op = "replace"
+	}
+	op.Path = ptr
+	if dif.NewValue != nil {
+		if op.Value, err = ValueToJSON(dif.NewValue); err != nil {
+			return op, fmt.Errorf("Can't express the change at %s as a JSON Patch: %s", dif.Path.String(), err)
+		}
+	}
+	return
+}
+
+// pointer returns the JSON Pointer (RFC 6901) for the Path of |dif|, at the
+// time its operation is applied.
+func (pw *jsonPatchWriter) pointer(dif Difference) (string, error) {
+	cannot := func(reason string) (string, error) {
+		return "", fmt.Errorf("Can't express the change at %s as a JSON Patch: %s", dif.Path.String(), reason)
+	}
+
+	ptr := &bytes.Buffer{}
+	v := pw.root
+	for i, part := range dif.Path {
+		last := i == len(dif.Path)-1
+		var next types.Value
+		switch parent := v.(type) {
+		case types.Struct:
+			fp, ok := part.(types.FieldPath)
+			if !ok {
+				return cannot("unexpected path in Struct")
+			}
+			writePointerToken(ptr, fp.Name)
+			next, _ = parent.MaybeGet(fp.Name)
+		case types.Map:
+			ip, ok := part.(types.IndexPath)
+			if !ok {
+				return cannot("Map has a key that isn't a String")
+			}
+			key, ok := ip.Index.(types.String)
+			if !ok {
+				return cannot("Map has a key that isn't a String")
+			}
+			writePointerToken(ptr, string(key))
+			next = parent.Get(key)
+		case types.List:
+			ip, ok := part.(types.IndexPath)
+			if !ok {
+				return cannot("unexpected path in List")
+			}
+			oldIdx := int64(ip.Index.(types.Number))
+			listPath := dif.Path[:i].String()
+			offset := pw.listOffsets[listPath]
+			idx := oldIdx + offset
+			if last {
+				switch dif.ChangeType {
+				case types.DiffChangeAdded:
+					// Added elements are already at their index in the new List.
+					idx = oldIdx
+					pw.listOffsets[listPath]++
+				case types.DiffChangeRemoved:
+					pw.listOffsets[listPath]--
+				}
+			}
+			writePointerToken(ptr, strconv.FormatInt(idx, 10))
+			if !last {
+				next = parent.Get(uint64(oldIdx))
+			}
+		default:
+			return cannot(fmt.Sprintf("%s can't be represented as JSON", types.KindToString[v.Kind()]))
+		}
+		v = next
+	}
+	return ptr.String(), nil
+}
+
+func writePointerToken(buff *bytes.Buffer, token string) {
+	buff.WriteByte('/')
+	buff.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+}
+
+// streamDiff calls |f| with each Difference between |v1| and |v2|, stopping
+// at the first error.
+func streamDiff(v1, v2 types.Value, leftRight bool, filter Filter, f func(Difference) error) (err error) {
+	if v1.Equals(v2) {
+		return nil
+	}
+	dChan := make(chan Difference, 16)
+	stopChan := make(chan struct{})
+	go func() {
+		DiffWithFilter(v1, v2, dChan, stopChan, leftRight, filter)
+		close(dChan)
+	}()
+	for dif := range dChan {
+		if err = f(dif); err != nil {
+			close(stopChan)
+			for range dChan {
+			}
+			break
+		}
+	}
+	return
+}
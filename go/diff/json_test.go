@@ -0,0 +1,144 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/attic-labs/noms/go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteJSON(t *testing.T) {
+	assert := assert.New(t)
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	v1 := types.NewStruct("", types.StructData{
+		"name": types.String("a"),
+		"tags": types.NewSet(vs, types.String("x")),
+		"blob": types.NewBlob(vs, strings.NewReader("abc")),
+	})
+	v2 := types.NewStruct("", types.StructData{
+		"name": types.String("b"),
+		"tags": types.NewSet(vs, types.String("x"), types.String("y")),
+		"list": types.NewList(vs, types.Number(1), types.Bool(false)),
+		"blob": types.NewBlob(vs, strings.NewReader("abcd")),
+	})
+
+	buff := &bytes.Buffer{}
+	assert.NoError(WriteJSON(buff, v1, v2, false, Filter{}))
+	assert.Equal(`{"path":".blob","change":"modified","oldEncoded":"Blob (3 B)","newEncoded":"Blob (4 B)"}
+{"path":".list","change":"added","new":[1,false]}
+{"path":".name","change":"modified","old":"a","new":"b"}
+{"path":".tags[\"y\"]","change":"added","new":"y"}
+`, buff.String())
+
+	buff.Reset()
+	assert.NoError(WriteJSON(buff, types.Number(1), types.String("one"), false, Filter{}))
+	assert.Equal(`{"path":"","change":"modified","old":1,"new":"one"}`+"\n", buff.String())
+}
+
+func TestWriteJSONPatch(t *testing.T) {
+	assert := assert.New(t)
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	numbers := func(ns ...int) types.List {
+		vals := make([]types.Value, len(ns))
+		for i, n := range ns {
+			vals[i] = types.Number(n)
+		}
+		return types.NewList(vs, vals...)
+	}
+
+	test := func(v1, v2 types.Value) {
+		buff := &bytes.Buffer{}
+		if !assert.NoError(WriteJSONPatch(buff, v1, v2, false, Filter{})) {
+			return
+		}
+		var ops []map[string]interface{}
+		if !assert.NoError(json.Unmarshal(buff.Bytes(), &ops), buff.String()) {
+			return
+		}
+		j1, err := ValueToJSON(v1)
+		assert.NoError(err)
+		j2, err := ValueToJSON(v2)
+		assert.NoError(err)
+		assert.Equal(j2, applyJSONPatch(j1, ops), buff.String())
+	}
+
+	test(numbers(1, 2, 3, 4, 5, 6), numbers(1, 7, 8, 3, 5, 9, 10))
+	test(numbers(1, 2, 3), numbers())
+	test(numbers(), numbers(1, 2))
+	test(
+		types.NewStruct("", types.StructData{
+			"map":  types.NewMap(vs, types.String("a/b"), types.String("x")),
+			"lst":  types.NewList(vs, types.NewMap(vs, types.String("k"), numbers(1, 2)), types.Number(3)),
+			"gone": types.Bool(true),
+		}),
+		types.NewStruct("", types.StructData{
+			"map": types.NewMap(vs, types.String("a/b"), types.String("y")),
+			"lst": types.NewList(vs, types.Number(0), types.NewMap(vs, types.String("k"), numbers(2, 3), types.String("~"), types.Bool(false))),
+			"new": types.Number(1),
+		}),
+	)
+
+	buff := &bytes.Buffer{}
+	err := WriteJSONPatch(buff, types.NewSet(vs, types.Number(1)), types.NewSet(vs, types.Number(2)), false, Filter{})
+	assert.Error(err)
+	err = WriteJSONPatch(buff, types.NewMap(vs, types.Number(1), types.Number(1)), types.NewMap(vs, types.Number(1), types.Number(2)), false, Filter{})
+	assert.Error(err)
+}
+
+// applyJSONPatch applies the RFC 6902 add, remove and replace operations in
+// |ops| to |doc|.
+func applyJSONPatch(doc interface{}, ops []map[string]interface{}) interface{} {
+	var apply func(doc interface{}, tokens []string, op string, value interface{}) interface{}
+	apply = func(doc interface{}, tokens []string, op string, value interface{}) interface{} {
+		if len(tokens) == 0 {
+			return value
+		}
+		token := strings.NewReplacer("~1", "/", "~0", "~").Replace(tokens[0])
+		switch doc := doc.(type) {
+		case map[string]interface{}:
+			if len(tokens) > 1 {
+				doc[token] = apply(doc[token], tokens[1:], op, value)
+			} else if op == "remove" {
+				delete(doc, token)
+			} else {
+				doc[token] = value
+			}
+			return doc
+		case []interface{}:
+			idx, _ := strconv.Atoi(token)
+			if len(tokens) > 1 {
+				doc[idx] = apply(doc[idx], tokens[1:], op, value)
+				return doc
+			}
+			switch op {
+			case "add":
+				return append(doc[:idx], append([]interface{}{value}, doc[idx:]...)...)
+			case "remove":
+				return append(doc[:idx], doc[idx+1:]...)
+			}
+			doc[idx] = value
+			return doc
+		}
+		panic("can't apply patch")
+	}
+	for _, op := range ops {
+		var tokens []string
+		if path := op["path"].(string); path != "" {
+			tokens = strings.Split(path, "/")[1:]
+		}
+		doc = apply(doc, tokens, op["op"].(string), op["value"])
+	}
+	return doc
+}
@@ -0,0 +1,182 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package diff
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/attic-labs/noms/go/types"
+)
+
+const differenceName = "Difference"
+
+// MakePatch returns the Differences between |v1| and |v2|, as a Patch that
+// Apply can use to turn |v1| into |v2|. If |leftRight| is true then the
+// left-right diff is used for ordered sequences - see Diff vs DiffLeftRight in
+// Set and Map. Only the differences that DiffWithFilter finds using |filter|
+// are included.
+func MakePatch(v1, v2 types.Value, leftRight bool, filter Filter) Patch {
+	patch := Patch{}
+	streamDiff(v1, v2, leftRight, filter, func(dif Difference) error {
+		patch = append(patch, dif)
+		return nil
+	})
+	return patch
+}
+
+// Marshal encodes |r| as a List of Structs, so that it can be stored in a
+// Database:
+//
+//	List<struct Difference {
+//	  path: String,
+//	  changeType: String,
+//	  oldValue: Value,
+//	  newValue: Value,
+//	  newKeyValue: Value,
+//	}>
+//
+// changeType is one of "added", "removed" and "modified". Fields that would
+// be nil are omitted.
+func (r Patch) Marshal(vrw types.ValueReadWriter) types.List {
+	difs := make([]types.Value, len(r))
+	for i, dif := range r {
+		data := types.StructData{
+			"path":       types.String(dif.Path.String()),
+			"changeType": types.String(changeTypeNames[dif.ChangeType]),
+		}
+		for name, v := range map[string]types.Value{"oldValue": dif.OldValue, "newValue": dif.NewValue, "newKeyValue": dif.NewKeyValue} {
+			if v != nil {
+				data[name] = v
+			}
+		}
+		difs[i] = types.NewStruct(differenceName, data)
+	}
+	return types.NewList(vrw, difs...)
+}
+
+// UnmarshalPatch decodes a Patch encoded by Patch.Marshal.
+func UnmarshalPatch(v types.Value) (patch Patch, err error) {
+	l, ok := v.(types.List)
+	if !ok {
+		return nil, fmt.Errorf("A Patch must be a List, not a %s", types.TypeOf(v).Describe())
+	}
+	patch = make(Patch, 0, l.Len())
+	l.IterAll(func(v types.Value, idx uint64) {
+		if err != nil {
+			return
+		}
+		var dif Difference
+		dif, err = unmarshalDifference(v)
+		patch = append(patch, dif)
+	})
+	return
+}
+
+func unmarshalDifference(v types.Value) (dif Difference, err error) {
+	st, ok := v.(types.Struct)
+	if !ok || st.Name() != differenceName {
+		return dif, fmt.Errorf("Not a %s: %s", differenceName, types.TypeOf(v).Describe())
+	}
+	path, pathOk := st.MaybeGet("path")
+	changeType, ctOk := st.MaybeGet("changeType")
+	if !pathOk || !ctOk || path.Kind() != types.StringKind || changeType.Kind() != types.StringKind {
+		return dif, fmt.Errorf("Malformed %s", differenceName)
+	}
+	if str := string(path.(types.String)); str != "" {
+		if dif.Path, err = types.ParsePath(str); err != nil {
+			return
+		}
+	}
+	found := false
+	for ct, name := range changeTypeNames {
+		if name == string(changeType.(types.String)) {
+			dif.ChangeType, found = ct, true
+		}
+	}
+	if !found {
+		return dif, fmt.Errorf("Malformed %s: unknown changeType %s", differenceName, changeType)
+	}
+	dif.OldValue, _ = st.MaybeGet("oldValue")
+	dif.NewValue, _ = st.MaybeGet("newValue")
+	dif.NewKeyValue, _ = st.MaybeGet("newKeyValue")
+	return
+}
+
+// Validate checks that |patch| can be applied to |root| as it was made: that
+// the value at the Path of each removal or modification in |patch| is still
+// its OldValue, and that nothing is yet at the Path of each addition. If the
+// values have drifted, the error lists every Path at which they have.
+// Elements added to a List are exempt, since their Paths give their index in
+// the new List, but the List itself must be there.
+func Validate(root types.Value, patch Patch, vr types.ValueReader) error {
+	drifted := &bytes.Buffer{}
+	for _, dif := range patch {
+		var parent, current types.Value
+		if len(dif.Path) > 0 {
+			parent = dif.Path[:len(dif.Path)-1].Resolve(root, vr)
+		}
+		if s, isSet := parent.(types.Set); isSet {
+			// Paths don't resolve to the elements of Sets.
+			v := dif.OldValue
+			if dif.ChangeType == types.DiffChangeAdded {
+				v = dif.NewValue
+			}
+			if s.Has(v) {
+				current = v
+			}
+		} else {
+			current = dif.Path.Resolve(root, vr)
+		}
+
+		ok := true
+		if dif.ChangeType == types.DiffChangeAdded {
+			if _, isList := parent.(types.List); !isList {
+				ok = parent != nil && current == nil
+			}
+		} else {
+			ok = current != nil && current.Equals(dif.OldValue)
+		}
+		if !ok {
+			path := dif.Path.String()
+			if path == "" {
+				path = "(root)"
+			}
+			fmt.Fprintf(drifted, "\n  %s", path)
+		}
+	}
+	if drifted.Len() > 0 {
+		return fmt.Errorf("The patch no longer applies, because values have changed at:%s", drifted.String())
+	}
+	return nil
+}
+
+// CheckRefs returns an error if a value that |patch| adds refers to a value
+// that can't be read from |vr|. Committing the result of applying |patch| to a
+// value in |vr| would otherwise leave it with dangling refs, which happens if
+// the patch was made from values in another database.
+func CheckRefs(patch Patch, vr types.ValueReader) error {
+	for _, dif := range patch {
+		for _, v := range []types.Value{dif.NewValue, dif.NewKeyValue} {
+			if v == nil {
+				continue
+			}
+			var missing *types.Ref
+			v.WalkRefs(func(r types.Ref) {
+				if missing == nil && vr.ReadValue(r.TargetHash()) == nil {
+					missing = &r
+				}
+			})
+			if missing != nil {
+				path := dif.Path.String()
+				if path == "" {
+					path = "(root)"
+				}
+				return fmt.Errorf("The value added at %s refers to #%s, which isn't in the database; the patch must be made from values in the same database", path, missing.TargetHash())
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package diff
+
+import (
+	"testing"
+
+	"github.com/attic-labs/noms/go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPatchMarshalRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	for k1, g1 := range testValues(vs) {
+		for k2, g2 := range testValues(vs) {
+			if k1 == k2 {
+				continue
+			}
+			patch, err := UnmarshalPatch(MakePatch(g1, g2, true, Filter{}).Marshal(vs))
+			if !assert.NoError(err) {
+				continue
+			}
+			assert.NoError(Validate(g1, patch, vs), "%s -> %s", k1, k2)
+			assert.True(g2.Equals(Apply(g1, patch)), "failed to apply decoded patch for %s -> %s", k1, k2)
+		}
+	}
+}
+
+func TestPatchValidate(t *testing.T) {
+	assert := assert.New(t)
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	base := types.NewStruct("", types.StructData{
+		"a": types.Number(1),
+		"l": types.NewList(vs, types.Number(1), types.Number(2)),
+	})
+	changed := types.NewStruct("", types.StructData{
+		"a": types.Number(2),
+		"b": types.Number(3),
+		"l": types.NewList(vs, types.Number(1), types.Number(2), types.Number(3)),
+	})
+	patch := MakePatch(base, changed, false, Filter{})
+	assert.NoError(Validate(base, patch, vs))
+
+	drifted := base.Set("a", types.Number(42)).Set("b", types.Number(3))
+	err := Validate(drifted, patch, vs)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "\n  .a")
+		assert.Contains(err.Error(), "\n  .b")
+		assert.NotContains(err.Error(), ".l")
+	}
+
+	err = Validate(types.Number(1), MakePatch(types.Number(2), types.Number(3), false, Filter{}), vs)
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "(root)")
+	}
+
+	_, err = UnmarshalPatch(types.NewList(vs, types.NewStruct(differenceName, types.StructData{
+		"path":       types.String(".a"),
+		"changeType": types.String("renamed"),
+	})))
+	assert.Error(err)
+}
+
+func TestPatchCheckRefs(t *testing.T) {
+	assert := assert.New(t)
+	vs := newTestValueStore()
+	defer vs.Close()
+	other := newTestValueStore()
+	defer other.Close()
+
+	s := types.NewStruct("", types.StructData{"a": types.Number(1)})
+	patch := MakePatch(s, s.Set("r", vs.WriteValue(types.String("here"))), false, Filter{})
+	assert.NoError(CheckRefs(patch, vs))
+	assert.EqualError(CheckRefs(patch, other), "The value added at .r refers to #"+types.String("here").Hash().String()+", which isn't in the database; the patch must be made from values in the same database")
+}
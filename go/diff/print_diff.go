@@ -27,6 +27,12 @@ type (
 // to |w|. If |leftRight| is true then the left-right diff is used for ordered
 // sequences - see Diff vs DiffLeftRight in Set and Map.
 func PrintDiff(w io.Writer, v1, v2 types.Value, leftRight bool) (err error) {
+	return PrintDiffWithFilter(w, v1, v2, leftRight, Filter{})
+}
+
+// PrintDiffWithFilter is like PrintDiff, but only prints the differences
+// that DiffWithFilter finds using |filter|.
+func PrintDiffWithFilter(w io.Writer, v1, v2 types.Value, leftRight bool, filter Filter) (err error) {
 	// In the case where the diff involves two simple values, just print out the
 	// diff and return. This is needed because the code below assumes that the
 	// values being compared have a parent.
@@ -46,7 +52,7 @@ func PrintDiff(w io.Writer, v1, v2 types.Value, leftRight bool) (err error) {
 	// From here on, we can assume that every Difference will have at least one
 	// element in the Path
 	go func() {
-		Diff(v1, v2, dChan, stopChan, leftRight)
+		DiffWithFilter(v1, v2, dChan, stopChan, leftRight, filter)
 		close(dChan)
 	}()
 
@@ -7,18 +7,30 @@ package diff
 import (
 	"fmt"
 
-	"github.com/attic-labs/noms/go/datas"
+	"github.com/attic-labs/noms/go/nomdl"
 	"github.com/attic-labs/noms/go/types"
 	"github.com/attic-labs/noms/go/util/status"
 	humanize "github.com/dustin/go-humanize"
 )
 
+// commitType is the type of a commit, as in datas.IsCommit. datas can't be
+// imported here, since it serves GraphQL queries that use this package.
+var commitType = nomdl.MustParseType(`Struct Commit {
+        meta: Struct {},
+        parents: Set<Ref<Cycle<Commit>>>,
+        value: Value,
+}`)
+
+func isCommit(v types.Value) bool {
+	return types.IsValueSubtypeOf(v, commitType)
+}
+
 // Summary prints a summary of the diff between two values to stdout.
 func Summary(value1, value2 types.Value) {
-	if datas.IsCommit(value1) && datas.IsCommit(value2) {
+	if isCommit(value1) && isCommit(value2) {
 		fmt.Println("Comparing commit values")
-		value1 = value1.(types.Struct).Get(datas.ValueField)
-		value2 = value2.(types.Struct).Get(datas.ValueField)
+		value1 = value1.(types.Struct).Get("value")
+		value2 = value2.(types.Struct).Get("value")
 	}
 
 	var singular, plural string
@@ -44,11 +56,7 @@ func Summary(value1, value2 types.Value) {
 
 	acc := diffSummaryProgress{}
 	for p := range ch {
-		acc.Adds += p.Adds
-		acc.Removes += p.Removes
-		acc.Changes += p.Changes
-		acc.NewSize += p.NewSize
-		acc.OldSize += p.OldSize
+		acc.add(p)
 		if status.WillPrint() {
 			formatStatus(acc, singular, plural)
 		}
@@ -57,10 +65,53 @@ func Summary(value1, value2 types.Value) {
 	status.Done()
 }
 
+// SummaryByKey is like Summary, but first prints, for each top-level field
+// or key at which |value1| and |value2| differ, a line counting the changes
+// beneath it. This is only done if both values are Structs or both are Maps.
+func SummaryByKey(value1, value2 types.Value) {
+	if isCommit(value1) && isCommit(value2) {
+		value1 = value1.(types.Struct).Get("value")
+		value2 = value2.(types.Struct).Get("value")
+	}
+
+	k1, k2 := value1.Kind(), value2.Kind()
+	if k1 == k2 && (k1 == types.StructKind || k1 == types.MapKind) {
+		streamDiff(value1, value2, true, Filter{MaxDepth: 1}, func(dif Difference) error {
+			acc := diffSummaryProgress{}
+			switch dif.ChangeType {
+			case types.DiffChangeAdded:
+				acc.Adds = 1
+			case types.DiffChangeRemoved:
+				acc.Removes = 1
+			case types.DiffChangeModified:
+				ch := make(chan diffSummaryProgress)
+				go func() {
+					diffSummary(ch, dif.OldValue, dif.NewValue)
+					close(ch)
+				}()
+				for p := range ch {
+					acc.add(p)
+				}
+			}
+			fmt.Printf("%s: %s, %s, %s\n", dif.Path.String(), pluralize("insertion", "insertions", acc.Adds), pluralize("deletion", "deletions", acc.Removes), pluralize("change", "changes", acc.Changes))
+			return nil
+		})
+	}
+	Summary(value1, value2)
+}
+
 type diffSummaryProgress struct {
 	Adds, Removes, Changes, NewSize, OldSize uint64
 }
 
+func (acc *diffSummaryProgress) add(p diffSummaryProgress) {
+	acc.Adds += p.Adds
+	acc.Removes += p.Removes
+	acc.Changes += p.Changes
+	acc.NewSize += p.NewSize
+	acc.OldSize += p.OldSize
+}
+
 func diffSummary(ch chan diffSummaryProgress, v1, v2 types.Value) {
 	if !v1.Equals(v2) {
 		if shouldDescend(v1, v2) {
@@ -151,17 +202,17 @@ func reportChanges(ch chan<- diffSummaryProgress, changeChan chan types.ValueCha
 	}
 }
 
-func formatStatus(acc diffSummaryProgress, singular, plural string) {
-	pluralize := func(singular, plural string, n uint64) string {
-		var noun string
-		if n != 1 {
-			noun = plural
-		} else {
-			noun = singular
-		}
-		return fmt.Sprintf("%s %s", humanize.Comma(int64(n)), noun)
+func pluralize(singular, plural string, n uint64) string {
+	var noun string
+	if n != 1 {
+		noun = plural
+	} else {
+		noun = singular
 	}
+	return fmt.Sprintf("%s %s", humanize.Comma(int64(n)), noun)
+}
 
+func formatStatus(acc diffSummaryProgress, singular, plural string) {
 	insertions := pluralize("insertion", "insertions", acc.Adds)
 	deletions := pluralize("deletion", "deletions", acc.Removes)
 	changes := pluralize("change", "changes", acc.Changes)
@@ -0,0 +1,404 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+// Package codegen generates Go types that go/marshal encodes to, and decodes
+// from, Noms values of a given Noms type.
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/attic-labs/noms/go/types"
+)
+
+const (
+	marshalImport = "github.com/attic-labs/noms/go/marshal"
+	typesImport   = "github.com/attic-labs/noms/go/types"
+)
+
+// Generate returns the source of a Go file in package |pkg| that declares a Go
+// type for |t|, and for each struct type in |t|. Noms types map to Go types as
+// follows:
+//
+//  - Bool, Number and String are bool, float64 and string.
+//  - Blob, Ref, Type and Value are types.Blob, types.Ref, *types.Type and
+//    types.Value.
+//  - List<T> is a slice of T.
+//  - Map<K, V> is a map from K to V if K is a Bool, Number or String, and
+//    types.Map otherwise.
+//  - Set<T> of a struct field is a map from T to struct{} if T is a Bool,
+//    Number or String, and otherwise a slice of T, tagged with "set". Other Sets
+//    are types.Set.
+//  - A struct is a Go struct, named after the Noms struct, whose fields are
+//    tagged with the names of the Noms fields, and "omitempty" if they're
+//    optional. Anonymous structs are named after where they're used.
+//  - A union of structs is an interface that the Go types of the structs
+//    implement. Other unions are interface{}. In either case, the Go types of
+//    the structs are registered with marshal.Register, so that they can be
+//    decoded onto the interface.
+//
+// The Go type for |t| itself is named after its struct, if it's a named
+// struct, and |name| otherwise.
+func Generate(pkg, name string, t *types.Type) (src []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if cerr, ok := r.(codegenError); ok {
+				err = cerr
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	g := &generator{
+		structs:    map[*types.Type]*decl{},
+		named:      map[string]*decl{},
+		interfaces: map[hash.Hash]*decl{},
+		goNames:    map[string]bool{},
+		imports:    map[string]bool{},
+	}
+	g.root(t, exportedName(name))
+	return g.write(pkg)
+}
+
+type codegenError struct {
+	msg string
+}
+
+func (e codegenError) Error() string {
+	return e.msg
+}
+
+func raise(format string, args ...interface{}) {
+	panic(codegenError{fmt.Sprintf(format, args...)})
+}
+
+// decl is a declaration of a Go type.
+type decl struct {
+	goName string
+	// Exactly one of these is set.
+	strct *types.Type // the struct type of a Go struct
+	iface *types.Type // the union type of an interface
+	expr  string      // the definition of any other type
+	kind  types.NomsKind
+	// For Go structs.
+	fields     []goField
+	implements []*decl
+	register   bool
+}
+
+type goField struct {
+	name, expr, tag string
+}
+
+type generator struct {
+	decls      []*decl
+	structs    map[*types.Type]*decl
+	named      map[string]*decl // the Go structs of named Noms structs
+	interfaces map[hash.Hash]*decl
+	goNames    map[string]bool
+	imports    map[string]bool
+}
+
+// exportedName returns |name|, which is a valid Noms struct or field name,
+// with an upper case first letter.
+func exportedName(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// newDecl adds a declaration for a Go type with a name based on |name| that
+// isn't already taken.
+func (g *generator) newDecl(name string) *decl {
+	goName := name
+	for i := 2; g.goNames[goName]; i++ {
+		goName = fmt.Sprintf("%s%d", name, i)
+	}
+	g.goNames[goName] = true
+	dcl := &decl{goName: goName}
+	g.decls = append(g.decls, dcl)
+	return dcl
+}
+
+func (g *generator) root(t *types.Type, name string) {
+	switch t.TargetKind() {
+	case types.StructKind:
+		g.structType(t, name)
+	case types.UnionKind:
+		if g.isStructUnion(t) {
+			g.interfaceType(t, name)
+			return
+		}
+		fallthrough
+	default:
+		dcl := g.newDecl(name)
+		dcl.expr, dcl.kind = g.typeExpr(t, name), t.TargetKind()
+	}
+}
+
+// typeExpr returns the Go type expression for |t|, declaring any types it
+// needs. |hint| is the name of the types that have to be named after where
+// they're used.
+func (g *generator) typeExpr(t *types.Type, hint string) string {
+	switch t.TargetKind() {
+	case types.BoolKind:
+		return "bool"
+	case types.NumberKind:
+		return "float64"
+	case types.StringKind:
+		return "string"
+	case types.BlobKind:
+		return g.typesExpr("Blob")
+	case types.RefKind:
+		return g.typesExpr("Ref")
+	case types.TypeKind:
+		return "*" + g.typesExpr("Type")
+	case types.ValueKind:
+		return g.typesExpr("Value")
+	case types.ListKind:
+		return "[]" + g.typeExpr(elemTypes(t)[0], hint)
+	case types.SetKind:
+		// Only struct fields can be tagged as sets.
+		g.typeExpr(elemTypes(t)[0], hint)
+		return g.typesExpr("Set")
+	case types.MapKind:
+		kt, vt := elemTypes(t)[0], elemTypes(t)[1]
+		key := g.typeExpr(kt, hint+"Key")
+		value := g.typeExpr(vt, hint+"Value")
+		if !isComparable(kt) {
+			return g.typesExpr("Map")
+		}
+		return fmt.Sprintf("map[%s]%s", key, value)
+	case types.StructKind:
+		return g.structType(t, hint).goName
+	case types.CycleKind:
+		name := string(t.Desc.(types.CycleDesc))
+		dcl, ok := g.named[name]
+		if !ok {
+			raise("Cycle<%s> does not refer to a struct", name)
+		}
+		return dcl.goName
+	case types.UnionKind:
+		if g.isStructUnion(t) {
+			return g.interfaceType(t, hint).goName
+		}
+		for _, et := range elemTypes(t) {
+			if et.TargetKind() == types.StructKind || et.TargetKind() == types.CycleKind {
+				g.registeredStruct(et, hint)
+			} else {
+				g.typeExpr(et, hint)
+			}
+		}
+		return "interface{}"
+	}
+	panic("unreachable")
+}
+
+// fieldExpr is like typeExpr, but for the type of a struct field, which can
+// be tagged as a set.
+func (g *generator) fieldExpr(t *types.Type, hint string) (expr string, set bool) {
+	if t.TargetKind() != types.SetKind {
+		return g.typeExpr(t, hint), false
+	}
+	et := elemTypes(t)[0]
+	elem := g.typeExpr(et, hint)
+	if isComparable(et) {
+		return fmt.Sprintf("map[%s]struct{}", elem), true
+	}
+	return "[]" + elem, true
+}
+
+func (g *generator) typesExpr(name string) string {
+	g.imports[typesImport] = true
+	return "types." + name
+}
+
+// structType returns the declaration of the Go struct for |t|, which is named
+// after the Noms struct, or |hint| if it's anonymous.
+func (g *generator) structType(t *types.Type, hint string) *decl {
+	if dcl, ok := g.structs[t]; ok {
+		return dcl
+	}
+	desc := t.Desc.(types.StructDesc)
+	if dcl, ok := g.named[desc.Name]; ok && desc.Name != "" {
+		return dcl
+	}
+
+	name := hint
+	if desc.Name != "" {
+		name = exportedName(desc.Name)
+	}
+	dcl := g.newDecl(name)
+	dcl.strct = t
+	g.structs[t] = dcl
+	if desc.Name != "" {
+		g.named[desc.Name] = dcl
+	}
+
+	goNames := map[string]string{}
+	desc.IterFields(func(name string, ft *types.Type, optional bool) {
+		fieldName := exportedName(name)
+		if other, ok := goNames[fieldName]; ok {
+			raise("Fields %s and %s of struct %s have the same Go name", other, name, dcl.goName)
+		}
+		goNames[fieldName] = name
+		if ft == t || ft.TargetKind() == types.CycleKind && string(ft.Desc.(types.CycleDesc)) == desc.Name {
+			raise("Struct %s cannot contain itself", desc.Name)
+		}
+
+		expr, set := g.fieldExpr(ft, dcl.goName+fieldName)
+		tag := name
+		if optional {
+			tag += ",omitempty"
+		}
+		if set {
+			tag += ",set"
+		}
+		dcl.fields = append(dcl.fields, goField{fieldName, expr, fmt.Sprintf("`noms:\"%s\"`", tag)})
+	})
+	return dcl
+}
+
+// registeredStruct is like structType, for a struct in a union, whose Go
+// struct must be registered to be decoded onto an interface.
+func (g *generator) registeredStruct(t *types.Type, hint string) *decl {
+	var dcl *decl
+	if t.TargetKind() == types.CycleKind {
+		g.typeExpr(t, hint)
+		dcl = g.named[string(t.Desc.(types.CycleDesc))]
+	} else {
+		if t.Desc.(types.StructDesc).Name == "" {
+			raise("Anonymous structs in unions are not supported")
+		}
+		dcl = g.structType(t, hint)
+	}
+	if !dcl.register {
+		dcl.register = true
+		g.imports[marshalImport] = true
+	}
+	return dcl
+}
+
+// isStructUnion returns true if |t| is a union of one or more structs, and
+// nothing else.
+func (g *generator) isStructUnion(t *types.Type) bool {
+	ets := elemTypes(t)
+	for _, et := range ets {
+		if et.TargetKind() != types.StructKind && et.TargetKind() != types.CycleKind {
+			return false
+		}
+	}
+	return len(ets) > 0
+}
+
+// interfaceType returns the declaration of the Go interface for the union of
+// structs |t|. The interface is named |hint|, and the structs implement it
+// with a method that does nothing.
+func (g *generator) interfaceType(t *types.Type, hint string) *decl {
+	if dcl, ok := g.interfaces[t.Hash()]; ok {
+		return dcl
+	}
+	dcl := g.newDecl(hint)
+	dcl.iface = t
+	g.interfaces[t.Hash()] = dcl
+	for _, et := range elemTypes(t) {
+		sdcl := g.registeredStruct(et, hint)
+		sdcl.implements = append(sdcl.implements, dcl)
+	}
+	return dcl
+}
+
+func elemTypes(t *types.Type) []*types.Type {
+	return t.Desc.(types.CompoundDesc).ElemTypes
+}
+
+// isComparable returns true if the Go type for |t| can be a map key.
+func isComparable(t *types.Type) bool {
+	switch t.TargetKind() {
+	case types.BoolKind, types.NumberKind, types.StringKind:
+		return true
+	}
+	return false
+}
+
+func (g *generator) write(pkg string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by noms codegen. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	if len(g.imports) > 0 {
+		fmt.Fprintln(buf, "import (")
+		for _, imp := range []string{marshalImport, typesImport} {
+			if g.imports[imp] {
+				fmt.Fprintf(buf, "%q\n", imp)
+			}
+		}
+		fmt.Fprintln(buf, ")")
+	}
+
+	registered := []string{}
+	for _, dcl := range g.decls {
+		fmt.Fprintln(buf)
+		switch {
+		case dcl.strct != nil:
+			writeStruct(buf, dcl)
+			if dcl.register {
+				registered = append(registered, dcl.goName)
+			}
+		case dcl.iface != nil:
+			names := []string{}
+			for _, et := range elemTypes(dcl.iface) {
+				if et.TargetKind() == types.CycleKind {
+					names = append(names, string(et.Desc.(types.CycleDesc)))
+				} else {
+					names = append(names, et.Desc.(types.StructDesc).Name)
+				}
+			}
+			fmt.Fprintf(buf, "// %s is the Go type of the union of the Noms structs %s.\n", dcl.goName, strings.Join(names, ", "))
+			fmt.Fprintf(buf, "type %s interface {\n%s()\n}\n", dcl.goName, markerMethod(dcl))
+		default:
+			fmt.Fprintf(buf, "// %s is the Go type of a Noms %s.\n", dcl.goName, dcl.kind)
+			fmt.Fprintf(buf, "type %s %s\n", dcl.goName, dcl.expr)
+		}
+	}
+
+	if len(registered) > 0 {
+		fmt.Fprintln(buf, "\nfunc init() {")
+		for _, name := range registered {
+			fmt.Fprintf(buf, "marshal.Register(%s{})\n", name)
+		}
+		fmt.Fprintln(buf, "}")
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+func writeStruct(buf *bytes.Buffer, dcl *decl) {
+	name := dcl.strct.Desc.(types.StructDesc).Name
+	if name == "" {
+		fmt.Fprintf(buf, "// %s is the Go type of an anonymous Noms struct.\n", dcl.goName)
+	} else {
+		fmt.Fprintf(buf, "// %s is the Go type of the Noms struct %s.\n", dcl.goName, name)
+	}
+	fmt.Fprintf(buf, "type %s struct {\n", dcl.goName)
+	for _, f := range dcl.fields {
+		fmt.Fprintf(buf, "%s %s %s\n", f.name, f.expr, f.tag)
+	}
+	fmt.Fprintln(buf, "}")
+
+	// Marshal names structs after their Go type, in title case.
+	if name != strings.Title(dcl.goName) {
+		fmt.Fprintf(buf, "\nfunc (%s) MarshalNomsStructName() string {\nreturn %q\n}\n", dcl.goName, name)
+	}
+	for _, iface := range dcl.implements {
+		fmt.Fprintf(buf, "\nfunc (%s) %s() {}\n", dcl.goName, markerMethod(iface))
+	}
+}
+
+// markerMethod returns the name of the method that the structs in the union
+// of the interface |dcl| implement it with.
+func markerMethod(dcl *decl) string {
+	return "is" + dcl.goName
+}
@@ -0,0 +1,140 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package codegen
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/marshal"
+	"github.com/attic-labs/noms/go/nomdl"
+	"github.com/attic-labs/noms/go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// drawingType is the type that drawing_generated_test.go was generated from.
+var drawingType = nomdl.MustParseType(`Struct Drawing {
+	author?: String,
+	layers: Map<String, Struct {
+		opacity: Number,
+		visible: Bool,
+	}>,
+	shape: Struct Circle {
+		radius: Number,
+	} | Struct Group {
+		shapes: List<Cycle<Circle> | Cycle<Group> | Struct Rect {
+			h: Number,
+			w: Number,
+		}>,
+	} | Struct Rect {
+		h: Number,
+		w: Number,
+	},
+	tags: Set<String>,
+	title: String,
+}`)
+
+func TestGenerateDrawing(t *testing.T) {
+	assert := assert.New(t)
+
+	src, err := Generate("codegen", "Root", drawingType)
+	assert.NoError(err)
+	expected, err := ioutil.ReadFile("drawing_generated_test.go")
+	assert.NoError(err)
+	assert.Equal(string(expected), string(src))
+}
+
+func TestGeneratedRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	vs := types.NewValueStore((&chunks.TestStorage{}).NewView())
+	defer vs.Close()
+
+	typ, err := marshal.MarshalType(vs, Drawing{})
+	assert.NoError(err)
+	assert.True(drawingType.Equals(typ), typ.Describe())
+
+	d := Drawing{
+		Layers: map[string]DrawingLayersValue{"bg": {Opacity: 0.5, Visible: true}},
+		Shape:  Group{[]DrawingShape{Circle{1}, Rect{H: 2, W: 3}, Group{}}},
+		Tags:   map[string]struct{}{"art": {}},
+		Title:  "shapes",
+	}
+	v, err := marshal.Marshal(vs, d)
+	assert.NoError(err)
+	assert.True(types.IsValueSubtypeOf(v, drawingType))
+
+	var d2 Drawing
+	assert.NoError(marshal.Unmarshal(v, &d2))
+	assert.Equal(d, d2)
+}
+
+func TestGenerate(t *testing.T) {
+	assert := assert.New(t)
+
+	generate := func(typ string) string {
+		src, err := Generate("main", "root", nomdl.MustParseType(typ))
+		assert.NoError(err)
+		return string(src)
+	}
+
+	src := generate(`List<Struct person {
+		friends: List<Cycle<person>>,
+		name: String,
+	}>`)
+	assert.Contains(src, "package main\n")
+	assert.Contains(src, "// Root is the Go type of a Noms List.\ntype Root []Person\n")
+	assert.Contains(src, "\tFriends []Person `noms:\"friends\"`\n")
+	assert.Contains(src, "func (Person) MarshalNomsStructName() string {\n\treturn \"person\"\n}\n")
+	assert.NotContains(src, "import")
+
+	src = generate(`Struct {
+		b: Blob,
+		l: List<Set<Number>>,
+		m: Map<Struct K {}, Number>,
+		r: Ref<Number>,
+		s: Set<Struct S {}>,
+		t: Type,
+		u: Number | String | Struct U {},
+		v: Value,
+	}`)
+	assert.Contains(src, "type Root struct {\n")
+	assert.Contains(src, "func (Root) MarshalNomsStructName() string {\n\treturn \"\"\n}\n")
+	assert.Contains(src, "\tB types.Blob ")
+	assert.Contains(src, "\tL []types.Set ")
+	assert.Contains(src, "\tM types.Map ")
+	assert.Contains(src, "\tR types.Ref ")
+	assert.Contains(src, "\tS []S ")
+	assert.Contains(src, "`noms:\"s,set\"`")
+	assert.Contains(src, "\tT *types.Type ")
+	assert.Contains(src, "\tU interface{} ")
+	assert.Contains(src, "\tV types.Value ")
+	assert.Contains(src, "func init() {\n\tmarshal.Register(U{})\n}\n")
+	assert.Contains(src, "\"github.com/attic-labs/noms/go/types\"")
+
+	// Go names are made unique.
+	src = generate(`Struct {
+		a: Struct { x: Number },
+		b: Struct RootA {},
+	}`)
+	assert.Contains(src, "\tA RootA  `noms:\"a\"`\n")
+	assert.Contains(src, "\tB RootA2 `noms:\"b\"`\n")
+	assert.Contains(src, "func (RootA2) MarshalNomsStructName() string {\n\treturn \"RootA\"\n}\n")
+}
+
+func TestGenerateErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	generate := func(typ string) error {
+		_, err := Generate("main", "Root", nomdl.MustParseType(typ))
+		return err
+	}
+
+	assert.EqualError(generate(`Struct S { a: Number, A: String }`), "Fields A and a of struct S have the same Go name")
+	assert.EqualError(generate(`List<Number | Struct { x: Number }>`), "Anonymous structs in unions are not supported")
+	assert.EqualError(generate(`Struct S { s: Cycle<S> }`), "Struct S cannot contain itself")
+	assert.EqualError(generate(`List<Cycle<S>>`), "Cycle<S> does not refer to a struct")
+}
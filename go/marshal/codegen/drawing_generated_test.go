@@ -0,0 +1,59 @@
+// Code generated by noms codegen. DO NOT EDIT.
+
+package codegen
+
+import (
+	"github.com/attic-labs/noms/go/marshal"
+)
+
+// Drawing is the Go type of the Noms struct Drawing.
+type Drawing struct {
+	Author string                        `noms:"author,omitempty"`
+	Layers map[string]DrawingLayersValue `noms:"layers"`
+	Shape  DrawingShape                  `noms:"shape"`
+	Tags   map[string]struct{}           `noms:"tags,set"`
+	Title  string                        `noms:"title"`
+}
+
+// DrawingLayersValue is the Go type of an anonymous Noms struct.
+type DrawingLayersValue struct {
+	Opacity float64 `noms:"opacity"`
+	Visible bool    `noms:"visible"`
+}
+
+func (DrawingLayersValue) MarshalNomsStructName() string {
+	return ""
+}
+
+// DrawingShape is the Go type of the union of the Noms structs Circle, Group, Rect.
+type DrawingShape interface {
+	isDrawingShape()
+}
+
+// Circle is the Go type of the Noms struct Circle.
+type Circle struct {
+	Radius float64 `noms:"radius"`
+}
+
+func (Circle) isDrawingShape() {}
+
+// Group is the Go type of the Noms struct Group.
+type Group struct {
+	Shapes []DrawingShape `noms:"shapes"`
+}
+
+func (Group) isDrawingShape() {}
+
+// Rect is the Go type of the Noms struct Rect.
+type Rect struct {
+	H float64 `noms:"h"`
+	W float64 `noms:"w"`
+}
+
+func (Rect) isDrawingShape() {}
+
+func init() {
+	marshal.Register(Circle{})
+	marshal.Register(Group{})
+	marshal.Register(Rect{})
+}
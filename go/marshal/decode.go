@@ -25,7 +25,9 @@ import (
 // exported fields on the Go struct must be present in the Noms struct, unless
 // the field on the Go struct is marked with the "omitempty" tag. Go struct
 // fields also support the "original" tag which causes the Go field to receive
-// the entire original unmarshaled Noms struct.
+// the entire original unmarshaled Noms struct. Fields of embedded struct
+// pointers are optional, and the pointer is only allocated if any of them are
+// present.
 //
 // To unmarshal a Noms list or set into a slice, Unmarshal resets the slice
 // length to zero and then appends each element to the slice. If the Go slice
@@ -54,8 +56,12 @@ import (
 //  - types.String -> string
 //  - *types.Type -> *types.Type
 //  - types.Union -> interface
+//  - types.Struct -> the Go type registered for its name, see Register
 //  - Everything else an error
 //
+// A Noms struct can also be unmarshaled onto any other interface, if the Go
+// type registered for its name implements the interface.
+//
 // Unmarshal returns an UnmarshalTypeMismatchError if:
 //  - a Noms value is not appropriate for a given target type
 //  - a Noms number overflows the target type
@@ -289,9 +295,10 @@ type decField struct {
 	index     []int
 	omitEmpty bool
 	original  bool
+	tagged    bool
 }
 
-func structDecoderFields(t reflect.Type) []decField {
+func structDecoderFields(t reflect.Type, embedded bool) []decField {
 	fields := make([]decField, 0, t.NumField())
 	for i := 0; i < t.NumField(); i++ {
 		index := make([]int, 1)
@@ -302,15 +309,21 @@ func structDecoderFields(t reflect.Type) []decField {
 			continue
 		}
 
-		if f.Anonymous && f.PkgPath == "" && !tags.hasName {
-			embeddedFields := structDecoderFields(f.Type)
+		if et, ptr, ok := embeddedStruct(f, tags); ok {
+			embeddedFields := structDecoderFields(et, true)
 			for _, ef := range embeddedFields {
 				ef.index = append(index, ef.index...)
+				// Nil pointers leave their fields out when marshaled.
+				ef.omitEmpty = ef.omitEmpty || ptr
 				fields = append(fields, ef)
 			}
 			continue
 		}
 
+		if f.Anonymous && f.PkgPath != "" {
+			continue
+		}
+
 		validateField(f, t)
 
 		fields = append(fields, decField{
@@ -319,9 +332,41 @@ func structDecoderFields(t reflect.Type) []decField {
 			index:     index,
 			omitEmpty: tags.omitEmpty,
 			original:  tags.original,
+			tagged:    tags.hasName,
 		})
 	}
-	return fields
+
+	if embedded {
+		return fields
+	}
+	names := make([]fieldName, len(fields))
+	for i, f := range fields {
+		names[i] = fieldName{f.name, len(f.index), f.tagged}
+	}
+	dominant := dominantFields(names)
+	visible := fields[:0]
+	for i, f := range fields {
+		// There is only one original field, whatever its name.
+		if dominant[i] || f.original {
+			visible = append(visible, f)
+		}
+	}
+	return visible
+}
+
+// allocFieldByIndex is like reflect.Value.FieldByIndex, except that it
+// allocates the nil pointers to embedded structs on the way to the field.
+func allocFieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
 }
 
 func structDecoder(t reflect.Type) decoderFunc {
@@ -334,7 +379,7 @@ func structDecoder(t reflect.Type) decoderFunc {
 		return d
 	}
 
-	fields := structDecoderFields(t)
+	fields := structDecoderFields(t, false)
 
 	d = func(v types.Value, rv reflect.Value) {
 		s, ok := v.(types.Struct)
@@ -343,8 +388,8 @@ func structDecoder(t reflect.Type) decoderFunc {
 		}
 
 		for _, f := range fields {
-			sf := rv.FieldByIndex(f.index)
 			if f.original {
+				sf := allocFieldByIndex(rv, f.index)
 				if sf.Type() != reflect.TypeOf(s) {
 					panic(&UnmarshalTypeMismatchError{v, rv.Type(), ", field with tag \"original\" must have type Struct"})
 				}
@@ -353,7 +398,7 @@ func structDecoder(t reflect.Type) decoderFunc {
 			}
 			fv, ok := s.MaybeGet(f.name)
 			if ok {
-				f.decoder(fv, sf)
+				f.decoder(fv, allocFieldByIndex(rv, f.index))
 			} else if !f.omitEmpty {
 				panic(&UnmarshalTypeMismatchError{v, rv.Type(), ", missing field \"" + f.name + "\""})
 			}
@@ -550,10 +595,20 @@ func interfaceDecoder(t reflect.Type) decoderFunc {
 	}
 
 	if t != emptyInterface {
-		panic(&UnsupportedTypeError{Type: t})
+		return func(v types.Value, rv reflect.Value) {
+			s, ok := v.(types.Struct)
+			if !ok {
+				panic(&UnmarshalTypeMismatchError{v, rv.Type(), ""})
+			}
+			rv.Set(registeredStructValue(s, rv.Type()))
+		}
 	}
 
 	return func(v types.Value, rv reflect.Value) {
+		if s, ok := v.(types.Struct); ok {
+			rv.Set(registeredStructValue(s, rv.Type()))
+			return
+		}
 		// TODO: Go directly from value to go type
 		t := getGoTypeForNomsType(types.TypeOf(v), rv.Type(), v)
 		i := reflect.New(t).Elem()
@@ -562,6 +617,26 @@ func interfaceDecoder(t reflect.Type) decoderFunc {
 	}
 }
 
+// registeredStructValue decodes |s| as a new value of the type registered for
+// its name, which must implement the interface |it|.
+func registeredStructValue(s types.Struct, it reflect.Type) reflect.Value {
+	t := registeredType(s.Name())
+	if t == nil {
+		panic(&UnmarshalTypeMismatchError{s, it, ""})
+	}
+	if !t.Implements(it) {
+		panic(&UnmarshalTypeMismatchError{s, it, fmt.Sprintf(", registered type %s does not implement it", t)})
+	}
+	if t.Kind() == reflect.Ptr {
+		ptr := reflect.New(t.Elem())
+		typeDecoder(t.Elem(), nomsTags{})(s, ptr.Elem())
+		return ptr
+	}
+	v := reflect.New(t).Elem()
+	typeDecoder(t, nomsTags{})(s, v)
+	return v
+}
+
 func getGoTypeForNomsType(nt *types.Type, rt reflect.Type, v types.Value) reflect.Type {
 	switch nt.TargetKind() {
 	case types.BoolKind:
@@ -583,8 +658,13 @@ func getGoTypeForNomsType(nt *types.Type, rt reflect.Type, v types.Value) reflec
 			getGoTypeForNomsType(ut, rt, v)
 		}
 		return emptyInterface
-	// case types.StructKind:
-	// 	reflect.StructOf was not added until Go 1.7
+	case types.StructKind:
+		// Structs are decoded onto interface{} as the type registered for
+		// their name.
+		if registeredType(nt.Desc.(types.StructDesc).Name) != nil {
+			return emptyInterface
+		}
+		fallthrough
 	default:
 		panic(&UnmarshalTypeMismatchError{Value: v, Type: rt})
 	}
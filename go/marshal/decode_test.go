@@ -376,6 +376,82 @@ func TestDecodeEmbeddedStruct(tt *testing.T) {
 	assert.Equal(OuterTest{true, TestStruct{EmbeddedStruct{2}}}, ts2)
 }
 
+func TestDecodeEmbeddedStructPointer(tt *testing.T) {
+	assert := assert.New(tt)
+
+	type EmbeddedStruct struct {
+		X int
+	}
+	type TestStruct struct {
+		*EmbeddedStruct
+		Y int
+	}
+	var ts TestStruct
+	err := Unmarshal(types.NewStruct("S", types.StructData{
+		"x": types.Number(1),
+		"y": types.Number(2),
+	}), &ts)
+	assert.NoError(err)
+	assert.Equal(TestStruct{&EmbeddedStruct{1}, 2}, ts)
+
+	// The pointer is only allocated if its fields are present.
+	var ts2 TestStruct
+	err = Unmarshal(types.NewStruct("S", types.StructData{
+		"y": types.Number(2),
+	}), &ts2)
+	assert.NoError(err)
+	assert.Equal(TestStruct{nil, 2}, ts2)
+}
+
+func TestDecodeEmbeddedUnexported(tt *testing.T) {
+	assert := assert.New(tt)
+
+	type embeddedStruct struct {
+		X int
+	}
+	type embeddedInt int
+	type TestStruct struct {
+		embeddedStruct
+		embeddedInt
+		Y int
+	}
+	var ts TestStruct
+	err := Unmarshal(types.NewStruct("S", types.StructData{
+		"x": types.Number(1),
+		"y": types.Number(3),
+	}), &ts)
+	assert.NoError(err)
+	assert.Equal(TestStruct{embeddedStruct{1}, 0, 3}, ts)
+}
+
+func TestDecodeEmbeddedConflicts(tt *testing.T) {
+	assert := assert.New(tt)
+
+	type A struct {
+		X int
+		Y int
+		Z int
+	}
+	type B struct {
+		X int
+		Y int `noms:"y"`
+		Z int
+	}
+	type TestStruct struct {
+		A
+		B
+		Z int
+	}
+	var ts TestStruct
+	err := Unmarshal(types.NewStruct("S", types.StructData{
+		"x": types.Number(1),
+		"y": types.Number(2),
+		"z": types.Number(3),
+	}), &ts)
+	assert.NoError(err)
+	assert.Equal(TestStruct{A{}, B{Y: 2}, 3}, ts)
+}
+
 func TestDecodeEmbeddedStructSkip(tt *testing.T) {
 	assert := assert.New(tt)
 
@@ -881,11 +957,12 @@ func TestDecodeOntoNonSupportedInterface(t *testing.T) {
 		M() int
 	}
 	var i I
-	assertDecodeErrorMessage(t, types.Number(1), &i, "Type is not supported, type: marshal.I")
+	assertDecodeErrorMessage(t, types.Number(1), &i, "Cannot unmarshal Number into Go value of type marshal.I")
+	assertDecodeErrorMessage(t, types.NewStruct("I", types.StructData{}), &i, "Cannot unmarshal Struct I {} into Go value of type marshal.I")
 }
 
 func TestDecodeOntoInterfaceStruct(t *testing.T) {
-	// Not implemented because it requires Go 1.7.
+	// Only structs whose names have been registered can be decoded.
 	var i interface{}
 	assertDecodeErrorMessage(t, types.NewStruct("", types.StructData{}), &i, "Cannot unmarshal Struct {} into Go value of type interface {}")
 }
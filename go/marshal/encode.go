@@ -82,15 +82,25 @@ import (
 // Anonymous struct fields are usually marshaled as if their inner exported
 // fields were fields in the outer struct, subject to the usual Go visibility.
 // An anonymous struct field with a name given in its Noms tag is treated as
-// having that name, rather than being anonymous.
+// having that name, rather than being anonymous. The same goes for exported
+// anonymous pointers to structs, except that none of the inner fields are
+// marshaled if the pointer is nil. Anonymous fields of other types are
+// marshaled like named fields, unless they are unexported, in which case they
+// are ignored. As with encoding/json, if several fields end up with the same
+// name the least nested one is used, then the one with a name in its Noms tag,
+// and if there is still more than one candidate none of them are marshaled.
 //
 // Noms values (values implementing types.Value) are copied over without any
 // change.
 //
-// When marshalling interface{} the dynamic type is used.
+// When marshalling interface{} the dynamic type is used. If the dynamic type is
+// a pointer to a struct and it has been registered, see Register, the struct it
+// points to is marshaled. Interface fields holding different registered structs
+// therefore marshal to Noms structs of different names, which Unmarshal can
+// decode back onto the interface.
 //
-// Go pointers, complex, function are not supported. Attempting to encode such a
-// value causes Marshal to return an UnsupportedTypeError.
+// Other Go pointers, complex, function are not supported. Attempting to encode
+// such a value causes Marshal to return an UnsupportedTypeError.
 func Marshal(vrw types.ValueReadWriter, v interface{}) (types.Value, error) {
 	return MarshalOpt(vrw, v, Opt{})
 }
@@ -268,6 +278,9 @@ func typeEncoder(vrw types.ValueReadWriter, t reflect.Type, seenStructs map[stri
 		return func(v reflect.Value) types.Value {
 			// Get the dynamic type.
 			v2 := reflect.ValueOf(v.Interface())
+			if isRegisteredStructPtr(v2) {
+				v2 = v2.Elem()
+			}
 			return typeEncoder(vrw, v2.Type(), seenStructs, tags)(v2)
 		}
 	case reflect.Ptr:
@@ -313,7 +326,7 @@ func structEncoder(vrw types.ValueReadWriter, t reflect.Type, seenStructs map[st
 		e = func(v reflect.Value) types.Value {
 			values := make(types.ValueSlice, len(fields))
 			for i, f := range fields {
-				values[i] = f.encoder(v.FieldByIndex(f.index))
+				values[i] = f.encoder(fieldByIndex(v, f.index))
 			}
 			return structTemplate.NewStruct(values)
 		}
@@ -323,7 +336,7 @@ func structEncoder(vrw types.ValueReadWriter, t reflect.Type, seenStructs map[st
 		e = func(v reflect.Value) types.Value {
 			data := make(types.StructData, len(fields))
 			for _, f := range fields {
-				fv := v.FieldByIndex(f.index)
+				fv := fieldByIndex(v, f.index)
 				if !fv.IsValid() || f.omitEmpty && isEmptyValue(fv) {
 					continue
 				}
@@ -335,13 +348,15 @@ func structEncoder(vrw types.ValueReadWriter, t reflect.Type, seenStructs map[st
 		// Slowest path - we are extending some other struct. We need to start with the
 		// type of that struct and extend.
 		e = func(v reflect.Value) types.Value {
-			fv := v.FieldByIndex(originalFieldIndex)
-			ret := fv.Interface().(types.Struct)
+			ret := types.Struct{}
+			if fv := fieldByIndex(v, originalFieldIndex); fv.IsValid() {
+				ret = fv.Interface().(types.Struct)
+			}
 			if ret.IsZeroValue() {
 				ret = types.NewStruct(structName, nil)
 			}
 			for _, f := range fields {
-				fv := v.FieldByIndex(f.index)
+				fv := fieldByIndex(v, f.index)
 				if !fv.IsValid() || f.omitEmpty && isEmptyValue(fv) {
 					continue
 				}
@@ -376,12 +391,39 @@ func isEmptyValue(v reflect.Value) bool {
 	return false
 }
 
+// isRegisteredStructPtr returns true if |v| is a non-nil pointer to a struct,
+// and its type has been registered.
+func isRegisteredStructPtr(v reflect.Value) bool {
+	return v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Kind() == reflect.Struct &&
+		registeredType(getStructName(v.Type().Elem())) == v.Type()
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex, except that it returns an
+// invalid Value, rather than panicking, if the field is in an embedded struct
+// whose pointer is nil.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
 type field struct {
 	name      string
 	encoder   encoderFunc
 	index     []int
 	nomsType  *types.Type
 	omitEmpty bool
+	// optional is true for the fields of embedded struct pointers, which are
+	// missing when the pointer is nil.
+	optional bool
+	tagged   bool
 }
 
 type fieldSlice []field
@@ -452,6 +494,58 @@ func getTags(f reflect.StructField) (tags nomsTags) {
 	return
 }
 
+// embeddedStruct returns the type of the struct whose fields |f| contributes
+// to its parent struct, if |f| is an anonymous struct, or an exported
+// anonymous pointer to a struct, without a name in its tags.
+func embeddedStruct(f reflect.StructField, tags nomsTags) (t reflect.Type, ptr, ok bool) {
+	if !f.Anonymous || tags.hasName {
+		return nil, false, false
+	}
+	t = f.Type
+	if t.Kind() == reflect.Ptr {
+		// Unexported pointers can't be allocated when unmarshaling.
+		if f.PkgPath != "" {
+			return nil, false, false
+		}
+		t, ptr = t.Elem(), true
+	}
+	return t, ptr, t.Kind() == reflect.Struct
+}
+
+// fieldName is what decides which of several struct fields with the same name
+// is marshaled.
+type fieldName struct {
+	name   string
+	depth  int // how many structs deep the field is embedded
+	tagged bool
+}
+
+// dominantFields returns which of the fields named by |names| are marshaled.
+// Like encoding/json, of the fields with the same name, the least deeply
+// embedded one is used. If there are several, the only one with a name in
+// its tags is used, and if there isn't exactly one of those none are.
+func dominantFields(names []fieldName) []bool {
+	byName := map[string][]int{}
+	for i, fn := range names {
+		byName[fn.name] = append(byName[fn.name], i)
+	}
+	dominant := make([]bool, len(names))
+	for _, candidates := range byName {
+		best, count := candidates[0], 1
+		for _, i := range candidates[1:] {
+			fn, bfn := names[i], names[best]
+			switch {
+			case fn.depth < bfn.depth || fn.depth == bfn.depth && fn.tagged && !bfn.tagged:
+				best, count = i, 1
+			case fn.depth == bfn.depth && fn.tagged == bfn.tagged:
+				count++
+			}
+		}
+		dominant[best] = count == 1
+	}
+	return dominant
+}
+
 func validateField(f reflect.StructField, t reflect.Type) {
 	// PkgPath is the package path that qualifies a lower case (unexported)
 	// field name. It is empty for upper case (exported) field names.
@@ -477,21 +571,29 @@ func typeFields(vrw types.ValueReadWriter, t reflect.Type, seenStructs map[strin
 			continue
 		}
 
-		if f.Anonymous && f.PkgPath == "" && !tags.hasName {
-			embeddedFields, embeddedKnownShape, embeddedOriginalFieldIndex := typeFields(vrw, f.Type, seenStructs, computeType, true)
+		if et, ptr, ok := embeddedStruct(f, tags); ok {
+			embeddedFields, embeddedKnownShape, embeddedOriginalFieldIndex := typeFields(vrw, et, seenStructs, computeType, true)
 			if embeddedOriginalFieldIndex != nil {
 				originalFieldIndex = append(index, embeddedOriginalFieldIndex...)
 			}
 			knownShape = knownShape && embeddedKnownShape
+			if ptr && !computeType {
+				knownShape = false
+			}
 
 			for _, ef := range embeddedFields {
 				ef.index = append(index, ef.index...)
+				ef.optional = ef.optional || ptr
 				fields = append(fields, ef)
 			}
 
 			continue
 		}
 
+		if f.Anonymous && f.PkgPath != "" {
+			continue
+		}
+
 		var nt *types.Type
 		validateField(f, t)
 		if computeType {
@@ -511,10 +613,23 @@ func typeFields(vrw types.ValueReadWriter, t reflect.Type, seenStructs map[strin
 			index:     index,
 			nomsType:  nt,
 			omitEmpty: tags.omitEmpty,
+			tagged:    tags.hasName,
 		})
 	}
 
 	if !embedded {
+		names := make([]fieldName, len(fields))
+		for i, f := range fields {
+			names[i] = fieldName{f.name, len(f.index), f.tagged}
+		}
+		dominant := dominantFields(names)
+		visible := fields[:0]
+		for i, f := range fields {
+			if dominant[i] {
+				visible = append(visible, f)
+			}
+		}
+		fields = visible
 		sort.Sort(fields)
 	}
 	// If embedded then the fields gets sorted once we return to the caller.
@@ -249,6 +249,104 @@ func TestEncodeEmbeddedStruct(t *testing.T) {
 	}).Equals(v2))
 }
 
+func TestEncodeEmbeddedStructPointer(t *testing.T) {
+	assert := assert.New(t)
+
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	type EmbeddedStruct struct {
+		X int
+	}
+	type TestStruct struct {
+		*EmbeddedStruct
+		Y int
+	}
+	v, err := Marshal(vs, TestStruct{&EmbeddedStruct{1}, 2})
+	assert.NoError(err)
+	assert.True(types.NewStruct("TestStruct", types.StructData{
+		"x": types.Number(1),
+		"y": types.Number(2),
+	}).Equals(v))
+
+	// The fields of nil pointers are left out.
+	v, err = Marshal(vs, TestStruct{nil, 2})
+	assert.NoError(err)
+	assert.True(types.NewStruct("TestStruct", types.StructData{
+		"y": types.Number(2),
+	}).Equals(v))
+}
+
+func TestEncodeEmbeddedUnexported(t *testing.T) {
+	assert := assert.New(t)
+
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	type embeddedStruct struct {
+		X int
+	}
+	type embeddedInt int
+	type TestStruct struct {
+		embeddedStruct
+		embeddedInt
+		Y int
+	}
+	v, err := Marshal(vs, TestStruct{embeddedStruct{1}, 2, 3})
+	assert.NoError(err)
+	assert.True(types.NewStruct("TestStruct", types.StructData{
+		"x": types.Number(1),
+		"y": types.Number(3),
+	}).Equals(v))
+}
+
+func TestEncodeEmbeddedNonStruct(t *testing.T) {
+	assert := assert.New(t)
+
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	type EmbeddedInt int
+	type TestStruct struct {
+		EmbeddedInt
+	}
+	v, err := Marshal(vs, TestStruct{1})
+	assert.NoError(err)
+	assert.True(types.NewStruct("TestStruct", types.StructData{
+		"embeddedInt": types.Number(1),
+	}).Equals(v))
+}
+
+func TestEncodeEmbeddedConflicts(t *testing.T) {
+	assert := assert.New(t)
+
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	type A struct {
+		X int
+		Y int
+		Z int
+	}
+	type B struct {
+		X int
+		Y int `noms:"y"`
+		Z int
+	}
+	type TestStruct struct {
+		A
+		B
+		Z int
+	}
+	// Z is least nested, Y is only tagged in B, and X is ambiguous.
+	v, err := Marshal(vs, TestStruct{A{1, 2, 3}, B{4, 5, 6}, 7})
+	assert.NoError(err)
+	assert.True(types.NewStruct("TestStruct", types.StructData{
+		"y": types.Number(5),
+		"z": types.Number(7),
+	}).Equals(v))
+}
+
 func TestEncodeEmbeddedStructOriginal(t *testing.T) {
 	assert := assert.New(t)
 
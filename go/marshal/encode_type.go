@@ -22,6 +22,11 @@ import (
 //
 // If a Go struct contains a noms tag with original the field is skipped since
 // the Noms type depends on the original Noms value which is not available.
+//
+// The fields of embedded struct pointers are optional, since they are missing
+// when the pointer is nil. An interface type, other than interface{}, becomes
+// the union of the struct types of the registered types that implement it, see
+// Register.
 func MarshalType(vrw types.ValueReadWriter, v interface{}) (nt *types.Type, err error) {
 	return MarshalTypeOpt(vrw, v, Opt{})
 }
@@ -138,6 +143,8 @@ func encodeType(vrw types.ValueReadWriter, t reflect.Type, seenStructs map[strin
 		return types.StringType
 	case reflect.Struct:
 		return structEncodeType(vrw, t, seenStructs)
+	case reflect.Interface:
+		return interfaceEncodeType(vrw, t, seenStructs)
 	case reflect.Array, reflect.Slice:
 		elemType := encodeType(vrw, t.Elem(), seenStructs, nomsTags{})
 		if elemType == nil {
@@ -190,7 +197,7 @@ func structEncodeType(vrw types.ValueReadWriter, t reflect.Type, seenStructs map
 			structTypeFields[i] = types.StructField{
 				Name:     fs.name,
 				Type:     fs.nomsType,
-				Optional: fs.omitEmpty,
+				Optional: fs.omitEmpty || fs.optional,
 			}
 		}
 		structType = types.MakeStructType(getStructName(t), structTypeFields...)
@@ -198,3 +205,27 @@ func structEncodeType(vrw types.ValueReadWriter, t reflect.Type, seenStructs map
 
 	return structType
 }
+
+// interfaceEncodeType returns the union of the types of the registered types
+// that implement the interface |t|, or nil if there aren't any, or if |t| is
+// interface{} and so could hold any value at all.
+func interfaceEncodeType(vrw types.ValueReadWriter, t reflect.Type, seenStructs map[string]reflect.Type) *types.Type {
+	if t == emptyInterface {
+		return nil
+	}
+	impls := registeredImplementations(t)
+	if len(impls) == 0 {
+		return nil
+	}
+	elemTypes := make([]*types.Type, len(impls))
+	for i, it := range impls {
+		if it.Kind() == reflect.Ptr {
+			it = it.Elem()
+		}
+		elemTypes[i] = encodeType(vrw, it, seenStructs, nomsTags{})
+		if elemTypes[i] == nil {
+			return nil
+		}
+	}
+	return types.MakeUnionType(elemTypes...)
+}
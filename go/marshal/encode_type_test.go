@@ -143,6 +143,56 @@ func TestMarshalTypeEmbeddedStruct(t *testing.T) {
 	}).Equals(typ))
 }
 
+func TestMarshalTypeEmbeddedStructPointer(t *testing.T) {
+	assert := assert.New(t)
+
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	type EmbeddedStruct struct {
+		B bool
+	}
+	type TestStruct struct {
+		*EmbeddedStruct
+		A int
+	}
+
+	var s TestStruct
+	typ := MustMarshalType(vs, s)
+
+	assert.True(types.MakeStructType("TestStruct",
+		types.StructField{Name: "a", Type: types.NumberType},
+		types.StructField{Name: "b", Type: types.BoolType, Optional: true},
+	).Equals(typ))
+}
+
+func TestMarshalTypeEmbeddedConflicts(t *testing.T) {
+	assert := assert.New(t)
+
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	type A struct {
+		X int
+		Y bool
+	}
+	type B struct {
+		X string
+		Y string `noms:"y"`
+	}
+	type TestStruct struct {
+		A
+		B
+	}
+
+	var s TestStruct
+	typ := MustMarshalType(vs, s)
+
+	assert.True(types.MakeStructTypeFromFields("TestStruct", types.FieldMap{
+		"y": types.StringType,
+	}).Equals(typ))
+}
+
 func TestMarshalTypeEmbeddedStructSkip(t *testing.T) {
 	assert := assert.New(t)
 
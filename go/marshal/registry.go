@@ -0,0 +1,85 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package marshal
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Register records the Go type of |v| as the type that Noms structs with its
+// struct name decode to when they are unmarshaled onto an interface. |v| must
+// be a struct, or a pointer to a struct, in which case a pointer to a new
+// struct is decoded. The struct name is the one Marshal would use, see
+// StructNameMarshaler.
+//
+// Registered types also determine the type MarshalType computes for an
+// interface: the union of the struct types of all the registered types that
+// implement it.
+//
+// Register is typically called from an init function. It panics if a
+// different type is already registered with the same struct name.
+func Register(v interface{}) {
+	t := reflect.TypeOf(v)
+	st := t
+	if st != nil && st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st == nil || st.Kind() != reflect.Struct {
+		panic(&UnsupportedTypeError{Type: t, Message: "Only structs and pointers to structs can be registered"})
+	}
+
+	name := getStructName(st)
+	if name == "" {
+		panic(&UnsupportedTypeError{Type: t, Message: "Structs without a name cannot be registered"})
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if rt, ok := registry.types[name]; ok {
+		if rt != t {
+			panic(fmt.Errorf("Struct name %s is already registered to %s", name, rt))
+		}
+		return
+	}
+	if registry.types == nil {
+		registry.types = map[string]reflect.Type{}
+	}
+	registry.types[name] = t
+	registry.names = append(registry.names, name)
+	sort.Strings(registry.names)
+}
+
+type registryT struct {
+	sync.RWMutex
+	types map[string]reflect.Type
+	names []string // sorted, so that implementations are found in a stable order
+}
+
+var registry = &registryT{}
+
+// registeredType returns the type registered for the struct name |name|, or
+// nil if there isn't one.
+func registeredType(name string) reflect.Type {
+	registry.RLock()
+	defer registry.RUnlock()
+	return registry.types[name]
+}
+
+// registeredImplementations returns the registered types that implement the
+// interface |it|, ordered by struct name.
+func registeredImplementations(it reflect.Type) []reflect.Type {
+	registry.RLock()
+	defer registry.RUnlock()
+	impls := []reflect.Type{}
+	for _, name := range registry.names {
+		if t := registry.types[name]; t.Implements(it) {
+			impls = append(impls, t)
+		}
+	}
+	return impls
+}
@@ -0,0 +1,135 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package marshal
+
+import (
+	"testing"
+
+	"github.com/attic-labs/noms/go/nomdl"
+	"github.com/attic-labs/noms/go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type shape interface {
+	area() float64
+}
+
+type circle struct {
+	Radius float64
+}
+
+func (c circle) area() float64 {
+	return 3 * c.Radius * c.Radius
+}
+
+type rect struct {
+	Width, Height float64
+}
+
+func (r *rect) area() float64 {
+	return r.Width * r.Height
+}
+
+type group struct {
+	Shapes []shape
+}
+
+func (g group) area() float64 {
+	a := float64(0)
+	for _, s := range g.Shapes {
+		a += s.area()
+	}
+	return a
+}
+
+type drawing struct {
+	Title string
+	Shape shape
+}
+
+func init() {
+	Register(circle{})
+	Register(&rect{})
+	Register(group{})
+}
+
+func TestRegisteredInterfaceRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	d := drawing{"shapes", group{[]shape{circle{1}, &rect{2, 3}}}}
+	v, err := Marshal(vs, d)
+	assert.NoError(err)
+	assert.True(types.NewStruct("Drawing", types.StructData{
+		"title": types.String("shapes"),
+		"shape": types.NewStruct("Group", types.StructData{
+			"shapes": types.NewList(vs,
+				types.NewStruct("Circle", types.StructData{"radius": types.Number(1)}),
+				types.NewStruct("Rect", types.StructData{"width": types.Number(2), "height": types.Number(3)}),
+			),
+		}),
+	}).Equals(v))
+
+	var d2 drawing
+	assert.NoError(Unmarshal(v, &d2))
+	assert.Equal(d, d2)
+	assert.Equal(float64(9), d2.Shape.area())
+
+	// Registered structs can be decoded onto interface{} too.
+	var i interface{}
+	assert.NoError(Unmarshal(v.(types.Struct).Get("shape").(types.Struct).Get("shapes"), &i))
+	assert.Equal([]interface{}{circle{1}, &rect{2, 3}}, i)
+}
+
+func TestRegisteredInterfaceErrors(t *testing.T) {
+	type notShape struct {
+		Radius float64
+	}
+	Register(notShape{})
+
+	var s shape
+	assertDecodeErrorMessage(t, types.NewStruct("NotShape", types.StructData{"radius": types.Number(1)}), &s,
+		"Cannot unmarshal Struct NotShape {\n  radius: Number,\n} into Go value of type marshal.shape, registered type marshal.notShape does not implement it")
+	assertDecodeErrorMessage(t, types.NewStruct("Triangle", types.StructData{}), &s,
+		"Cannot unmarshal Struct Triangle {} into Go value of type marshal.shape")
+
+	// Circle is already registered, to circle.
+	assert.Panics(t, func() { Register(&circle{}) })
+	assert.Panics(t, func() { Register(42) })
+	assert.Panics(t, func() { Register(struct{ X int }{}) })
+}
+
+func TestMarshalTypeRegisteredInterface(t *testing.T) {
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	typ, err := MarshalType(vs, drawing{})
+	assert.NoError(t, err)
+	assert.True(t, nomdl.MustParseType(`Struct Drawing {
+		shape: Struct Circle {
+			radius: Number,
+		} | Struct Group {
+			shapes: List<Cycle<Circle> | Cycle<Group> | Struct Rect {
+				height: Number,
+				width: Number,
+			}>,
+		} | Struct Rect {
+			height: Number,
+			width: Number,
+		},
+		title: String,
+	}`).Equals(typ), typ.Describe())
+
+	// Interfaces that no registered type implements have no type.
+	type unknown interface {
+		unknown()
+	}
+	type withUnknown struct {
+		U unknown
+	}
+	assertMarshalTypeErrorMessage(t, withUnknown{}, "Type is not supported, type: marshal.withUnknown")
+}
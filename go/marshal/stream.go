@@ -0,0 +1,205 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package marshal
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/attic-labs/noms/go/types"
+)
+
+// ListEncoder marshals Go values one at a time into the elements of a new Noms
+// List, so that the List can be larger than would fit in memory as a Go slice.
+// The List is written to the ValueReadWriter as it is built.
+//
+// List must be called once all the elements have been encoded.
+type ListEncoder struct {
+	vrw    types.ValueReadWriter
+	opt    Opt
+	values chan types.Value
+	list   <-chan types.List
+}
+
+// NewListEncoder returns a ListEncoder that marshals each element with the same
+// rules as Marshal.
+func NewListEncoder(vrw types.ValueReadWriter) *ListEncoder {
+	return NewListEncoderOpt(vrw, Opt{})
+}
+
+// NewListEncoderOpt is like NewListEncoder but with additional options.
+func NewListEncoderOpt(vrw types.ValueReadWriter, opt Opt) *ListEncoder {
+	values := make(chan types.Value, 16)
+	return &ListEncoder{vrw, opt, values, types.NewStreamingList(vrw, values)}
+}
+
+// Encode marshals |v| and appends it to the List. If |v| can't be marshaled,
+// nothing is appended and the error is returned.
+func (le *ListEncoder) Encode(v interface{}) error {
+	nv, err := MarshalOpt(le.vrw, v, le.opt)
+	if err != nil {
+		return err
+	}
+	le.values <- nv
+	return nil
+}
+
+// List returns the List of the elements encoded so far. The ListEncoder can't
+// be used after it's called.
+func (le *ListEncoder) List() types.List {
+	close(le.values)
+	return <-le.list
+}
+
+// MapEncoder marshals Go keys and values one entry at a time into the entries
+// of a new Noms Map, so that the Map can be larger than would fit in memory as
+// a Go map. The Map is written to the ValueReadWriter as it is built, so the
+// entries must be encoded in increasing Noms order of their keys. Use
+// types.MapEditor to build a Map from entries in any order.
+//
+// Map must be called once all the entries have been encoded.
+type MapEncoder struct {
+	vrw     types.ValueReadWriter
+	opt     Opt
+	kvs     chan types.Value
+	m       <-chan types.Map
+	lastKey types.Value
+}
+
+// NewMapEncoder returns a MapEncoder that marshals each key and value with the
+// same rules as Marshal.
+func NewMapEncoder(vrw types.ValueReadWriter) *MapEncoder {
+	return NewMapEncoderOpt(vrw, Opt{})
+}
+
+// NewMapEncoderOpt is like NewMapEncoder but with additional options.
+func NewMapEncoderOpt(vrw types.ValueReadWriter, opt Opt) *MapEncoder {
+	kvs := make(chan types.Value, 16)
+	return &MapEncoder{vrw, opt, kvs, types.NewStreamingMap(vrw, kvs), nil}
+}
+
+// Encode marshals |k| and |v| and adds the entry to the Map. If either can't
+// be marshaled, or the key isn't greater than the key of the previous entry,
+// nothing is added and the error is returned.
+func (me *MapEncoder) Encode(k, v interface{}) error {
+	nk, err := MarshalOpt(me.vrw, k, me.opt)
+	if err != nil {
+		return err
+	}
+	if me.lastKey != nil && !me.lastKey.Less(nk) {
+		return fmt.Errorf("Map keys must be encoded in increasing order, but %s follows %s", types.EncodedValue(nk), types.EncodedValue(me.lastKey))
+	}
+	nv, err := MarshalOpt(me.vrw, v, me.opt)
+	if err != nil {
+		return err
+	}
+	me.lastKey = nk
+	me.kvs <- nk
+	me.kvs <- nv
+	return nil
+}
+
+// Map returns the Map of the entries encoded so far. The MapEncoder can't be
+// used after it's called.
+func (me *MapEncoder) Map() types.Map {
+	close(me.kvs)
+	return <-me.m
+}
+
+var errorInterface = reflect.TypeOf((*error)(nil)).Elem()
+
+// UnmarshalEach unmarshals the elements of the List or Set |v| one at a time,
+// with the same rules as Unmarshal, and calls |f| with each of them in turn.
+// |f| must be a func(T) or func(T) error, where T is the Go type to unmarshal
+// the elements onto. If |v| is a Map, |f| must instead be a func(K, V) or
+// func(K, V) error, and is called with the key and value of each entry.
+//
+// Only one element of |v| is in memory at a time, so it can be larger than
+// would fit in memory as a Go slice or map. If |f| returns an error, or an
+// element can't be unmarshaled, no more elements are unmarshaled and the error
+// is returned.
+func UnmarshalEach(v types.Value, f interface{}) error {
+	return UnmarshalEachOpt(v, Opt{}, f)
+}
+
+// UnmarshalEachOpt is like UnmarshalEach but with additional options.
+func UnmarshalEachOpt(v types.Value, opt Opt, f interface{}) error {
+	fv := reflect.ValueOf(f)
+	ft := fv.Type()
+	nargs := 1
+	if _, ok := v.(types.Map); ok {
+		nargs = 2
+	}
+	if ft.Kind() != reflect.Func || ft.NumIn() != nargs || ft.NumOut() > 1 || ft.NumOut() == 1 && ft.Out(0) != errorInterface {
+		return &UnsupportedTypeError{Type: ft, Message: "Expected a func with one argument per element, and no result or an error"}
+	}
+
+	call := func(args ...types.Value) error {
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			ptr := reflect.New(ft.In(i))
+			if err := UnmarshalOpt(arg, opt, ptr.Interface()); err != nil {
+				return err
+			}
+			in[i] = ptr.Elem()
+		}
+		if out := fv.Call(in); len(out) == 1 && !out[0].IsNil() {
+			return out[0].Interface().(error)
+		}
+		return nil
+	}
+
+	switch v := v.(type) {
+	case types.List:
+		it := v.Iterator()
+		for elem := it.Next(); elem != nil; elem = it.Next() {
+			if err := call(elem); err != nil {
+				return err
+			}
+		}
+	case types.Set:
+		it := v.Iterator()
+		for elem := it.Next(); elem != nil; elem = it.Next() {
+			if err := call(elem); err != nil {
+				return err
+			}
+		}
+	case types.Map:
+		it := v.Iterator()
+		for k, elem := it.Next(); k != nil; k, elem = it.Next() {
+			if err := call(k, elem); err != nil {
+				return err
+			}
+		}
+	default:
+		return &UnmarshalTypeMismatchError{v, ft.In(0), ", expected List, Set or Map"}
+	}
+	return nil
+}
+
+// UnmarshalChan is like UnmarshalEach, except that the elements of the List or
+// Set |v| are sent to |ch|, which must be a channel of the Go type to unmarshal
+// them onto. It returns once all the elements have been sent, without closing
+// |ch|.
+func UnmarshalChan(v types.Value, ch interface{}) error {
+	return UnmarshalChanOpt(v, Opt{}, ch)
+}
+
+// UnmarshalChanOpt is like UnmarshalChan but with additional options.
+func UnmarshalChanOpt(v types.Value, opt Opt, ch interface{}) error {
+	cv := reflect.ValueOf(ch)
+	ct := cv.Type()
+	if ct.Kind() != reflect.Chan || ct.ChanDir()&reflect.SendDir == 0 {
+		return &UnsupportedTypeError{Type: ct, Message: "Expected a channel that can be sent to"}
+	}
+	if _, ok := v.(types.Map); ok {
+		return &UnmarshalTypeMismatchError{v, ct.Elem(), ", expected List or Set"}
+	}
+	send := reflect.MakeFunc(reflect.FuncOf([]reflect.Type{ct.Elem()}, nil, false), func(args []reflect.Value) []reflect.Value {
+		cv.Send(args[0])
+		return nil
+	})
+	return UnmarshalEachOpt(v, opt, send.Interface())
+}
@@ -0,0 +1,141 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package marshal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/attic-labs/noms/go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type streamRow struct {
+	Name string
+	Tags []string `noms:",set"`
+	Note string   `noms:",omitempty"`
+}
+
+func TestListEncoder(t *testing.T) {
+	assert := assert.New(t)
+
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	le := NewListEncoder(vs)
+	rows := []streamRow{}
+	for i := 0; i < 1000; i++ {
+		row := streamRow{Name: string('a' + rune(i%26)), Tags: []string{"x"}}
+		rows = append(rows, row)
+		assert.NoError(le.Encode(row))
+	}
+	assert.EqualError(le.Encode(make(chan int)), "Type is not supported, type: chan int")
+	l := le.List()
+
+	// The List is the same as marshaling the whole slice at once.
+	assert.True(MustMarshal(vs, rows).Equals(l))
+	assert.Equal(uint64(1000), l.Len())
+
+	assert.True(types.NewList(vs).Equals(NewListEncoder(vs).List()))
+}
+
+func TestMapEncoder(t *testing.T) {
+	assert := assert.New(t)
+
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	me := NewMapEncoderOpt(vs, Opt{Set: true})
+	for i, k := range []string{"a", "b", "c"} {
+		assert.NoError(me.Encode(k, []int{i}))
+	}
+	assert.Error(me.Encode("d", make(chan int)))
+	assert.EqualError(me.Encode("b", []int{3}), `Map keys must be encoded in increasing order, but "b" follows "c"`)
+	assert.EqualError(me.Encode("c", []int{3}), `Map keys must be encoded in increasing order, but "c" follows "c"`)
+	assert.NoError(me.Encode("d", []int{3}))
+	assert.True(types.NewMap(vs,
+		types.String("a"), types.NewSet(vs, types.Number(0)),
+		types.String("b"), types.NewSet(vs, types.Number(1)),
+		types.String("c"), types.NewSet(vs, types.Number(2)),
+		types.String("d"), types.NewSet(vs, types.Number(3)),
+	).Equals(me.Map()))
+
+	// Keys are in Noms order, in which Numbers come before Strings.
+	me = NewMapEncoder(vs)
+	assert.NoError(me.Encode(1000, "x"))
+	assert.NoError(me.Encode("a", "y"))
+	assert.EqualError(me.Encode(1, "z"), `Map keys must be encoded in increasing order, but 1 follows "a"`)
+	assert.True(types.NewMap(vs, types.Number(1000), types.String("x"), types.String("a"), types.String("y")).Equals(me.Map()))
+
+	assert.True(types.NewMap(vs).Equals(NewMapEncoder(vs).Map()))
+}
+
+func TestUnmarshalEach(t *testing.T) {
+	assert := assert.New(t)
+
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	rows := []streamRow{{"a", []string{"x"}, ""}, {"b", []string{"y", "z"}, "note"}}
+	l := MustMarshal(vs, rows)
+
+	got := []streamRow{}
+	assert.NoError(UnmarshalEach(l, func(row streamRow) {
+		got = append(got, row)
+	}))
+	assert.Equal(rows, got)
+
+	got = []streamRow{}
+	assert.NoError(UnmarshalEach(MustMarshalOpt(vs, rows, Opt{Set: true}), func(row streamRow) {
+		got = append(got, row)
+	}))
+	assert.Equal(rows, got)
+
+	// Iteration stops at the first error.
+	stop := errors.New("stop")
+	n := 0
+	assert.Equal(stop, UnmarshalEach(l, func(row streamRow) error {
+		n++
+		return stop
+	}))
+	assert.Equal(1, n)
+
+	m := types.NewMap(vs, types.String("a"), types.Number(1), types.String("b"), types.Number(2))
+	entries := map[string]int{}
+	assert.NoError(UnmarshalEach(m, func(k string, v int) {
+		entries[k] = v
+	}))
+	assert.Equal(map[string]int{"a": 1, "b": 2}, entries)
+
+	assert.EqualError(UnmarshalEach(l, func(n float64) {}), "Cannot unmarshal Struct StreamRow {\n  name: String,\n  tags: Set<String>,\n} into Go value of type float64")
+	assert.EqualError(UnmarshalEach(types.Number(1), func(n float64) {}), "Cannot unmarshal Number into Go value of type float64, expected List, Set or Map")
+	assert.EqualError(UnmarshalEach(m, func(k string) {}), "Expected a func with one argument per element, and no result or an error, type: func(string)")
+	assert.EqualError(UnmarshalEach(l, func(row streamRow) bool { return true }), "Expected a func with one argument per element, and no result or an error, type: func(marshal.streamRow) bool")
+}
+
+func TestUnmarshalChan(t *testing.T) {
+	assert := assert.New(t)
+
+	vs := newTestValueStore()
+	defer vs.Close()
+
+	l := types.NewList(vs, types.Number(1), types.Number(2), types.Number(3))
+	ch := make(chan int)
+	errCh := make(chan error)
+	go func() {
+		err := UnmarshalChan(l, ch)
+		close(ch)
+		errCh <- err
+	}()
+	got := []int{}
+	for n := range ch {
+		got = append(got, n)
+	}
+	assert.NoError(<-errCh)
+	assert.Equal([]int{1, 2, 3}, got)
+
+	assert.EqualError(UnmarshalChan(l, make(<-chan int)), "Expected a channel that can be sent to, type: <-chan int")
+	assert.EqualError(UnmarshalChan(types.NewMap(vs), make(chan int)), "Cannot unmarshal Map<> into Go value of type int, expected List or Set")
+}
@@ -0,0 +1,295 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package merge
+
+import (
+	"fmt"
+
+	"github.com/attic-labs/noms/go/types"
+)
+
+const (
+	stateName    = "MergeState"
+	conflictName = "Conflict"
+)
+
+// State is a merge whose conflicts are being resolved. It can be stored in a
+// Database, so that conflicts can be resolved one at a time, over as many
+// sessions as necessary, before the merge is finished.
+type State struct {
+	// Ours and Theirs are Refs to the Commits being merged.
+	Ours, Theirs types.Ref
+	// Merged is the result of ThreeWayCollect, with any conflicts resolved so
+	// far applied to it.
+	Merged types.Value
+	// Conflicts are the conflicts that remain to be resolved.
+	Conflicts []Conflict
+}
+
+// NewState returns the State of merging the Commits |ours| and |theirs|,
+// given the result of merging their values with ThreeWayCollect.
+func NewState(ours, theirs types.Ref, merged types.Value, conflicts []Conflict) State {
+	return State{ours, theirs, merged, conflicts}
+}
+
+// Done returns true if there are no conflicts left to resolve.
+func (s State) Done() bool {
+	return len(s.Conflicts) == 0
+}
+
+// Conflict returns the conflict at |path|, if there is one.
+func (s State) Conflict(path types.Path) (c Conflict, ok bool) {
+	for _, c := range s.Conflicts {
+		if c.Path.Equals(path) {
+			return c, true
+		}
+	}
+	return
+}
+
+// Resolve returns a new State in which the conflict at |path| is resolved by
+// setting the merged value at |path| to |v|, or by removing it if |v| is nil.
+func (s State) Resolve(path types.Path, v types.Value, vrw types.ValueReadWriter) (State, error) {
+	c, ok := s.Conflict(path)
+	if !ok {
+		return s, fmt.Errorf("No conflict at %s", path.String())
+	}
+
+	merged, err := setAtPath(s.Merged, c.Path, c.Key, v, vrw)
+	if err != nil {
+		return s, err
+	}
+	remaining := make([]Conflict, 0, len(s.Conflicts)-1)
+	for _, other := range s.Conflicts {
+		if !other.Path.Equals(path) {
+			remaining = append(remaining, other)
+		}
+	}
+	return State{s.Ours, s.Theirs, merged, remaining}, nil
+}
+
+// setAtPath returns |root| with the value at |path| replaced by |v|, or
+// removed if |v| is nil. Merging sees through Refs, so paths of conflicts do
+// too: any Ref found on the way to |path| is followed, and replaced by a Ref
+// to the updated value. |key| is used as the final Map key or Set value if
+// the last part of |path| is the hash of a key that isn't in |root|.
+func setAtPath(root types.Value, path types.Path, key, v types.Value, vrw types.ValueReadWriter) (types.Value, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+	if r, ok := root.(types.Ref); ok {
+		updated, err := setAtPath(r.TargetValue(vrw), path, key, v, vrw)
+		if err != nil {
+			return nil, err
+		}
+		return vrw.WriteValue(updated), nil
+	}
+
+	part, rest := path[0], path[1:]
+	last := len(rest) == 0
+	cannot := func() (types.Value, error) {
+		return nil, fmt.Errorf("Cannot set %s in %s", part.String(), describe(root))
+	}
+	recurse := func(child types.Value) (types.Value, error) {
+		if child == nil {
+			return nil, fmt.Errorf("Nothing at %s", part.String())
+		}
+		return setAtPath(child, rest, key, v, vrw)
+	}
+
+	switch part := part.(type) {
+	case types.FieldPath:
+		s, ok := root.(types.Struct)
+		if !ok {
+			return cannot()
+		}
+		if last {
+			if v == nil {
+				return s.Delete(part.Name), nil
+			}
+			return s.Set(part.Name, v), nil
+		}
+		child, _ := s.MaybeGet(part.Name)
+		updated, err := recurse(child)
+		if err != nil {
+			return nil, err
+		}
+		return s.Set(part.Name, updated), nil
+
+	case types.IndexPath:
+		switch root := root.(type) {
+		case types.Map:
+			return setInMap(root, part.Index, last, v, recurse)
+		case types.Set:
+			if last {
+				return setInSet(root, part.Index, v), nil
+			}
+		case types.List:
+			n, ok := part.Index.(types.Number)
+			if !ok || n < 0 || uint64(n) >= root.Len() {
+				return nil, fmt.Errorf("Index %s out of range", part.String())
+			}
+			idx := uint64(n)
+			if last {
+				if v == nil {
+					return root.Edit().RemoveAt(idx).List(), nil
+				}
+				return root.Edit().Set(idx, v).List(), nil
+			}
+			updated, err := recurse(root.Get(idx))
+			if err != nil {
+				return nil, err
+			}
+			return root.Edit().Set(idx, updated).List(), nil
+		}
+
+	case types.HashIndexPath:
+		k := types.NewHashIndexIntoKeyPath(part.Hash).Resolve(root, vrw)
+		if k == nil && last && key != nil && key.Hash() == part.Hash {
+			k = key
+		}
+		switch root := root.(type) {
+		case types.Map:
+			if k == nil {
+				return nil, fmt.Errorf("Nothing at %s", part.String())
+			}
+			return setInMap(root, k, last, v, recurse)
+		case types.Set:
+			if last && k != nil {
+				return setInSet(root, k, v), nil
+			}
+		}
+	}
+	return cannot()
+}
+
+func setInMap(m types.Map, k types.Value, last bool, v types.Value, recurse func(types.Value) (types.Value, error)) (types.Value, error) {
+	if last {
+		if v == nil {
+			return m.Edit().Remove(k).Map(), nil
+		}
+		return m.Edit().Set(k, v).Map(), nil
+	}
+	updated, err := recurse(m.Get(k))
+	if err != nil {
+		return nil, err
+	}
+	return m.Edit().Set(k, updated).Map(), nil
+}
+
+func setInSet(s types.Set, k, v types.Value) types.Value {
+	se := s.Edit().Remove(k)
+	if v != nil {
+		se.Insert(v)
+	}
+	return se.Set()
+}
+
+func describe(v types.Value) string {
+	if v == nil {
+		return "nil Value"
+	}
+	return types.TypeOf(v).Describe()
+}
+
+// Marshal encodes |s| as a Noms struct, so that it can be stored in a
+// Database. The struct looks like:
+//
+//	struct MergeState {
+//	  ours: Ref<Cycle<Commit>>,
+//	  theirs: Ref<Cycle<Commit>>,
+//	  merged: Value,
+//	  conflicts: List<struct Conflict {
+//	    path: String,
+//	    key: Value,
+//	    ancestor: Value,
+//	    ours: Value,
+//	    theirs: Value,
+//	  }>,
+//	}
+//
+// Fields that would be nil are omitted.
+func (s State) Marshal(vrw types.ValueReadWriter) types.Struct {
+	conflicts := make([]types.Value, len(s.Conflicts))
+	for i, c := range s.Conflicts {
+		data := types.StructData{"path": types.String(c.Path.String())}
+		setIfNotNil(data, "key", c.Key)
+		setIfNotNil(data, "ancestor", c.Ancestor)
+		setIfNotNil(data, "ours", c.Ours)
+		setIfNotNil(data, "theirs", c.Theirs)
+		conflicts[i] = types.NewStruct(conflictName, data)
+	}
+	data := types.StructData{
+		"ours":      s.Ours,
+		"theirs":    s.Theirs,
+		"conflicts": types.NewList(vrw, conflicts...),
+	}
+	setIfNotNil(data, "merged", s.Merged)
+	return types.NewStruct(stateName, data)
+}
+
+func setIfNotNil(data types.StructData, name string, v types.Value) {
+	if v != nil {
+		data[name] = v
+	}
+}
+
+// UnmarshalState decodes a State encoded by State.Marshal.
+func UnmarshalState(v types.Value) (State, error) {
+	st, ok := v.(types.Struct)
+	if !ok || st.Name() != stateName {
+		return State{}, fmt.Errorf("Not a %s: %s", stateName, describe(v))
+	}
+	s := State{}
+	ours, oursOk := st.MaybeGet("ours")
+	theirs, theirsOk := st.MaybeGet("theirs")
+	conflicts, conflictsOk := st.MaybeGet("conflicts")
+	if !oursOk || !theirsOk || !conflictsOk {
+		return State{}, fmt.Errorf("Malformed %s", stateName)
+	}
+	if s.Ours, ok = ours.(types.Ref); !ok {
+		return State{}, fmt.Errorf("Malformed %s: ours is a %s", stateName, describe(ours))
+	}
+	if s.Theirs, ok = theirs.(types.Ref); !ok {
+		return State{}, fmt.Errorf("Malformed %s: theirs is a %s", stateName, describe(theirs))
+	}
+	s.Merged, _ = st.MaybeGet("merged")
+
+	list, ok := conflicts.(types.List)
+	if !ok {
+		return State{}, fmt.Errorf("Malformed %s: conflicts is a %s", stateName, describe(conflicts))
+	}
+	var err error
+	list.IterAll(func(v types.Value, idx uint64) {
+		if err != nil {
+			return
+		}
+		var c Conflict
+		c, err = unmarshalConflict(v)
+		s.Conflicts = append(s.Conflicts, c)
+	})
+	return s, err
+}
+
+func unmarshalConflict(v types.Value) (c Conflict, err error) {
+	st, ok := v.(types.Struct)
+	if !ok || st.Name() != conflictName {
+		return c, fmt.Errorf("Not a %s: %s", conflictName, describe(v))
+	}
+	path, ok := st.MaybeGet("path")
+	if !ok || path.Kind() != types.StringKind {
+		return c, fmt.Errorf("Malformed %s: missing path", conflictName)
+	}
+	if str := string(path.(types.String)); str != "" {
+		if c.Path, err = types.ParsePath(str); err != nil {
+			return c, err
+		}
+	}
+	c.Key, _ = st.MaybeGet("key")
+	c.Ancestor, _ = st.MaybeGet("ancestor")
+	c.Ours, _ = st.MaybeGet("ours")
+	c.Theirs, _ = st.MaybeGet("theirs")
+	return c, nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package merge
+
+import (
+	"testing"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreeWayCollect(t *testing.T) {
+	assert := assert.New(t)
+	vs := types.NewValueStore((&chunks.MemoryStorage{}).NewView())
+	defer vs.Close()
+
+	structKey := types.NewStruct("Key", types.StructData{"id": types.Number(1)})
+	parent := types.NewStruct("", types.StructData{
+		"num": types.Number(1),
+		"str": types.String("parent"),
+		"map": types.NewMap(vs,
+			types.String("same"), types.Number(1),
+			types.String("gone"), types.Number(2),
+		),
+		"ref": vs.WriteValue(types.NewMap(vs, types.String("x"), types.Number(1))),
+	})
+	a := types.NewStruct("", types.StructData{
+		"num": types.Number(2),
+		"str": types.String("ours"),
+		"map": types.NewMap(vs,
+			types.String("same"), types.Number(1),
+			types.String("gone"), types.Number(3),
+			structKey, types.String("ours"),
+		),
+		"ref": vs.WriteValue(types.NewMap(vs, types.String("x"), types.Number(2))),
+	})
+	b := types.NewStruct("", types.StructData{
+		"num": types.Number(3),
+		"str": types.String("parent"),
+		"map": types.NewMap(vs,
+			types.String("same"), types.Number(1),
+			types.String("new"), types.Number(4),
+			structKey, types.String("theirs"),
+		),
+		"ref": vs.WriteValue(types.NewMap(vs, types.String("x"), types.Number(3))),
+	})
+
+	_, err := ThreeWay(a, b, parent, vs, nil, nil)
+	assert.Error(err)
+
+	merged, conflicts := ThreeWayCollect(a, b, parent, vs, nil, nil)
+	paths := map[string]Conflict{}
+	for _, c := range conflicts {
+		paths[c.Path.String()] = c
+	}
+	structKeyPath := `.map[#` + structKey.Hash().String() + `]`
+	assert.Len(paths, 4)
+	assert.Contains(paths, ".num")
+	assert.Contains(paths, `.map["gone"]`)
+	assert.Contains(paths, structKeyPath)
+	assert.Contains(paths, `.ref["x"]`)
+
+	gone := paths[`.map["gone"]`]
+	assert.True(types.Number(2).Equals(gone.Ancestor))
+	assert.True(types.Number(3).Equals(gone.Ours))
+	assert.Nil(gone.Theirs)
+	assert.True(structKey.Equals(paths[structKeyPath].Key))
+	assert.Nil(paths[structKeyPath].Ancestor)
+
+	// Everything that could be merged was, and the parent's values are left at conflicting paths.
+	ms := merged.(types.Struct)
+	assert.True(types.Number(1).Equals(ms.Get("num")))
+	assert.True(types.String("ours").Equals(ms.Get("str")))
+	assert.True(types.NewMap(vs,
+		types.String("same"), types.Number(1),
+		types.String("gone"), types.Number(2),
+		types.String("new"), types.Number(4),
+	).Equals(ms.Get("map")))
+
+	// Resolve everything, and round-trip the State along the way.
+	st := NewState(vs.WriteValue(a), vs.WriteValue(b), merged, conflicts)
+	for _, c := range conflicts {
+		var err error
+		st, err = UnmarshalState(st.Marshal(vs))
+		assert.NoError(err)
+		assert.False(st.Done())
+		st, err = st.Resolve(c.Path, c.Theirs, vs)
+		assert.NoError(err)
+	}
+	assert.True(st.Done())
+	_, err = st.Resolve(types.MustParsePath(".num"), types.Number(42), vs)
+	assert.Error(err)
+
+	ms = st.Merged.(types.Struct)
+	assert.True(types.Number(3).Equals(ms.Get("num")))
+	assert.True(types.NewMap(vs,
+		types.String("same"), types.Number(1),
+		types.String("new"), types.Number(4),
+		structKey, types.String("theirs"),
+	).Equals(ms.Get("map")))
+	assert.True(types.NewMap(vs, types.String("x"), types.Number(3)).Equals(ms.Get("ref").(types.Ref).TargetValue(vs)))
+}
+
+func TestThreeWayCollectRoot(t *testing.T) {
+	assert := assert.New(t)
+	vs := types.NewValueStore((&chunks.MemoryStorage{}).NewView())
+	defer vs.Close()
+
+	parent := types.NewList(vs, types.Number(1))
+	a := types.NewList(vs, types.Number(2))
+	b := types.NewList(vs, types.Number(3))
+	merged, conflicts := ThreeWayCollect(a, b, parent, vs, nil, nil)
+	assert.True(parent.Equals(merged))
+	if assert.Len(conflicts, 1) {
+		assert.True(conflicts[0].Path.IsEmpty())
+		assert.True(a.Equals(conflicts[0].Ours))
+	}
+
+	merged, conflicts = ThreeWayCollect(types.Number(1), types.String("one"), nil, vs, nil, nil)
+	assert.Nil(merged)
+	assert.Len(conflicts, 1)
+
+	st := NewState(vs.WriteValue(a), vs.WriteValue(b), merged, conflicts)
+	st, err := UnmarshalState(st.Marshal(vs))
+	assert.NoError(err)
+	assert.Nil(st.Merged)
+	st, err = st.Resolve(types.Path{}, types.String("one"), vs)
+	assert.NoError(err)
+	assert.True(types.String("one").Equals(st.Merged))
+}
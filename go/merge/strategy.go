@@ -0,0 +1,280 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package merge
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/attic-labs/noms/go/types"
+)
+
+// The strategies that can be given for a path in Strategies.
+const (
+	// CounterStrategy treats Numbers as counters: both candidates' changes
+	// relative to the parent are added to the parent's value, which is
+	// taken to be 0 if there isn't one.
+	CounterStrategy = "counter"
+	// LastWriterWinsStrategy takes the value from whichever candidate's
+	// Commit has the later meta date. Ties go to the first candidate.
+	LastWriterWinsStrategy = "lww"
+	// MaxStrategy and MinStrategy take the greater or lesser of the two
+	// candidates' values, using the ordering of Value.Less. A value is
+	// always preferred to a removal.
+	MaxStrategy = "max"
+	MinStrategy = "min"
+	// UnionStrategy merges Lists as if they were Sets: elements added by
+	// either candidate are kept, and elements removed by either are
+	// dropped. The result keeps the order of the first candidate, followed
+	// by the elements added only by the second.
+	UnionStrategy = "union"
+	// OursStrategy and TheirsStrategy always take the value of the first or
+	// second candidate respectively.
+	OursStrategy   = "ours"
+	TheirsStrategy = "theirs"
+)
+
+var knownStrategies = map[string]bool{
+	CounterStrategy:        true,
+	LastWriterWinsStrategy: true,
+	MaxStrategy:            true,
+	MinStrategy:            true,
+	UnionStrategy:          true,
+	OursStrategy:           true,
+	TheirsStrategy:         true,
+}
+
+const strategyRuleName = "MergeStrategy"
+
+// Strategies maps path patterns to the strategy used to merge changes made by
+// both candidates of a merge at matching paths. Patterns are types.PathGlobs,
+// so `.*` matches any field and `[*]` matches any index or key. The empty
+// pattern matches the root of the merge.
+// Rules are tried in the order they were added, and the first match wins.
+//
+// A strategy only comes into play where both candidates changed the value at
+// a path; a change made by just one of them is taken as is. Everywhere else,
+// merging is done by ThreeWay.
+type Strategies struct {
+	rules []strategyRule
+}
+
+type strategyRule struct {
+	glob     types.PathGlob
+	strategy string
+}
+
+// Add returns a copy of |s| with a rule that merges changes at paths matching
+// |pattern| using |strategy|.
+func (s Strategies) Add(pattern, strategy string) (Strategies, error) {
+	if !knownStrategies[strategy] {
+		return s, fmt.Errorf("Unknown merge strategy: %s", strategy)
+	}
+	glob, err := types.ParsePathGlob(pattern)
+	if err != nil {
+		return s, err
+	}
+	rules := make([]strategyRule, len(s.rules), len(s.rules)+1)
+	copy(rules, s.rules)
+	return Strategies{append(rules, strategyRule{glob, strategy})}, nil
+}
+
+// Len returns the number of rules in |s|.
+func (s Strategies) Len() int {
+	return len(s.rules)
+}
+
+// String returns the rules of |s|, one per line, as <pattern>=<strategy>.
+func (s Strategies) String() string {
+	buff := &bytes.Buffer{}
+	for _, r := range s.rules {
+		fmt.Fprintf(buff, "%s=%s\n", r.glob.String(), r.strategy)
+	}
+	return buff.String()
+}
+
+// Strategy returns the strategy for |path|, if any rule matches it.
+func (s Strategies) Strategy(path types.Path) (strategy string, ok bool) {
+	for _, r := range s.rules {
+		if r.glob.Matches(path) {
+			return r.strategy, true
+		}
+	}
+	return
+}
+
+// Policy returns a Policy that merges using ThreeWay, with |resolve| as the
+// fallback for conflicts at paths that no rule matches. |aMeta| and |bMeta|
+// are the meta Structs of the Commits whose values are being merged; their
+// "date" fields, if any, are used by LastWriterWinsStrategy.
+func (s Strategies) Policy(aMeta, bMeta types.Struct, resolve ResolveFunc) Policy {
+	sc := &strategyContext{s, commitDate(aMeta), commitDate(bMeta)}
+	return func(a, b, parent types.Value, vrw types.ValueReadWriter, progress chan struct{}) (types.Value, error) {
+		if strategy, ok := s.Strategy(types.Path{}); ok {
+			merged, ok := sc.apply(strategy, a, b, parent, vrw)
+			if !ok {
+				return parent, newMergeConflict("Cannot merge %s with %s using the %s strategy.", describe(a), describe(b), strategy)
+			}
+			return merged, nil
+		}
+		if a == nil && b == nil {
+			return parent, nil
+		} else if unmergeable(a, b) {
+			return parent, newMergeConflict("Cannot merge %s with %s.", describe(a), describe(b))
+		}
+		if resolve == nil {
+			resolve = None
+		}
+		m := &merger{vrw, resolve, progress, nil, sc}
+		return m.threeWay(a, b, parent, types.Path{})
+	}
+}
+
+// Marshal encodes |s| as a List of Structs, so that it can be stored in a
+// Database:
+//
+//	List<struct MergeStrategy {
+//	  pattern: String,
+//	  strategy: String,
+//	}>
+func (s Strategies) Marshal(vrw types.ValueReadWriter) types.List {
+	rules := make([]types.Value, len(s.rules))
+	for i, r := range s.rules {
+		rules[i] = types.NewStruct(strategyRuleName, types.StructData{
+			"pattern":  types.String(r.glob.String()),
+			"strategy": types.String(r.strategy),
+		})
+	}
+	return types.NewList(vrw, rules...)
+}
+
+// UnmarshalStrategies decodes Strategies encoded by Strategies.Marshal.
+func UnmarshalStrategies(v types.Value) (s Strategies, err error) {
+	l, ok := v.(types.List)
+	if !ok {
+		return s, fmt.Errorf("Merge strategies must be a List, not %s", describe(v))
+	}
+	l.IterAll(func(v types.Value, idx uint64) {
+		if err != nil {
+			return
+		}
+		st, ok := v.(types.Struct)
+		if !ok || st.Name() != strategyRuleName {
+			err = fmt.Errorf("Not a %s: %s", strategyRuleName, describe(v))
+			return
+		}
+		pattern, pOk := st.MaybeGet("pattern")
+		strategy, sOk := st.MaybeGet("strategy")
+		if !pOk || !sOk || pattern.Kind() != types.StringKind || strategy.Kind() != types.StringKind {
+			err = fmt.Errorf("Malformed %s", strategyRuleName)
+			return
+		}
+		s, err = s.Add(string(pattern.(types.String)), string(strategy.(types.String)))
+	})
+	return
+}
+
+func commitDate(meta types.Struct) (t time.Time) {
+	if meta.IsZeroValue() {
+		return
+	}
+	if date, ok := meta.MaybeGet("date"); ok {
+		if s, ok := date.(types.String); ok {
+			t, _ = time.Parse(time.RFC3339, string(s))
+		}
+	}
+	return
+}
+
+// strategyContext is what a merger needs to apply Strategies.
+type strategyContext struct {
+	strategies   Strategies
+	aDate, bDate time.Time
+}
+
+// apply merges |a| and |b|, either of which may be nil, against |parent|
+// using |strategy|. It returns false if the values can't be merged that way.
+func (sc *strategyContext) apply(strategy string, a, b, parent types.Value, vrw types.ValueReadWriter) (merged types.Value, ok bool) {
+	switch strategy {
+	case OursStrategy:
+		return a, true
+	case TheirsStrategy:
+		return b, true
+	case LastWriterWinsStrategy:
+		if sc.bDate.After(sc.aDate) {
+			return b, true
+		}
+		return a, true
+	case MaxStrategy, MinStrategy:
+		if a == nil || b == nil {
+			if a == nil {
+				return b, true
+			}
+			return a, true
+		}
+		if b.Less(a) == (strategy == MaxStrategy) {
+			return a, true
+		}
+		return b, true
+	case CounterStrategy:
+		aNum, aOk := a.(types.Number)
+		bNum, bOk := b.(types.Number)
+		var pNum types.Number
+		if parent != nil {
+			var pOk bool
+			if pNum, pOk = parent.(types.Number); !pOk {
+				return nil, false
+			}
+		}
+		if !aOk || !bOk {
+			return nil, a == nil && b == nil
+		}
+		return aNum + bNum - pNum, true
+	case UnionStrategy:
+		aList, aOk := a.(types.List)
+		bList, bOk := b.(types.List)
+		if !aOk || !bOk {
+			return nil, false
+		}
+		pList, pOk := parent.(types.List)
+		if !pOk {
+			if parent != nil {
+				return nil, false
+			}
+			pList = types.NewList(vrw)
+		}
+		return listUnion(aList, bList, pList, vrw), true
+	}
+	return nil, false
+}
+
+// listUnion merges |a| and |b| as if they were Sets, i.e. into every element
+// both of them kept from |parent|, plus every element either of them added.
+func listUnion(a, b, parent types.List, vrw types.ValueReadWriter) types.List {
+	hashes := func(l types.List) hash.HashSet {
+		hs := hash.HashSet{}
+		l.IterAll(func(v types.Value, idx uint64) { hs.Insert(v.Hash()) })
+		return hs
+	}
+	aHashes, bHashes, pHashes := hashes(a), hashes(b), hashes(parent)
+	keep := func(h hash.Hash) bool {
+		inA, inB, inP := aHashes.Has(h), bHashes.Has(h), pHashes.Has(h)
+		return (inA && inB) || (inA && !inP) || (inB && !inP)
+	}
+
+	seen := hash.HashSet{}
+	out := []types.Value{}
+	add := func(v types.Value, idx uint64) {
+		if h := v.Hash(); !seen.Has(h) && keep(h) {
+			seen.Insert(h)
+			out = append(out, v)
+		}
+	}
+	a.IterAll(add)
+	b.IterAll(add)
+	return types.NewList(vrw, out...)
+}
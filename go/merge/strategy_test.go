@@ -0,0 +1,137 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package merge
+
+import (
+	"testing"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrategiesMatch(t *testing.T) {
+	assert := assert.New(t)
+	s := Strategies{}
+	var err error
+	for _, r := range [][2]string{
+		{`.counters[*]`, CounterStrategy},
+		{`.*.tags`, UnionStrategy},
+		{`.scores["best"]`, MaxStrategy},
+		{`.scores[#01234567890123456789012345678901]`, MinStrategy},
+		{``, OursStrategy},
+	} {
+		s, err = s.Add(r[0], r[1])
+		assert.NoError(err)
+	}
+
+	match := func(path string) string {
+		p := types.Path{}
+		if path != "" {
+			p = types.MustParsePath(path)
+		}
+		strategy, _ := s.Strategy(p)
+		return strategy
+	}
+	assert.Equal(CounterStrategy, match(`.counters["a"]`))
+	assert.Equal(CounterStrategy, match(`.counters[42]`))
+	assert.Equal("", match(`.counters`))
+	assert.Equal("", match(`.counters["a"].b`))
+	assert.Equal(UnionStrategy, match(`.post.tags`))
+	assert.Equal("", match(`.post["x"].tags`))
+	assert.Equal(MaxStrategy, match(`.scores["best"]`))
+	assert.Equal("", match(`.scores["worst"]`))
+	assert.Equal(MinStrategy, match(`.scores[#01234567890123456789012345678901]`))
+	assert.Equal(OursStrategy, match(``))
+
+	_, err = s.Add(".x", "nope")
+	assert.Error(err)
+	_, err = s.Add(".x[", CounterStrategy)
+	assert.Error(err)
+	_, err = s.Add("x", CounterStrategy)
+	assert.Error(err)
+
+	vs := types.NewValueStore((&chunks.MemoryStorage{}).NewView())
+	defer vs.Close()
+	roundTripped, err := UnmarshalStrategies(s.Marshal(vs))
+	assert.NoError(err)
+	assert.Equal(s, roundTripped)
+}
+
+func TestStrategiesPolicy(t *testing.T) {
+	assert := assert.New(t)
+	vs := types.NewValueStore((&chunks.MemoryStorage{}).NewView())
+	defer vs.Close()
+
+	s := Strategies{}
+	for _, r := range [][2]string{
+		{`.counts[*]`, CounterStrategy},
+		{`.tags`, UnionStrategy},
+		{`.high`, MaxStrategy},
+		{`.low`, MinStrategy},
+		{`.name`, LastWriterWinsStrategy},
+		{`.mine`, OursStrategy},
+	} {
+		var err error
+		s, err = s.Add(r[0], r[1])
+		assert.NoError(err)
+	}
+
+	list := func(vals ...string) types.List {
+		l := types.NewList(vs).Edit()
+		for _, v := range vals {
+			l.Append(types.String(v))
+		}
+		return l.List()
+	}
+	value := func(a, b float64, tags types.List, high, low float64, name, mine, other string) types.Struct {
+		return types.NewStruct("", types.StructData{
+			"counts": types.NewMap(vs, types.String("a"), types.Number(a), types.String("b"), types.Number(b)),
+			"tags":   tags,
+			"high":   types.Number(high),
+			"low":    types.Number(low),
+			"name":   types.String(name),
+			"mine":   types.String(mine),
+			"other":  types.String(other),
+		})
+	}
+	meta := func(date string) types.Struct {
+		return types.NewStruct("", types.StructData{"date": types.String(date)})
+	}
+
+	parent := value(1, 1, list("x", "y"), 5, 5, "parent", "parent", "parent")
+	a := value(3, 1, list("x", "a"), 7, 4, "a", "a", "parent")
+	b := value(2, 4, list("y", "x", "b"), 6, 3, "b", "b", "b")
+	expected := value(4, 4, list("x", "a", "b"), 7, 3, "b", "a", "b")
+
+	merged, err := s.Policy(meta("2017-01-01T00:00:00Z"), meta("2017-01-02T00:00:00Z"), nil)(a, b, parent, vs, nil)
+	if assert.NoError(err) {
+		assert.True(expected.Equals(merged), "%s", types.EncodedValue(merged))
+	}
+
+	// Without dates, the last writer is taken to be the first candidate.
+	merged, err = s.Policy(types.EmptyStruct, types.Struct{}, nil)(a, b, parent, vs, nil)
+	if assert.NoError(err) {
+		assert.True(types.String("a").Equals(merged.(types.Struct).Get("name")))
+	}
+
+	// Conflicts elsewhere still fail the merge, unless resolved.
+	c := value(3, 1, list("x", "a"), 7, 4, "a", "a", "c")
+	_, err = s.Policy(types.EmptyStruct, types.EmptyStruct, nil)(c, b, parent, vs, nil)
+	assert.IsType(&ErrMergeConflict{}, err)
+	merged, err = s.Policy(types.EmptyStruct, types.EmptyStruct, Theirs)(c, b, parent, vs, nil)
+	if assert.NoError(err) {
+		assert.True(types.String("b").Equals(merged.(types.Struct).Get("other")))
+	}
+
+	// Strategies that don't fit the values are conflicts.
+	counter, err := Strategies{}.Add("", CounterStrategy)
+	assert.NoError(err)
+	merged, err = counter.Policy(types.EmptyStruct, types.EmptyStruct, nil)(types.Number(3), types.Number(4), types.Number(2), vs, nil)
+	assert.NoError(err)
+	assert.True(types.Number(5).Equals(merged))
+	_, err = counter.Policy(types.EmptyStruct, types.EmptyStruct, nil)(types.Number(3), types.String("4"), types.Number(2), vs, nil)
+	assert.IsType(&ErrMergeConflict{}, err)
+}
@@ -73,7 +73,9 @@ func NewThreeWay(resolve ResolveFunc) Policy {
 //
 // - If any of the three values have a different [kind](link): conflict
 // - If the two candidates are identical: the result is that value
-// - If the values are primitives or Blob: conflict
+// - If the values are Strings or text Blobs: merge them line by line, as
+//   diff3 does. If both candidates changed the same lines differently: conflict
+// - If the values are other primitives or Blobs: conflict
 // - If the values are maps:
 //   - if the same key was inserted or updated in both candidates:
 //     - first run this same algorithm on those two values to attempt to merge them
@@ -134,13 +136,6 @@ func NewThreeWay(resolve ResolveFunc) Policy {
 // b:      [a, d, e]
 // merged: [a, d, e]
 func ThreeWay(a, b, parent types.Value, vrw types.ValueReadWriter, resolve ResolveFunc, progress chan struct{}) (merged types.Value, err error) {
-	describe := func(v types.Value) string {
-		if v != nil {
-			return types.TypeOf(v).Describe()
-		}
-		return "nil Value"
-	}
-
 	if a == nil && b == nil {
 		return parent, nil
 	} else if unmergeable(a, b) {
@@ -150,15 +145,55 @@ func ThreeWay(a, b, parent types.Value, vrw types.ValueReadWriter, resolve Resol
 	if resolve == nil {
 		resolve = None
 	}
-	m := &merger{vrw, resolve, progress}
+	m := &merger{vrw, resolve, progress, nil, nil}
 	return m.threeWay(a, b, parent, types.Path{})
 }
 
-// a and b cannot be merged if they are of different NomsKind, or if at least one of the two is nil, or if either is a Noms primitive.
+// Conflict describes a change that ThreeWayCollect could not merge. Ancestor,
+// Ours and Theirs are the values at Path in the parent, a and b respectively,
+// or nil where there is no value, e.g. because it was removed.
+type Conflict struct {
+	Path types.Path
+	// Key is the key of the Map or Set entry, or the name of the Struct field,
+	// at the end of Path. Non-primitive Map keys can't be spelled in a Path, so
+	// this is needed to resolve conflicts at keys the merged value lacks. It's
+	// nil if the conflict is at the root of the merge.
+	Key                    types.Value
+	Ancestor, Ours, Theirs types.Value
+}
+
+// ThreeWayCollect is like ThreeWay, except that it doesn't give up at the
+// first change that can't be merged. Instead, it leaves the parent's value in
+// place at the path of every such change, carries on merging everything else,
+// and returns the partially merged value along with a Conflict describing each
+// change it couldn't merge. If there are no conflicts, |merged| is the same as
+// the result of ThreeWay.
+//
+// Conflicts are never reported within Lists: if the changes to a List can't be
+// merged, the whole List is in conflict.
+func ThreeWayCollect(a, b, parent types.Value, vrw types.ValueReadWriter, resolve ResolveFunc, progress chan struct{}) (merged types.Value, conflicts []Conflict) {
+	if a == nil && b == nil {
+		return parent, nil
+	} else if unmergeable(a, b) {
+		return parent, []Conflict{{types.Path{}, nil, parent, a, b}}
+	}
+
+	if resolve == nil {
+		resolve = None
+	}
+	m := &merger{vrw, resolve, progress, &conflicts, nil}
+	merged, err := m.threeWay(a, b, parent, types.Path{})
+	if err != nil {
+		return parent, append(conflicts, Conflict{types.Path{}, nil, parent, a, b})
+	}
+	return merged, conflicts
+}
+
+// a and b cannot be merged if they are of different NomsKind, or if at least one of the two is nil, or if either is a Noms primitive other than String or Blob.
 func unmergeable(a, b types.Value) bool {
 	if a != nil && b != nil {
 		aKind, bKind := a.Kind(), b.Kind()
-		return aKind != bKind || types.IsPrimitiveKind(aKind) || types.IsPrimitiveKind(bKind)
+		return aKind != bKind || (types.IsPrimitiveKind(aKind) && !isText(a))
 	}
 	return true
 }
@@ -167,6 +202,12 @@ type merger struct {
 	vrw      types.ValueReadWriter
 	resolve  ResolveFunc
 	progress chan<- struct{}
+	// If non-nil, conflicts found within Maps, Sets and Structs are appended
+	// here rather than failing the merge.
+	conflicts *[]Conflict
+	// If non-nil, changes made by both candidates at paths matching one of
+	// its Strategies are merged using that strategy.
+	strategies *strategyContext
 }
 
 func updateProgress(progress chan<- struct{}) {
@@ -183,6 +224,16 @@ func (m *merger) threeWay(a, b, parent types.Value, path types.Path) (merged typ
 	}
 
 	switch a.Kind() {
+	case types.StringKind:
+		if bString, ok := b.(types.String); ok {
+			return threeWayStringMerge(a.(types.String), bString, parent)
+		}
+
+	case types.BlobKind:
+		if bBlob, ok := b.(types.Blob); ok {
+			return threeWayBlobMerge(a.(types.Blob), bBlob, parent, m.vrw)
+		}
+
 	case types.ListKind:
 		if aList, bList, pList, ok := listAssert(m.vrw, a, b, parent); ok {
 			return threeWayListMerge(aList, bList, pList)
@@ -62,9 +62,16 @@ func (m *merger) threeWayOrderedSequenceMerge(a, b, parent candidate, apply appl
 
 		change, mergedVal, err := m.mergeChanges(aChange, bChange, a, b, parent, apply, path)
 		if err != nil {
-			return parent.getValue(), err
+			if m.conflicts == nil {
+				return parent.getValue(), err
+			}
+			// Leave the parent's value at this key in place, so that merging can continue.
+			*m.conflicts = append(*m.conflicts, Conflict{
+				a.pathConcat(aChange, path), aChange.Key, parent.get(aChange.Key), a.get(aChange.Key), b.get(bChange.Key),
+			})
+		} else {
+			merged = apply(merged, change, mergedVal)
 		}
-		merged = apply(merged, change, mergedVal)
 		aChange, bChange = types.ValueChanged{}, types.ValueChanged{}
 	}
 	return merged.getValue(), nil
@@ -73,6 +80,21 @@ func (m *merger) threeWayOrderedSequenceMerge(a, b, parent candidate, apply appl
 func (m *merger) mergeChanges(aChange, bChange types.ValueChanged, a, b, p candidate, apply applyFunc, path types.Path) (change types.ValueChanged, mergedVal types.Value, err error) {
 	path = a.pathConcat(aChange, path)
 	aValue, bValue := a.get(aChange.Key), b.get(bChange.Key)
+	if m.strategies != nil {
+		if strategy, ok := m.strategies.strategies.Strategy(path); ok {
+			pValue := p.get(aChange.Key)
+			if mergedVal, ok = m.strategies.apply(strategy, aValue, bValue, pValue, m.vrw); !ok {
+				return change, nil, newMergeConflict("Conflict:\n%s\nvs\n%s\ncannot be merged using the %s strategy", describeChange(aChange), describeChange(bChange), strategy)
+			}
+			changeType := types.DiffChangeModified
+			if mergedVal == nil {
+				changeType = types.DiffChangeRemoved
+			} else if pValue == nil {
+				changeType = types.DiffChangeAdded
+			}
+			return types.ValueChanged{changeType, aChange.Key, nil, nil}, mergedVal, nil
+		}
+	}
 	// If the two diffs generate different kinds of changes at the same key, conflict.
 	if aChange.ChangeType != bChange.ChangeType {
 		if change, mergedVal, ok := m.resolve(aChange.ChangeType, bChange.ChangeType, aValue, bValue, path); ok {
@@ -88,19 +110,27 @@ func (m *merger) mergeChanges(aChange, bChange types.ValueChanged, a, b, p candi
 	}
 
 	// There's one case that might still be OK even if aValue and bValue differ: different, but mergeable, compound values of the same type being added/modified at the same key, e.g. a Map being added to both a and b. If either is a primitive, or Values of different Kinds were added, though, we're in conflict.
+	// Text whose lines can't be merged is still given to m.resolve, as other primitives are.
+	var textErr error
 	if !unmergeable(aValue, bValue) {
 		// TODO: Add concurrency.
 		var err error
 		if mergedVal, err = m.threeWay(aValue, bValue, p.get(aChange.Key), path); err == nil {
 			return aChange, mergedVal, nil
 		}
-		return change, nil, err
+		if !isText(aValue) {
+			return change, nil, err
+		}
+		textErr = err
 	}
 
 	if change, mergedVal, ok := m.resolve(aChange.ChangeType, bChange.ChangeType, aValue, bValue, path); ok {
 		// TODO: Correctly encode Old/NewValue with this change report. https://github.com/attic-labs/noms/issues/3467
 		return types.ValueChanged{change, aChange.Key, nil, nil}, mergedVal, nil
 	}
+	if textErr != nil {
+		return change, nil, textErr
+	}
 	return change, nil, newMergeConflict("Conflict:\n%s = %s\nvs\n%s = %s", describeChange(aChange), types.EncodedValue(aValue), describeChange(bChange), types.EncodedValue(bValue))
 }
 
@@ -0,0 +1,202 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package merge
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/attic-labs/noms/go/d"
+	"github.com/attic-labs/noms/go/types"
+)
+
+// isText returns true if |v| might be merged line-by-line by
+// threeWayTextMerge, i.e. if it's a String or a Blob.
+func isText(v types.Value) bool {
+	k := v.Kind()
+	return k == types.StringKind || k == types.BlobKind
+}
+
+func threeWayStringMerge(a, b types.String, parent types.Value) (types.Value, error) {
+	pStr := ""
+	if parent != nil {
+		p, ok := parent.(types.String)
+		if !ok {
+			return parent, newMergeConflict("Cannot merge String and String on top of %s.", describe(parent))
+		}
+		pStr = string(p)
+	}
+	merged, err := threeWayTextMerge(string(a), string(b), pStr)
+	if err != nil {
+		return parent, err
+	}
+	return types.String(merged), nil
+}
+
+func threeWayBlobMerge(a, b types.Blob, parent types.Value, vrw types.ValueReadWriter) (types.Value, error) {
+	readText := func(blob types.Blob) (string, bool) {
+		data, err := ioutil.ReadAll(blob.Reader())
+		d.PanicIfError(err)
+		if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+			return "", false
+		}
+		return string(data), true
+	}
+
+	aStr, aOk := readText(a)
+	bStr, bOk := readText(b)
+	pStr, pOk := "", true
+	if parent != nil {
+		if p, ok := parent.(types.Blob); ok {
+			pStr, pOk = readText(p)
+		} else {
+			pOk = false
+		}
+	}
+	if !aOk || !bOk || !pOk {
+		return parent, newMergeConflict("Cannot merge Blobs that aren't all text.")
+	}
+	merged, err := threeWayTextMerge(aStr, bStr, pStr)
+	if err != nil {
+		return parent, err
+	}
+	return types.NewBlob(vrw, strings.NewReader(merged)), nil
+}
+
+// threeWayTextMerge merges the lines of |a| and |b| in the manner of diff3.
+// The lines changed by |a| and by |b| relative to |parent| are found using
+// types.CalcSplices. Changes that don't touch the same lines are both
+// applied. Changes that overlap are grouped into hunks, and a hunk is only
+// merged if |a| and |b| made exactly the same change to it; otherwise, the
+// merge fails with an ErrMergeConflict that describes each conflicting hunk.
+func threeWayTextMerge(a, b, parent string) (string, error) {
+	aLines, bLines, pLines := splitLines(a), splitLines(b), splitLines(parent)
+
+	type edit struct {
+		fromA bool
+		types.Splice
+	}
+	edits := []edit{}
+	for _, sp := range lineSplices(pLines, aLines) {
+		edits = append(edits, edit{true, sp})
+	}
+	for _, sp := range lineSplices(pLines, bLines) {
+		edits = append(edits, edit{false, sp})
+	}
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].SpAt < edits[j].SpAt })
+
+	// apply returns the lines of parent[start:end] with |edits|, all of which
+	// lie within that range, applied.
+	apply := func(edits []edit, start, end uint64) (out []string) {
+		pos := start
+		for _, e := range edits {
+			out = append(out, pLines[pos:e.SpAt]...)
+			lines := bLines
+			if e.fromA {
+				lines = aLines
+			}
+			out = append(out, lines[e.SpFrom:e.SpFrom+e.SpAdded]...)
+			pos = e.SpAt + e.SpRemoved
+		}
+		return append(out, pLines[pos:end]...)
+	}
+
+	merged := []string{}
+	conflicts := []string{}
+	pos := uint64(0)
+	for i := 0; i < len(edits); {
+		// Gather every edit that overlaps the hunk beginning with edits[i].
+		start, end := edits[i].SpAt, edits[i].SpAt+edits[i].SpRemoved
+		var aEdits, bEdits []edit
+		for ; i < len(edits) && (edits[i].SpAt == start || edits[i].SpAt < end); i++ {
+			if e := edits[i]; e.fromA {
+				aEdits = append(aEdits, e)
+			} else {
+				bEdits = append(bEdits, e)
+			}
+			if e := edits[i].SpAt + edits[i].SpRemoved; e > end {
+				end = e
+			}
+		}
+
+		merged = append(merged, pLines[pos:start]...)
+		pos = end
+		aHunk, bHunk := apply(aEdits, start, end), apply(bEdits, start, end)
+		switch {
+		case len(bEdits) == 0:
+			merged = append(merged, aHunk...)
+		case len(aEdits) == 0:
+			merged = append(merged, bHunk...)
+		case strings.Join(aHunk, "") == strings.Join(bHunk, ""):
+			merged = append(merged, aHunk...)
+		default:
+			conflicts = append(conflicts, describeHunk(start, end, aHunk, bHunk))
+		}
+	}
+	if len(conflicts) > 0 {
+		return "", newMergeConflict("Conflicting changes to text:\n%s", strings.Join(conflicts, ""))
+	}
+	merged = append(merged, pLines[pos:]...)
+	return strings.Join(merged, ""), nil
+}
+
+// splitLines splits |s| after each newline, so that joining the lines gives
+// back |s|.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// lineSplices returns the Splices that turn the lines |previous| into
+// |current|. If there are too many differing lines to find a minimal set of
+// Splices, it returns a single Splice that replaces all of the lines between
+// the common prefix and suffix.
+func lineSplices(previous, current []string) []types.Splice {
+	start := 0
+	for start < len(previous) && start < len(current) && previous[start] == current[start] {
+		start++
+	}
+	pEnd, cEnd := len(previous), len(current)
+	for pEnd > start && cEnd > start && previous[pEnd-1] == current[cEnd-1] {
+		pEnd--
+		cEnd--
+	}
+	if removed, added := uint64(pEnd-start), uint64(cEnd-start); removed*added > types.DEFAULT_MAX_SPLICE_MATRIX_SIZE {
+		return []types.Splice{{SpAt: uint64(start), SpRemoved: removed, SpAdded: added, SpFrom: uint64(start)}}
+	}
+
+	return types.CalcSplices(uint64(len(previous)), uint64(len(current)), types.DEFAULT_MAX_SPLICE_MATRIX_SIZE, func(p, c uint64) bool {
+		return previous[p] == current[c]
+	})
+}
+
+func describeHunk(start, end uint64, a, b []string) string {
+	buff := &bytes.Buffer{}
+	if start == end {
+		fmt.Fprintf(buff, "after line %d:\n<<<<<<< ours\n", start)
+	} else {
+		fmt.Fprintf(buff, "lines %d-%d:\n<<<<<<< ours\n", start+1, end)
+	}
+	writeLines := func(lines []string) {
+		for _, l := range lines {
+			buff.WriteString(l)
+			if !strings.HasSuffix(l, "\n") {
+				buff.WriteString("\n")
+			}
+		}
+	}
+	writeLines(a)
+	buff.WriteString("=======\n")
+	writeLines(b)
+	buff.WriteString(">>>>>>> theirs\n")
+	return buff.String()
+}
@@ -0,0 +1,125 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package merge
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreeWayTextMerge(t *testing.T) {
+	assert := assert.New(t)
+	parent := "one\ntwo\nthree\nfour\nfive\n"
+
+	type c struct {
+		a, b, merged string
+	}
+	for _, c := range []c{
+		// Changes to different lines.
+		{"ONE\ntwo\nthree\nfour\nfive\n", "one\ntwo\nthree\nfour\nFIVE\n", "ONE\ntwo\nthree\nfour\nFIVE\n"},
+		// Adjacent lines.
+		{"one\nTWO\nthree\nfour\nfive\n", "one\ntwo\nTHREE\nfour\nfive\n", "one\nTWO\nTHREE\nfour\nfive\n"},
+		// Insertions and removals.
+		{"zero\none\ntwo\nthree\nfour\nfive\n", "one\ntwo\nfour\nfive\nsix\n", "zero\none\ntwo\nfour\nfive\nsix\n"},
+		// The same change made by both.
+		{"one\n2\nthree\nfour\nFIVE\n", "one\n2\nthree\nfour\nfive\n", "one\n2\nthree\nfour\nFIVE\n"},
+		// A missing final newline is just part of the last line.
+		{"one\ntwo\nthree\nfour\nfive", "ONE\ntwo\nthree\nfour\nfive\n", "ONE\ntwo\nthree\nfour\nfive"},
+	} {
+		merged, err := threeWayTextMerge(c.a, c.b, parent)
+		if assert.NoError(err) {
+			assert.Equal(c.merged, merged)
+		}
+		merged, err = threeWayTextMerge(c.b, c.a, parent)
+		if assert.NoError(err) {
+			assert.Equal(c.merged, merged)
+		}
+	}
+
+	_, err := threeWayTextMerge("one\ntwo\nTHREE\nfour\nFIVE\n", "one\ntwo\n3\nfour\nfive\n", parent)
+	if assert.IsType(&ErrMergeConflict{}, err) {
+		assert.Contains(err.Error(), "lines 3-3:\n<<<<<<< ours\nTHREE\n=======\n3\n>>>>>>> theirs\n")
+	}
+	_, err = threeWayTextMerge("one\ntwo\nthree\nfour\nfive\nsix\n", "one\ntwo\nthree\nfour\nfive\n6\n", parent)
+	if assert.IsType(&ErrMergeConflict{}, err) {
+		assert.Contains(err.Error(), "after line 5:\n<<<<<<< ours\nsix\n=======\n6\n>>>>>>> theirs\n")
+	}
+}
+
+func TestThreeWayTextMergeManyLines(t *testing.T) {
+	assert := assert.New(t)
+
+	// Too many lines differ to find minimal Splices, so every line between the
+	// common prefix and suffix is replaced at once.
+	lines := func(prefix string) string {
+		buff := &bytes.Buffer{}
+		for i := 0; i < 5000; i++ {
+			fmt.Fprintf(buff, "%s%d\n", prefix, i)
+		}
+		return buff.String()
+	}
+	parent := "first\n" + lines("p") + "last\n"
+	a := "first\n" + lines("a") + "last\n"
+	b := "first\n" + lines("p") + "LAST\n"
+
+	merged, err := threeWayTextMerge(a, b, parent)
+	if assert.NoError(err) {
+		assert.Equal("first\n"+lines("a")+"LAST\n", merged)
+	}
+}
+
+func TestThreeWayMergeText(t *testing.T) {
+	assert := assert.New(t)
+	vs := types.NewValueStore((&chunks.MemoryStorage{}).NewView())
+	defer vs.Close()
+
+	blob := func(s string) types.Blob {
+		return types.NewBlob(vs, bytes.NewBufferString(s))
+	}
+	parent := types.NewStruct("", types.StructData{
+		"str":  types.String("a\nb\nc\n"),
+		"blob": blob("a\nb\nc\n"),
+		"bin":  blob("\x00\x01"),
+	})
+	a := types.NewStruct("", types.StructData{
+		"str":  types.String("A\nb\nc\n"),
+		"blob": blob("a\nb\nC\n"),
+		"bin":  blob("\x00\x02"),
+	})
+	b := types.NewStruct("", types.StructData{
+		"str":  types.String("a\nb\nC\n"),
+		"blob": blob("A\nb\nc\n"),
+		"bin":  blob("\x00\x03"),
+	})
+
+	// Binary Blobs can't be merged, but like other primitives can be resolved.
+	_, err := ThreeWay(a, b, parent, vs, nil, nil)
+	assert.IsType(&ErrMergeConflict{}, err)
+
+	merged, err := ThreeWay(a, b, parent, vs, Theirs, nil)
+	if assert.NoError(err) {
+		ms := merged.(types.Struct)
+		assert.True(types.String("A\nb\nC\n").Equals(ms.Get("str")))
+		data, err := ioutil.ReadAll(ms.Get("blob").(types.Blob).Reader())
+		assert.NoError(err)
+		assert.Equal("A\nb\nC\n", string(data))
+		assert.True(b.Get("bin").Equals(ms.Get("bin")))
+	}
+
+	// Conflicting lines are given to the ResolveFunc too.
+	merged, err = ThreeWay(types.String("x\n"), types.String("y\n"), types.String("z\n"), vs, Ours, nil)
+	assert.IsType(&ErrMergeConflict{}, err)
+	c := types.NewStruct("", types.StructData{"str": types.String("x\n")})
+	merged, err = ThreeWay(c, c.Set("str", types.String("y\n")), c.Set("str", types.String("z\n")), vs, Ours, nil)
+	if assert.NoError(err) {
+		assert.True(c.Equals(merged))
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PrometheusContentType is the HTTP Content-Type of the Prometheus text
+// exposition format, as written by PrometheusWriter.
+const PrometheusContentType = "text/plain; version=0.0.4"
+
+// Labels are the label names and values that identify a single sample.
+type Labels map[string]string
+
+// PrometheusWriter writes metrics in the Prometheus text exposition format,
+// described at https://prometheus.io/docs/instrumenting/exposition_formats/.
+//
+// All the samples of a given metric must be written consecutively. HELP and
+// TYPE lines are written before the first sample of each metric. The first
+// error encountered while writing is retained, and returned by Err().
+type PrometheusWriter struct {
+	w         io.Writer
+	err       error
+	described map[string]bool
+}
+
+func NewPrometheusWriter(w io.Writer) *PrometheusWriter {
+	return &PrometheusWriter{w: w, described: map[string]bool{}}
+}
+
+// Err returns the first error encountered while writing, if any.
+func (pw *PrometheusWriter) Err() error {
+	return pw.err
+}
+
+// Counter writes a single sample of the counter |name|.
+func (pw *PrometheusWriter) Counter(name, help string, labels Labels, v uint64) {
+	pw.describe(name, help, "counter")
+	pw.sample(name, labels, "", "", float64(v))
+}
+
+// Gauge writes a single sample of the gauge |name|.
+func (pw *PrometheusWriter) Gauge(name, help string, labels Labels, v float64) {
+	pw.describe(name, help, "gauge")
+	pw.sample(name, labels, "", "", v)
+}
+
+// Histogram writes |h| as a sample of the histogram |name|. Each bucket of
+// |h| holds values between consecutive powers of two, so each becomes a
+// Prometheus bucket whose upper bound is one less than the next power of two.
+// Every bucket up to the largest non-empty one is written, so that the set of
+// buckets only grows over time. Values, including bucket bounds, are
+// multiplied by |scale|, e.g. 1e-9 to report nanosecond samples in seconds.
+func (pw *PrometheusWriter) Histogram(name, help string, labels Labels, h Histogram, scale float64) {
+	pw.describe(name, help, "histogram")
+
+	last := -1
+	for i := 0; i < bucketCount; i++ {
+		if h.buckets[i] > 0 {
+			last = i
+		}
+	}
+	var cumulative uint64
+	for i := 0; i <= last; i++ {
+		cumulative += h.buckets[i]
+		upper := float64(h.bucketVal(i)*2-1) * scale
+		pw.sample(name+"_bucket", labels, "le", formatFloat(upper), float64(cumulative))
+	}
+	pw.sample(name+"_bucket", labels, "le", "+Inf", float64(cumulative))
+	pw.sample(name+"_sum", labels, "", "", float64(h.Sum())*scale)
+	pw.sample(name+"_count", labels, "", "", float64(cumulative))
+}
+
+// StructHistograms writes each exported Histogram field of the struct |v|
+// (or pointer to struct) as a histogram named |prefix| followed by the
+// snake_cased field name. Fields whose names contain "Latency" are taken to
+// be samples of nanoseconds, and reported in seconds. Fields whose names
+// contain "Bytes" are reported in bytes. Anything else that isn't a struct is
+// ignored.
+func (pw *PrometheusWriter) StructHistograms(prefix string, labels Labels, v interface{}) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return
+	}
+	histType := reflect.TypeOf(Histogram{})
+	for i := 0; i < rv.NumField(); i++ {
+		f := rv.Type().Field(i)
+		if f.PkgPath != "" || f.Type != histType {
+			continue
+		}
+		name, scale := prefix+snakeCase(f.Name), 1.0
+		if strings.Contains(f.Name, "Latency") {
+			name, scale = name+"_seconds", 1e-9
+		} else if strings.Contains(f.Name, "Bytes") {
+			name += "_bytes"
+		}
+		pw.Histogram(name, f.Name, labels, rv.Field(i).Interface().(Histogram), scale)
+	}
+}
+
+func (pw *PrometheusWriter) describe(name, help, typ string) {
+	if pw.described[name] {
+		return
+	}
+	pw.described[name] = true
+	pw.printf("# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	pw.printf("# TYPE %s %s\n", name, typ)
+}
+
+func (pw *PrometheusWriter) sample(name string, labels Labels, extraName, extraValue string, v float64) {
+	pairs := make([]string, 0, len(labels)+1)
+	for k, lv := range labels {
+		pairs = append(pairs, k+"="+quoteLabelValue(lv))
+	}
+	sort.Strings(pairs)
+	if extraName != "" {
+		pairs = append(pairs, extraName+"="+quoteLabelValue(extraValue))
+	}
+	if len(pairs) > 0 {
+		name += "{" + strings.Join(pairs, ",") + "}"
+	}
+	pw.printf("%s %s\n", name, formatFloat(v))
+}
+
+func (pw *PrometheusWriter) printf(format string, args ...interface{}) {
+	if pw.err == nil {
+		_, pw.err = fmt.Fprintf(pw.w, format, args...)
+	}
+}
+
+func quoteLabelValue(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v) + `"`
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// snakeCase converts a CamelCase identifier to snake_case, keeping runs of
+// capitals (as in "MemTable" or "IOPS") together.
+func snakeCase(s string) string {
+	runes := []rune(s)
+	out := make([]rune, 0, len(runes)+4)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && !unicode.IsUpper(runes[i-1])
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])
+			if prevLower || nextLower {
+				out = append(out, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
@@ -0,0 +1,88 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusWriterCounter(t *testing.T) {
+	assert := assert.New(t)
+	buff := &bytes.Buffer{}
+	pw := NewPrometheusWriter(buff)
+
+	pw.Counter("requests_total", "Requests handled.", Labels{"handler": "root", "method": "GET"}, 3)
+	pw.Counter("requests_total", "Requests handled.", Labels{"handler": `we"ird`}, 0)
+	assert.NoError(pw.Err())
+	assert.Equal(`# HELP requests_total Requests handled.
+# TYPE requests_total counter
+requests_total{handler="root",method="GET"} 3
+requests_total{handler="we\"ird"} 0
+`, buff.String())
+}
+
+func TestPrometheusWriterHistogram(t *testing.T) {
+	assert := assert.New(t)
+	buff := &bytes.Buffer{}
+	pw := NewPrometheusWriter(buff)
+
+	h := Histogram{}
+	h.Sample(1)
+	h.Sample(5)
+	h.Sample(6)
+	pw.Histogram("size", "Sizes.", nil, h, 1)
+	pw.Histogram("empty", "Nothing.", Labels{"a": "b"}, Histogram{}, 1)
+	assert.Equal(`# HELP size Sizes.
+# TYPE size histogram
+size_bucket{le="1"} 1
+size_bucket{le="3"} 1
+size_bucket{le="7"} 3
+size_bucket{le="+Inf"} 3
+size_sum 12
+size_count 3
+# HELP empty Nothing.
+# TYPE empty histogram
+empty_bucket{a="b",le="+Inf"} 0
+empty_sum{a="b"} 0
+empty_count{a="b"} 0
+`, buff.String())
+}
+
+func TestPrometheusWriterStructHistograms(t *testing.T) {
+	assert := assert.New(t)
+	buff := &bytes.Buffer{}
+	pw := NewPrometheusWriter(buff)
+
+	stats := struct {
+		GetLatency      Histogram
+		S3BytesPerRead  Histogram
+		ChunksPerGet    Histogram
+		notExported     Histogram
+		SomethingElse   int
+		MemTableLatency Histogram
+	}{}
+	stats.GetLatency.Sample(2e9)
+	pw.StructHistograms("noms_", nil, &stats)
+
+	out := buff.String()
+	assert.Contains(out, "# TYPE noms_get_latency_seconds histogram\n")
+	assert.Contains(out, "noms_get_latency_seconds_sum 2\n")
+	assert.Contains(out, "# TYPE noms_s3_bytes_per_read_bytes histogram\n")
+	assert.Contains(out, "# TYPE noms_chunks_per_get histogram\n")
+	assert.Contains(out, "# TYPE noms_mem_table_latency_seconds histogram\n")
+	assert.NotContains(out, "not_exported")
+	assert.NotContains(out, "something_else")
+}
+
+func TestSnakeCase(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("read_manifest_latency", snakeCase("ReadManifestLatency"))
+	assert.Equal("s3_read_latency", snakeCase("S3ReadLatency"))
+	assert.Equal("http_request", snakeCase("HTTPRequest"))
+	assert.Equal("get", snakeCase("Get"))
+}
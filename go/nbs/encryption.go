@@ -0,0 +1,179 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package nbs
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/attic-labs/noms/go/d"
+)
+
+/*
+   Encrypted NBS stores use envelope encryption. Each table is written with its own randomly generated Data Key, which seals every Chunk Record in the table using AES-256-GCM. The Data Key is itself sealed ("wrapped") by a MasterKey and stored in the table footer, so any process holding the MasterKey can open any table in the store. The manifest is sealed directly by the MasterKey.
+
+   Chunk addresses are always computed over plaintext chunk data, so encrypted stores dedup, sync and conjoin exactly like plaintext ones. Each Chunk Record is bound to its address by passing the address as additional authenticated data, so records can't be swapped between addresses without detection.
+
+   Encrypted Chunk Record:
+   +-------------+-----------------------------------------------+-----------------+----------------+
+   | (12) Nonce  | AES-GCM((Chunk Length) Chunk Data, Address)   | (16) GCM Tag    | (Uint32) CRC32 |
+   +-------------+-----------------------------------------------+-----------------+----------------+
+
+   Wrapped Data Key:
+   +-------------+--------------------------------+--------------+
+   | (12) Nonce  | AES-GCM((32) Data Key)         | (16) GCM Tag |
+   +-------------+--------------------------------+--------------+
+*/
+
+const (
+	masterKeySize  = 32
+	dataKeySize    = 32
+	gcmNonceSize   = 12
+	gcmTagSize     = 16
+	sealOverhead   = gcmNonceSize + gcmTagSize
+	wrappedKeySize = dataKeySize + sealOverhead
+
+	encryptedManifestPrefix = "nbs-encrypted:"
+)
+
+var (
+	// ErrMasterKeyRequired is the error given when an encrypted table or
+	// manifest is opened by a store that was not configured with a MasterKey.
+	ErrMasterKeyRequired = errors.New("NBS data is encrypted; a master key is required to open it")
+
+	errDecryptionFailed = errors.New("NBS decryption failed; wrong master key or corrupt data")
+)
+
+// MasterKey wraps and unwraps the per-table data keys used to encrypt NBS
+// tables, and seals the manifests of encrypted stores. MasterKey is
+// goroutine safe.
+type MasterKey struct {
+	aead cipher.AEAD
+}
+
+// NewMasterKey returns a MasterKey built from |key|, which must be exactly 32
+// bytes long.
+func NewMasterKey(key []byte) (*MasterKey, error) {
+	if len(key) != masterKeySize {
+		return nil, fmt.Errorf("NBS master key must be %d bytes, got %d", masterKeySize, len(key))
+	}
+	return &MasterKey{newAEAD(key)}, nil
+}
+
+// ReadMasterKeyFile reads a MasterKey from the file at |path|. The file must
+// contain either exactly 32 raw bytes or their hex encoding, optionally
+// followed by whitespace.
+func ReadMasterKeyFile(path string) (*MasterKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == hex.EncodedLen(masterKeySize) {
+		key := make([]byte, masterKeySize)
+		if _, err := hex.Decode(key, trimmed); err == nil {
+			return NewMasterKey(key)
+		}
+	}
+	return NewMasterKey(data)
+}
+
+// newDataKey generates a fresh data key, returning it along with its wrapped
+// form, suitable for writing into a table footer.
+func (mk *MasterKey) newDataKey() (dk *dataKey, wrapped []byte) {
+	key := make([]byte, dataKeySize)
+	_, err := io.ReadFull(rand.Reader, key)
+	d.PanicIfError(err)
+	wrapped = seal(mk.aead, make([]byte, 0, wrappedKeySize), key, nil)
+	return &dataKey{newAEAD(key)}, wrapped
+}
+
+func (mk *MasterKey) unwrap(wrapped []byte) *dataKey {
+	key, err := open(mk.aead, wrapped, nil)
+	d.PanicIfError(err)
+	return &dataKey{newAEAD(key)}
+}
+
+func (mk *MasterKey) sealManifest(plaintext []byte) []byte {
+	sealed := seal(mk.aead, nil, plaintext, nil)
+	return []byte(encryptedManifestPrefix + base64.StdEncoding.EncodeToString(sealed))
+}
+
+func (mk *MasterKey) openManifest(data []byte) []byte {
+	sealed, err := base64.StdEncoding.DecodeString(string(data[len(encryptedManifestPrefix):]))
+	d.PanicIfError(err)
+	plaintext, err := open(mk.aead, sealed, nil)
+	d.PanicIfError(err)
+	return plaintext
+}
+
+func isEncryptedManifest(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptedManifestPrefix))
+}
+
+// unwrapTableKey returns the data key needed to read the table described by
+// |index|, or nil if the table is not encrypted.
+func unwrapTableKey(index tableIndex, mk *MasterKey) *dataKey {
+	if !index.encrypted() {
+		return nil
+	}
+	if mk == nil {
+		d.PanicIfError(ErrMasterKeyRequired)
+	}
+	return mk.unwrap(index.wrappedKey)
+}
+
+// dataKey seals and opens the chunk records of a single table.
+type dataKey struct {
+	aead cipher.AEAD
+}
+
+// sealRecord appends the sealed form of |compressed| to |dst|, binding it to
+// address |h|.
+func (dk *dataKey) sealRecord(dst []byte, h addr, compressed []byte) []byte {
+	return seal(dk.aead, dst, compressed, h[:])
+}
+
+func (dk *dataKey) openRecord(h addr, sealed []byte) []byte {
+	compressed, err := open(dk.aead, sealed, h[:])
+	d.PanicIfError(err)
+	return compressed
+}
+
+func newAEAD(key []byte) cipher.AEAD {
+	block, err := aes.NewCipher(key)
+	d.PanicIfError(err)
+	aead, err := cipher.NewGCM(block)
+	d.PanicIfError(err)
+	return aead
+}
+
+// seal appends a random nonce followed by the sealed form of |plaintext| to
+// |dst|.
+func seal(aead cipher.AEAD, dst, plaintext, additionalData []byte) []byte {
+	nonce := make([]byte, gcmNonceSize)
+	_, err := io.ReadFull(rand.Reader, nonce)
+	d.PanicIfError(err)
+	dst = append(dst, nonce...)
+	return aead.Seal(dst, nonce, plaintext, additionalData)
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < sealOverhead {
+		return nil, errDecryptionFailed
+	}
+	plaintext, err := aead.Open(nil, sealed[:gcmNonceSize], sealed[gcmNonceSize:], additionalData)
+	if err != nil {
+		return nil, errDecryptionFailed
+	}
+	return plaintext, nil
+}
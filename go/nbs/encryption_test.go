@@ -0,0 +1,162 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package nbs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeTestMasterKey(t *testing.T) *MasterKey {
+	key := make([]byte, masterKeySize)
+	_, err := rand.Read(key)
+	assert.NoError(t, err)
+	mk, err := NewMasterKey(key)
+	assert.NoError(t, err)
+	return mk
+}
+
+func TestReadMasterKeyFile(t *testing.T) {
+	assert := assert.New(t)
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+
+	key := make([]byte, masterKeySize)
+	_, err := rand.Read(key)
+	assert.NoError(err)
+
+	raw := filepath.Join(dir, "raw")
+	assert.NoError(ioutil.WriteFile(raw, key, 0600))
+	hexed := filepath.Join(dir, "hex")
+	assert.NoError(ioutil.WriteFile(hexed, []byte(hex.EncodeToString(key)+"\n"), 0600))
+	short := filepath.Join(dir, "short")
+	assert.NoError(ioutil.WriteFile(short, key[1:], 0600))
+
+	mk1, err := ReadMasterKeyFile(raw)
+	assert.NoError(err)
+	mk2, err := ReadMasterKeyFile(hexed)
+	assert.NoError(err)
+
+	// Both keys must be able to unwrap a data key wrapped by the other.
+	_, wrapped := mk1.newDataKey()
+	assert.NotPanics(func() { mk2.unwrap(wrapped) })
+
+	_, err = ReadMasterKeyFile(short)
+	assert.Error(err)
+	_, err = ReadMasterKeyFile(filepath.Join(dir, "missing"))
+	assert.Error(err)
+}
+
+func TestEncryptedTable(t *testing.T) {
+	assert := assert.New(t)
+	mk := makeTestMasterKey(t)
+
+	mt := newMemTable(testMemTableSize)
+	for _, c := range testChunks {
+		assert.True(mt.addChunk(computeAddr(c), c))
+	}
+	_, data, count := mt.writeEncrypted(nil, mk, &Stats{})
+	assert.EqualValues(len(testChunks), count)
+	for _, c := range testChunks {
+		assert.False(bytes.Contains(data, c))
+	}
+
+	index := parseTableIndex(data)
+	assert.True(index.encrypted())
+	assert.EqualValues(len(testChunks), index.chunkCount)
+
+	tr := newEncryptedTableReader(index, tableReaderAtFromBytes(data), fileBlockSize, mk)
+	assertChunksInReader(testChunks, tr, assert)
+	for _, c := range testChunks {
+		assert.Equal(c, tr.get(computeAddr(c), &Stats{}))
+	}
+
+	assert.Panics(func() { newEncryptedTableReader(index, tableReaderAtFromBytes(data), fileBlockSize, nil) })
+	assert.Panics(func() {
+		newEncryptedTableReader(index, tableReaderAtFromBytes(data), fileBlockSize, makeTestMasterKey(t))
+	})
+}
+
+func TestPlaintextTableReadableWithKey(t *testing.T) {
+	assert := assert.New(t)
+	data, _ := buildTable(testChunks)
+
+	index := parseTableIndex(data)
+	assert.False(index.encrypted())
+	tr := newEncryptedTableReader(index, tableReaderAtFromBytes(data), fileBlockSize, makeTestMasterKey(t))
+	for _, c := range testChunks {
+		assert.Equal(c, tr.get(computeAddr(c), &Stats{}))
+	}
+}
+
+func TestEncryptedFSTablePersisterConjoinAll(t *testing.T) {
+	assert := assert.New(t)
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+	fc := newFDCache(defaultMaxTables)
+	defer fc.Drop()
+	mk := makeTestMasterKey(t)
+	fts := newEncryptedFSTablePersister(dir, fc, nil, mk)
+
+	sources := make(chunkSources, len(testChunks))
+	for i, c := range testChunks {
+		src, err := persistTableData(fts, c)
+		assert.NoError(err)
+		sources[i] = src
+	}
+	// A plaintext table written before encryption was turned on
+	name, err := writeTableData(dir, []byte("plaintext"))
+	assert.NoError(err)
+	sources = append(sources, fts.Open(name, 1, &Stats{}))
+
+	src := fts.ConjoinAll(sources, &Stats{})
+	assert.EqualValues(len(testChunks)+1, src.count())
+	assert.True(src.index().encrypted())
+	for _, c := range append(testChunks, []byte("plaintext")) {
+		assert.Equal(c, src.get(computeAddr(c), &Stats{}))
+	}
+}
+
+func TestEncryptedLocalStore(t *testing.T) {
+	assert := assert.New(t)
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+	mk := makeTestMasterKey(t)
+
+	input := []byte("some very secret chunk data")
+	c := chunks.NewChunk(input)
+	store := NewEncryptedLocalStore(dir, testMemTableSize, mk)
+	store.Put(c)
+	assert.True(store.Commit(c.Hash(), store.Root()))
+	assert.NoError(store.Close())
+
+	manifest, err := ioutil.ReadFile(filepath.Join(dir, manifestFileName))
+	assert.NoError(err)
+	assert.True(isEncryptedManifest(manifest))
+	assert.False(bytes.Contains(manifest, []byte(c.Hash().String())))
+
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(err)
+	for _, fi := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, fi.Name()))
+		assert.NoError(err)
+		assert.False(bytes.Contains(data, input))
+	}
+
+	reopened := NewEncryptedLocalStore(dir, testMemTableSize, mk)
+	assert.Equal(c.Hash(), reopened.Root())
+	assertInputInStore(input, c.Hash(), reopened, assert)
+
+	assert.Panics(func() { NewLocalStore(dir, testMemTableSize) })
+	assert.Panics(func() { NewEncryptedLocalStore(dir, testMemTableSize, makeTestMasterKey(t)) })
+}
@@ -129,14 +129,14 @@ func (lsf *LocalStoreFactory) CreateStore(ns string) chunks.ChunkStore {
 	path := path.Join(lsf.dir, ns)
 	d.PanicIfError(os.MkdirAll(path, 0777))
 
-	mm := manifestManager{fileManifest{path}, lsf.manifestCache, lsf.manifestLocks}
+	mm := manifestManager{fileManifest{dir: path}, lsf.manifestCache, lsf.manifestLocks}
 	p := newFSTablePersister(path, lsf.fc, lsf.indexCache)
 	return newNomsBlockStore(mm, p, lsf.conjoiner, defaultMemTableSize)
 }
 
 func (lsf *LocalStoreFactory) CreateStoreFromCache(ns string) chunks.ChunkStore {
 	path := path.Join(lsf.dir, ns)
-	mm := manifestManager{fileManifest{path}, lsf.manifestCache, lsf.manifestLocks}
+	mm := manifestManager{fileManifest{dir: path}, lsf.manifestCache, lsf.manifestLocks}
 
 	contents, _, present := lsf.manifestCache.Get(mm.Name())
 	if present {
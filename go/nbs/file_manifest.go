@@ -5,6 +5,7 @@
 package nbs
 
 import (
+	"bytes"
 	"io"
 	"io/ioutil"
 	"os"
@@ -29,8 +30,14 @@ const (
 //
 // |-- String --|-- String --|-------- String --------|-------- String --------|-- String --|- String --|...|-- String --|- String --|
 // | nbs version:Noms version:Base32-encoded lock hash:Base32-encoded root hash:table 1 hash:table 1 cnt:...:table N hash:table N cnt|
+//
+// If |mk| is non-nil, the manifest is written sealed by |mk|, as
+// "nbs-encrypted:" followed by the Base64-encoded nonce and ciphertext of the
+// format above. Plaintext manifests can still be read, so that existing
+// stores can be encrypted going forward.
 type fileManifest struct {
 	dir string
+	mk  *MasterKey
 }
 
 func (fm fileManifest) Name() string {
@@ -63,7 +70,7 @@ func (fm fileManifest) ParseIfExists(stats *Stats, readHook func()) (exists bool
 		if f != nil {
 			defer checkClose(f)
 			exists = true
-			contents = parseManifest(f)
+			contents = fm.parse(f)
 		}
 	}
 	return
@@ -79,10 +86,25 @@ func openIfExists(path string) *os.File {
 	return f
 }
 
-func parseManifest(r io.Reader) manifestContents {
+func (fm fileManifest) parse(r io.Reader) manifestContents {
 	manifest, err := ioutil.ReadAll(r)
 	d.PanicIfError(err)
+	return decodeManifest(manifest, fm.mk)
+}
+
+// decodeManifest parses |manifest|, first opening it with |mk| if it's
+// encrypted.
+func decodeManifest(manifest []byte, mk *MasterKey) manifestContents {
+	if isEncryptedManifest(manifest) {
+		if mk == nil {
+			d.PanicIfError(ErrMasterKeyRequired)
+		}
+		manifest = mk.openManifest(manifest)
+	}
+	return parseManifest(manifest)
+}
 
+func parseManifest(manifest []byte) manifestContents {
 	slices := strings.Split(string(manifest), ":")
 	if len(slices) < 4 || len(slices)%2 == 1 {
 		d.Chk.Fail("Malformed manifest: " + string(manifest))
@@ -107,7 +129,7 @@ func (fm fileManifest) Update(lastLock addr, newContents manifestContents, stats
 		temp, err := ioutil.TempFile(fm.dir, "nbs_manifest_")
 		d.PanicIfError(err)
 		defer checkClose(temp)
-		writeManifest(temp, newContents)
+		fm.write(temp, newContents)
 		return temp.Name()
 	}()
 	defer os.Remove(tempManifestPath) // If we rename below, this will be a no-op
@@ -126,7 +148,7 @@ func (fm fileManifest) Update(lastLock addr, newContents manifestContents, stats
 		if f := openIfExists(manifestPath); f != nil {
 			defer checkClose(f)
 
-			upstream := parseManifest(f)
+			upstream := fm.parse(f)
 			d.PanicIfFalse(constants.NomsVersion == upstream.vers)
 			return upstream
 		}
@@ -142,6 +164,22 @@ func (fm fileManifest) Update(lastLock addr, newContents manifestContents, stats
 	return newContents
 }
 
+func (fm fileManifest) write(temp io.Writer, contents manifestContents) {
+	_, err := temp.Write(encodeManifest(contents, fm.mk))
+	d.PanicIfError(err)
+}
+
+// encodeManifest formats |contents|, sealing the result with |mk| if it's
+// non-nil.
+func encodeManifest(contents manifestContents, mk *MasterKey) []byte {
+	buff := &bytes.Buffer{}
+	writeManifest(buff, contents)
+	if mk == nil {
+		return buff.Bytes()
+	}
+	return mk.sealManifest(buff.Bytes())
+}
+
 func writeManifest(temp io.Writer, contents manifestContents) {
 	strs := make([]string, 2*len(contents.specs)+4)
 	strs[0], strs[1], strs[2], strs[3] = StorageVersion, contents.vers, contents.lock.String(), contents.root.String()
@@ -108,7 +108,7 @@ func TestFileManifestUpdateEmpty(t *testing.T) {
 	assert.True(upstream.root.IsEmpty())
 	assert.Empty(upstream.specs)
 
-	fm2 := fileManifest{fm.dir} // Open existent, but empty manifest
+	fm2 := fileManifest{dir: fm.dir} // Open existent, but empty manifest
 	exists, upstream := fm2.ParseIfExists(stats, nil)
 	assert.True(exists)
 	assert.Equal(l, upstream.lock)
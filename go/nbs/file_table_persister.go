@@ -17,21 +17,32 @@ import (
 const tempTablePrefix = "nbs_table_"
 
 func newFSTablePersister(dir string, fc *fdCache, indexCache *indexCache) tablePersister {
+	return newEncryptedFSTablePersister(dir, fc, indexCache, nil)
+}
+
+// newEncryptedFSTablePersister returns a tablePersister that encrypts every
+// table it writes using |mk|. If |mk| is nil, tables are written in plaintext.
+func newEncryptedFSTablePersister(dir string, fc *fdCache, indexCache *indexCache, mk *MasterKey) tablePersister {
 	d.PanicIfTrue(fc == nil)
-	return &fsTablePersister{dir, fc, indexCache}
+	return &fsTablePersister{dir, fc, indexCache, mk}
 }
 
 type fsTablePersister struct {
 	dir        string
 	fc         *fdCache
 	indexCache *indexCache
+	mk         *MasterKey
 }
 
 func (ftp *fsTablePersister) Open(name addr, chunkCount uint32, stats *Stats) chunkSource {
-	return newMmapTableReader(ftp.dir, name, chunkCount, ftp.indexCache, ftp.fc)
+	return newMmapTableReader(ftp.dir, name, chunkCount, ftp.indexCache, ftp.fc, ftp.mk)
 }
 
 func (ftp *fsTablePersister) Persist(mt *memTable, haver chunkReader, stats *Stats) chunkSource {
+	if ftp.mk != nil {
+		name, data, chunkCount := mt.writeEncrypted(haver, ftp.mk, stats)
+		return ftp.persistTable(name, data, chunkCount, stats)
+	}
 	name, data, chunkCount := mt.write(haver, stats)
 	return ftp.persistTable(name, data, chunkCount, stats)
 }
@@ -59,6 +70,10 @@ func (ftp *fsTablePersister) persistTable(name addr, data []byte, chunkCount uin
 }
 
 func (ftp *fsTablePersister) ConjoinAll(sources chunkSources, stats *Stats) chunkSource {
+	if ftp.mk != nil {
+		name, data, chunkCount := conjoinSealed(sources, ftp.mk, stats)
+		return ftp.persistTable(name, data, chunkCount, stats)
+	}
 	plan := planConjoin(sources, stats)
 
 	if plan.chunkCount == 0 {
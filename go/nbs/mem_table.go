@@ -100,9 +100,17 @@ func (mt *memTable) extract(chunks chan<- extractRecord) {
 
 func (mt *memTable) write(haver chunkReader, stats *Stats) (name addr, data []byte, count uint32) {
 	maxSize := maxTableSize(uint64(len(mt.order)), mt.totalData)
-	buff := make([]byte, maxSize)
-	tw := newTableWriter(buff, mt.snapper)
+	return mt.writeTable(newTableWriter(make([]byte, maxSize), mt.snapper), haver, stats)
+}
+
+// writeEncrypted is like write, but seals the table it writes using a new data
+// key wrapped by |mk|.
+func (mt *memTable) writeEncrypted(haver chunkReader, mk *MasterKey, stats *Stats) (name addr, data []byte, count uint32) {
+	maxSize := maxEncryptedTableSize(uint64(len(mt.order)), mt.totalData)
+	return mt.writeTable(newEncryptingTableWriter(make([]byte, maxSize), mt.snapper, mk), haver, stats)
+}
 
+func (mt *memTable) writeTable(tw *tableWriter, haver chunkReader, stats *Stats) (name addr, data []byte, count uint32) {
 	if haver != nil {
 		sort.Sort(hasRecordByPrefix(mt.order)) // hasMany() requires addresses to be sorted.
 		haver.hasMany(mt.order)
@@ -125,5 +133,5 @@ func (mt *memTable) write(haver chunkReader, stats *Stats) (name addr, data []by
 		stats.ChunksPerPersist.Sample(uint64(count))
 	}
 
-	return name, buff[:tableSize], count
+	return name, tw.buff[:tableSize], count
 }
@@ -38,7 +38,7 @@ func init() {
 	}
 }
 
-func newMmapTableReader(dir string, h addr, chunkCount uint32, indexCache *indexCache, fc *fdCache) chunkSource {
+func newMmapTableReader(dir string, h addr, chunkCount uint32, indexCache *indexCache, fc *fdCache, mk *MasterKey) chunkSource {
 	path := filepath.Join(dir, h.String())
 
 	var index tableIndex
@@ -57,8 +57,11 @@ func newMmapTableReader(dir string, h addr, chunkCount uint32, indexCache *index
 		fi, err := f.Stat()
 		d.PanicIfError(err)
 		d.PanicIfTrue(fi.Size() < 0)
-		// index. Mmap won't take an offset that's not page-aligned, so find the nearest page boundary preceding the index.
-		indexOffset := fi.Size() - int64(footerSize) - int64(indexSize(chunkCount))
+		// index. Mmap won't take an offset that's not page-aligned, so find the nearest page boundary preceding the index. Until the footer is parsed, we don't know whether the table is encrypted, so leave room for the larger, encrypted footer.
+		indexOffset := fi.Size() - int64(encryptedFooterSize) - int64(indexSize(chunkCount))
+		if indexOffset < 0 {
+			indexOffset = 0
+		}
 		aligned := indexOffset / pageSize * pageSize // Thanks, integer arithmetic!
 		d.PanicIfTrue(fi.Size()-aligned > maxInt)
 		buff, err := unix.Mmap(int(f.Fd()), aligned, int(fi.Size()-aligned), unix.PROT_READ, unix.MAP_SHARED)
@@ -74,7 +77,7 @@ func newMmapTableReader(dir string, h addr, chunkCount uint32, indexCache *index
 
 	d.PanicIfFalse(chunkCount == index.chunkCount)
 	return &mmapTableReader{
-		newTableReader(index, &cacheReaderAt{path, fc}, fileBlockSize),
+		newEncryptedTableReader(index, &cacheReaderAt{path, fc}, fileBlockSize, mk),
 		fc,
 		h,
 	}
@@ -0,0 +1,332 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package nbs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ObjectStore is a minimal interface to a blob store. NBS can keep both its
+// tables and its manifest in any ObjectStore (see NewObjectBackedStore), so
+// supporting a new storage service only requires implementing this
+// interface. Implementations must be goroutine-safe.
+type ObjectStore interface {
+	// Put durably stores |data| as the object named |key|, replacing any
+	// existing object with that name.
+	Put(key string, data []byte) error
+
+	// GetRange returns up to |length| bytes of the object named |key|,
+	// starting at |off|. A negative |off| is relative to the end of the
+	// object, and a negative |length| reads to the end of the object. Ranges
+	// that extend past either end of the object are truncated. Returns
+	// ErrObjectNotFound if there's no such object.
+	GetRange(key string, off, length int64) ([]byte, error)
+
+	// List returns the names of all objects whose names begin with |prefix|,
+	// in lexicographic order.
+	List(prefix string) ([]string, error)
+
+	// Delete removes the object named |key|, if it exists.
+	Delete(key string) error
+
+	// CompareAndSwap atomically replaces the contents of the object named
+	// |key| with |data|, but only if its current contents are exactly
+	// |expected|. A nil |expected| means that the object must not exist.
+	// Returns true iff the swap happened. This is the only primitive NBS
+	// needs in order to update its manifest safely from multiple processes.
+	CompareAndSwap(key string, expected, data []byte) (bool, error)
+}
+
+// ErrObjectNotFound is returned by ObjectStore.GetRange when the requested
+// object doesn't exist.
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectStoreOpener opens the ObjectStore found at |location|. The format of
+// |location| is up to each kind of ObjectStore.
+type ObjectStoreOpener func(location string) (ObjectStore, error)
+
+var (
+	objectStoreKindsMu = &sync.RWMutex{}
+	objectStoreKinds   = map[string]ObjectStoreOpener{}
+)
+
+func init() {
+	RegisterObjectStoreKind("local", NewLocalObjectStore)
+	RegisterObjectStoreKind("mem", openNamedMemoryObjectStore)
+}
+
+// RegisterObjectStoreKind makes a kind of ObjectStore available to
+// OpenObjectStore, and thus to the "objectstore" database spec protocol.
+// Registering a kind twice replaces the earlier registration.
+func RegisterObjectStoreKind(kind string, open ObjectStoreOpener) {
+	objectStoreKindsMu.Lock()
+	defer objectStoreKindsMu.Unlock()
+	objectStoreKinds[kind] = open
+}
+
+// IsObjectStoreKind returns true if |kind| has been registered with
+// RegisterObjectStoreKind.
+func IsObjectStoreKind(kind string) bool {
+	objectStoreKindsMu.RLock()
+	defer objectStoreKindsMu.RUnlock()
+	_, ok := objectStoreKinds[kind]
+	return ok
+}
+
+// OpenObjectStore opens the ObjectStore of the given |kind| at |location|.
+// The kinds "local", a directory on the local filesystem, and "mem", an
+// in-memory store shared by everything in the process that opens the same
+// |location|, are always available.
+func OpenObjectStore(kind, location string) (ObjectStore, error) {
+	objectStoreKindsMu.RLock()
+	open, ok := objectStoreKinds[kind]
+	objectStoreKindsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Unknown object store kind %s", kind)
+	}
+	return open(location)
+}
+
+// getRange returns the sub-slice of |data| described by |off| and |length|,
+// according to the semantics of ObjectStore.GetRange.
+func getRange(data []byte, off, length int64) []byte {
+	size := int64(len(data))
+	if off < 0 {
+		off += size
+		if off < 0 {
+			off = 0
+		}
+	}
+	if off > size {
+		off = size
+	}
+	end := size
+	if length >= 0 && off+length < size {
+		end = off + length
+	}
+	return data[off:end]
+}
+
+// NewMemoryObjectStore returns an empty ObjectStore that keeps all of its
+// objects in memory.
+func NewMemoryObjectStore() ObjectStore {
+	return &memoryObjectStore{objects: map[string][]byte{}}
+}
+
+var (
+	namedMemoryObjectStoresMu = &sync.Mutex{}
+	namedMemoryObjectStores   = map[string]ObjectStore{}
+)
+
+func openNamedMemoryObjectStore(name string) (ObjectStore, error) {
+	namedMemoryObjectStoresMu.Lock()
+	defer namedMemoryObjectStoresMu.Unlock()
+	if store, ok := namedMemoryObjectStores[name]; ok {
+		return store, nil
+	}
+	store := NewMemoryObjectStore()
+	namedMemoryObjectStores[name] = store
+	return store, nil
+}
+
+type memoryObjectStore struct {
+	mu      sync.RWMutex
+	objects map[string][]byte
+}
+
+func (ms *memoryObjectStore) Put(key string, data []byte) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.objects[key] = append([]byte(nil), data...)
+	return nil
+}
+
+func (ms *memoryObjectStore) GetRange(key string, off, length int64) ([]byte, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	data, ok := ms.objects[key]
+	if !ok {
+		return nil, ErrObjectNotFound
+	}
+	return append([]byte(nil), getRange(data, off, length)...), nil
+}
+
+func (ms *memoryObjectStore) List(prefix string) ([]string, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	keys := []string{}
+	for key := range ms.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (ms *memoryObjectStore) Delete(key string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.objects, key)
+	return nil
+}
+
+func (ms *memoryObjectStore) CompareAndSwap(key string, expected, data []byte) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	current, ok := ms.objects[key]
+	if ok != (expected != nil) || !bytes.Equal(current, expected) {
+		return false, nil
+	}
+	ms.objects[key] = append([]byte(nil), data...)
+	return true, nil
+}
+
+const (
+	localObjectStoreLockFile   = ".lock"
+	localObjectStoreTempPrefix = ".tmp_"
+)
+
+// NewLocalObjectStore returns an ObjectStore that keeps each object as a file
+// in |dir|, which must already exist. Object names must be valid file names,
+// and must not begin with ".".
+func NewLocalObjectStore(dir string) (ObjectStore, error) {
+	if err := checkDir(dir); err != nil {
+		return nil, err
+	}
+	return localObjectStore{dir}, nil
+}
+
+type localObjectStore struct {
+	dir string
+}
+
+func (ls localObjectStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, ".") || strings.ContainsRune(key, filepath.Separator) || strings.ContainsRune(key, '/') {
+		return "", fmt.Errorf("Invalid object name %q", key)
+	}
+	return filepath.Join(ls.dir, key), nil
+}
+
+func (ls localObjectStore) Put(key string, data []byte) error {
+	path, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+	return ls.writeAndRename(path, data)
+}
+
+func (ls localObjectStore) writeAndRename(path string, data []byte) error {
+	temp, err := ioutil.TempFile(ls.dir, localObjectStoreTempPrefix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name()) // If we rename below, this will be a no-op
+	_, err = temp.Write(data)
+	if cerr := temp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+func (ls localObjectStore) GetRange(key string, off, length int64) ([]byte, error) {
+	path, err := ls.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, ErrObjectNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+	if off < 0 {
+		off += size
+		if off < 0 {
+			off = 0
+		}
+	}
+	if off > size {
+		off = size
+	}
+	if length < 0 || off+length > size {
+		length = size - off
+	}
+	buff := make([]byte, length)
+	n, err := f.ReadAt(buff, off)
+	if err == io.EOF && int64(n) == length {
+		err = nil
+	}
+	return buff[:n], err
+}
+
+func (ls localObjectStore) List(prefix string) ([]string, error) {
+	infos, err := ioutil.ReadDir(ls.dir)
+	if err != nil {
+		return nil, err
+	}
+	keys := []string{}
+	for _, fi := range infos {
+		name := fi.Name()
+		if !fi.IsDir() && !strings.HasPrefix(name, ".") && strings.HasPrefix(name, prefix) {
+			keys = append(keys, name)
+		}
+	}
+	return keys, nil
+}
+
+func (ls localObjectStore) Delete(key string) error {
+	path, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (ls localObjectStore) CompareAndSwap(key string, expected, data []byte) (bool, error) {
+	path, err := ls.path(key)
+	if err != nil {
+		return false, err
+	}
+
+	// Take the store-wide lock, so that only one process at a time can be comparing and swapping.
+	defer checkClose(flock(filepath.Join(ls.dir, localObjectStoreLockFile))) // closing releases the lock
+
+	current, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		current, err = nil, nil
+	} else if err == nil && current == nil {
+		current = []byte{}
+	}
+	if err != nil {
+		return false, err
+	}
+	if (current == nil) != (expected == nil) || !bytes.Equal(current, expected) {
+		return false, nil
+	}
+	return true, ls.writeAndRename(path, data)
+}
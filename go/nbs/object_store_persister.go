@@ -0,0 +1,229 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package nbs
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/attic-labs/noms/go/constants"
+	"github.com/attic-labs/noms/go/d"
+)
+
+const (
+	objectStoreBlockSize = (1 << 10) * 512 // 512K
+
+	objectStoreManifestKey = "manifest"
+)
+
+// NewObjectBackedStore returns a NomsBlockStore that keeps both its tables
+// and its manifest in |objs|. Tables are stored as objects named by the
+// Base32 encoding of their addresses, and the manifest as an object named
+// "manifest". |name| is used to identify the store in the process-wide
+// manifest cache, so it must be unique to |objs|.
+func NewObjectBackedStore(name string, objs ObjectStore, memTableSize uint64) *NomsBlockStore {
+	return NewEncryptedObjectBackedStore(name, objs, memTableSize, nil)
+}
+
+// NewEncryptedObjectBackedStore is like NewObjectBackedStore, but encrypts
+// the tables and manifest it writes using |mk|. If |mk| is nil, the store is
+// not encrypted.
+func NewEncryptedObjectBackedStore(name string, objs ObjectStore, memTableSize uint64, mk *MasterKey) *NomsBlockStore {
+	cacheOnce.Do(makeGlobalCaches)
+	mm := makeManifestManager(objectStoreManifest{name, objs, mk})
+	p := newObjectStoreTablePersister(objs, globalIndexCache, mk)
+	return newNomsBlockStore(mm, p, inlineConjoiner{defaultMaxTables}, memTableSize)
+}
+
+func newObjectStoreTablePersister(objs ObjectStore, indexCache *indexCache, mk *MasterKey) tablePersister {
+	d.PanicIfTrue(objs == nil)
+	return &objectStoreTablePersister{objs, indexCache, mk}
+}
+
+// objectStoreTablePersister stores each table as a single object in an
+// ObjectStore.
+type objectStoreTablePersister struct {
+	objs       ObjectStore
+	indexCache *indexCache
+	mk         *MasterKey
+}
+
+func (otp *objectStoreTablePersister) Open(name addr, chunkCount uint32, stats *Stats) chunkSource {
+	tra := &objectStoreReaderAt{otp.objs, name.String()}
+	if otp.indexCache != nil {
+		otp.indexCache.lockEntry(name)
+		defer otp.indexCache.unlockEntry(name)
+		if index, found := otp.indexCache.get(name); found {
+			return &objectStoreChunkSource{newEncryptedTableReader(index, tra, objectStoreBlockSize, otp.mk), name}
+		}
+	}
+
+	// Until the footer is parsed, we don't know whether the table is encrypted, so leave room for the larger, encrypted footer.
+	t1 := time.Now()
+	buff, err := otp.objs.GetRange(name.String(), -int64(indexSize(chunkCount)+encryptedFooterSize), -1)
+	d.PanicIfError(err)
+	stats.IndexBytesPerRead.Sample(uint64(len(buff)))
+	stats.IndexReadLatency.SampleTimeSince(t1)
+
+	index := parseTableIndex(buff)
+	d.PanicIfFalse(chunkCount == index.chunkCount)
+	if otp.indexCache != nil {
+		otp.indexCache.put(name, index)
+	}
+	return &objectStoreChunkSource{newEncryptedTableReader(index, tra, objectStoreBlockSize, otp.mk), name}
+}
+
+func (otp *objectStoreTablePersister) Persist(mt *memTable, haver chunkReader, stats *Stats) chunkSource {
+	if otp.mk != nil {
+		name, data, chunkCount := mt.writeEncrypted(haver, otp.mk, stats)
+		return otp.persistTable(name, data, chunkCount, stats)
+	}
+	name, data, chunkCount := mt.write(haver, stats)
+	return otp.persistTable(name, data, chunkCount, stats)
+}
+
+func (otp *objectStoreTablePersister) persistTable(name addr, data []byte, chunkCount uint32, stats *Stats) chunkSource {
+	if chunkCount == 0 {
+		return emptyChunkSource{}
+	}
+	d.PanicIfError(otp.objs.Put(name.String(), data))
+	if otp.indexCache != nil {
+		otp.indexCache.lockEntry(name)
+		otp.indexCache.put(name, parseTableIndex(data))
+		otp.indexCache.unlockEntry(name)
+	}
+	return otp.Open(name, chunkCount, stats)
+}
+
+func (otp *objectStoreTablePersister) ConjoinAll(sources chunkSources, stats *Stats) chunkSource {
+	if otp.mk != nil {
+		name, data, chunkCount := conjoinSealed(sources, otp.mk, stats)
+		return otp.persistTable(name, data, chunkCount, stats)
+	}
+	plan := planConjoin(sources, stats)
+
+	if plan.chunkCount == 0 {
+		return emptyChunkSource{}
+	}
+
+	name := nameFromSuffixes(plan.suffixes())
+	buff := bytes.NewBuffer(make([]byte, 0, plan.totalCompressedData+uint64(len(plan.mergedIndex))))
+	for _, sws := range plan.sources {
+		n, err := io.CopyN(buff, sws.source.reader(), int64(sws.dataLen))
+		d.PanicIfError(err)
+		d.PanicIfFalse(uint64(n) == sws.dataLen)
+	}
+	buff.Write(plan.mergedIndex)
+
+	return otp.persistTable(name, buff.Bytes(), plan.chunkCount, stats)
+}
+
+type objectStoreChunkSource struct {
+	tableReader
+	name addr
+}
+
+func (ocs *objectStoreChunkSource) hash() addr {
+	return ocs.name
+}
+
+// objectStoreReaderAt reads ranges of a single table object.
+type objectStoreReaderAt struct {
+	objs ObjectStore
+	key  string
+}
+
+func (ora *objectStoreReaderAt) ReadAtWithStats(p []byte, off int64, stats *Stats) (n int, err error) {
+	t1 := time.Now()
+	defer func() {
+		if n > 0 {
+			stats.ObjectBytesPerRead.Sample(uint64(n))
+		}
+		stats.ObjectReadLatency.SampleTimeSince(t1)
+	}()
+
+	data, err := ora.objs.GetRange(ora.key, off, int64(len(p)))
+	if err != nil {
+		return 0, err
+	}
+	n = copy(p, data)
+	if n < len(p) {
+		err = io.ErrUnexpectedEOF
+	}
+	return
+}
+
+// objectStoreManifest keeps a NomsBlockStore manifest in an ObjectStore, in
+// the same format as fileManifest. Updates are made safe against concurrent
+// writers using ObjectStore.CompareAndSwap.
+type objectStoreManifest struct {
+	name string
+	objs ObjectStore
+	mk   *MasterKey
+}
+
+func (om objectStoreManifest) Name() string {
+	return om.name
+}
+
+func (om objectStoreManifest) ParseIfExists(stats *Stats, readHook func()) (exists bool, contents manifestContents) {
+	t1 := time.Now()
+	defer func() { stats.ReadManifestLatency.SampleTimeSince(t1) }()
+
+	if readHook != nil {
+		readHook()
+	}
+	if data := om.read(); data != nil {
+		return true, decodeManifest(data, om.mk)
+	}
+	return false, manifestContents{}
+}
+
+// read returns the raw contents of the manifest object, or nil if there is no
+// manifest yet.
+func (om objectStoreManifest) read() []byte {
+	data, err := om.objs.GetRange(objectStoreManifestKey, 0, -1)
+	if err == ErrObjectNotFound {
+		return nil
+	}
+	d.PanicIfError(err)
+	if data == nil {
+		data = []byte{}
+	}
+	return data
+}
+
+func (om objectStoreManifest) Update(lastLock addr, newContents manifestContents, stats *Stats, writeHook func()) manifestContents {
+	t1 := time.Now()
+	defer func() { stats.WriteManifestLatency.SampleTimeSince(t1) }()
+
+	encoded := encodeManifest(newContents, om.mk)
+	for {
+		// writeHook is for testing, allowing other code to slip in and try to do stuff between our read and swap.
+		if writeHook != nil {
+			writeHook()
+		}
+
+		current := om.read()
+		upstream := manifestContents{}
+		if current != nil {
+			upstream = decodeManifest(current, om.mk)
+			d.PanicIfFalse(constants.NomsVersion == upstream.vers)
+		} else {
+			d.Chk.True(lastLock == addr{})
+		}
+
+		if lastLock != upstream.lock {
+			return upstream
+		}
+		swapped, err := om.objs.CompareAndSwap(objectStoreManifestKey, current, encoded)
+		d.PanicIfError(err)
+		if swapped {
+			return newContents
+		}
+		// Someone else changed the manifest between our read and swap. Go around again to find out who.
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package nbs
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/constants"
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObjectStoreTablePersisterPersist(t *testing.T) {
+	assert := assert.New(t)
+	objs := NewMemoryObjectStore()
+	otp := newObjectStoreTablePersister(objs, nil, nil)
+
+	src, err := persistTableData(otp, testChunks...)
+	assert.NoError(err)
+	if assert.True(src.count() > 0) {
+		buff, err := objs.GetRange(src.hash().String(), 0, -1)
+		assert.NoError(err)
+		tr := newTableReader(parseTableIndex(buff), tableReaderAtFromBytes(buff), fileBlockSize)
+		assertChunksInReader(testChunks, tr, assert)
+	}
+
+	stats := &Stats{}
+	reopened := otp.Open(src.hash(), src.count(), stats)
+	for _, c := range testChunks {
+		assert.Equal(c, reopened.get(computeAddr(c), stats))
+	}
+	assert.True(stats.ObjectReadLatency.Samples() > 0)
+}
+
+func TestObjectStoreReaderAtErrors(t *testing.T) {
+	assert := assert.New(t)
+	objs := NewMemoryObjectStore()
+	assert.NoError(objs.Put("obj", []byte("data")))
+
+	stats := &Stats{}
+	ora := &objectStoreReaderAt{objs, "missing"}
+	n, err := ora.ReadAtWithStats(make([]byte, 4), 0, stats)
+	assert.Equal(0, n)
+	assert.Equal(ErrObjectNotFound, err)
+
+	ora = &objectStoreReaderAt{objs, "obj"}
+	n, err = ora.ReadAtWithStats(make([]byte, 4), 4, stats)
+	assert.Equal(0, n)
+	assert.Equal(io.ErrUnexpectedEOF, err)
+	assert.Equal(uint64(0), stats.ObjectBytesPerRead.Samples())
+}
+
+func TestObjectStoreTablePersisterPersistNoData(t *testing.T) {
+	assert := assert.New(t)
+	mt := newMemTable(testMemTableSize)
+	existingTable := newMemTable(testMemTableSize)
+
+	for _, c := range testChunks {
+		assert.True(mt.addChunk(computeAddr(c), c))
+		assert.True(existingTable.addChunk(computeAddr(c), c))
+	}
+
+	objs := NewMemoryObjectStore()
+	src := newObjectStoreTablePersister(objs, nil, nil).Persist(mt, existingTable, &Stats{})
+	assert.True(src.count() == 0)
+
+	keys, err := objs.List("")
+	assert.NoError(err)
+	assert.Empty(keys)
+}
+
+func TestObjectStoreTablePersisterConjoinAll(t *testing.T) {
+	assert := assert.New(t)
+	objs := NewMemoryObjectStore()
+	otp := newObjectStoreTablePersister(objs, newIndexCache(1024), nil)
+
+	sources := make(chunkSources, len(testChunks))
+	for i, c := range testChunks {
+		src, err := persistTableData(otp, c)
+		assert.NoError(err)
+		sources[i] = src
+	}
+
+	src := otp.ConjoinAll(sources, &Stats{})
+	if assert.True(src.count() > 0) {
+		buff, err := objs.GetRange(src.hash().String(), 0, -1)
+		assert.NoError(err)
+		tr := newTableReader(parseTableIndex(buff), tableReaderAtFromBytes(buff), fileBlockSize)
+		assertChunksInReader(testChunks, tr, assert)
+	}
+}
+
+func TestEncryptedObjectStoreTablePersisterConjoinAll(t *testing.T) {
+	assert := assert.New(t)
+	objs := NewMemoryObjectStore()
+	otp := newObjectStoreTablePersister(objs, nil, makeTestMasterKey(t))
+
+	sources := make(chunkSources, len(testChunks))
+	for i, c := range testChunks {
+		src, err := persistTableData(otp, c)
+		assert.NoError(err)
+		sources[i] = src
+	}
+
+	src := otp.ConjoinAll(sources, &Stats{})
+	assert.EqualValues(len(testChunks), src.count())
+	assert.True(src.index().encrypted())
+	for _, c := range testChunks {
+		assert.Equal(c, src.get(computeAddr(c), &Stats{}))
+	}
+}
+
+func TestObjectStoreManifestUpdate(t *testing.T) {
+	assert := assert.New(t)
+	objs := NewMemoryObjectStore()
+	om := objectStoreManifest{"test", objs, nil}
+	stats := &Stats{}
+
+	exists, _ := om.ParseIfExists(stats, nil)
+	assert.False(exists)
+
+	// First, test losing the race against another writer which sneaks in between our read and swap.
+	contents := manifestContents{
+		vers:  constants.NomsVersion,
+		lock:  computeAddr([]byte("locker")),
+		root:  hash.Of([]byte("new root")),
+		specs: []tableSpec{{computeAddr([]byte("a")), 3}},
+	}
+	jerk := manifestContents{vers: constants.NomsVersion, lock: computeAddr([]byte("jerk")), root: hash.Of([]byte("jerk root"))}
+	clobbered := false
+	upstream := om.Update(addr{}, contents, stats, func() {
+		if !clobbered {
+			clobbered = true
+			swapped, err := objs.CompareAndSwap(objectStoreManifestKey, nil, encodeManifest(jerk, nil))
+			assert.NoError(err)
+			assert.True(swapped)
+		}
+	})
+	assert.Equal(jerk.lock, upstream.lock)
+	assert.Equal(jerk.root, upstream.root)
+
+	// Now, with the right lock, the update should succeed.
+	upstream = om.Update(jerk.lock, contents, stats, nil)
+	assert.Equal(contents.lock, upstream.lock)
+	assert.Equal(contents.root, upstream.root)
+	assert.Equal(contents.specs, upstream.specs)
+
+	exists, upstream = objectStoreManifest{"test", objs, nil}.ParseIfExists(stats, nil)
+	assert.True(exists)
+	assert.Equal(contents.lock, upstream.lock)
+	assert.Equal(contents.root, upstream.root)
+	assert.Equal(contents.specs, upstream.specs)
+
+	// Updates must not clobber a store written by a different Noms version.
+	old := manifestContents{vers: "0", lock: computeAddr([]byte("old"))}
+	assert.NoError(objs.Put(objectStoreManifestKey, encodeManifest(old, nil)))
+	assert.Panics(func() { om.Update(old.lock, contents, stats, nil) })
+}
+
+func TestObjectBackedStore(t *testing.T) {
+	assert := assert.New(t)
+	objs := NewMemoryObjectStore()
+
+	input := []byte("abc")
+	c := chunks.NewChunk(input)
+	store := NewObjectBackedStore("TestObjectBackedStore", objs, testMemTableSize)
+	store.Put(c)
+	assert.True(store.Commit(c.Hash(), store.Root()))
+	assert.NoError(store.Close())
+
+	reopened := NewObjectBackedStore("TestObjectBackedStore", objs, testMemTableSize)
+	assert.Equal(c.Hash(), reopened.Root())
+	assertInputInStore(input, c.Hash(), reopened, assert)
+}
+
+func TestEncryptedObjectBackedStore(t *testing.T) {
+	assert := assert.New(t)
+	objs := NewMemoryObjectStore()
+	mk := makeTestMasterKey(t)
+
+	input := []byte("some very secret chunk data")
+	c := chunks.NewChunk(input)
+	store := NewEncryptedObjectBackedStore("TestEncryptedObjectBackedStore", objs, testMemTableSize, mk)
+	store.Put(c)
+	assert.True(store.Commit(c.Hash(), store.Root()))
+	assert.NoError(store.Close())
+
+	keys, err := objs.List("")
+	assert.NoError(err)
+	for _, key := range keys {
+		data, err := objs.GetRange(key, 0, -1)
+		assert.NoError(err)
+		assert.False(bytes.Contains(data, input))
+	}
+
+	reopened := NewEncryptedObjectBackedStore("TestEncryptedObjectBackedStore", objs, testMemTableSize, mk)
+	assert.Equal(c.Hash(), reopened.Root())
+	assertInputInStore(input, c.Hash(), reopened, assert)
+}
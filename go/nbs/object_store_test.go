@@ -0,0 +1,121 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package nbs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryObjectStore(t *testing.T) {
+	testObjectStore(t, NewMemoryObjectStore())
+}
+
+func TestLocalObjectStore(t *testing.T) {
+	dir := makeTempDir(t)
+	defer os.RemoveAll(dir)
+	objs, err := NewLocalObjectStore(dir)
+	assert.NoError(t, err)
+	testObjectStore(t, objs)
+
+	assert.Error(t, objs.Put("../escape", []byte("nope")))
+	assert.Error(t, objs.Put(".hidden", []byte("nope")))
+	_, err = NewLocalObjectStore(dir + "/missing")
+	assert.Error(t, err)
+}
+
+func testObjectStore(t *testing.T, objs ObjectStore) {
+	assert := assert.New(t)
+
+	_, err := objs.GetRange("a", 0, -1)
+	assert.Equal(ErrObjectNotFound, err)
+	assert.NoError(objs.Delete("a"))
+
+	assert.NoError(objs.Put("a", []byte("0123456789")))
+	assert.NoError(objs.Put("ab", []byte{}))
+	assert.NoError(objs.Put("b", []byte("b")))
+
+	get := func(key string, off, length int64) string {
+		data, err := objs.GetRange(key, off, length)
+		assert.NoError(err)
+		return string(data)
+	}
+	assert.Equal("0123456789", get("a", 0, -1))
+	assert.Equal("234", get("a", 2, 3))
+	assert.Equal("789", get("a", -3, -1))
+	assert.Equal("78", get("a", -3, 2))
+	assert.Equal("0123456789", get("a", -100, -1))
+	assert.Equal("89", get("a", 8, 100))
+	assert.Equal("", get("a", 100, 1))
+	assert.Equal("", get("ab", 0, -1))
+
+	keys, err := objs.List("a")
+	assert.NoError(err)
+	assert.Equal([]string{"a", "ab"}, keys)
+	keys, err = objs.List("")
+	assert.NoError(err)
+	assert.Equal([]string{"a", "ab", "b"}, keys)
+
+	assert.NoError(objs.Delete("ab"))
+	keys, err = objs.List("a")
+	assert.NoError(err)
+	assert.Equal([]string{"a"}, keys)
+
+	swapped, err := objs.CompareAndSwap("c", []byte("nope"), []byte("c1"))
+	assert.NoError(err)
+	assert.False(swapped)
+	swapped, err = objs.CompareAndSwap("c", nil, []byte("c1"))
+	assert.NoError(err)
+	assert.True(swapped)
+	swapped, err = objs.CompareAndSwap("c", nil, []byte("c2"))
+	assert.NoError(err)
+	assert.False(swapped)
+	swapped, err = objs.CompareAndSwap("c", []byte("c1"), []byte{})
+	assert.NoError(err)
+	assert.True(swapped)
+	swapped, err = objs.CompareAndSwap("c", nil, []byte("c3"))
+	assert.NoError(err)
+	assert.False(swapped, "An empty object still exists")
+	swapped, err = objs.CompareAndSwap("c", []byte{}, []byte("c3"))
+	assert.NoError(err)
+	assert.True(swapped)
+	assert.Equal("c3", get("c", 0, -1))
+}
+
+func TestOpenObjectStore(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(IsObjectStoreKind("local"))
+	assert.True(IsObjectStoreKind("mem"))
+	assert.False(IsObjectStoreKind("bogus"))
+	_, err := OpenObjectStore("bogus", "whatever")
+	assert.Error(err)
+
+	// Opening the same named in-memory store twice yields the same objects.
+	objs1, err := OpenObjectStore("mem", "TestOpenObjectStore")
+	assert.NoError(err)
+	objs2, err := OpenObjectStore("mem", "TestOpenObjectStore")
+	assert.NoError(err)
+	other, err := OpenObjectStore("mem", "TestOpenObjectStore-other")
+	assert.NoError(err)
+	assert.NoError(objs1.Put("key", []byte("value")))
+	data, err := objs2.GetRange("key", 0, -1)
+	assert.NoError(err)
+	assert.Equal("value", string(data))
+	_, err = other.GetRange("key", 0, -1)
+	assert.Equal(ErrObjectNotFound, err)
+
+	called := false
+	RegisterObjectStoreKind("test", func(location string) (ObjectStore, error) {
+		called = true
+		assert.Equal("somewhere", location)
+		return NewMemoryObjectStore(), nil
+	})
+	_, err = OpenObjectStore("test", "somewhere")
+	assert.NoError(err)
+	assert.True(called)
+}
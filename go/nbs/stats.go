@@ -32,6 +32,9 @@ type Stats struct {
 	DynamoReadLatency  metrics.Histogram
 	DynamoBytesPerRead metrics.Histogram
 
+	ObjectReadLatency  metrics.Histogram
+	ObjectBytesPerRead metrics.Histogram
+
 	HasLatency      metrics.Histogram
 	AddressesPerHas metrics.Histogram
 
@@ -68,6 +71,8 @@ func NewStats() *Stats {
 		MemBytesPerRead:                  metrics.NewByteHistogram(),
 		DynamoReadLatency:                metrics.NewTimeHistogram(),
 		DynamoBytesPerRead:               metrics.NewByteHistogram(),
+		ObjectReadLatency:                metrics.NewTimeHistogram(),
+		ObjectBytesPerRead:               metrics.NewByteHistogram(),
 		HasLatency:                       metrics.NewTimeHistogram(),
 		PutLatency:                       metrics.NewTimeHistogram(),
 		PersistLatency:                   metrics.NewTimeHistogram(),
@@ -103,6 +108,9 @@ func (s *Stats) Add(other Stats) {
 	s.DynamoReadLatency.Add(other.DynamoReadLatency)
 	s.DynamoBytesPerRead.Add(other.DynamoBytesPerRead)
 
+	s.ObjectReadLatency.Add(other.ObjectReadLatency)
+	s.ObjectBytesPerRead.Add(other.ObjectBytesPerRead)
+
 	s.HasLatency.Add(other.HasLatency)
 	s.AddressesPerHas.Add(other.AddressesPerHas)
 
@@ -147,6 +155,9 @@ func (s Stats) Delta(other Stats) Stats {
 		s.DynamoReadLatency.Delta(other.DynamoReadLatency),
 		s.DynamoBytesPerRead.Delta(other.DynamoBytesPerRead),
 
+		s.ObjectReadLatency.Delta(other.ObjectReadLatency),
+		s.ObjectBytesPerRead.Delta(other.ObjectBytesPerRead),
+
 		s.HasLatency.Delta(other.HasLatency),
 		s.AddressesPerHas.Delta(other.AddressesPerHas),
 
@@ -185,6 +196,8 @@ MemReadLatency:                   %s
 MemBytesPerRead:                  %s
 DynamoReadLatency:                %s
 DynamoBytesPerRead:               %s
+ObjectReadLatency:                %s
+ObjectBytesPerRead:               %s
 HasLatency:                       %s
 AddressesHasGet:                  %s
 PutLatency:                       %s
@@ -221,6 +234,9 @@ WriteManifestLatency:             %s
 		s.DynamoReadLatency,
 		s.DynamoBytesPerRead,
 
+		s.ObjectReadLatency,
+		s.ObjectBytesPerRead,
+
 		s.HasLatency,
 		s.AddressesPerHas,
 
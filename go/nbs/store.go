@@ -81,11 +81,18 @@ func NewAWSStore(table, ns, bucket string, s3 s3svc, ddb ddbsvc, memTableSize ui
 }
 
 func NewLocalStore(dir string, memTableSize uint64) *NomsBlockStore {
+	return NewEncryptedLocalStore(dir, memTableSize, nil)
+}
+
+// NewEncryptedLocalStore returns a NomsBlockStore in |dir| which encrypts the
+// tables and manifest it writes using |mk|. Unencrypted tables already in the
+// store remain readable. If |mk| is nil, the store is not encrypted.
+func NewEncryptedLocalStore(dir string, memTableSize uint64, mk *MasterKey) *NomsBlockStore {
 	cacheOnce.Do(makeGlobalCaches)
 	d.PanicIfError(checkDir(dir))
 
-	mm := makeManifestManager(fileManifest{dir})
-	p := newFSTablePersister(dir, globalFDCache, globalIndexCache)
+	mm := makeManifestManager(fileManifest{dir, mk})
+	p := newEncryptedFSTablePersister(dir, globalFDCache, globalIndexCache, mk)
 	return newNomsBlockStore(mm, p, inlineConjoiner{defaultMaxTables}, memTableSize)
 }
 
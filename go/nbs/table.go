@@ -78,6 +78,14 @@ import (
      -Total Uncompressed Chunk Data is the sum of the uncompressed byte lengths of all contained chunk byte slices.
      -Magic Number is the first 8 bytes of the SHA256 hash of "https://github.com/attic-labs/nbs".
 
+   Encrypted Footer:
+   +-----------------------+----------------------+----------------------------------------+----------------------------+
+   | (60) Wrapped Data Key | (Uint32) Chunk Count | (Uint64) Total Uncompressed Chunk Data | (8) Encrypted Magic Number |
+   +-----------------------+----------------------+----------------------------------------+----------------------------+
+
+     -Tables written by an encrypted store end with an Encrypted Footer instead. See encryption.go for the layout of the Wrapped Data Key and of encrypted Chunk Records.
+     -Encrypted Magic Number is the first 8 bytes of the SHA256 hash of "https://github.com/attic-labs/nbs/encrypted".
+
     NOTE: Unsigned integer quanities, hashes and hash suffix are all encoded big-endian
 
 
@@ -103,20 +111,22 @@ import (
 */
 
 const (
-	addrSize           uint64 = 20
-	addrPrefixSize     uint64 = 8
-	addrSuffixSize            = addrSize - addrPrefixSize
-	uint64Size         uint64 = 8
-	uint32Size         uint64 = 4
-	ordinalSize        uint64 = uint32Size
-	lengthSize         uint64 = uint32Size
-	magicNumber               = "\xff\xb5\xd8\xc2\x24\x63\xee\x50"
-	magicNumberSize    uint64 = uint64(len(magicNumber))
-	footerSize                = uint32Size + uint64Size + magicNumberSize
-	prefixTupleSize           = addrPrefixSize + ordinalSize
-	checksumSize       uint64 = uint32Size
-	maxChunkLengthSize uint64 = binary.MaxVarintLen64
-	maxChunkSize       uint64 = 0xffffffff // Snappy won't compress slices bigger than this
+	addrSize             uint64 = 20
+	addrPrefixSize       uint64 = 8
+	addrSuffixSize              = addrSize - addrPrefixSize
+	uint64Size           uint64 = 8
+	uint32Size           uint64 = 4
+	ordinalSize          uint64 = uint32Size
+	lengthSize           uint64 = uint32Size
+	magicNumber                 = "\xff\xb5\xd8\xc2\x24\x63\xee\x50"
+	magicNumberSize      uint64 = uint64(len(magicNumber))
+	footerSize                  = uint32Size + uint64Size + magicNumberSize
+	encryptedMagicNumber        = "\xfe\xf0\xad\xfb\x0b\x7a\x83\x50"
+	encryptedFooterSize         = wrappedKeySize + footerSize
+	prefixTupleSize             = addrPrefixSize + ordinalSize
+	checksumSize         uint64 = uint32Size
+	maxChunkLengthSize   uint64 = binary.MaxVarintLen64
+	maxChunkSize         uint64 = 0xffffffff // Snappy won't compress slices bigger than this
 )
 
 var crcTable = crc32.MakeTable(crc32.Castagnoli)
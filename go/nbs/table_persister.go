@@ -186,12 +186,43 @@ func planConjoin(sources chunkSources, stats *Stats) (plan compactionPlan) {
 		pfxPos += ordinalSize
 	}
 
-	writeFooter(plan.mergedIndex[uint64(len(plan.mergedIndex))-footerSize:], plan.chunkCount, totalUncompressedData)
+	writeFooter(plan.mergedIndex[uint64(len(plan.mergedIndex))-footerSize:], plan.chunkCount, totalUncompressedData, nil)
 
 	stats.BytesPerConjoin.Sample(uint64(plan.totalCompressedData) + uint64(len(plan.mergedIndex)))
 	return plan
 }
 
+// conjoinSealed conjoins |sources| by re-sealing all of their chunks into a
+// single new table encrypted using |mk|. Chunk records in encrypted tables
+// can't be copied byte-for-byte as planConjoin() expects, because each source
+// table was sealed with its own data key. Any plaintext |sources| are
+// encrypted in the process.
+func conjoinSealed(sources chunkSources, mk *MasterKey, stats *Stats) (name addr, data []byte, chunkCount uint32) {
+	var totalData uint64
+	for _, src := range sources {
+		totalData += src.uncompressedLen()
+	}
+	if totalData == 0 {
+		return
+	}
+
+	mt := newMemTable(totalData)
+	recs := make(chan extractRecord)
+	go func() {
+		defer close(recs)
+		for _, src := range sources {
+			src.extract(recs)
+		}
+	}()
+	for rec := range recs {
+		d.PanicIfFalse(mt.addChunk(rec.a, rec.data))
+	}
+
+	name, data, chunkCount = mt.writeEncrypted(nil, mk, stats)
+	stats.BytesPerConjoin.Sample(uint64(len(data)))
+	return
+}
+
 func nameFromSuffixes(suffixes []byte) (name addr) {
 	sha := sha512.New()
 	sha.Write(suffixes)
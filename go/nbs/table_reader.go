@@ -23,6 +23,7 @@ type tableIndex struct {
 	prefixes, offsets     []uint64
 	lengths, ordinals     []uint32
 	suffixes              []byte
+	wrappedKey            []byte // non-nil iff the table is encrypted
 }
 
 type tableReaderAt interface {
@@ -35,6 +36,7 @@ type tableReader struct {
 	tableIndex
 	r         tableReaderAt
 	blockSize uint64
+	dk        *dataKey // non-nil iff the table is encrypted
 }
 
 // parses a valid nbs tableIndex from a byte stream. |buff| must end with an NBS index and footer, though it may contain an unspecified number of bytes before that data. |tableIndex| doesn't keep alive any references to |buff|.
@@ -43,7 +45,8 @@ func parseTableIndex(buff []byte) tableIndex {
 
 	// footer
 	pos -= magicNumberSize
-	d.Chk.True(string(buff[pos:]) == magicNumber)
+	magic := string(buff[pos:])
+	d.Chk.True(magic == magicNumber || magic == encryptedMagicNumber)
 
 	// total uncompressed chunk data
 	pos -= uint64Size
@@ -52,6 +55,13 @@ func parseTableIndex(buff []byte) tableIndex {
 	pos -= uint32Size
 	chunkCount := binary.BigEndian.Uint32(buff[pos:])
 
+	var wrappedKey []byte
+	if magic == encryptedMagicNumber {
+		pos -= wrappedKeySize
+		wrappedKey = make([]byte, wrappedKeySize)
+		copy(wrappedKey, buff[pos:])
+	}
+
 	// index
 	suffixesSize := uint64(chunkCount) * addrSuffixSize
 	pos -= suffixesSize
@@ -71,9 +81,14 @@ func parseTableIndex(buff []byte) tableIndex {
 		prefixes, offsets,
 		lengths, ordinals,
 		suffixes,
+		wrappedKey,
 	}
 }
 
+func (ti tableIndex) encrypted() bool {
+	return ti.wrappedKey != nil
+}
+
 func computeOffsets(count uint32, buff []byte) (lengths []uint32, offsets []uint64) {
 	lengths = make([]uint32, count)
 	offsets = make([]uint64, count)
@@ -144,7 +159,14 @@ func (ti tableIndex) lookupOrdinal(h addr) uint32 {
 
 // newTableReader parses a valid nbs table byte stream and returns a reader. buff must end with an NBS index and footer, though it may contain an unspecified number of bytes before that data. r should allow retrieving any desired range of bytes from the table.
 func newTableReader(index tableIndex, r tableReaderAt, blockSize uint64) tableReader {
-	return tableReader{index, r, blockSize}
+	return tableReader{index, r, blockSize, nil}
+}
+
+// newEncryptedTableReader is like newTableReader, but uses |mk| to open the
+// table if it's encrypted. Panics with ErrMasterKeyRequired if the table is
+// encrypted and |mk| is nil.
+func newEncryptedTableReader(index tableIndex, r tableReaderAt, blockSize uint64, mk *MasterKey) tableReader {
+	return tableReader{index, r, blockSize, unwrapTableKey(index, mk)}
 }
 
 // Scan across (logically) two ordered slices of address prefixes.
@@ -222,7 +244,7 @@ func (tr tableReader) get(h addr, stats *Stats) (data []byte) {
 	n, err := tr.r.ReadAtWithStats(buff, int64(offset), stats)
 	d.Chk.NoError(err)
 	d.Chk.True(n == int(length))
-	data = tr.parseChunk(buff)
+	data = tr.parseChunk(h, buff)
 	d.Chk.True(data != nil)
 
 	return
@@ -262,7 +284,7 @@ func (tr tableReader) readAtOffsets(
 		localStart := rec.offset - readStart
 		localEnd := localStart + uint64(tr.lengths[rec.ordinal])
 		d.Chk.True(localEnd <= readLength)
-		data := tr.parseChunk(buff[localStart:localEnd])
+		data := tr.parseChunk(*rec.a, buff[localStart:localEnd])
 		c := chunks.NewChunkWithHash(hash.Hash(*rec.a), data)
 		foundChunks <- &c
 	}
@@ -397,14 +419,18 @@ func canReadAhead(fRec offsetRec, fLength uint32, readStart, readEnd, blockSize
 	return fRec.offset + uint64(fLength), true
 }
 
-// Fetches the byte stream of data logically encoded within the table starting at |pos|.
-func (tr tableReader) parseChunk(buff []byte) []byte {
+// Fetches the byte stream of data logically encoded within the table starting at |pos|. |h| is the address of the chunk, which is needed to open chunk records in encrypted tables.
+func (tr tableReader) parseChunk(h addr, buff []byte) []byte {
 	dataLen := uint64(len(buff)) - checksumSize
 
 	chksum := binary.BigEndian.Uint32(buff[dataLen:])
 	d.Chk.True(chksum == crc(buff[:dataLen]))
 
-	data, err := snappy.Decode(nil, buff[:dataLen])
+	compressed := buff[:dataLen]
+	if tr.dk != nil {
+		compressed = tr.dk.openRecord(h, compressed)
+	}
+	data, err := snappy.Decode(nil, compressed)
 	d.Chk.NoError(err)
 
 	return data
@@ -465,7 +491,7 @@ func (tr tableReader) extract(chunks chan<- extractRecord) {
 
 	sendChunk := func(i uint32) {
 		localOffset := tr.offsets[i] - tr.offsets[0]
-		chunks <- extractRecord{a: hashes[i], data: tr.parseChunk(buff[localOffset : localOffset+uint64(tr.lengths[i])])}
+		chunks <- extractRecord{a: hashes[i], data: tr.parseChunk(hashes[i], buff[localOffset:localOffset+uint64(tr.lengths[i])])}
 	}
 
 	for i := uint32(0); i < tr.chunkCount; i++ {
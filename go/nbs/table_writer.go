@@ -25,6 +25,11 @@ type tableWriter struct {
 	blockHash             hash.Hash
 
 	snapper snappyEncoder
+
+	// The following are used only when writing an encrypted table.
+	dk         *dataKey
+	wrappedKey []byte
+	scratch    []byte
 }
 
 type snappyEncoder interface {
@@ -45,6 +50,12 @@ func maxTableSize(numChunks, totalData uint64) uint64 {
 	return numChunks*(prefixTupleSize+lengthSize+addrSuffixSize+checksumSize+uint64(maxSnappySize)) + footerSize
 }
 
+// maxEncryptedTableSize is like maxTableSize, but leaves room for the sealing
+// overhead of each chunk record and for the wrapped data key.
+func maxEncryptedTableSize(numChunks, totalData uint64) uint64 {
+	return maxTableSize(numChunks, totalData) + numChunks*sealOverhead + wrappedKeySize
+}
+
 func indexSize(numChunks uint32) uint64 {
 	return uint64(numChunks) * (addrSuffixSize + lengthSize + prefixTupleSize)
 }
@@ -69,6 +80,15 @@ func newTableWriter(buff []byte, snapper snappyEncoder) *tableWriter {
 	}
 }
 
+// len(buff) must be >= maxEncryptedTableSize(numChunks, totalData). The
+// returned tableWriter seals every chunk record with a new data key wrapped
+// by |mk|.
+func newEncryptingTableWriter(buff []byte, snapper snappyEncoder, mk *MasterKey) *tableWriter {
+	tw := newTableWriter(buff, snapper)
+	tw.dk, tw.wrappedKey = mk.newDataKey()
+	return tw
+}
+
 func (tw *tableWriter) addChunk(h addr, data []byte) bool {
 	if len(data) == 0 {
 		panic("NBS blocks cannont be zero length")
@@ -87,6 +107,14 @@ func (tw *tableWriter) addChunk(h addr, data []byte) bool {
 		panic(fmt.Errorf("BUG 3156: unbuffered chunk %s: uncompressed %d, compressed %d, snappy max %d, tw.buff %d\n", h.String(), len(data), dataLength, snappy.MaxEncodedLen(len(data)), len(tw.buff[tw.pos:])))
 	}
 
+	if tw.dk != nil {
+		// Move the compressed data aside and seal it back into tw.buff
+		tw.scratch = append(tw.scratch[:0], compressed...)
+		compressed = tw.dk.sealRecord(tw.buff[tw.pos:tw.pos], h, tw.scratch)
+		d.Chk.True(&compressed[0] == &tw.buff[tw.pos])
+		dataLength = uint64(len(compressed))
+	}
+
 	tw.pos += dataLength
 	tw.totalUncompressedData += uint64(len(data))
 
@@ -110,6 +138,11 @@ func (tw *tableWriter) finish() (uncompressedLength uint64, blockAddr addr) {
 	tw.writeFooter()
 	uncompressedLength = tw.pos
 
+	// Encrypted tables holding the same chunks differ in their data keys, so they must also differ in name. Otherwise, cached indices (and the wrapped keys in them) could be applied to the wrong table.
+	if tw.wrappedKey != nil {
+		tw.blockHash.Write(tw.wrappedKey)
+	}
+
 	var h []byte
 	h = tw.blockHash.Sum(h) // Appends hash to h
 	copy(blockAddr[:], h)
@@ -163,10 +196,19 @@ func (tw *tableWriter) writeIndex() {
 }
 
 func (tw *tableWriter) writeFooter() {
-	tw.pos += writeFooter(tw.buff[tw.pos:], uint32(len(tw.prefixes)), tw.totalUncompressedData)
+	tw.pos += writeFooter(tw.buff[tw.pos:], uint32(len(tw.prefixes)), tw.totalUncompressedData, tw.wrappedKey)
 }
 
-func writeFooter(dst []byte, chunkCount uint32, uncData uint64) (consumed uint64) {
+// writeFooter writes a table footer into |dst|. If |wrappedKey| is non-nil,
+// the footer marks the table as encrypted with that key.
+func writeFooter(dst []byte, chunkCount uint32, uncData uint64, wrappedKey []byte) (consumed uint64) {
+	magic := magicNumber
+	if wrappedKey != nil {
+		d.Chk.True(uint64(len(wrappedKey)) == wrappedKeySize)
+		consumed += uint64(copy(dst, wrappedKey))
+		magic = encryptedMagicNumber
+	}
+
 	// chunk count
 	binary.BigEndian.PutUint32(dst[consumed:], chunkCount)
 	consumed += uint32Size
@@ -176,7 +218,7 @@ func writeFooter(dst []byte, chunkCount uint32, uncData uint64) (consumed uint64
 	consumed += uint64Size
 
 	// magic number
-	copy(dst[consumed:], magicNumber)
+	copy(dst[consumed:], magic)
 	consumed += magicNumberSize
 	return
 }
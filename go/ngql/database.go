@@ -0,0 +1,527 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package ngql
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/attic-labs/graphql"
+	"github.com/attic-labs/graphql/language/ast"
+	"github.com/attic-labs/noms/go/diff"
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/attic-labs/noms/go/types"
+)
+
+// The fields of a Commit. These match those in package datas, which can't be
+// imported here.
+const (
+	commitMetaField    = "meta"
+	commitParentsField = "parents"
+	commitValueField   = "value"
+)
+
+const (
+	afterKey       = "after"
+	changeKey      = "change"
+	commitKey      = "commit"
+	cursorKey      = "cursor"
+	datasetKey     = "dataset"
+	datasetsKey    = "datasets"
+	diffKey        = "diff"
+	edgesKey       = "edges"
+	endCursorKey   = "endCursor"
+	firstKey       = "first"
+	fromKey        = "from"
+	hasNextPageKey = "hasNextPage"
+	headKey        = "head"
+	heightKey      = "height"
+	historyKey     = "history"
+	metaKey        = "meta"
+	nameKey        = "name"
+	newValueKey    = "newValue"
+	nodeKey        = "node"
+	oldValueKey    = "oldValue"
+	pageInfoKey    = "pageInfo"
+	parentsKey     = "parents"
+	pathKey        = "path"
+	toKey          = "to"
+	valueHashKey   = "valueHash"
+)
+
+// The names of the types in the database-level schema.
+const (
+	commitType      = "Commit"
+	connectionType  = "CommitConnection"
+	databaseQuery   = "Database"
+	datasetType     = "Dataset"
+	diffChangeType  = "DiffChange"
+	differenceType  = "Difference"
+	edgeType        = "CommitEdge"
+	jsonScalarType  = "JSON"
+	pageInfoType    = "PageInfo"
+	jsonDescription = "A Noms value as JSON, or as its Noms encoding if it can't be represented as JSON."
+)
+
+// Database is what a database-level schema queries: the values in a database
+// and its datasets.
+type Database interface {
+	types.ValueReadWriter
+	// Datasets returns the Map from the name of each dataset to a Ref of its
+	// head Commit.
+	Datasets() types.Map
+}
+
+// datasetSource is what the fields of a Dataset resolve from.
+type datasetSource struct {
+	name    string
+	headRef types.Ref
+}
+
+// historyPage is what the fields of a CommitConnection resolve from.
+type historyPage struct {
+	commits []types.Struct
+	hasNext bool
+}
+
+// jsonScalar is the type of Noms values that vary from commit to commit, so
+// can't be given a GraphQL type.
+var jsonScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        jsonScalarType,
+	Description: jsonDescription,
+	Serialize: func(value interface{}) interface{} {
+		v := value.(types.Value)
+		if j, err := diff.ValueToJSON(v); err == nil {
+			return j
+		}
+		return types.EncodedValue(v)
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return nil
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		return nil
+	},
+})
+
+// NewDatabaseQueryObject creates a "root" query object for the database
+// |db| as a whole, rather than for a single value. It has the fields:
+//   - datasets: every dataset, with its name, head and history.
+//   - dataset(name): the dataset called |name|, or null.
+//   - commit(hash): the commit with hash |hash|, or null.
+//   - diff(from, to): the differences between the values with hashes |from|
+//     and |to|, or between their values if they're both commits.
+//
+// Commits have their hash, height, parents, meta and value, and a history,
+// which is a Relay style connection that pages through the commit and its
+// ancestors, tallest first, with arguments first and after. Since their type
+// can vary from commit to commit, meta and value are JSON. The typed value
+// can be queried by its valueHash.
+func (tc *TypeConverter) NewDatabaseQueryObject(db Database) *graphql.Object {
+	datasetObject := tc.datasetObject()
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: databaseQuery,
+		Fields: graphql.Fields{
+			datasetsKey: &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(datasetObject))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					datasets := []interface{}{}
+					db.Datasets().IterAll(func(k, v types.Value) {
+						datasets = append(datasets, datasetSource{string(k.(types.String)), v.(types.Ref)})
+					})
+					return datasets, nil
+				},
+			},
+			datasetKey: &graphql.Field{
+				Type: datasetObject,
+				Args: graphql.FieldConfigArgument{
+					nameKey: &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					name := p.Args[nameKey].(string)
+					if r, ok := db.Datasets().MaybeGet(types.String(name)); ok {
+						return datasetSource{name, r.(types.Ref)}, nil
+					}
+					return nil, nil
+				},
+			},
+			commitKey: &graphql.Field{
+				Type: tc.commitObject(),
+				Args: graphql.FieldConfigArgument{
+					hashKey: &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					v, err := readValue(db, p.Args[hashKey].(string))
+					if v == nil || err != nil {
+						return nil, err
+					}
+					if !isCommit(v) {
+						return nil, fmt.Errorf("%s is not a commit", p.Args[hashKey])
+					}
+					return v, nil
+				},
+			},
+			diffKey: &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(tc.differenceObject()))),
+				Args: graphql.FieldConfigArgument{
+					fromKey: &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					toKey:   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					values := make([]types.Value, 2)
+					for i, key := range []string{fromKey, toKey} {
+						v, err := readValue(db, p.Args[key].(string))
+						if err != nil {
+							return nil, err
+						}
+						if v == nil {
+							return nil, fmt.Errorf("%s not found", p.Args[key])
+						}
+						values[i] = v
+					}
+					if isCommit(values[0]) && isCommit(values[1]) {
+						values[0] = values[0].(types.Struct).Get(commitValueField)
+						values[1] = values[1].(types.Struct).Get(commitValueField)
+					}
+					return differences(values[0], values[1]), nil
+				},
+			},
+		},
+	})
+}
+
+// QueryDatabase builds the schema of NewDatabaseQueryObject for |db| and
+// executes |query| against it, encoding the result to |w|. Results are cached
+// by the hash of the datasets of |db| and |query|.
+func QueryDatabase(db Database, query string, w io.Writer) {
+	cachedQuery(resultKey{databaseSchema, db.Datasets().Hash(), query}, w, func(w io.Writer) bool {
+		tc := NewTypeConverter()
+		schema, _ := graphql.NewSchema(graphql.SchemaConfig{Query: tc.NewDatabaseQueryObject(db)})
+		return do(schema, query, db, w)
+	})
+}
+
+func (tc *TypeConverter) datasetObject() *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: datasetType,
+		Fields: graphql.Fields{
+			nameKey: &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(datasetSource).name, nil
+				},
+			},
+			headKey: &graphql.Field{
+				Type: graphql.NewNonNull(tc.commitObject()),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					vr := p.Context.Value(vrwKey).(types.ValueReader)
+					return p.Source.(datasetSource).headRef.TargetValue(vr), nil
+				},
+			},
+			historyKey: tc.historyField(func(p graphql.ResolveParams) types.Struct {
+				vr := p.Context.Value(vrwKey).(types.ValueReader)
+				return p.Source.(datasetSource).headRef.TargetValue(vr).(types.Struct)
+			}),
+		},
+	})
+}
+
+// commitObject returns the type of Commits, which is cached in |tc| since
+// Commits refer to themselves through their parents.
+func (tc *TypeConverter) commitObject() *graphql.Object {
+	key := typeMapKey{commitType, false}
+	if t, ok := tc.tm[key]; ok {
+		return t.(*graphql.Object)
+	}
+	t := graphql.NewObject(graphql.ObjectConfig{
+		Name: commitType,
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				hashKey: &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(types.Struct).Hash().String(), nil
+					},
+				},
+				heightKey: &graphql.Field{
+					Type: graphql.NewNonNull(graphql.Float),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return float64(types.NewRef(p.Source.(types.Struct)).Height()), nil
+					},
+				},
+				parentsKey: &graphql.Field{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(tc.commitObject()))),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						vr := p.Context.Value(vrwKey).(types.ValueReader)
+						refs := parentRefs(p.Source.(types.Struct))
+						prefetch(vr, refs)
+						parents := []interface{}{}
+						for _, r := range refs {
+							parents = append(parents, r.TargetValue(vr))
+						}
+						return parents, nil
+					},
+				},
+				metaKey: &graphql.Field{
+					Type:        jsonScalar,
+					Description: jsonDescription,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(types.Struct).Get(commitMetaField), nil
+					},
+				},
+				valueKey: &graphql.Field{
+					Type:        jsonScalar,
+					Description: jsonDescription,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(types.Struct).Get(commitValueField), nil
+					},
+				},
+				valueHashKey: &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(types.Struct).Get(commitValueField).Hash().String(), nil
+					},
+				},
+				historyKey: tc.historyField(func(p graphql.ResolveParams) types.Struct {
+					return p.Source.(types.Struct)
+				}),
+			}
+		}),
+	})
+	tc.tm[key] = t
+	return t
+}
+
+// historyField creates a field that pages through the history of the commit
+// returned by |head|, with the arguments first and after.
+func (tc *TypeConverter) historyField(head func(p graphql.ResolveParams) types.Struct) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(tc.connectionObject()),
+		Args: graphql.FieldConfigArgument{
+			firstKey: &graphql.ArgumentConfig{Type: graphql.Int},
+			afterKey: &graphql.ArgumentConfig{Type: graphql.String},
+		},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			first := -1
+			if f, ok := p.Args[firstKey].(int); ok {
+				if f < 0 {
+					return nil, fmt.Errorf("first must not be negative")
+				}
+				first = f
+			}
+			after, _ := p.Args[afterKey].(string)
+			vr := p.Context.Value(vrwKey).(types.ValueReader)
+			return history(vr, head(p), first, after)
+		},
+	}
+}
+
+func (tc *TypeConverter) connectionObject() *graphql.Object {
+	key := typeMapKey{connectionType, false}
+	if t, ok := tc.tm[key]; ok {
+		return t.(*graphql.Object)
+	}
+	edge := graphql.NewObject(graphql.ObjectConfig{
+		Name: edgeType,
+		Fields: graphql.Fields{
+			cursorKey: &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(types.Struct).Hash().String(), nil
+				},
+			},
+			nodeKey: &graphql.Field{
+				Type: graphql.NewNonNull(tc.commitObject()),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+	pageInfo := graphql.NewObject(graphql.ObjectConfig{
+		Name: pageInfoType,
+		Fields: graphql.Fields{
+			hasNextPageKey: &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(historyPage).hasNext, nil
+				},
+			},
+			endCursorKey: &graphql.Field{
+				Type: graphql.String,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					commits := p.Source.(historyPage).commits
+					if len(commits) == 0 {
+						return nil, nil
+					}
+					return commits[len(commits)-1].Hash().String(), nil
+				},
+			},
+		},
+	})
+	t := graphql.NewObject(graphql.ObjectConfig{
+		Name: connectionType,
+		Fields: graphql.Fields{
+			edgesKey: &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(edge))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					edges := []interface{}{}
+					for _, c := range p.Source.(historyPage).commits {
+						edges = append(edges, c)
+					}
+					return edges, nil
+				},
+			},
+			pageInfoKey: &graphql.Field{
+				Type: graphql.NewNonNull(pageInfo),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				},
+			},
+		},
+	})
+	tc.tm[key] = t
+	return t
+}
+
+func (tc *TypeConverter) differenceObject() *graphql.Object {
+	change := graphql.NewEnum(graphql.EnumConfig{
+		Name: diffChangeType,
+		Values: graphql.EnumValueConfigMap{
+			"added":    &graphql.EnumValueConfig{Value: types.DiffChangeAdded},
+			"removed":  &graphql.EnumValueConfig{Value: types.DiffChangeRemoved},
+			"modified": &graphql.EnumValueConfig{Value: types.DiffChangeModified},
+		},
+	})
+	value := func(get func(dif diff.Difference) types.Value) *graphql.Field {
+		return &graphql.Field{
+			Type:        jsonScalar,
+			Description: jsonDescription,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return get(p.Source.(diff.Difference)), nil
+			},
+		}
+	}
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: differenceType,
+		Fields: graphql.Fields{
+			pathKey: &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(diff.Difference).Path.String(), nil
+				},
+			},
+			changeKey: &graphql.Field{
+				Type: graphql.NewNonNull(change),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(diff.Difference).ChangeType, nil
+				},
+			},
+			oldValueKey: value(func(dif diff.Difference) types.Value { return dif.OldValue }),
+			newValueKey: value(func(dif diff.Difference) types.Value { return dif.NewValue }),
+		},
+	})
+}
+
+// history returns a page of at most |first|, or all if |first| is negative,
+// of the commits in the history of |head|, tallest first, starting after the
+// commit with hash |after|, or at |head| if |after| is empty.
+func history(vr types.ValueReader, head types.Struct, first int, after string) (page historyPage, err error) {
+	afterHash := hash.Hash{}
+	if after != "" {
+		var ok bool
+		if afterHash, ok = hash.MaybeParse(strings.TrimPrefix(after, "#")); !ok {
+			return page, fmt.Errorf("Invalid hash: %s", after)
+		}
+	}
+
+	refs := types.RefByHeight{types.NewRef(head)}
+	seen := hash.HashSet{}
+	started := after == ""
+	for !refs.Empty() {
+		r := refs.PopBack()
+		if seen.Has(r.TargetHash()) {
+			continue
+		}
+		seen.Insert(r.TargetHash())
+		commit := r.TargetValue(vr).(types.Struct)
+		if started {
+			if len(page.commits) == first {
+				page.hasNext = true
+				break
+			}
+			page.commits = append(page.commits, commit)
+		} else {
+			started = r.TargetHash() == afterHash
+		}
+		parents := parentRefs(commit)
+		prefetch(vr, parents)
+		for _, p := range parents {
+			refs.PushBack(p)
+		}
+		sort.Sort(refs)
+	}
+	if !started {
+		return page, fmt.Errorf("%s is not in the history of #%s", after, head.Hash())
+	}
+	return page, nil
+}
+
+// differences returns the differences between |v1| and |v2|.
+func differences(v1, v2 types.Value) []interface{} {
+	difs := []interface{}{}
+	dChan := make(chan diff.Difference, 16)
+	go func() {
+		diff.Diff(v1, v2, dChan, make(chan struct{}), false)
+		close(dChan)
+	}()
+	for dif := range dChan {
+		difs = append(difs, dif)
+	}
+	return difs
+}
+
+func parentRefs(commit types.Struct) []types.Ref {
+	refs := []types.Ref{}
+	commit.Get(commitParentsField).(types.Set).IterAll(func(v types.Value) {
+		refs = append(refs, v.(types.Ref))
+	})
+	return refs
+}
+
+// prefetch reads the targets of |refs| in a single batch, if |vr| is the
+// loader of a query.
+func prefetch(vr types.ValueReader, refs []types.Ref) {
+	if l, ok := vr.(*loader); ok && len(refs) > 1 {
+		l.prefetch(refs)
+	}
+}
+
+// readValue reads the value with hash |str|, which may start with #.
+func readValue(vr types.ValueReader, str string) (types.Value, error) {
+	h, ok := hash.MaybeParse(strings.TrimPrefix(str, "#"))
+	if !ok {
+		return nil, fmt.Errorf("Invalid hash: %s", str)
+	}
+	return vr.ReadValue(h), nil
+}
+
+// isCommit returns true if |v| looks like a Commit, which is the best that
+// can be done without package datas.
+func isCommit(v types.Value) bool {
+	s, ok := v.(types.Struct)
+	if !ok || s.Name() != commitType {
+		return false
+	}
+	_, hasValue := s.MaybeGet(commitValueField)
+	parents, hasParents := s.MaybeGet(commitParentsField)
+	if _, isSet := parents.(types.Set); !hasValue || !hasParents || !isSet {
+		return false
+	}
+	_, hasMeta := s.MaybeGet(commitMetaField)
+	return hasMeta
+}
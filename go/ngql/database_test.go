@@ -0,0 +1,178 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package ngql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/attic-labs/noms/go/types"
+	"github.com/stretchr/testify/suite"
+)
+
+// testDatabase is a Database whose datasets are set directly.
+type testDatabase struct {
+	*types.ValueStore
+	datasets types.Map
+}
+
+func (db *testDatabase) Datasets() types.Map {
+	return db.datasets
+}
+
+type DatabaseGraphQLSuite struct {
+	suite.Suite
+	db *testDatabase
+}
+
+func TestDatabaseGraphQL(t *testing.T) {
+	suite.Run(t, &DatabaseGraphQLSuite{})
+}
+
+func (suite *DatabaseGraphQLSuite) SetupTest() {
+	vs := newTestValueStore()
+	suite.db = &testDatabase{vs, types.NewMap(vs)}
+}
+
+// commit writes a Commit, like those made by package datas, of |v| with
+// |message| in its meta.
+func (suite *DatabaseGraphQLSuite) commit(v types.Value, message string, parents ...types.Struct) types.Struct {
+	refs := make(types.ValueSlice, len(parents))
+	for i, p := range parents {
+		refs[i] = types.NewRef(p)
+	}
+	c := types.NewStruct(commitType, types.StructData{
+		commitMetaField:    types.NewStruct("", types.StructData{"message": types.String(message)}),
+		commitParentsField: types.NewSet(suite.db, refs...),
+		commitValueField:   v,
+	})
+	suite.db.WriteValue(c)
+	return c
+}
+
+func (suite *DatabaseGraphQLSuite) setHead(ds string, c types.Struct) {
+	suite.db.datasets = suite.db.datasets.Edit().Set(types.String(ds), types.NewRef(c)).Map()
+}
+
+func (suite *DatabaseGraphQLSuite) assertQueryResult(q, expect string) {
+	buf := &bytes.Buffer{}
+	QueryDatabase(suite.db, q, buf)
+	suite.JSONEq(expect, buf.String())
+}
+
+func (suite *DatabaseGraphQLSuite) TestDatasets() {
+	c1 := suite.commit(types.Number(1), "one")
+	c2 := suite.commit(types.NewMap(suite.db, types.String("a"), types.Number(2)), "two", c1)
+	suite.setHead("foo", c2)
+	suite.setHead("bar", c1)
+
+	suite.assertQueryResult(`{datasets{name head{hash}}}`, fmt.Sprintf(`{"data":{"datasets":[
+		{"name":"bar","head":{"hash":"%s"}},
+		{"name":"foo","head":{"hash":"%s"}}]}}`, c1.Hash(), c2.Hash()))
+	suite.assertQueryResult(`{dataset(name: "foo"){head{height meta value valueHash parents{hash}}}}`, fmt.Sprintf(`{"data":{"dataset":{"head":{
+		"height":2,"meta":{"message":"two"},"value":{"a":2},"valueHash":"%s","parents":[{"hash":"%s"}]}}}}`,
+		c2.Get(commitValueField).Hash(), c1.Hash()))
+	suite.assertQueryResult(`{dataset(name: "baz"){name}}`, `{"data":{"dataset":null}}`)
+}
+
+func (suite *DatabaseGraphQLSuite) TestCommit() {
+	c := suite.commit(types.NewSet(suite.db, types.Number(1)), "set")
+	suite.assertQueryResult(fmt.Sprintf(`{commit(hash: "#%s"){meta value}}`, c.Hash()),
+		`{"data":{"commit":{"meta":{"message":"set"},"value":"set {\n  1,\n}"}}}`)
+
+	notCommit := suite.db.WriteValue(types.Number(1)).TargetHash()
+	buf := &bytes.Buffer{}
+	QueryDatabase(suite.db, fmt.Sprintf(`{commit(hash: "%s"){hash}}`, notCommit), buf)
+	suite.Contains(buf.String(), fmt.Sprintf("%s is not a commit", notCommit))
+
+	suite.assertQueryResult(fmt.Sprintf(`{commit(hash: "%s"){hash}}`, types.String("nope").Hash()), `{"data":{"commit":null}}`)
+}
+
+func (suite *DatabaseGraphQLSuite) TestHistory() {
+	// c1 <- c2 <- c4
+	//    \- c3 <-/
+	c1 := suite.commit(types.Number(1), "1")
+	c2 := suite.commit(types.Number(2), "2", c1)
+	c3 := suite.commit(types.Number(3), "3", c1)
+	c4 := suite.commit(types.Number(4), "4", c2, c3)
+	suite.setHead("foo", c4)
+
+	page := func(q string) string {
+		buf := &bytes.Buffer{}
+		QueryDatabase(suite.db, fmt.Sprintf(`{dataset(name: "foo"){history%s{edges{node{meta}} pageInfo{hasNextPage}}}}`, q), buf)
+		return strings.TrimSpace(buf.String())
+	}
+	edges := func(hasNext bool, messages ...string) string {
+		nodes := ""
+		for i, m := range messages {
+			if i > 0 {
+				nodes += ","
+			}
+			nodes += fmt.Sprintf(`{"node":{"meta":{"message":"%s"}}}`, m)
+		}
+		return fmt.Sprintf(`{"data":{"dataset":{"history":{"edges":[%s],"pageInfo":{"hasNextPage":%t}}}}}`, nodes, hasNext)
+	}
+
+	all := page("")
+	suite.Contains([]string{edges(false, "4", "2", "3", "1"), edges(false, "4", "3", "2", "1")}, all)
+	suite.JSONEq(edges(true, "4"), page("(first: 1)"))
+
+	// Page through with the cursors.
+	messages := []string{}
+	after := ""
+	for {
+		buf := &bytes.Buffer{}
+		QueryDatabase(suite.db, fmt.Sprintf(`{dataset(name: "foo"){history(first: 2%s){edges{cursor node{meta}} pageInfo{hasNextPage endCursor}}}}`, after), buf)
+		res := struct {
+			Data struct {
+				Dataset struct {
+					History struct {
+						Edges []struct {
+							Node struct {
+								Meta struct{ Message string }
+							}
+						}
+						PageInfo struct {
+							HasNextPage bool
+							EndCursor   string
+						}
+					}
+				}
+			}
+		}{}
+		suite.NoError(json.Unmarshal(buf.Bytes(), &res))
+		h := res.Data.Dataset.History
+		for _, e := range h.Edges {
+			messages = append(messages, e.Node.Meta.Message)
+		}
+		if !h.PageInfo.HasNextPage {
+			break
+		}
+		after = fmt.Sprintf(`, after: "%s"`, h.PageInfo.EndCursor)
+	}
+	suite.Len(messages, 4)
+	suite.Equal("4", messages[0])
+	suite.Equal("1", messages[3])
+
+	suite.assertQueryResult(fmt.Sprintf(`{commit(hash: "%s"){history{edges{cursor}}}}`, c2.Hash()), fmt.Sprintf(
+		`{"data":{"commit":{"history":{"edges":[{"cursor":"%s"},{"cursor":"%s"}]}}}}`, c2.Hash(), c1.Hash()))
+	suite.Contains(page(`(after: "#00000000000000000000000000000000")`), "is not in the history of")
+}
+
+func (suite *DatabaseGraphQLSuite) TestDiff() {
+	c1 := suite.commit(types.NewStruct("", types.StructData{"a": types.Number(1), "b": types.String("x")}), "1")
+	c2 := suite.commit(types.NewStruct("", types.StructData{"a": types.Number(2), "c": types.Bool(true)}), "2", c1)
+
+	suite.assertQueryResult(fmt.Sprintf(`{diff(from: "%s", to: "%s"){path change oldValue newValue}}`, c1.Hash(), c2.Hash()), `{"data":{"diff":[
+		{"path":".a","change":"modified","oldValue":1,"newValue":2},
+		{"path":".b","change":"removed","oldValue":"x","newValue":null},
+		{"path":".c","change":"added","oldValue":null,"newValue":true}]}}`)
+
+	v := suite.db.WriteValue(types.Number(1)).TargetHash()
+	suite.assertQueryResult(fmt.Sprintf(`{diff(from: "%s", to: "%s"){path change}}`, v, v), `{"data":{"diff":[]}}`)
+}
@@ -0,0 +1,165 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package ngql
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/attic-labs/graphql"
+	"github.com/attic-labs/graphql/language/ast"
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/attic-labs/noms/go/types"
+)
+
+// loader is the ValueReadWriter that the resolvers of a single query use. It
+// caches the Values it reads by hash for the rest of the query, and can read
+// the targets of many Refs in a single batch, see prefetch.
+type loader struct {
+	types.ValueReader
+	mu     sync.Mutex
+	values map[hash.Hash]types.Value
+}
+
+func newLoader(vr types.ValueReader) *loader {
+	return &loader{ValueReader: vr, values: map[hash.Hash]types.Value{}}
+}
+
+func (l *loader) cached(h hash.Hash) (types.Value, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	v, ok := l.values[h]
+	return v, ok
+}
+
+func (l *loader) ReadValue(h hash.Hash) types.Value {
+	if v, ok := l.cached(h); ok {
+		atomic.AddUint64(&stats.LoaderCacheHits, 1)
+		return v
+	}
+	v := l.ValueReader.ReadValue(h)
+	atomic.AddUint64(&stats.ValuesRead, 1)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.values[h] = v
+	return v
+}
+
+func (l *loader) ReadManyValues(hashes hash.HashSet, foundValues chan<- types.Value) {
+	missing := hash.HashSet{}
+	for h := range hashes {
+		if v, ok := l.cached(h); !ok {
+			missing.Insert(h)
+		} else if v != nil {
+			atomic.AddUint64(&stats.LoaderCacheHits, 1)
+			foundValues <- v
+		}
+	}
+	for _, v := range l.readMany(missing) {
+		foundValues <- v
+	}
+}
+
+func (l *loader) WriteValue(v types.Value) types.Ref {
+	return l.ValueReader.(types.ValueWriter).WriteValue(v)
+}
+
+// prefetch reads the targets of |refs| that haven't already been read, in a
+// single batch.
+func (l *loader) prefetch(refs []types.Ref) {
+	missing := hash.HashSet{}
+	for _, r := range refs {
+		if _, ok := l.cached(r.TargetHash()); !ok {
+			missing.Insert(r.TargetHash())
+		}
+	}
+	l.readMany(missing)
+}
+
+// readMany reads the Values with |hashes| in a single batch, and caches them.
+func (l *loader) readMany(hashes hash.HashSet) []types.Value {
+	if len(hashes) == 0 {
+		return nil
+	}
+	valueChan := make(chan types.Value, len(hashes))
+	go func() {
+		l.ValueReader.ReadManyValues(hashes, valueChan)
+		close(valueChan)
+	}()
+	values := make([]types.Value, 0, len(hashes))
+	for v := range valueChan {
+		values = append(values, v)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, v := range values {
+		l.values[v.Hash()] = v
+	}
+	atomic.AddUint64(&stats.BatchedReads, 1)
+	atomic.AddUint64(&stats.BatchedValues, uint64(len(values)))
+	return values
+}
+
+// prefetchTargets reads the targets of the Refs among |elems|, the elements
+// of a collection that a field resolved to, in a single batch if the query
+// selects the targetValue of any of them. Both the keys and values of Map
+// entries are prefetched.
+func prefetchTargets(p graphql.ResolveParams, elems interface{}) {
+	l, ok := p.Context.Value(vrwKey).(*loader)
+	values, isSlice := elems.([]interface{})
+	if !ok || !isSlice || !selects(p.Info, targetValueKey) {
+		return
+	}
+	refs := []types.Ref{}
+	add := func(v interface{}) {
+		if r, ok := v.(types.Ref); ok {
+			refs = append(refs, r)
+		}
+	}
+	for _, e := range values {
+		if entry, ok := e.(mapEntry); ok {
+			add(entry.key)
+			add(entry.value)
+		} else {
+			add(e)
+		}
+	}
+	l.prefetch(refs)
+}
+
+// selects returns true if the query selects a field called |name| anywhere
+// beneath the field being resolved.
+func selects(info graphql.ResolveInfo, name string) bool {
+	var walk func(ss *ast.SelectionSet) bool
+	walk = func(ss *ast.SelectionSet) bool {
+		if ss == nil {
+			return false
+		}
+		for _, sel := range ss.Selections {
+			switch sel := sel.(type) {
+			case *ast.Field:
+				if sel.Name.Value == name || walk(sel.SelectionSet) {
+					return true
+				}
+			case *ast.InlineFragment:
+				if walk(sel.SelectionSet) {
+					return true
+				}
+			case *ast.FragmentSpread:
+				if def, ok := info.Fragments[sel.Name.Value].(*ast.FragmentDefinition); ok && walk(def.SelectionSet) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for _, f := range info.FieldASTs {
+		if walk(f.SelectionSet) {
+			return true
+		}
+	}
+	return false
+}
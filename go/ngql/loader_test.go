@@ -0,0 +1,123 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package ngql
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/attic-labs/noms/go/types"
+	"github.com/attic-labs/noms/go/util/sizecache"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingReader counts the reads made through it.
+type countingReader struct {
+	types.ValueReadWriter
+	reads, batches int
+}
+
+func (cr *countingReader) ReadValue(h hash.Hash) types.Value {
+	cr.reads++
+	return cr.ValueReadWriter.ReadValue(h)
+}
+
+func (cr *countingReader) ReadManyValues(hashes hash.HashSet, foundValues chan<- types.Value) {
+	cr.batches++
+	cr.ValueReadWriter.ReadManyValues(hashes, foundValues)
+}
+
+func TestPrefetchTargets(t *testing.T) {
+	assert := assert.New(t)
+	resultCache = sizecache.New(resultCacheSize)
+	vs := newTestValueStore()
+	refs := types.ValueSlice{}
+	for i := 0; i < 3; i++ {
+		refs = append(refs, vs.WriteValue(types.NewStruct("S", types.StructData{"a": types.Number(i)})))
+	}
+	m := types.NewMap(vs, types.String("x"), refs[0], types.String("y"), refs[1])
+	root := types.NewStruct("", types.StructData{"l": types.NewList(vs, refs...), "m": m})
+
+	query := func(q string) (string, *countingReader) {
+		cr := &countingReader{ValueReadWriter: vs}
+		buf := &bytes.Buffer{}
+		Query(root, q, cr, buf)
+		return buf.String(), cr
+	}
+
+	res, cr := query(`{root{l{values{targetValue{a}}}}}`)
+	assert.JSONEq(`{"data":{"root":{"l":{"values":[
+		{"targetValue":{"a":0}},{"targetValue":{"a":1}},{"targetValue":{"a":2}}]}}}}`, res)
+	assert.Equal(0, cr.reads)
+	assert.Equal(1, cr.batches)
+
+	// Values already read by the query aren't read again.
+	res, cr = query(`{root{x: l{values{targetValue{a}}} y: l{values{targetValue{a}}}}}`)
+	assert.Contains(res, `"y":{"values":[{"targetValue":{"a":0}},{"targetValue":{"a":1}},{"targetValue":{"a":2}}]}`)
+	assert.Equal(0, cr.reads)
+	assert.Equal(1, cr.batches)
+
+	res, cr = query(`{root{m{entries{key value{targetValue{a}}}}}}`)
+	assert.Contains(res, `{"key":"y","value":{"targetValue":{"a":1}}}`)
+	assert.Equal(0, cr.reads)
+	assert.Equal(1, cr.batches)
+
+	// Nothing is read if no targetValue is selected.
+	res, cr = query(`{root{l{values{targetHash}}}}`)
+	assert.Contains(res, refs[2].(types.Ref).TargetHash().String())
+	assert.Equal(0, cr.reads)
+	assert.Equal(0, cr.batches)
+}
+
+func TestResultCache(t *testing.T) {
+	assert := assert.New(t)
+	resultCache = sizecache.New(resultCacheSize)
+	vs := newTestValueStore()
+	root := types.NewList(vs, vs.WriteValue(types.String("result cache")))
+
+	query := func(q string) (string, *countingReader) {
+		cr := &countingReader{ValueReadWriter: vs}
+		buf := &bytes.Buffer{}
+		Query(root, q, cr, buf)
+		return buf.String(), cr
+	}
+
+	stats := Stats()
+	res1, cr := query(`{root{values{targetValue}}}`)
+	assert.Equal(1, cr.batches)
+	res2, cr := query(`{root{values{targetValue}}}`)
+	assert.Equal(0, cr.batches)
+	assert.Equal(res1, res2)
+	assert.Equal(stats.Queries+2, Stats().Queries)
+	assert.Equal(stats.ResultCacheHits+1, Stats().ResultCacheHits)
+
+	// Results with errors aren't cached.
+	stats = Stats()
+	query(`{root{`)
+	res, _ := query(`{root{`)
+	assert.Contains(res, "errors")
+	assert.Equal(stats.ResultCacheHits, Stats().ResultCacheHits)
+
+	// Neither are mutations.
+	c := newTestCommitter(types.String("mutation cache"))
+	q := `mutation {replaceHead(value: "x") {hash}}`
+	buf := &bytes.Buffer{}
+	QueryWithMutations(c, q, vs, buf)
+	head := c.Head()
+	QueryWithMutations(c, q, vs, buf)
+	assert.True(head.Equals(c.Head().Get("parent")))
+	assert.Contains(buf.String(), fmt.Sprintf(`"hash":"%s"`, c.Head().Hash()))
+	assert.Equal(stats.ResultCacheHits, Stats().ResultCacheHits)
+	// The same query of the same root has a different result with mutations.
+	q = `{__schema{mutationType{name}}}`
+	buf = &bytes.Buffer{}
+	Query(c.Head(), q, vs, buf)
+	assert.Contains(buf.String(), `"mutationType":null`)
+	buf = &bytes.Buffer{}
+	QueryWithMutations(c, q, vs, buf)
+	assert.Contains(buf.String(), `"mutationType":{"name":"Mutation"}`)
+}
@@ -0,0 +1,272 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package ngql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/attic-labs/graphql"
+	"github.com/attic-labs/noms/go/types"
+)
+
+const (
+	deleteEntryKey  = "deleteEntry"
+	expectedHeadKey = "expectedHead"
+	fieldsKey       = "fields"
+	hashKey         = "hash"
+	insertElemsKey  = "insertElements"
+	messageKey      = "message"
+	mutationKey     = "Mutation"
+	mutationResult  = "MutationResult"
+	removeElemsKey  = "removeElements"
+	replaceHeadKey  = "replaceHead"
+	setEntryKey     = "setEntry"
+	setFieldsKey    = "setFields"
+)
+
+// Committer commits new values to the dataset that mutations write to.
+type Committer interface {
+	// Head returns the current head Commit of the dataset.
+	Head() types.Struct
+	// Commit commits |value| to the dataset, with Head as its parent and
+	// |message|, if it isn't empty, in its meta, and returns the new head.
+	Commit(value types.Value, message string) (types.Struct, error)
+}
+
+// mutateFn computes the value that a mutation commits from the value of the
+// head, |v|, and the arguments of the mutation.
+type mutateFn func(vrw types.ValueReadWriter, v types.Value, args map[string]interface{}) (types.Value, error)
+
+// NewMutationObject creates a "mutation" object whose fields each commit a
+// change to the value of the head of the dataset that |c| commits to. Which
+// fields there are depends on the type of the value:
+//   - replaceHead(value) commits a new value, of the same type.
+//   - For a Map, setEntry(key, value) and deleteEntry(key).
+//   - For a List, insertElements(at, values) and removeElements(at, count).
+//   - For a Struct, setFields(fields), which sets the fields given.
+//
+// Every field also takes an optional message, put in the meta of the commit,
+// and an optional expectedHead, the hash of the commit that the head must be
+// for the mutation to be made. The fields resolve to the new head. Arguments
+// are GraphQL input types, in which a union is an input object with a field
+// for each of its types, so fields whose arguments would involve cyclic types
+// or empty unions are left out. NewMutationObject returns nil if there are no
+// fields at all.
+func (tc *TypeConverter) NewMutationObject(c Committer) *graphql.Object {
+	nomsType := types.TypeOf(c.Head().Get(commitValueField))
+	fields := graphql.Fields{}
+	addField := func(name string, args graphql.FieldConfigArgument, mutate mutateFn) {
+		fields[name] = tc.mutationField(c, args, mutate)
+	}
+
+	if valueType, err := tc.nomsTypeToGraphQLInputType(nomsType); err == nil {
+		addField(replaceHeadKey, graphql.FieldConfigArgument{
+			valueKey: &graphql.ArgumentConfig{Type: graphql.NewNonNull(valueType)},
+		}, func(vrw types.ValueReadWriter, v types.Value, args map[string]interface{}) (types.Value, error) {
+			return InputToNomsValue(vrw, args[valueKey], nomsType)
+		})
+	}
+
+	switch nomsType.TargetKind() {
+	case types.MapKind:
+		tc.addMapMutations(nomsType, addField)
+	case types.ListKind:
+		tc.addListMutations(nomsType, addField)
+	case types.StructKind:
+		tc.addStructMutations(nomsType, addField)
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:   mutationKey,
+		Fields: fields,
+	})
+}
+
+func (tc *TypeConverter) addMapMutations(nomsType *types.Type, addField func(string, graphql.FieldConfigArgument, mutateFn)) {
+	nomsKeyType := nomsType.Desc.(types.CompoundDesc).ElemTypes[0]
+	nomsValueType := nomsType.Desc.(types.CompoundDesc).ElemTypes[1]
+	keyType, err := tc.nomsTypeToGraphQLInputType(nomsKeyType)
+	if err != nil {
+		return
+	}
+
+	addField(deleteEntryKey, graphql.FieldConfigArgument{
+		keyKey: &graphql.ArgumentConfig{Type: graphql.NewNonNull(keyType)},
+	}, func(vrw types.ValueReadWriter, v types.Value, args map[string]interface{}) (types.Value, error) {
+		m, ok := v.(types.Map)
+		if !ok {
+			return nil, fmt.Errorf("Head value is a %s, not a Map", types.TypeOf(v).Describe())
+		}
+		k, err := InputToNomsValue(vrw, args[keyKey], nomsKeyType)
+		if err != nil {
+			return nil, err
+		}
+		return m.Edit().Remove(k).Map(), nil
+	})
+
+	valueType, err := tc.nomsTypeToGraphQLInputType(nomsValueType)
+	if err != nil {
+		return
+	}
+	addField(setEntryKey, graphql.FieldConfigArgument{
+		keyKey:   &graphql.ArgumentConfig{Type: graphql.NewNonNull(keyType)},
+		valueKey: &graphql.ArgumentConfig{Type: graphql.NewNonNull(valueType)},
+	}, func(vrw types.ValueReadWriter, v types.Value, args map[string]interface{}) (types.Value, error) {
+		m, ok := v.(types.Map)
+		if !ok {
+			return nil, fmt.Errorf("Head value is a %s, not a Map", types.TypeOf(v).Describe())
+		}
+		k, err := InputToNomsValue(vrw, args[keyKey], nomsKeyType)
+		if err != nil {
+			return nil, err
+		}
+		val, err := InputToNomsValue(vrw, args[valueKey], nomsValueType)
+		if err != nil {
+			return nil, err
+		}
+		return m.Edit().Set(k, val).Map(), nil
+	})
+}
+
+func (tc *TypeConverter) addListMutations(nomsType *types.Type, addField func(string, graphql.FieldConfigArgument, mutateFn)) {
+	nomsElemType := nomsType.Desc.(types.CompoundDesc).ElemTypes[0]
+
+	addField(removeElemsKey, graphql.FieldConfigArgument{
+		atKey:    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+		countKey: &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+	}, func(vrw types.ValueReadWriter, v types.Value, args map[string]interface{}) (types.Value, error) {
+		l, ok := v.(types.List)
+		if !ok {
+			return nil, fmt.Errorf("Head value is a %s, not a List", types.TypeOf(v).Describe())
+		}
+		at, count := args[atKey].(int), args[countKey].(int)
+		if at < 0 || count < 0 || uint64(at+count) > l.Len() {
+			return nil, fmt.Errorf("Cannot remove %d elements at %d from a List of length %d", count, at, l.Len())
+		}
+		return l.Edit().Remove(uint64(at), uint64(at+count)).List(), nil
+	})
+
+	elemType, err := tc.nomsTypeToGraphQLInputType(nomsElemType)
+	if err != nil {
+		return
+	}
+	addField(insertElemsKey, graphql.FieldConfigArgument{
+		// at defaults to the end of the List.
+		atKey:     &graphql.ArgumentConfig{Type: graphql.Int},
+		valuesKey: &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(elemType)))},
+	}, func(vrw types.ValueReadWriter, v types.Value, args map[string]interface{}) (types.Value, error) {
+		l, ok := v.(types.List)
+		if !ok {
+			return nil, fmt.Errorf("Head value is a %s, not a List", types.TypeOf(v).Describe())
+		}
+		at := l.Len()
+		if i, ok := args[atKey].(int); ok {
+			if i < 0 || uint64(i) > l.Len() {
+				return nil, fmt.Errorf("Cannot insert at %d in a List of length %d", i, l.Len())
+			}
+			at = uint64(i)
+		}
+		values := args[valuesKey].([]interface{})
+		elems := make([]types.Valuable, len(values))
+		for i, ev := range values {
+			elem, err := InputToNomsValue(vrw, ev, nomsElemType)
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = elem
+		}
+		return l.Edit().Insert(at, elems...).List(), nil
+	})
+}
+
+func (tc *TypeConverter) addStructMutations(nomsType *types.Type, addField func(string, graphql.FieldConfigArgument, mutateFn)) {
+	// Every field of the input object is optional, since only the fields
+	// given are set.
+	structDesc := nomsType.Desc.(types.StructDesc)
+	inputFields := graphql.InputObjectConfigFieldMap{}
+	structDesc.IterFields(func(name string, nomsFieldType *types.Type, optional bool) {
+		if fieldType, err := tc.nomsTypeToGraphQLInputType(nomsFieldType); err == nil {
+			inputFields[name] = &graphql.InputObjectFieldConfig{Type: fieldType}
+		}
+	})
+	if len(inputFields) == 0 {
+		return
+	}
+
+	addField(setFieldsKey, graphql.FieldConfigArgument{
+		fieldsKey: &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewInputObject(graphql.InputObjectConfig{
+			Name:   getTypeName(nomsType, "FieldsInput"),
+			Fields: inputFields,
+		}))},
+	}, func(vrw types.ValueReadWriter, v types.Value, args map[string]interface{}) (types.Value, error) {
+		s, ok := v.(types.Struct)
+		if !ok {
+			return nil, fmt.Errorf("Head value is a %s, not a Struct", types.TypeOf(v).Describe())
+		}
+		for name, fv := range args[fieldsKey].(map[string]interface{}) {
+			if fv == nil {
+				continue
+			}
+			nomsFieldType, _ := structDesc.Field(name)
+			fieldValue, err := InputToNomsValue(vrw, fv, nomsFieldType)
+			if err != nil {
+				return nil, err
+			}
+			s = s.Set(name, fieldValue)
+		}
+		return s, nil
+	})
+}
+
+// mutationField creates a field that commits the value computed by |mutate|.
+func (tc *TypeConverter) mutationField(c Committer, args graphql.FieldConfigArgument, mutate mutateFn) *graphql.Field {
+	args[messageKey] = &graphql.ArgumentConfig{Type: graphql.String}
+	args[expectedHeadKey] = &graphql.ArgumentConfig{Type: graphql.String}
+	return &graphql.Field{
+		Type: tc.mutationResultObject(),
+		Args: args,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			head := c.Head()
+			if expected, ok := p.Args[expectedHeadKey].(string); ok {
+				if strings.TrimPrefix(expected, "#") != head.Hash().String() {
+					return nil, fmt.Errorf("Head is #%s, not %s", head.Hash(), expected)
+				}
+			}
+			vrw := p.Context.Value(vrwKey).(types.ValueReadWriter)
+			v, err := mutate(vrw, head.Get(commitValueField), p.Args)
+			if err != nil {
+				return nil, err
+			}
+			message, _ := p.Args[messageKey].(string)
+			return c.Commit(v, message)
+		},
+	}
+}
+
+// mutationResultObject returns the type of the new head that mutations
+// resolve to.
+func (tc *TypeConverter) mutationResultObject() graphql.Type {
+	key := typeMapKey{mutationResult, false}
+	if t, ok := tc.tm[key]; ok {
+		return t
+	}
+	t := graphql.NewObject(graphql.ObjectConfig{
+		Name: mutationResult,
+		Fields: graphql.Fields{
+			hashKey: &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(types.Struct).Hash().String(), nil
+				},
+			},
+		},
+	})
+	tc.tm[key] = t
+	return t
+}
@@ -0,0 +1,163 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package ngql
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/attic-labs/noms/go/types"
+	"github.com/stretchr/testify/suite"
+)
+
+// testCommitter keeps its commits in memory. Each commit is a struct with the
+// value, the message and the previous commit.
+type testCommitter struct {
+	head types.Struct
+}
+
+func newTestCommitter(v types.Value) *testCommitter {
+	return &testCommitter{types.NewStruct("Commit", types.StructData{commitValueField: v})}
+}
+
+func (c *testCommitter) Head() types.Struct {
+	return c.head
+}
+
+func (c *testCommitter) Commit(value types.Value, message string) (types.Struct, error) {
+	c.head = types.NewStruct("Commit", types.StructData{
+		commitValueField: value,
+		"message":        types.String(message),
+		"parent":         c.head,
+	})
+	return c.head, nil
+}
+
+type MutationGraphQLSuite struct {
+	suite.Suite
+	vs *types.ValueStore
+}
+
+func TestMutationGraphQL(t *testing.T) {
+	suite.Run(t, &MutationGraphQLSuite{})
+}
+
+func (suite *MutationGraphQLSuite) SetupTest() {
+	suite.vs = newTestValueStore()
+}
+
+func (suite *MutationGraphQLSuite) query(c Committer, q string) string {
+	buf := &bytes.Buffer{}
+	QueryWithMutations(c, q, suite.vs, buf)
+	return buf.String()
+}
+
+func (suite *MutationGraphQLSuite) assertMutation(c *testCommitter, q string, expected types.Value) {
+	head := c.Head()
+	res := suite.query(c, q)
+	suite.NotEqual(head, c.Head(), res)
+	suite.Contains(res, c.Head().Hash().String())
+	suite.True(expected.Equals(c.Head().Get(commitValueField)), types.EncodedValue(c.Head().Get(commitValueField)))
+	suite.True(head.Equals(c.Head().Get("parent")))
+}
+
+func (suite *MutationGraphQLSuite) TestMap() {
+	m := types.NewMap(suite.vs, types.String("a"), types.Number(1))
+	c := newTestCommitter(m)
+
+	suite.assertMutation(c, `mutation {setEntry(key: "b", value: 2) {hash}}`,
+		types.NewMap(suite.vs, types.String("a"), types.Number(1), types.String("b"), types.Number(2)))
+	suite.assertMutation(c, `mutation {setEntry(key: "a", value: 3) {hash}}`,
+		types.NewMap(suite.vs, types.String("a"), types.Number(3), types.String("b"), types.Number(2)))
+	suite.assertMutation(c, `mutation {deleteEntry(key: "a") {hash}}`,
+		types.NewMap(suite.vs, types.String("b"), types.Number(2)))
+	suite.assertMutation(c, `mutation {replaceHead(value: [{key: "c", value: 4}]) {hash}}`,
+		types.NewMap(suite.vs, types.String("c"), types.Number(4)))
+}
+
+func (suite *MutationGraphQLSuite) TestList() {
+	n := func(ns ...float64) types.List {
+		vs := make(types.ValueSlice, len(ns))
+		for i, n := range ns {
+			vs[i] = types.Number(n)
+		}
+		return types.NewList(suite.vs, vs...)
+	}
+	c := newTestCommitter(n(0, 1))
+
+	suite.assertMutation(c, `mutation {insertElements(values: [2, 3]) {hash}}`, n(0, 1, 2, 3))
+	suite.assertMutation(c, `mutation {insertElements(at: 1, values: [4]) {hash}}`, n(0, 4, 1, 2, 3))
+	suite.assertMutation(c, `mutation {removeElements(at: 0) {hash}}`, n(4, 1, 2, 3))
+	suite.assertMutation(c, `mutation {removeElements(at: 1, count: 2) {hash}}`, n(4, 3))
+
+	head := c.Head()
+	suite.Contains(suite.query(c, `mutation {removeElements(at: 1, count: 2) {hash}}`), "Cannot remove 2 elements at 1 from a List of length 2")
+	suite.Contains(suite.query(c, `mutation {insertElements(at: 3, values: [1]) {hash}}`), "Cannot insert at 3 in a List of length 2")
+	suite.True(head.Equals(c.Head()))
+}
+
+func (suite *MutationGraphQLSuite) TestStruct() {
+	s := types.NewStruct("Foo", types.StructData{
+		"a": types.String("aaa"),
+		"b": types.Number(1),
+	})
+	c := newTestCommitter(s)
+
+	suite.assertMutation(c, `mutation {setFields(fields: {b: 2}) {hash}}`, s.Set("b", types.Number(2)))
+	suite.assertMutation(c, `mutation {setFields(fields: {a: "x", b: 3}) {hash}}`,
+		types.NewStruct("Foo", types.StructData{"a": types.String("x"), "b": types.Number(3)}))
+}
+
+func (suite *MutationGraphQLSuite) TestMalformedInput() {
+	c := newTestCommitter(types.NewStruct("Foo", types.StructData{
+		"b": types.NewBlob(suite.vs),
+		"t": types.NumberType,
+		"u": types.NewList(suite.vs, types.Number(1), types.String("a")),
+	}))
+	head := c.Head()
+
+	suite.Contains(suite.query(c, `mutation {setFields(fields: {b: "not base64"}) {hash}}`), "Invalid base64 for Blob")
+	suite.Contains(suite.query(c, `mutation {setFields(fields: {t: "List<"}) {hash}}`), `"errors"`)
+	suite.Contains(suite.query(c, `mutation {setFields(fields: {u: [{}]}) {hash}}`), "A member of the union must be given")
+	suite.Contains(suite.query(c, `mutation {setFields(fields: {u: [{Number: 1, String: "a"}]}) {hash}}`), "Only one member of a union can be given")
+	suite.True(head.Equals(c.Head()))
+
+	suite.assertMutation(c, `mutation {setFields(fields: {t: "String", u: [{String: "b"}]}) {hash}}`,
+		types.NewStruct("Foo", types.StructData{
+			"b": types.NewBlob(suite.vs),
+			"t": types.StringType,
+			"u": types.NewList(suite.vs, types.String("b")),
+		}))
+}
+
+func (suite *MutationGraphQLSuite) TestMessageAndExpectedHead() {
+	c := newTestCommitter(types.Number(1))
+	head := c.Head()
+
+	res := suite.query(c, fmt.Sprintf(`mutation {replaceHead(value: 2, message: "two", expectedHead: "#%s") {hash}}`, head.Hash()))
+	suite.JSONEq(fmt.Sprintf(`{"data":{"replaceHead":{"hash":"%s"}}}`, c.Head().Hash()), res)
+	suite.Equal(types.String("two"), c.Head().Get("message"))
+
+	// The head is no longer |head|.
+	res = suite.query(c, fmt.Sprintf(`mutation {replaceHead(value: 3, expectedHead: "%s") {hash}}`, head.Hash()))
+	suite.Contains(res, fmt.Sprintf("Head is #%s, not %s", c.Head().Hash(), head.Hash()))
+	suite.True(types.Number(2).Equals(c.Head().Get(commitValueField)))
+}
+
+func (suite *MutationGraphQLSuite) TestQueryRoot() {
+	c := newTestCommitter(types.String("aaa"))
+	suite.JSONEq(`{"data":{"root":{"value":"aaa"}}}`, suite.query(c, "{root{value}}"))
+}
+
+func (suite *MutationGraphQLSuite) TestNoMutations() {
+	// Cyclic types can't be GraphQL input types.
+	s := types.NewStruct("S", types.StructData{
+		"c": types.NewList(suite.vs, types.NewStruct("S", types.StructData{"c": types.NewList(suite.vs)})),
+	})
+	c := newTestCommitter(s)
+	suite.Nil(NewTypeConverter().NewMutationObject(c))
+	suite.Contains(suite.query(c, "{root{value{c{size}}}}"), `"size":1`)
+}
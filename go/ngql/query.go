@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"sync/atomic"
 
 	"github.com/attic-labs/graphql"
 	"github.com/attic-labs/graphql/gqlerrors"
@@ -17,18 +18,26 @@ import (
 
 const (
 	atKey          = "at"
+	base64Key      = "base64"
 	countKey       = "count"
+	descriptionKey = "description"
+	elemTypesKey   = "elemTypes"
 	elementsKey    = "elements"
 	entriesKey     = "entries"
 	keyKey         = "key"
 	keysKey        = "keys"
+	kindKey        = "kind"
+	optionalKey    = "optional"
 	rootKey        = "root"
 	rootQueryKey   = "Root"
 	scalarValue    = "scalarValue"
 	sizeKey        = "size"
 	targetHashKey  = "targetHash"
 	targetValueKey = "targetValue"
+	textKey        = "text"
 	throughKey     = "through"
+	typeKey        = "type"
+	typeFieldType  = "TypeField"
 	valueKey       = "value"
 	valuesKey      = "values"
 	vrwKey         = "vrw"
@@ -60,32 +69,59 @@ func (tc *TypeConverter) NewRootQueryObject(rootValue types.Value) *graphql.Obje
 }
 
 // NewContext creates a new context.Context with the extra data added to it
-// that is required by ngql.
+// that is required by ngql. Values read through the context are cached for as
+// long as it is used.
 func NewContext(vrw types.ValueReader) context.Context {
-	return context.WithValue(context.Background(), vrwKey, vrw)
+	return context.WithValue(context.Background(), vrwKey, newLoader(vrw))
 }
 
 // Query takes |rootValue|, builds a GraphQL scheme from rootValue.Type() and
-// executes |query| against it, encoding the result to |w|.
+// executes |query| against it, encoding the result to |w|. Results are cached
+// by the hash of |rootValue| and |query|.
 func Query(rootValue types.Value, query string, vrw types.ValueReadWriter, w io.Writer) {
-	schemaConfig := graphql.SchemaConfig{}
-	tc := NewTypeConverter()
-	queryWithSchemaConfig(rootValue, query, schemaConfig, vrw, tc, w)
+	cachedQuery(resultKey{valueSchema, rootValue.Hash(), query}, w, func(w io.Writer) bool {
+		schemaConfig := graphql.SchemaConfig{}
+		tc := NewTypeConverter()
+		return queryWithSchemaConfig(rootValue, query, schemaConfig, vrw, tc, w)
+	})
+}
+
+// QueryWithMutations is like Query, with the head of the dataset that |c|
+// commits to as the root value, but the schema also has mutations that commit
+// to the dataset. See NewMutationObject. The results of queries without
+// mutations are cached.
+func QueryWithMutations(c Committer, query string, vrw types.ValueReadWriter, w io.Writer) {
+	run := func(w io.Writer) bool {
+		tc := NewTypeConverter()
+		schemaConfig := graphql.SchemaConfig{Mutation: tc.NewMutationObject(c)}
+		return queryWithSchemaConfig(c.Head(), query, schemaConfig, vrw, tc, w)
+	}
+	if isMutation(query) {
+		atomic.AddUint64(&stats.Queries, 1)
+		run(w)
+		return
+	}
+	cachedQuery(resultKey{mutationSchema, c.Head().Hash(), query}, w, run)
 }
 
-func queryWithSchemaConfig(rootValue types.Value, query string, schemaConfig graphql.SchemaConfig, vrw types.ValueReadWriter, tc *TypeConverter, w io.Writer) {
+func queryWithSchemaConfig(rootValue types.Value, query string, schemaConfig graphql.SchemaConfig, vrw types.ValueReadWriter, tc *TypeConverter, w io.Writer) bool {
 	schemaConfig.Query = tc.NewRootQueryObject(rootValue)
 	schema, _ := graphql.NewSchema(schemaConfig)
-	ctx := NewContext(vrw)
+	return do(schema, query, vrw, w)
+}
 
+// do executes |query| against |schema|, encoding the result to |w|. It
+// returns false if the result has errors.
+func do(schema graphql.Schema, query string, vrw types.ValueReadWriter, w io.Writer) bool {
 	r := graphql.Do(graphql.Params{
 		Schema:        schema,
 		RequestString: query,
-		Context:       ctx,
+		Context:       NewContext(vrw),
 	})
 
 	err := json.NewEncoder(w).Encode(r)
 	d.PanicIfError(err)
+	return !r.HasErrors()
 }
 
 // Error writes an error as a GraphQL error to a writer.
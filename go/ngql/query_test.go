@@ -449,13 +449,63 @@ func (suite *QueryGraphQLSuite) TestNestedCollection() {
 		`{"data":{"root":{"values":[{"values":[{"entries":[{"key":40,"value":"bat"}]}]}]}}}`)
 }
 
-func (suite *QueryGraphQLSuite) TestLoFi() {
+func (suite *QueryGraphQLSuite) TestBlob() {
 	b := types.NewBlob(suite.vs, bytes.NewBufferString("I am a blob"))
 
-	suite.assertQueryResult(b, "{root}", `{"data":{"root":"0123456789abcdefghijklmnopqrstuv"}}`)
+	suite.assertQueryResult(b, "{root{hash size}}", `{"data":{"root":{"hash":"0123456789abcdefghijklmnopqrstuv","size":11}}}`)
+	suite.assertQueryResult(b, "{root{text base64}}", `{"data":{"root":{"text":"I am a blob","base64":"SSBhbSBhIGJsb2I="}}}`)
+	suite.assertQueryResult(b, "{root{text(at:2,count:2)}}", `{"data":{"root":{"text":"am"}}}`)
+	suite.assertQueryResult(b, "{root{text(at:5)}}", `{"data":{"root":{"text":"a blob"}}}`)
+	suite.assertQueryResult(b, "{root{text(at:5,count:100)}}", `{"data":{"root":{"text":"a blob"}}}`)
+	suite.assertQueryResult(b, "{root{text(at:100) base64(count:0)}}", `{"data":{"root":{"text":"","base64":""}}}`)
+}
+
+func (suite *QueryGraphQLSuite) TestType() {
+	suite.assertQueryResult(types.StringType, "{root{kind name description elemTypes{kind} fields{name}}}",
+		`{"data":{"root":{"kind":"String","name":null,"description":"String","elemTypes":[],"fields":[]}}}`)
+
+	t := types.MakeStructType("Foo",
+		types.StructField{Name: "a", Type: types.MakeListType(types.NumberType)},
+		types.StructField{Name: "b", Type: types.MakeUnionType(types.BoolType, types.StringType), Optional: true},
+	)
+	suite.assertQueryResult(t, "{root{kind name fields{name optional type{kind elemTypes{kind}}}}}",
+		`{"data":{"root":{"kind":"Struct","name":"Foo","fields":[
+			{"name":"a","optional":false,"type":{"kind":"List","elemTypes":[{"kind":"Number"}]}},
+			{"name":"b","optional":true,"type":{"kind":"Union","elemTypes":[{"kind":"Bool"},{"kind":"String"}]}}]}}}`)
+	suite.assertQueryResult(t, "{root{description}}", fmt.Sprintf(`{"data":{"root":{"description":%q}}}`, t.Describe()))
+}
+
+func (suite *QueryGraphQLSuite) TestValueKind() {
+	s := types.NewStruct("", types.StructData{"v": types.NewList(suite.vs, types.Number(1))})
+	t := types.MakeStructType("", types.StructField{Name: "v", Type: types.ValueType})
+	tc := NewTypeConverter()
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Root",
+			Fields: graphql.Fields{
+				"root": &graphql.Field{
+					Type: tc.NomsTypeToGraphQLType(t),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return s, nil
+					},
+				},
+			},
+		}),
+	})
+	suite.NoError(err)
+	r := graphql.Do(graphql.Params{Schema: schema, RequestString: "{root{v}}", Context: NewContext(suite.vs)})
+	suite.Equal(map[string]interface{}{"root": map[string]interface{}{"v": s.Get("v").Hash().String()}}, r.Data)
+}
 
-	t := types.StringType
-	suite.assertQueryResult(t, "{root}", `{"data":{"root":"0123456789abcdefghijklmnopqrstuv"}}`)
+func (suite *QueryGraphQLSuite) TestMapOfUnionOfScalars() {
+	m := types.NewMap(suite.vs,
+		types.String("a"), types.Number(1),
+		types.String("b"), types.String("two"),
+		types.String("c"), types.NewBlob(suite.vs, bytes.NewBufferString("three")),
+	)
+
+	suite.assertQueryResult(m, `{root{values{... on NumberValue{n: scalarValue} ... on StringValue{s: scalarValue} ... on Blob{text}}}}`,
+		`{"data":{"root":{"values":[{"n":1},{"s":"two"},{"text":"three"}]}}}`)
 }
 
 func (suite *QueryGraphQLSuite) TestError() {
@@ -1115,7 +1165,9 @@ func (suite *QueryGraphQLSuite) TestSetWithComplexKeys() {
 
 func (suite *QueryGraphQLSuite) TestInputToNomsValue() {
 	test := func(expected types.Value, val interface{}) {
-		suite.True(expected.Equals(InputToNomsValue(suite.vs, val, types.TypeOf(expected))))
+		v, err := InputToNomsValue(suite.vs, val, types.TypeOf(expected))
+		suite.NoError(err)
+		suite.True(expected.Equals(v))
 	}
 
 	test(types.Number(42), int(42))
@@ -1177,13 +1229,17 @@ func (suite *QueryGraphQLSuite) TestInputToNomsValue() {
 	val := map[string]interface{}{
 		"x": float64(42),
 	}
-	suite.Equal(expected, InputToNomsValue(suite.vs, val, expectedType))
+	v, err := InputToNomsValue(suite.vs, val, expectedType)
+	suite.NoError(err)
+	suite.Equal(expected, v)
 
 	val = map[string]interface{}{
 		"x": float64(42),
 		"a": nil,
 	}
-	suite.Equal(expected, InputToNomsValue(suite.vs, val, expectedType))
+	v, err = InputToNomsValue(suite.vs, val, expectedType)
+	suite.NoError(err)
+	suite.Equal(expected, v)
 
 	val = map[string]interface{}{
 		"x": nil,
@@ -1194,21 +1250,14 @@ func (suite *QueryGraphQLSuite) TestInputToNomsValue() {
 }
 
 func (suite *QueryGraphQLSuite) TestErrorsInInputType() {
-	ut := types.MakeUnionType(types.BoolType, types.NumberType)
-
 	test := func(t *types.Type) {
 		tm := NewTypeMap()
 		_, err := NomsTypeToGraphQLInputType(t, tm)
 		suite.Error(err)
 	}
 
-	test(ut)
-	test(types.MakeListType(ut))
-	test(types.MakeSetType(ut))
-	test(types.MakeMapType(ut, types.BoolType))
-	test(types.MakeMapType(types.BoolType, ut))
-	test(types.MakeMapType(ut, ut))
-	test(types.MakeStructTypeFromFields("", types.FieldMap{"u": ut}))
+	test(types.MakeUnionType())
+	test(types.MakeListType(types.MakeUnionType()))
 
 	test(types.MakeStructTypeFromFields("S", types.FieldMap{
 		"l": types.MakeListType(types.MakeCycleType("S")),
@@ -1219,6 +1268,47 @@ func (suite *QueryGraphQLSuite) TestErrorsInInputType() {
 	}))
 }
 
+func (suite *QueryGraphQLSuite) TestUnionInput() {
+	ut := types.MakeUnionType(types.BoolType, types.NumberType, types.MakeListType(types.StringType))
+	tm := NewTypeMap()
+	inType, err := NomsTypeToGraphQLInputType(types.MakeMapType(types.StringType, ut), tm)
+	suite.NoError(err)
+	suite.Equal("[StringBooleanOrNumberOrStringListInputEntryInput!]", inType.String())
+
+	test := func(expected types.Value, arg map[string]interface{}) {
+		v, err := InputToNomsValue(suite.vs, arg, ut)
+		suite.NoError(err)
+		suite.True(expected.Equals(v))
+	}
+	test(types.Number(42), map[string]interface{}{"Number": float64(42)})
+	test(types.Bool(true), map[string]interface{}{"Boolean": true, "Number": nil})
+	test(types.NewList(suite.vs, types.String("a")), map[string]interface{}{"StringList": []interface{}{"a"}})
+
+	_, err = InputToNomsValue(suite.vs, map[string]interface{}{}, ut)
+	suite.EqualError(err, "A member of the union must be given")
+	_, err = InputToNomsValue(suite.vs, map[string]interface{}{"Boolean": true, "Number": float64(1)}, ut)
+	suite.EqualError(err, "Only one member of a union can be given")
+}
+
+func (suite *QueryGraphQLSuite) TestBlobAndTypeInput() {
+	b, err := InputToNomsValue(suite.vs, "SSBhbSBhIGJsb2I=", types.BlobType)
+	suite.NoError(err)
+	suite.True(types.NewBlob(suite.vs, bytes.NewBufferString("I am a blob")).Equals(b))
+
+	t, err := InputToNomsValue(suite.vs, "Struct Foo {a: Map<String, Number>, b?: Bool}", types.TypeType)
+	suite.NoError(err)
+	expected := types.MakeStructType("Foo",
+		types.StructField{Name: "a", Type: types.MakeMapType(types.StringType, types.NumberType)},
+		types.StructField{Name: "b", Type: types.BoolType, Optional: true},
+	)
+	suite.True(expected.Equals(t))
+
+	_, err = InputToNomsValue(suite.vs, "List<", types.TypeType)
+	suite.Error(err)
+	_, err = InputToNomsValue(suite.vs, "not base64", types.BlobType)
+	suite.Error(err)
+}
+
 func (suite *QueryGraphQLSuite) TestVariables() {
 	test := func(rootValue types.Value, expected string, query string, vars map[string]interface{}) {
 		tc := NewTypeConverter()
@@ -1489,17 +1579,20 @@ func TestGetListElementsWithSet(t *testing.T) {
 	assert := assert.New(t)
 	vs := newTestValueStore()
 	v := types.NewSet(vs, types.Number(0), types.Number(1), types.Number(2))
-	r := getListElements(vs, v, map[string]interface{}{})
+	r, err := getListElements(vs, v, map[string]interface{}{})
+	assert.NoError(err)
 	assert.Equal([]interface{}{float64(0), float64(1), float64(2)}, r)
 
-	r = getListElements(vs, v, map[string]interface{}{
+	r, err = getListElements(vs, v, map[string]interface{}{
 		atKey: 1,
 	})
+	assert.NoError(err)
 	assert.Equal([]interface{}{float64(1), float64(2)}, r)
 
-	r = getListElements(vs, v, map[string]interface{}{
+	r, err = getListElements(vs, v, map[string]interface{}{
 		countKey: 2,
 	})
+	assert.NoError(err)
 	assert.Equal([]interface{}{float64(0), float64(1)}, r)
 }
 
@@ -0,0 +1,104 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package ngql
+
+import (
+	"bytes"
+	"io"
+	"sync/atomic"
+
+	"github.com/attic-labs/graphql/language/ast"
+	"github.com/attic-labs/graphql/language/parser"
+	"github.com/attic-labs/noms/go/d"
+	"github.com/attic-labs/noms/go/hash"
+	"github.com/attic-labs/noms/go/util/sizecache"
+)
+
+// resultCacheSize is the number of bytes of results that resultCache holds.
+const resultCacheSize = 1 << 26 // 64MB
+
+// The schemas that a resultKey can be for. The same query of the same root
+// has different results in each.
+const (
+	valueSchema = "value"
+	// The schema of a value with mutations of the dataset it's the head of.
+	mutationSchema = "mutation"
+	databaseSchema = "database"
+)
+
+// resultKey identifies the result of a query. Since the root of a query is
+// immutable, so is the result.
+type resultKey struct {
+	schema string
+	root   hash.Hash
+	query  string
+}
+
+// resultCache holds the encoded results of recent queries, by resultKey.
+var resultCache = sizecache.New(resultCacheSize)
+
+// QueryStats counts the work done by the queries this process has run.
+type QueryStats struct {
+	// Queries run, including those answered from the result cache.
+	Queries uint64
+	// Queries answered from the result cache.
+	ResultCacheHits uint64
+	// Values read one at a time.
+	ValuesRead uint64
+	// Batches of Values read together, and the Values read in them.
+	BatchedReads  uint64
+	BatchedValues uint64
+	// Reads of Values that the query had already read.
+	LoaderCacheHits uint64
+}
+
+var stats QueryStats
+
+// Stats returns the QueryStats of all queries run so far.
+func Stats() QueryStats {
+	return QueryStats{
+		Queries:         atomic.LoadUint64(&stats.Queries),
+		ResultCacheHits: atomic.LoadUint64(&stats.ResultCacheHits),
+		ValuesRead:      atomic.LoadUint64(&stats.ValuesRead),
+		BatchedReads:    atomic.LoadUint64(&stats.BatchedReads),
+		BatchedValues:   atomic.LoadUint64(&stats.BatchedValues),
+		LoaderCacheHits: atomic.LoadUint64(&stats.LoaderCacheHits),
+	}
+}
+
+// cachedQuery writes the result for |key| to |w|. It comes from the result
+// cache if possible and otherwise from |run|, which returns false if the
+// result it wrote has errors and so shouldn't be cached.
+func cachedQuery(key resultKey, w io.Writer, run func(w io.Writer) bool) {
+	atomic.AddUint64(&stats.Queries, 1)
+	if result, ok := resultCache.Get(key); ok {
+		atomic.AddUint64(&stats.ResultCacheHits, 1)
+		_, err := w.Write(result.([]byte))
+		d.PanicIfError(err)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if run(buf) {
+		resultCache.Add(key, uint64(buf.Len()+len(key.query)), buf.Bytes())
+	}
+	_, err := io.Copy(w, buf)
+	d.PanicIfError(err)
+}
+
+// isMutation returns true if |query| has a mutation operation, or can't be
+// parsed.
+func isMutation(query string) bool {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return true
+	}
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok && op.Operation == ast.OperationTypeMutation {
+			return true
+		}
+	}
+	return false
+}
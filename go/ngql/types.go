@@ -5,14 +5,17 @@
 package ngql
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
-
+	"io"
 	"strings"
 
 	"github.com/attic-labs/graphql"
 	"github.com/attic-labs/noms/go/d"
+	"github.com/attic-labs/noms/go/nomdl"
 	"github.com/attic-labs/noms/go/types"
 )
 
@@ -49,7 +52,7 @@ func (tc *TypeConverter) NomsTypeToGraphQLType(nomsType *types.Type) graphql.Typ
 }
 
 // NomsTypeToGraphQLInputType creates a GraphQL input type from a Noms type.
-// Input types may not be unions or cyclic structs. If we encounter those
+// Input types may not be cyclic structs or empty unions. If we encounter those
 // this returns an error.
 func (tc *TypeConverter) NomsTypeToGraphQLInputType(nomsType *types.Type) (graphql.Input, error) {
 	return tc.nomsTypeToGraphQLInputType(nomsType)
@@ -77,7 +80,8 @@ func NewTypeMap() *TypeMap {
 // There is some overlap here. Scalars are the same and List can be used in
 // both.
 // The significant difference is graphql.Object (output) vs graphql.InputObject
-// Input types cannot be unions and input object types cannot contain cycles.
+// Input types cannot be unions, so Noms unions are input as input objects,
+// and input object types cannot contain cycles.
 
 type graphQLTypeMode uint8
 
@@ -96,7 +100,7 @@ type getFieldFn func(v interface{}, fieldName string, ctx context.Context) types
 // When a field name is resolved, it may take key:value arguments. A
 // getSubvaluesFn handles returning one or more *noms* values whose presence is
 // indicated by the provided arguments.
-type getSubvaluesFn func(vrw types.ValueReadWriter, v types.Value, args map[string]interface{}) interface{}
+type getSubvaluesFn func(vrw types.ValueReadWriter, v types.Value, args map[string]interface{}) (interface{}, error)
 
 // GraphQL requires all memberTypes in a Union to be Structs, so when a noms
 // union contains a scalar, we represent it in that context as a "boxed" value.
@@ -179,9 +183,14 @@ func (tc *TypeConverter) nomsTypeToGraphQLType(nomsType *types.Type, boxedIfScal
 	case types.UnionKind:
 		gqlType = tc.unionToGQLUnion(nomsType)
 
-	case types.BlobKind, types.ValueKind, types.TypeKind:
-		// TODO: https://github.com/attic-labs/noms/issues/3155
-		gqlType = graphql.String
+	case types.BlobKind:
+		gqlType = tc.blobToGraphQLObject(nomsType)
+
+	case types.TypeKind:
+		gqlType = tc.typeToGraphQLObject(nomsType)
+
+	case types.ValueKind:
+		gqlType = valueScalar
 
 	case types.CycleKind:
 		panic("not reached") // we should never attempt to create a schema for any unresolved cycle
@@ -195,7 +204,7 @@ func (tc *TypeConverter) nomsTypeToGraphQLType(nomsType *types.Type, boxedIfScal
 }
 
 // NomsTypeToGraphQLInputType creates a GraphQL input type from a Noms type.
-// Input types may not be unions or cyclic structs. If we encounter those
+// Input types may not be cyclic structs or empty unions. If we encounter those
 // this returns an error.
 func NomsTypeToGraphQLInputType(nomsType *types.Type, tm *TypeMap) (graphql.Input, error) {
 	tc := TypeConverter{*tm, DefaultNameFunc}
@@ -239,9 +248,17 @@ func (tc *TypeConverter) nomsTypeToGraphQLInputType(nomsType *types.Type) (graph
 		gqlType = graphql.String
 
 	case types.UnionKind:
-		return nil, errors.New("GraphQL input type cannot contain unions")
+		gqlType, err = tc.unionToGQLInputObject(nomsType)
+
+	case types.BlobKind:
+		// Blobs are given as base64.
+		gqlType = graphql.String
+
+	case types.TypeKind:
+		// Types are given in the syntax of Type.Describe.
+		gqlType = graphql.String
 
-	case types.BlobKind, types.ValueKind, types.TypeKind:
+	case types.ValueKind:
 		// TODO: https://github.com/attic-labs/noms/issues/3155
 		gqlType = graphql.String
 
@@ -404,7 +421,7 @@ var listArgs = graphql.FieldConfigArgument{
 	countKey: &graphql.ArgumentConfig{Type: graphql.Int},
 }
 
-func getListElements(vrw types.ValueReadWriter, v types.Value, args map[string]interface{}) interface{} {
+func getListElements(vrw types.ValueReadWriter, v types.Value, args map[string]interface{}) (interface{}, error) {
 	l := v.(types.Collection)
 	idx := 0
 	count := int(l.Len())
@@ -420,7 +437,7 @@ func getListElements(vrw types.ValueReadWriter, v types.Value, args map[string]i
 
 	// Clamp ranges
 	if count <= 0 || idx >= end {
-		return ([]interface{})(nil)
+		return ([]interface{})(nil), nil
 	}
 	if idx < 0 {
 		idx = 0
@@ -452,13 +469,13 @@ func getListElements(vrw types.ValueReadWriter, v types.Value, args map[string]i
 		}
 	}
 
-	return values
+	return values, nil
 }
 
-func getSetElements(vrw types.ValueReadWriter, v types.Value, args map[string]interface{}) interface{} {
+func getSetElements(vrw types.ValueReadWriter, v types.Value, args map[string]interface{}) (interface{}, error) {
 	s := v.(types.Set)
 
-	iter, nomsKey, nomsThrough, count, singleExactMatch := getCollectionArgs(vrw, s, args, iteratorFactory{
+	iter, nomsKey, nomsThrough, count, singleExactMatch, err := getCollectionArgs(vrw, s, args, iteratorFactory{
 		IteratorFrom: func(from types.Value) interface{} {
 			return s.IteratorFrom(from)
 		},
@@ -469,9 +486,12 @@ func getSetElements(vrw types.ValueReadWriter, v types.Value, args map[string]in
 			return &setFirstIterator{s: s}
 		},
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	if count == 0 {
-		return ([]interface{})(nil)
+		return ([]interface{})(nil), nil
 	}
 
 	setIter := iter.(types.SetIterator)
@@ -499,10 +519,10 @@ func getSetElements(vrw types.ValueReadWriter, v types.Value, args map[string]in
 		}
 	}
 
-	return values
+	return values, nil
 }
 
-func getCollectionArgs(vrw types.ValueReadWriter, col types.Collection, args map[string]interface{}, factory iteratorFactory) (iter interface{}, nomsKey, nomsThrough types.Value, count uint64, singleExactMatch bool) {
+func getCollectionArgs(vrw types.ValueReadWriter, col types.Collection, args map[string]interface{}, factory iteratorFactory) (iter interface{}, nomsKey, nomsThrough types.Value, count uint64, singleExactMatch bool, err error) {
 	typ := types.TypeOf(col)
 	length := col.Len()
 	nomsKeyType := typ.Desc.(types.CompoundDesc).ElemTypes[0]
@@ -511,9 +531,10 @@ func getCollectionArgs(vrw types.ValueReadWriter, col types.Collection, args map
 		slice := keys.([]interface{})
 		nomsKeys := make(types.ValueSlice, len(slice))
 		for i, v := range slice {
-			var nomsValue types.Value
-			nomsValue = InputToNomsValue(vrw, v, nomsKeyType)
-			nomsKeys[i] = nomsValue
+			nomsKeys[i], err = InputToNomsValue(vrw, v, nomsKeyType)
+			if err != nil {
+				return
+			}
 		}
 		count = uint64(len(slice))
 		iter = &mapIteratorForKeys{
@@ -523,12 +544,18 @@ func getCollectionArgs(vrw types.ValueReadWriter, col types.Collection, args map
 		return
 	}
 
-	nomsThrough = getThroughArg(vrw, nomsKeyType, args)
+	nomsThrough, err = getThroughArg(vrw, nomsKeyType, args)
+	if err != nil {
+		return
+	}
 
 	count, singleExactMatch = getCountArg(length, args)
 
 	if key, ok := args[keyKey]; ok {
-		nomsKey = InputToNomsValue(vrw, key, nomsKeyType)
+		nomsKey, err = InputToNomsValue(vrw, key, nomsKeyType)
+		if err != nil {
+			return
+		}
 		iter = factory.IteratorFrom(nomsKey)
 	} else if at, ok := args[atKey]; ok {
 		idx := at.(int)
@@ -554,7 +581,7 @@ type mapAppender func(slice []interface{}, k, v types.Value) []interface{}
 func getMapElements(vrw types.ValueReadWriter, v types.Value, args map[string]interface{}, app mapAppender) (interface{}, error) {
 	m := v.(types.Map)
 
-	iter, nomsKey, nomsThrough, count, singleExactMatch := getCollectionArgs(vrw, m, args, iteratorFactory{
+	iter, nomsKey, nomsThrough, count, singleExactMatch, err := getCollectionArgs(vrw, m, args, iteratorFactory{
 		IteratorFrom: func(from types.Value) interface{} {
 			return m.IteratorFrom(from)
 		},
@@ -565,6 +592,9 @@ func getMapElements(vrw types.ValueReadWriter, v types.Value, args map[string]in
 			return &mapFirstIterator{m: m}
 		},
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	if count == 0 {
 		return ([]interface{})(nil), nil
@@ -617,11 +647,11 @@ func getCountArg(count uint64, args map[string]interface{}) (c uint64, singleExa
 	return count, false
 }
 
-func getThroughArg(vrw types.ValueReadWriter, nomsKeyType *types.Type, args map[string]interface{}) types.Value {
+func getThroughArg(vrw types.ValueReadWriter, nomsKeyType *types.Type, args map[string]interface{}) (types.Value, error) {
 	if through, ok := args[throughKey]; ok {
 		return InputToNomsValue(vrw, through, nomsKeyType)
 	}
-	return nil
+	return nil, nil
 }
 
 type iteratorFactory struct {
@@ -825,7 +855,12 @@ func (tc *TypeConverter) listAndSetToGraphQLObject(nomsType *types.Type) *graphq
 					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 						c := p.Source.(types.Collection)
 						vrw := p.Context.Value(vrwKey).(types.ValueReadWriter)
-						return getSubvalues(vrw, c, p.Args), nil
+						values, err := getSubvalues(vrw, c, p.Args)
+						if err != nil {
+							return nil, err
+						}
+						prefetchTargets(p, values)
+						return values, nil
 					},
 				}
 				fields[valuesKey] = valuesField
@@ -869,7 +904,9 @@ func (tc *TypeConverter) mapToGraphQLObject(nomsType *types.Type) *graphql.Objec
 					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 						c := p.Source.(types.Collection)
 						vrw := p.Context.Value(vrwKey).(types.ValueReadWriter)
-						return getMapElements(vrw, c, p.Args, mapAppendEntry)
+						values, err := getMapElements(vrw, c, p.Args, mapAppendEntry)
+						prefetchTargets(p, values)
+						return values, err
 					},
 				}
 				fields[entriesKey] = entriesField
@@ -881,7 +918,9 @@ func (tc *TypeConverter) mapToGraphQLObject(nomsType *types.Type) *graphql.Objec
 					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 						c := p.Source.(types.Collection)
 						vrw := p.Context.Value(vrwKey).(types.ValueReadWriter)
-						return getMapElements(vrw, c, p.Args, mapAppendKey)
+						values, err := getMapElements(vrw, c, p.Args, mapAppendKey)
+						prefetchTargets(p, values)
+						return values, err
 					},
 				}
 				fields[valuesKey] = &graphql.Field{
@@ -890,7 +929,9 @@ func (tc *TypeConverter) mapToGraphQLObject(nomsType *types.Type) *graphql.Objec
 					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 						c := p.Source.(types.Collection)
 						vrw := p.Context.Value(vrwKey).(types.ValueReadWriter)
-						return getMapElements(vrw, c, p.Args, mapAppendValue)
+						values, err := getMapElements(vrw, c, p.Args, mapAppendValue)
+						prefetchTargets(p, values)
+						return values, err
 					},
 				}
 			}
@@ -946,6 +987,236 @@ func (tc *TypeConverter) refToGraphQLObject(nomsType *types.Type) *graphql.Objec
 	})
 }
 
+// valueScalar is the type of Values whose type is just Value. Bools, Numbers
+// and Strings are given as themselves and other Values as their hash.
+var valueScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name: "Value",
+	Serialize: func(value interface{}) interface{} {
+		if v, ok := value.(types.Value); ok {
+			return v.Hash().String()
+		}
+		return value
+	},
+})
+
+// Blobs are represented as structs:
+//
+// type Blob {
+//	 hash: String!
+//	 size: Float!
+//	 base64(at: Int, count: Int): String!
+//	 text(at: Int, count: Int): String!
+// }
+//
+// base64 and text read |count| bytes starting at |at|, or to the end of the
+// Blob if |count| isn't given, as base64 and as UTF-8 text respectively.
+func (tc *TypeConverter) blobToGraphQLObject(nomsType *types.Type) *graphql.Object {
+	bytesField := func(encode func([]byte) string) *graphql.Field {
+		return &graphql.Field{
+			Type: graphql.NewNonNull(graphql.String),
+			Args: listArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				b, err := readBlob(p.Source.(types.Blob), p.Args)
+				if err != nil {
+					return nil, err
+				}
+				return encode(b), nil
+			},
+		}
+	}
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: tc.getTypeName(nomsType),
+		Fields: graphql.Fields{
+			hashKey: &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(types.Blob).Hash().String(), nil
+				},
+			},
+			sizeKey: &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Float),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return float64(p.Source.(types.Blob).Len()), nil
+				},
+			},
+			base64Key: bytesField(base64.StdEncoding.EncodeToString),
+			textKey: bytesField(func(b []byte) string {
+				return string(b)
+			}),
+		},
+	})
+}
+
+// readBlob reads the range of |b| given by the at and count arguments in
+// |args|, which are clamped like those of Lists.
+func readBlob(b types.Blob, args map[string]interface{}) ([]byte, error) {
+	at, end := int64(0), int64(b.Len())
+	if a, ok := args[atKey].(int); ok && a > 0 {
+		at = a64(a, end)
+	}
+	count := end - at
+	if c, ok := args[countKey].(int); ok {
+		if c <= 0 {
+			return []byte{}, nil
+		}
+		if int64(c) < count {
+			count = int64(c)
+		}
+	}
+	buf := make([]byte, count)
+	n, err := b.ReadAt(buf, at)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// a64 returns |a| as an int64 no greater than |max|.
+func a64(a int, max int64) int64 {
+	if int64(a) > max {
+		return max
+	}
+	return int64(a)
+}
+
+// Types are represented as structs that describe their structure:
+//
+// type Type {
+//	 hash: String!
+//	 kind: String!
+//	 name: String
+//	 description: String!
+//	 elemTypes: [Type!]!
+//	 fields: [TypeField!]!
+// }
+//
+// type TypeField {
+//	 name: String!
+//	 type: Type!
+//	 optional: Boolean!
+// }
+//
+// kind is the name of the NomsKind, e.g. "Struct". name is the name of a
+// Struct or a Cycle. elemTypes are those of a List, Set, Ref, Map or Union,
+// and fields those of a Struct. description is the Type in the syntax of
+// Type.Describe.
+func (tc *TypeConverter) typeToGraphQLObject(nomsType *types.Type) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: tc.getTypeName(nomsType),
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			typeType := graphql.NewNonNull(tc.nomsTypeToGraphQLType(nomsType, false))
+			fieldType := graphql.NewObject(graphql.ObjectConfig{
+				Name: typeFieldType,
+				Fields: graphql.Fields{
+					nameKey: &graphql.Field{
+						Type: graphql.NewNonNull(graphql.String),
+						Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+							return p.Source.(types.StructField).Name, nil
+						},
+					},
+					typeKey: &graphql.Field{
+						Type: typeType,
+						Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+							return p.Source.(types.StructField).Type, nil
+						},
+					},
+					optionalKey: &graphql.Field{
+						Type: graphql.NewNonNull(graphql.Boolean),
+						Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+							return p.Source.(types.StructField).Optional, nil
+						},
+					},
+				},
+			})
+
+			return graphql.Fields{
+				hashKey: &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(*types.Type).Hash().String(), nil
+					},
+				},
+				kindKey: &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(*types.Type).TargetKind().String(), nil
+					},
+				},
+				nameKey: &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						switch desc := p.Source.(*types.Type).Desc.(type) {
+						case types.StructDesc:
+							return desc.Name, nil
+						case types.CycleDesc:
+							return string(desc), nil
+						}
+						return nil, nil
+					},
+				},
+				descriptionKey: &graphql.Field{
+					Type: graphql.NewNonNull(graphql.String),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return p.Source.(*types.Type).Describe(), nil
+					},
+				},
+				elemTypesKey: &graphql.Field{
+					Type: graphql.NewNonNull(graphql.NewList(typeType)),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						elemTypes := []interface{}{}
+						if desc, ok := p.Source.(*types.Type).Desc.(types.CompoundDesc); ok {
+							for _, t := range desc.ElemTypes {
+								elemTypes = append(elemTypes, t)
+							}
+						}
+						return elemTypes, nil
+					},
+				},
+				fieldsKey: &graphql.Field{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(fieldType))),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						fields := []interface{}{}
+						if desc, ok := p.Source.(*types.Type).Desc.(types.StructDesc); ok {
+							desc.IterFields(func(name string, t *types.Type, optional bool) {
+								fields = append(fields, types.StructField{Name: name, Type: t, Optional: optional})
+							})
+						}
+						return fields, nil
+					},
+				},
+			}
+		}),
+	})
+}
+
+// Unions are input as an input object with an optional field for each member
+// type, named for the type, exactly one of which must be given, e.g.
+//
+// input NumberOrStringInput {
+//	 Number: Float
+//	 String: String
+// }
+func (tc *TypeConverter) unionToGQLInputObject(nomsType *types.Type) (graphql.Input, error) {
+	nomsMemberTypes := nomsType.Desc.(types.CompoundDesc).ElemTypes
+	if len(nomsMemberTypes) == 0 {
+		return nil, errors.New("GraphQL input type cannot contain empty unions")
+	}
+
+	fields := make(graphql.InputObjectConfigFieldMap, len(nomsMemberTypes))
+	for _, t := range nomsMemberTypes {
+		fieldType, err := tc.nomsTypeToGraphQLInputType(t)
+		if err != nil {
+			return nil, err
+		}
+		fields[GetTypeName(t)] = &graphql.InputObjectFieldConfig{Type: fieldType}
+	}
+	return graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:   tc.getInputTypeName(nomsType),
+		Fields: fields,
+	}), nil
+}
+
 func MaybeGetScalar(v types.Value) interface{} {
 	switch v.(type) {
 	case types.Bool:
@@ -954,38 +1225,41 @@ func MaybeGetScalar(v types.Value) interface{} {
 		return float64(v.(types.Number))
 	case types.String:
 		return string(v.(types.String))
-	case *types.Type, types.Blob:
-		// TODO: https://github.com/attic-labs/noms/issues/3155
-		return v.Hash()
 	}
 
 	return v
 }
 
 // InputToNomsValue converts a GraphQL input value (as used in arguments and
-// variables) to a Noms value.
-func InputToNomsValue(vrw types.ValueReadWriter, arg interface{}, nomsType *types.Type) types.Value {
+// variables) to a Noms value. GraphQL checks that |arg| is of the input type
+// of |nomsType|, but Blobs, Types and unions can still be malformed, in which
+// case an error is returned.
+func InputToNomsValue(vrw types.ValueReadWriter, arg interface{}, nomsType *types.Type) (types.Value, error) {
 	switch nomsType.TargetKind() {
 	case types.BoolKind:
-		return types.Bool(arg.(bool))
+		return types.Bool(arg.(bool)), nil
 	case types.NumberKind:
 		if i, ok := arg.(int); ok {
-			return types.Number(i)
+			return types.Number(i), nil
 		}
-		return types.Number(arg.(float64))
+		return types.Number(arg.(float64)), nil
 	case types.StringKind:
-		return types.String(arg.(string))
+		return types.String(arg.(string)), nil
 	case types.ListKind, types.SetKind:
 		elemType := nomsType.Desc.(types.CompoundDesc).ElemTypes[0]
 		sl := arg.([]interface{})
 		vs := make(types.ValueSlice, len(sl))
 		for i, v := range sl {
-			vs[i] = InputToNomsValue(vrw, v, elemType)
+			var err error
+			vs[i], err = InputToNomsValue(vrw, v, elemType)
+			if err != nil {
+				return nil, err
+			}
 		}
 		if nomsType.TargetKind() == types.ListKind {
-			return types.NewList(vrw, vs...)
+			return types.NewList(vrw, vs...), nil
 		}
-		return types.NewSet(vrw, vs...)
+		return types.NewSet(vrw, vs...), nil
 	case types.MapKind:
 		// Maps are passed as [{key: K, value: V}, ...]
 		keyType := nomsType.Desc.(types.CompoundDesc).ElemTypes[0]
@@ -994,20 +1268,62 @@ func InputToNomsValue(vrw types.ValueReadWriter, arg interface{}, nomsType *type
 		kvs := make(types.ValueSlice, 2*len(sl))
 		for i, v := range sl {
 			v := v.(map[string]interface{})
-			kvs[2*i] = InputToNomsValue(vrw, v["key"], keyType)
-			kvs[2*i+1] = InputToNomsValue(vrw, v["value"], valType)
+			var err error
+			kvs[2*i], err = InputToNomsValue(vrw, v["key"], keyType)
+			if err != nil {
+				return nil, err
+			}
+			kvs[2*i+1], err = InputToNomsValue(vrw, v["value"], valType)
+			if err != nil {
+				return nil, err
+			}
 		}
-		return types.NewMap(vrw, kvs...)
+		return types.NewMap(vrw, kvs...), nil
 	case types.StructKind:
 		desc := nomsType.Desc.(types.StructDesc)
 		data := make(types.StructData, desc.Len())
 		m := arg.(map[string]interface{})
+		var err error
 		desc.IterFields(func(name string, t *types.Type, optional bool) {
-			if m[name] != nil || !optional {
-				data[name] = InputToNomsValue(vrw, m[name], t)
+			if err == nil && (m[name] != nil || !optional) {
+				data[name], err = InputToNomsValue(vrw, m[name], t)
 			}
 		})
-		return types.NewStruct(desc.Name, data)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewStruct(desc.Name, data), nil
+	case types.BlobKind:
+		b, err := base64.StdEncoding.DecodeString(arg.(string))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid base64 for Blob: %s", err)
+		}
+		return types.NewBlob(vrw, bytes.NewReader(b)), nil
+	case types.TypeKind:
+		t, err := nomdl.ParseType(arg.(string))
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	case types.UnionKind:
+		m := arg.(map[string]interface{})
+		var v types.Value
+		for _, t := range nomsType.Desc.(types.CompoundDesc).ElemTypes {
+			if a := m[GetTypeName(t)]; a != nil {
+				if v != nil {
+					return nil, errors.New("Only one member of a union can be given")
+				}
+				var err error
+				v, err = InputToNomsValue(vrw, a, t)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		if v == nil {
+			return nil, errors.New("A member of the union must be given")
+		}
+		return v, nil
 	}
 	panic("not yet implemented")
 }
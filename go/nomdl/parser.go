@@ -20,6 +20,10 @@ import (
 type Parser struct {
 	lex *lexer
 	vrw types.ValueReadWriter
+	// resolve, if set, is called with the names of types that aren't one of
+	// the built in types. It is used by schema files to refer to declared
+	// types.
+	resolve func(name string, pos scanner.Position) *types.Type
 }
 
 // ParserOptions allows passing options into New.
@@ -36,7 +40,7 @@ func New(vrw types.ValueReadWriter, r io.Reader, options ParserOptions) *Parser
 	s.Mode = scanner.ScanIdents | scanner.ScanComments | scanner.SkipComments | scanner.ScanFloats | scanner.ScanStrings // | scanner.ScanRawStrings
 	s.Error = func(s *scanner.Scanner, msg string) {}
 	lex := lexer{scanner: &s}
-	return &Parser{&lex, vrw}
+	return &Parser{lex: &lex, vrw: vrw}
 }
 
 // ParseType parses a string describing a Noms type.
@@ -188,6 +192,9 @@ func (p *Parser) parseSingleTypeWithToken(tok rune, tokenText string) *types.Typ
 		return p.parseCycleType()
 	}
 
+	if tok == scanner.Ident && p.resolve != nil {
+		return p.resolve(tokenText, p.lex.pos())
+	}
 	p.lex.unexpectedToken(tok)
 	return nil
 }
@@ -0,0 +1,355 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package nomdl
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/scanner"
+
+	"github.com/attic-labs/noms/go/d"
+	"github.com/attic-labs/noms/go/types"
+)
+
+// Schema is a set of named Noms types, declared in a schema file (by
+// convention with a .noms extension). For example:
+//
+//   import "address.noms"
+//
+//   // A Person has an Address, which is declared in address.noms.
+//   type Person = Struct Person {
+//     name: String,
+//     address: Address,
+//     friends: Set<Person>,
+//   }
+//
+//   type People = List<Person>
+//
+// Declared types can be referred to by name, before or after their
+// declaration, and a named struct type can refer to itself. Imported files
+// are resolved relative to the directory of the importing file, and their
+// declarations can be referred to as if they were in the importing file.
+// Imports are not transitive, and declarations in a file take precedence over
+// imported ones.
+type Schema struct {
+	// Filename is the name of the file the schema was parsed from.
+	Filename string
+
+	src     string
+	names   []string
+	decls   map[string]*typeDecl
+	types   map[string]*types.Type
+	imports []*Schema
+}
+
+type typeDecl struct {
+	name   string
+	offset int // of the declared type in the source
+	// structName is the name of the struct type the declaration is of, if it
+	// is a named struct type.
+	structName string
+	// aliasOf is the name of the type the declaration refers to, if it's just
+	// the name of another declared type.
+	aliasOf string
+}
+
+// SchemaFile :
+//   Import* TypeDecl*
+//
+// Import :
+//   `import` String
+//
+// TypeDecl :
+//   `type` TypeName `=` Type
+//
+// TypeName :
+//   Ident
+//
+// Within a schema file, TypeWithoutUnion can also be a TypeName.
+
+// ParseSchemaFile parses the schema file |filename|, and the schema files it
+// imports.
+func ParseSchemaFile(filename string) (*Schema, error) {
+	return newSchemaLoader().loadFile(filename)
+}
+
+// ParseSchema parses a schema from |r|. |filename| is used in errors and to
+// resolve the schema files it imports.
+func ParseSchema(filename string, r io.Reader) (*Schema, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	l := newSchemaLoader()
+	var s *Schema
+	err = catchSyntaxError(func() {
+		s = l.loadData(filename, string(data), scanner.Position{})
+	})
+	return s, err
+}
+
+// ParseSchemaType returns the type declared in a schema file by |spec|, which
+// is of the form <file>:<type name>.
+func ParseSchemaType(spec string) (*types.Type, error) {
+	i := strings.LastIndex(spec, ":")
+	if i == -1 {
+		return nil, fmt.Errorf("Invalid schema type %s, expected <file>:<type name>", spec)
+	}
+	s, err := ParseSchemaFile(spec[:i])
+	if err != nil {
+		return nil, err
+	}
+	return s.Type(spec[i+1:])
+}
+
+// Names returns the names of the types declared in the schema file, in the
+// order they are declared. Imported types are not included.
+func (s *Schema) Names() []string {
+	return s.names
+}
+
+// Type returns the type declared as |name|, in the schema file or one of the
+// files it imports.
+func (s *Schema) Type(name string) (t *types.Type, err error) {
+	err = catchSyntaxError(func() {
+		t = s.lookup(name, scanner.Position{Filename: s.Filename})
+	})
+	return
+}
+
+type schemaLoader struct {
+	schemas map[string]*Schema
+	loading map[string]bool
+}
+
+func newSchemaLoader() *schemaLoader {
+	return &schemaLoader{map[string]*Schema{}, map[string]bool{}}
+}
+
+func (l *schemaLoader) loadFile(filename string) (s *Schema, err error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	err = catchSyntaxError(func() {
+		s = l.loadData(filename, string(data), scanner.Position{})
+	})
+	return
+}
+
+// load parses the schema file |filename|, which is imported at |pos|. Each
+// file is only parsed once, no matter how many times it is imported.
+func (l *schemaLoader) load(filename string, pos scanner.Position) *Schema {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		raiseSyntaxError(err.Error(), pos)
+	}
+	return l.loadData(filename, string(data), pos)
+}
+
+func (l *schemaLoader) loadData(filename, src string, pos scanner.Position) *Schema {
+	abs, err := filepath.Abs(filename)
+	d.PanicIfError(err)
+	if s, ok := l.schemas[abs]; ok {
+		return s
+	}
+	if l.loading[abs] {
+		raiseSyntaxError(fmt.Sprintf("Import cycle, %s imports itself", filename), pos)
+	}
+
+	l.loading[abs] = true
+	s := l.parse(filename, src)
+	delete(l.loading, abs)
+	l.schemas[abs] = s
+	return s
+}
+
+func (l *schemaLoader) parse(filename, src string) *Schema {
+	s := &Schema{
+		Filename: filename,
+		src:      src,
+		decls:    map[string]*typeDecl{},
+		types:    map[string]*types.Type{},
+	}
+
+	// The first pass finds the imports and declarations. The types are
+	// parsed again once all the names they can refer to are known.
+	p := New(nil, strings.NewReader(src), ParserOptions{Filename: filename})
+	for {
+		tok := p.lex.next()
+		if tok == scanner.EOF {
+			break
+		}
+		p.lex.check(scanner.Ident, tok)
+		switch p.lex.tokenText() {
+		case "import":
+			if len(s.names) > 0 {
+				raiseSyntaxError("Imports must come before type declarations", p.lex.pos())
+			}
+			s.imports = append(s.imports, l.parseImport(p, filename))
+		case "type":
+			s.parseTypeDecl(p)
+		default:
+			p.lex.unexpectedToken(tok)
+		}
+	}
+
+	for _, name := range s.names {
+		s.types[name] = s.resolveDecl(s.decls[name], nil)
+	}
+	return s
+}
+
+func (l *schemaLoader) parseImport(p *Parser, filename string) *Schema {
+	p.lex.eat(scanner.String)
+	path, err := strconv.Unquote(p.lex.tokenText())
+	if err != nil {
+		raiseSyntaxError(fmt.Sprintf("Invalid string %s", p.lex.tokenText()), p.lex.pos())
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(filename), path)
+	}
+	return l.load(path, p.lex.pos())
+}
+
+func (s *Schema) parseTypeDecl(p *Parser) {
+	p.lex.eat(scanner.Ident)
+	name := p.lex.tokenText()
+	if isBuiltinTypeName(name) {
+		raiseSyntaxError(fmt.Sprintf("Cannot declare built in type %s", name), p.lex.pos())
+	}
+	if _, ok := s.decls[name]; ok {
+		raiseSyntaxError(fmt.Sprintf("Type %s is already declared", name), p.lex.pos())
+	}
+	p.lex.eat('=')
+
+	p.lex.peek()
+	decl := &typeDecl{name: name, offset: p.lex.scanner.Position.Offset}
+
+	// Names can't be resolved yet, so parse the type with placeholders just
+	// to find where it ends and whether it's a struct or an alias.
+	var alias *types.Type
+	p.resolve = func(name string, pos scanner.Position) *types.Type {
+		decl.aliasOf = name
+		alias = types.MakeCycleType(name)
+		return alias
+	}
+	t := p.parseType()
+	p.resolve = nil
+
+	if t != alias {
+		decl.aliasOf = ""
+	}
+	if desc, ok := t.Desc.(types.StructDesc); ok {
+		decl.structName = desc.Name
+	}
+	s.names = append(s.names, name)
+	s.decls[name] = decl
+}
+
+// resolveDecl parses the type of |decl|, resolving the names it refers to.
+// |stack| holds the declarations that are being resolved, which |decl| is
+// nested in.
+func (s *Schema) resolveDecl(decl *typeDecl, stack []*typeDecl) *types.Type {
+	// Only types resolved outside of any other declaration are complete.
+	if t, ok := s.types[decl.name]; ok {
+		return t
+	}
+
+	p := New(nil, strings.NewReader(blankPrefix(s.src, decl.offset)), ParserOptions{Filename: s.Filename})
+	stack = append(stack, decl)
+	p.resolve = func(name string, pos scanner.Position) *types.Type {
+		return s.resolveName(name, pos, stack)
+	}
+	return p.parseType()
+}
+
+func (s *Schema) resolveName(name string, pos scanner.Position, stack []*typeDecl) *types.Type {
+	decl, ok := s.decls[name]
+	if !ok {
+		return s.lookup(name, pos)
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] != decl {
+			continue
+		}
+		// The type is nested in itself, which is only possible through a
+		// struct type.
+		if structName := s.structName(decl); structName != "" {
+			return types.MakeCycleType(structName)
+		}
+		// If it's nested in itself through a struct type that it refers to,
+		// it's expanded again here, and refers to that struct as a cycle.
+		for _, sd := range stack[i+1:] {
+			if s.structName(sd) != "" {
+				return s.resolveDecl(decl, stack)
+			}
+		}
+		raiseSyntaxError(fmt.Sprintf("Type %s refers to itself, only named struct types can be recursive", name), pos)
+	}
+	return s.resolveDecl(decl, stack)
+}
+
+// lookup returns the type declared as |name| in the schema file or one of the
+// files it imports.
+func (s *Schema) lookup(name string, pos scanner.Position) *types.Type {
+	if t, ok := s.types[name]; ok {
+		return t
+	}
+	var t *types.Type
+	var from *Schema
+	for _, imp := range s.imports {
+		if it, ok := imp.types[name]; ok {
+			if from != nil && from != imp {
+				raiseSyntaxError(fmt.Sprintf("Type %s is declared in both %s and %s", name, from.Filename, imp.Filename), pos)
+			}
+			t, from = it, imp
+		}
+	}
+	if t == nil {
+		raiseSyntaxError(fmt.Sprintf("Undeclared type %s", name), pos)
+	}
+	return t
+}
+
+// structName returns the name of the struct type that |decl| is declared as,
+// following aliases, or the empty string if it's not a named struct type.
+func (s *Schema) structName(decl *typeDecl) string {
+	seen := map[*typeDecl]bool{}
+	for decl != nil && decl.aliasOf != "" && !seen[decl] {
+		seen[decl] = true
+		decl = s.decls[decl.aliasOf]
+	}
+	if decl == nil {
+		return ""
+	}
+	return decl.structName
+}
+
+// blankPrefix replaces everything before |offset| in |src| with whitespace,
+// so that a parser can start at |offset| and still report the right line and
+// column.
+func blankPrefix(src string, offset int) string {
+	buf := make([]rune, 0, len(src))
+	for _, r := range src[:offset] {
+		if r != '\n' {
+			r = ' '
+		}
+		buf = append(buf, r)
+	}
+	return string(buf) + src[offset:]
+}
+
+func isBuiltinTypeName(name string) bool {
+	switch name {
+	case "Blob", "Bool", "Cycle", "List", "Map", "Number", "Ref", "Set", "String", "Struct", "Type", "Value":
+		return true
+	}
+	return false
+}
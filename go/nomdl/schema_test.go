@@ -0,0 +1,190 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package nomdl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/attic-labs/noms/go/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func assertSchemaType(t *testing.T, s *Schema, name, expected string) {
+	actual, err := s.Type(name)
+	if assert.NoError(t, err) {
+		assert.True(t, MustParseType(expected).Equals(actual), "Expected: %s, Actual: %s", expected, actual.Describe())
+	}
+}
+
+func assertParseSchemaError(t *testing.T, code, msg string) {
+	t.Run(code, func(t *testing.T) {
+		_, err := ParseSchema("example", strings.NewReader(code))
+		if assert.Error(t, err) {
+			assert.Equal(t, msg, err.Error())
+		}
+	})
+}
+
+func TestSchema(t *testing.T) {
+	s, err := ParseSchema("example", strings.NewReader(`
+		// People and where they live.
+		type People = Map<String, Person>
+
+		type Person = Struct Person {
+			name: String,
+			home?: Address,
+			friends: Set<Person>,
+		}
+
+		type Address = Struct Address {
+			city: String,
+			residents: List<Person>,
+		}
+
+		type Id = Number | String
+		type PersonRef = Ref<Person>`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"People", "Person", "Address", "Id", "PersonRef"}, s.Names())
+
+	person := `Struct Person {
+		name: String,
+		home?: Struct Address {
+			city: String,
+			residents: List<Cycle<Person>>,
+		},
+		friends: Set<Cycle<Person>>,
+	}`
+	assertSchemaType(t, s, "Person", person)
+	assertSchemaType(t, s, "People", "Map<String, "+person+">")
+	assertSchemaType(t, s, "PersonRef", "Ref<"+person+">")
+	assertSchemaType(t, s, "Address", `Struct Address {
+		city: String,
+		residents: List<Struct Person {
+			name: String,
+			home?: Cycle<Address>,
+			friends: Set<Cycle<Person>>,
+		}>,
+	}`)
+	assertSchemaType(t, s, "Id", "Number | String")
+
+	_, err = s.Type("Company")
+	assert.EqualError(t, err, "Undeclared type Company, example")
+}
+
+func TestSchemaAlias(t *testing.T) {
+	s, err := ParseSchema("example", strings.NewReader(`
+		type Tree = Node
+		type Node = Struct Node {
+			children: List<Tree>,
+		}`))
+	assert.NoError(t, err)
+	assertSchemaType(t, s, "Tree", "Struct Node { children: List<Cycle<Node>> }")
+	tree, _ := s.Type("Tree")
+	node, _ := s.Type("Node")
+	assert.True(t, tree.Equals(node))
+}
+
+func TestSchemaDeclarationOrder(t *testing.T) {
+	// The types are the same whichever is declared first.
+	for _, code := range []string{
+		"type A = Struct Foo { b: B }\ntype B = List<A>",
+		"type B = List<A>\ntype A = Struct Foo { b: B }",
+	} {
+		s, err := ParseSchema("example", strings.NewReader(code))
+		if assert.NoError(t, err, code) {
+			assertSchemaType(t, s, "A", "Struct Foo { b: List<Cycle<Foo>> }")
+			assertSchemaType(t, s, "B", "List<Struct Foo { b: List<Cycle<Foo>> }>")
+		}
+	}
+}
+
+func TestSchemaErrors(t *testing.T) {
+	assertParseSchemaError(t, "type A = B", "Undeclared type B, example:1:11")
+	assertParseSchemaError(t, "type A = List<A>", "Type A refers to itself, only named struct types can be recursive, example:1:16")
+	assertParseSchemaError(t, "type A = B\ntype B = A", "Type A refers to itself, only named struct types can be recursive, example:2:11")
+	assertParseSchemaError(t, "type A = Number\ntype A = String", "Type A is already declared, example:2:7")
+	assertParseSchemaError(t, "type Number = String", "Cannot declare built in type Number, example:1:12")
+	assertParseSchemaError(t, "type A = Number\nimport \"b.noms\"", "Imports must come before type declarations, example:2:7")
+	assertParseSchemaError(t, "type A Number", `Unexpected token Ident, expected "=", example:1:14`)
+	assertParseSchemaError(t, "typ A = Number", "Unexpected token Ident, example:1:4")
+	assertParseSchemaError(t, "type A = Struct {\n  a: Number,\n  b: C,\n}", "Undeclared type C, example:3:7")
+}
+
+func TestSchemaImports(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	write := func(name, code string) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(os.MkdirAll(filepath.Dir(path), 0777))
+		assert.NoError(ioutil.WriteFile(path, []byte(code), 0666))
+		return path
+	}
+
+	write("common/address.noms", `
+		type Address = Struct Address {
+			city: String,
+		}
+		type Id = String`)
+	write("common/id.noms", `type Id = Number`)
+	people := write("people.noms", `
+		import "common/address.noms"
+
+		type Person = Struct Person {
+			id: Id,
+			address: Address,
+		}
+		type Id = Number`)
+
+	s, err := ParseSchemaFile(people)
+	assert.NoError(err)
+	assert.Equal([]string{"Person", "Id"}, s.Names())
+	// Declarations in the file take precedence over imported ones.
+	assertSchemaType(t, s, "Person", "Struct Person { id: Number, address: Struct Address { city: String } }")
+	assertSchemaType(t, s, "Address", "Struct Address { city: String }")
+
+	typ, err := ParseSchemaType(people + ":Person")
+	assert.NoError(err)
+	assert.Equal(types.StructKind, typ.TargetKind())
+	_, err = ParseSchemaType(people)
+	assert.Error(err)
+
+	// Imports are not transitive.
+	write("company.noms", `
+		import "people.noms"
+		type Company = Struct Company {
+			employees: List<Person>,
+			address: Address,
+		}`)
+	_, err = ParseSchemaFile(filepath.Join(dir, "company.noms"))
+	assert.EqualError(err, "Undeclared type Address, "+filepath.Join(dir, "company.noms")+":5:20")
+
+	write("ambiguous.noms", `
+		import "common/address.noms"
+		import "common/id.noms"
+		type Thing = Struct Thing { id: Id }`)
+	_, err = ParseSchemaFile(filepath.Join(dir, "ambiguous.noms"))
+	assert.EqualError(err, "Type Id is declared in both "+filepath.Join(dir, "common/address.noms")+" and "+filepath.Join(dir, "common/id.noms")+", "+filepath.Join(dir, "ambiguous.noms")+":4:37")
+
+	write("a.noms", `import "b.noms"`)
+	write("b.noms", `import "a.noms"`)
+	_, err = ParseSchemaFile(filepath.Join(dir, "a.noms"))
+	assert.EqualError(err, "Import cycle, "+filepath.Join(dir, "a.noms")+" imports itself, "+filepath.Join(dir, "b.noms")+":1:16")
+
+	write("missing.noms", `import "nope.noms"`)
+	_, err = ParseSchemaFile(filepath.Join(dir, "missing.noms"))
+	assert.Error(err)
+	assert.Contains(err.Error(), filepath.Join(dir, "missing.noms")+":1:19")
+
+	_, err = ParseSchemaFile(filepath.Join(dir, "nope.noms"))
+	assert.True(os.IsNotExist(err))
+}
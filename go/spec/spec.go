@@ -28,6 +28,10 @@ import (
 
 const Separator = "::"
 
+// storeSeparator separates the databases named by "mirror" and "overlay"
+// specs.
+const storeSeparator = "+"
+
 var datasetRe = regexp.MustCompile("^" + datas.DatasetRe.String() + "$")
 
 // SpecOptions customize Spec behavior.
@@ -35,13 +39,19 @@ type SpecOptions struct {
 	// Authorization token for requests. For example, if the database is HTTP
 	// this will used for an `Authorization: Bearer ${authorization}` header.
 	Authorization string
+
+	// KeyFile is the path of a file containing the master key used to encrypt
+	// "nbs" and "objectstore" databases at rest. See nbs.ReadMasterKeyFile for its format. If
+	// empty, the database is not encrypted.
+	KeyFile string
 }
 
 // Spec locates a Noms database, dataset, or value globally. Spec caches
 // its database instance so it therefore does not reflect new commits in
 // the db, by (legacy) design.
 type Spec struct {
-	// Protocol is one of "mem", "ldb", "http", or "https".
+	// Protocol is one of "mem", "nbs", "objectstore", "mirror", "overlay",
+	// "aws", "ipfs", "ipfs-local", "http", or "https".
 	Protocol string
 
 	// DatabaseName is the name of the Spec's database, which is the string after
@@ -178,7 +188,13 @@ func (sp Spec) NewChunkStore() chunks.ChunkStore {
 	case "aws":
 		return parseAWSSpec(sp.Href())
 	case "nbs":
-		return nbs.NewLocalStore(sp.DatabaseName, 1<<28)
+		return sp.newLocalStore()
+	case "objectstore":
+		return sp.newObjectBackedStore()
+	case "mirror":
+		return sp.newMirroringStore()
+	case "overlay":
+		return sp.newOverlayStore()
 	case "mem":
 		storage := &chunks.MemoryStorage{}
 		return storage.NewView()
@@ -191,6 +207,70 @@ func (sp Spec) NewChunkStore() chunks.ChunkStore {
 	panic("unreachable")
 }
 
+func (sp Spec) newLocalStore() chunks.ChunkStore {
+	return nbs.NewEncryptedLocalStore(sp.DatabaseName, 1<<28, sp.masterKey())
+}
+
+// newObjectBackedStore opens the store described by an "objectstore" spec,
+// whose DatabaseName is of the form <kind>:<location>.
+func (sp Spec) newObjectBackedStore() chunks.ChunkStore {
+	kind, location := splitObjectStoreName(sp.DatabaseName)
+	objs, err := nbs.OpenObjectStore(kind, location)
+	d.PanicIfError(err)
+	return nbs.NewEncryptedObjectBackedStore(sp.Protocol+":"+sp.DatabaseName, objs, 1<<28, sp.masterKey())
+}
+
+// newMirroringStore opens the store described by a "mirror" spec, whose
+// DatabaseName is of the form <primary>+<replica>[+<replica>...]. Replica
+// databases are created if need be, and may be remote.
+func (sp Spec) newMirroringStore() chunks.ChunkStore {
+	var stores []chunks.ChunkStore
+	for _, dbSpec := range strings.Split(sp.DatabaseName, storeSeparator) {
+		sub, err := newSpec(dbSpec, sp.Options)
+		d.PanicIfError(err)
+		stores = append(stores, sub.createChunkStore())
+	}
+	return datas.NewMirroringChunkStore(stores[0], stores[1:]...)
+}
+
+// newOverlayStore opens the store described by an "overlay" spec, whose
+// DatabaseName is of the form <base>+<scratch>. The base database must
+// already exist, and is never written to. The scratch database is created if
+// need be.
+func (sp Spec) newOverlayStore() chunks.ChunkStore {
+	dbSpecs := strings.Split(sp.DatabaseName, storeSeparator)
+	base, err := newSpec(dbSpecs[0], sp.Options)
+	d.PanicIfError(err)
+	scratch, err := newSpec(dbSpecs[1], sp.Options)
+	d.PanicIfError(err)
+
+	var baseCS chunks.ChunkStore
+	switch base.Protocol {
+	case "http", "https":
+		baseCS = datas.NewHTTPChunkStore(base.Href(), base.Options.Authorization)
+	default:
+		baseCS = base.NewChunkStore()
+	}
+	return chunks.NewOverlayStore(baseCS, scratch.createChunkStore())
+}
+
+// masterKey returns the key named by sp.Options.KeyFile, or nil if there is
+// none.
+func (sp Spec) masterKey() *nbs.MasterKey {
+	if sp.Options.KeyFile == "" {
+		return nil
+	}
+	mk, err := nbs.ReadMasterKeyFile(sp.Options.KeyFile)
+	d.PanicIfError(err)
+	return mk
+}
+
+func splitObjectStoreName(name string) (kind, location string) {
+	parts := strings.SplitN(name, ":", 2)
+	d.PanicIfFalse(len(parts) == 2)
+	return parts[0], parts[1]
+}
+
 func parseAWSSpec(awsURL string) chunks.ChunkStore {
 	u, _ := url.Parse(awsURL)
 	parts := strings.SplitN(u.Host, ":", 2) // [table] [, bucket]?
@@ -273,21 +353,28 @@ func (sp Spec) Close() error {
 }
 
 func (sp Spec) createDatabase() datas.Database {
+	return datas.NewDatabase(sp.createChunkStore())
+}
+
+// createChunkStore is like NewChunkStore, but creates the directories of
+// local databases if they don't exist yet, and returns a client ChunkStore
+// for remote databases.
+func (sp Spec) createChunkStore() chunks.ChunkStore {
 	switch sp.Protocol {
 	case "http", "https":
-		return datas.NewDatabase(datas.NewHTTPChunkStore(sp.Href(), sp.Options.Authorization))
-	case "aws":
-		return datas.NewDatabase(parseAWSSpec(sp.Href()))
+		return datas.NewHTTPChunkStore(sp.Href(), sp.Options.Authorization)
 	case "nbs":
 		os.Mkdir(sp.DatabaseName, 0777)
-		return datas.NewDatabase(nbs.NewLocalStore(sp.DatabaseName, 1<<28))
-	case "ipfs", "ipfs-local":
-		return datas.NewDatabase(sp.NewChunkStore())
-	case "mem":
-		storage := &chunks.MemoryStorage{}
-		return datas.NewDatabase(storage.NewView())
+	case "objectstore":
+		if kind, location := splitObjectStoreName(sp.DatabaseName); kind == "local" {
+			os.Mkdir(location, 0777)
+		}
+	case "mirror":
+		return sp.newMirroringStore()
+	case "overlay":
+		return sp.newOverlayStore()
 	}
-	panic("unreachable")
+	return sp.NewChunkStore()
 }
 
 func parseDatabaseSpec(spec string) (protocol, name string, err error) {
@@ -326,6 +413,39 @@ func parseDatabaseSpec(spec string) (protocol, name string, err error) {
 			protocol, name = parts[0], parts[1]
 		}
 
+	case "objectstore":
+		kind := strings.SplitN(parts[1], ":", 2) // [kind] [, location]?
+		if len(kind) != 2 || kind[1] == "" {
+			err = fmt.Errorf("%s must be of the form objectstore:<kind>:<location>", spec)
+		} else if !nbs.IsObjectStoreKind(kind[0]) {
+			err = fmt.Errorf("Unknown object store kind %s in %s", kind[0], spec)
+		} else {
+			protocol, name = parts[0], parts[1]
+		}
+
+	case "mirror", "overlay":
+		dbSpecs := strings.Split(parts[1], storeSeparator)
+		if parts[0] == "mirror" && len(dbSpecs) < 2 {
+			err = fmt.Errorf("%s must be of the form mirror:<primary>+<replica>[+<replica>...]", spec)
+			return
+		} else if parts[0] == "overlay" && len(dbSpecs) != 2 {
+			err = fmt.Errorf("%s must be of the form overlay:<base>+<scratch>", spec)
+			return
+		}
+		for i, dbSpec := range dbSpecs {
+			p, _, perr := parseDatabaseSpec(dbSpec)
+			if perr != nil {
+				err = perr
+				return
+			}
+			// A mem database can be the scratch of an overlay, but can't be mirrored: each would be separate, and lost on exit.
+			if p == "mirror" || p == "overlay" || (p == "mem" && (parts[0] == "mirror" || i == 0)) {
+				err = fmt.Errorf("%s databases can't be used in %s", p, spec)
+				return
+			}
+		}
+		protocol, name = parts[0], parts[1]
+
 	case "mem":
 		err = fmt.Errorf(`In-memory database must be specified as "mem", not "mem:"`)
 
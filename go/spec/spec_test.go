@@ -9,6 +9,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 	"testing"
 
 	"github.com/attic-labs/noms/go/datas"
@@ -149,6 +150,125 @@ func TestNBSDatabaseSpec(t *testing.T) {
 	run("nbs:")
 }
 
+func TestEncryptedNBSDatabaseSpec(t *testing.T) {
+	assert := assert.New(t)
+	tmpDir, err := ioutil.TempDir("", "spec_test")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	keyFile := path.Join(tmpDir, "key")
+	assert.NoError(ioutil.WriteFile(keyFile, []byte(strings.Repeat("ab", 32)), 0600))
+	store := path.Join(tmpDir, "store")
+
+	s := types.String("secret string")
+	func() {
+		sp, err := ForDatabaseOpts(store, SpecOptions{KeyFile: keyFile})
+		assert.NoError(err)
+		defer sp.Close()
+		db := sp.GetDatabase()
+		_, err = db.CommitValue(db.GetDataset("datasetID"), s)
+		assert.NoError(err)
+	}()
+
+	sp, err := ForPathOpts(store+"::datasetID.value", SpecOptions{KeyFile: keyFile})
+	assert.NoError(err)
+	defer sp.Close()
+	assert.Equal(s, sp.GetValue())
+
+	plain, err := ForDatabase(store)
+	assert.NoError(err)
+	assert.Panics(func() { plain.GetDatabase() })
+}
+
+func TestObjectStoreDatabaseSpec(t *testing.T) {
+	assert := assert.New(t)
+	tmpDir, err := ioutil.TempDir("", "spec_test")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	for _, dbSpec := range []string{"objectstore:local:" + path.Join(tmpDir, "store"), "objectstore:mem:TestObjectStoreDatabaseSpec"} {
+		s := types.String("string for " + dbSpec)
+		func() {
+			sp, err := ForDatabase(dbSpec)
+			assert.NoError(err)
+			defer sp.Close()
+			db := sp.GetDatabase()
+			_, err = db.CommitValue(db.GetDataset("datasetID"), s)
+			assert.NoError(err)
+		}()
+
+		sp, err := ForPath(dbSpec + "::datasetID.value")
+		assert.NoError(err)
+		defer sp.Close()
+		assert.Equal(s, sp.GetValue())
+	}
+}
+
+func TestMirrorDatabaseSpec(t *testing.T) {
+	assert := assert.New(t)
+	tmpDir, err := ioutil.TempDir("", "spec_test")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	primary, replica := path.Join(tmpDir, "primary"), path.Join(tmpDir, "replica")
+	s := types.String("mirrored string")
+	func() {
+		sp, err := ForDatabase("mirror:" + primary + storeSeparator + replica)
+		assert.NoError(err)
+		defer sp.Close() // Waits for the replica to catch up
+		db := sp.GetDatabase()
+		_, err = db.CommitValue(db.GetDataset("datasetID"), s)
+		assert.NoError(err)
+	}()
+
+	for _, dbSpec := range []string{primary, replica} {
+		sp, err := ForPath(dbSpec + "::datasetID.value")
+		assert.NoError(err)
+		defer sp.Close()
+		assert.Equal(s, sp.GetValue())
+	}
+}
+
+func TestOverlayDatabaseSpec(t *testing.T) {
+	assert := assert.New(t)
+	tmpDir, err := ioutil.TempDir("", "spec_test")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpDir)
+
+	base, scratch := path.Join(tmpDir, "base"), path.Join(tmpDir, "scratch")
+	original, changed := types.String("original"), types.String("changed")
+	func() {
+		sp, err := ForDatabase(base)
+		assert.NoError(err)
+		defer sp.Close()
+		db := sp.GetDatabase()
+		_, err = db.CommitValue(db.GetDataset("datasetID"), original)
+		assert.NoError(err)
+	}()
+
+	for _, scratchSpec := range []string{"mem", scratch} {
+		func() {
+			sp, err := ForDataset("overlay:" + base + storeSeparator + scratchSpec + "::datasetID")
+			assert.NoError(err)
+			defer sp.Close()
+			assert.Equal(original, sp.GetDataset().HeadValue())
+			_, err = sp.GetDatabase().CommitValue(sp.GetDataset(), changed)
+			assert.NoError(err)
+		}()
+	}
+
+	// Only the local scratch database keeps the change; the base is untouched.
+	sp, err := ForPath(base + "::datasetID.value")
+	assert.NoError(err)
+	defer sp.Close()
+	assert.Equal(original, sp.GetValue())
+
+	sp, err = ForPath("overlay:" + base + storeSeparator + scratch + "::datasetID.value")
+	assert.NoError(err)
+	defer sp.Close()
+	assert.Equal(changed, sp.GetValue())
+}
+
 // Skip LDB dataset and path tests: the database behaviour is tested in
 // TestLDBDatabaseSpec, TestMemDatasetSpec/TestMem*PathSpec cover general
 // dataset/path behaviour, and ForDataset/ForPath test LDB parsing.
@@ -207,6 +327,18 @@ func TestForDatabase(t *testing.T) {
 		"aws://t:b",
 		"aws://t",
 		"aws://t:",
+		"objectstore:",
+		"objectstore:local",
+		"objectstore:local:",
+		"objectstore:bogus:somewhere",
+		"mirror:",
+		"mirror:/tmp/a",
+		"mirror:/tmp/a+mem",
+		"mirror:/tmp/a+random:random",
+		"overlay:/tmp/a",
+		"overlay:/tmp/a+/tmp/b+/tmp/c",
+		"overlay:mem+/tmp/b",
+		"overlay:/tmp/a+mirror:/tmp/b+/tmp/c",
 	}
 
 	for _, spec := range badSpecs {
@@ -238,6 +370,10 @@ func TestForDatabase(t *testing.T) {
 		{"http://::ffff::1e::9a", "http", "//::ffff::1e::9a", ""},
 		{"aws://table:bucket/db", "aws", "//table:bucket/db", ""},
 		{"aws://table/db", "aws", "//table/db", ""},
+		{"objectstore:local:" + tmpDir, "objectstore", "local:" + tmpDir, ""},
+		{"objectstore:mem:TestForDatabase", "objectstore", "mem:TestForDatabase", ""},
+		{"mirror:" + tmpDir + "+nbs:" + tmpDir + "/replica", "mirror", tmpDir + "+nbs:" + tmpDir + "/replica", ""},
+		{"overlay:" + tmpDir + "+mem", "overlay", tmpDir + "+mem", ""},
 	}
 
 	for _, tc := range testCases {
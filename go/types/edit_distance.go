@@ -73,6 +73,16 @@ func addSplice(splices []Splice, s Splice) []Splice {
 	return splices
 }
 
+// CalcSplices returns the Splices that turn a sequence of |previousLength|
+// elements into one of |currentLength| elements, given |eqFn| to compare an
+// element of the former with one of the latter. Finding the Splices takes a
+// matrix of up to previousLength*currentLength entries; if that's more than
+// |maxSpliceMatrixSize|, a single approximate Splice is returned instead, as
+// List.Diff does.
+func CalcSplices(previousLength uint64, currentLength uint64, maxSpliceMatrixSize uint64, eqFn EditDistanceEqualsFn) []Splice {
+	return calcSplices(previousLength, currentLength, maxSpliceMatrixSize, eqFn)
+}
+
 func calcSplices(previousLength uint64, currentLength uint64, maxSpliceMatrixSize uint64, eqFn EditDistanceEqualsFn) []Splice {
 	minLength := uint64Min(previousLength, currentLength)
 	prefixCount := sharedPrefix(eqFn, minLength)
@@ -82,7 +82,8 @@ func readMapInput(vrw ValueReadWriter, kvs <-chan Value, outChan chan<- Map) {
 
 // Diff computes the diff from |last| to |m| using the top-down algorithm,
 // which completes as fast as possible while taking longer to return early
-// results than left-to-right.
+// results than left-to-right. Subtrees that differ are diffed in parallel,
+// but changes are still sent in key order.
 func (m Map) Diff(last Map, changes chan<- ValueChanged, closeChan <-chan struct{}) {
 	if m.Equals(last) {
 		return
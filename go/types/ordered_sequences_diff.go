@@ -107,50 +107,150 @@ func orderedSequenceDiffBest(last orderedSequence, current orderedSequence, chan
 	return true
 }
 
+const (
+	// The number of subtrees that a top-down diff may diff concurrently, besides
+	// the one it's sending changes from.
+	topDownDiffParallelism = 16
+	// The number of changes that may be buffered for each of those subtrees
+	// until the changes before them have been sent.
+	topDownDiffBufferSize = 256
+	// Splices of more chunks than this are diffed by streaming through their
+	// chunks, rather than by loading all of their children at once.
+	topDownDiffMaxSpliceChunks = 256
+)
+
 // Streams the diff from |last| to |current| into |changes|, using a top-down approach.
 // Top-down is parallel and efficiently returns the complete diff, but compared to left-right it's slow to start streaming changes.
+//
+// Where the trees differ, the refs to the children of their nodes are compared, and the runs of children that differ are diffed concurrently, in up to topDownDiffParallelism goroutines. Each run covers keys that no other does, so its changes are buffered, up to topDownDiffBufferSize of them, until those of the runs before it have been sent. Changes are therefore sent in key order, and the memory used is bounded no matter how big the trees are.
 func orderedSequenceDiffTopDown(last orderedSequence, current orderedSequence, changes chan<- ValueChanged, stopChan <-chan struct{}) bool {
-	return orderedSequenceDiffInternalNodes(last, current, changes, stopChan)
+	td := &topDownDiffer{
+		stop:   make(chan struct{}),
+		tokens: make(chan struct{}, topDownDiffParallelism),
+	}
+	// stopChan may only be sent to once, but every goroutine needs to know when to stop.
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-stopChan:
+		case <-finished:
+		}
+		close(td.stop)
+	}()
+
+	ok := td.diff(last, current, changes)
+	close(finished)
+	// Ensure that all goroutines have finished reading from the database before returning - see https://github.com/attic-labs/noms/issues/2165.
+	td.wg.Wait()
+	return ok
+}
+
+type topDownDiffer struct {
+	// Closed when diffing must stop.
+	stop chan struct{}
+	// Holds a token for each subtree being diffed concurrently. A token is
+	// released once the subtree's changes have all been forwarded.
+	tokens chan struct{}
+	wg     sync.WaitGroup
 }
 
-// TODO - something other than the literal edit-distance, which is way too much cpu work for this case - https://github.com/attic-labs/noms/issues/2027
-func orderedSequenceDiffInternalNodes(last orderedSequence, current orderedSequence, changes chan<- ValueChanged, stopChan <-chan struct{}) bool {
+func (td *topDownDiffer) diff(last orderedSequence, current orderedSequence, changes chan<- ValueChanged) bool {
 	if last.treeLevel() > current.treeLevel() {
+		if last.seqLen() > topDownDiffMaxSpliceChunks {
+			return orderedSequenceDiffLeftRight(last, current, changes, td.stop)
+		}
 		lastChild := last.getCompositeChildSequence(0, uint64(last.seqLen())).(orderedSequence)
-		return orderedSequenceDiffInternalNodes(lastChild, current, changes, stopChan)
+		return td.diff(lastChild, current, changes)
 	}
 
 	if current.treeLevel() > last.treeLevel() {
+		if current.seqLen() > topDownDiffMaxSpliceChunks {
+			return orderedSequenceDiffLeftRight(last, current, changes, td.stop)
+		}
 		currentChild := current.getCompositeChildSequence(0, uint64(current.seqLen())).(orderedSequence)
-		return orderedSequenceDiffInternalNodes(last, currentChild, changes, stopChan)
+		return td.diff(last, currentChild, changes)
 	}
 
 	if last.isLeaf() && current.isLeaf() {
-		return orderedSequenceDiffLeftRight(last, current, changes, stopChan)
+		return orderedSequenceDiffLeftRight(last, current, changes, td.stop)
 	}
 
+	// TODO - something other than the literal edit-distance, which is way too much cpu work for this case - https://github.com/attic-labs/noms/issues/2027
 	compareFn := last.getCompareFn(current)
-	initialSplices := calcSplices(uint64(last.seqLen()), uint64(current.seqLen()), DEFAULT_MAX_SPLICE_MATRIX_SIZE,
+	splices := calcSplices(uint64(last.seqLen()), uint64(current.seqLen()), DEFAULT_MAX_SPLICE_MATRIX_SIZE,
 		func(i uint64, j uint64) bool { return compareFn(int(i), int(j)) })
 
-	for _, splice := range initialSplices {
-		var lastChild, currentChild orderedSequence
-		functions.All(
-			func() {
-				lastChild = last.getCompositeChildSequence(splice.SpAt, splice.SpRemoved).(orderedSequence)
-			},
-			func() {
-				currentChild = current.getCompositeChildSequence(splice.SpFrom, splice.SpAdded).(orderedSequence)
-			},
-		)
-		if ok := orderedSequenceDiffInternalNodes(lastChild, currentChild, changes, stopChan); !ok {
-			return false
+	pending := []chan ValueChanged{}
+	for _, splice := range splices {
+		select {
+		case td.tokens <- struct{}{}:
+			spliceChanges := make(chan ValueChanged, topDownDiffBufferSize)
+			pending = append(pending, spliceChanges)
+			td.wg.Add(1)
+			go func(splice Splice) {
+				defer td.wg.Done()
+				td.diffSplice(last, current, splice, spliceChanges)
+				close(spliceChanges)
+			}(splice)
+		default:
+			// Every token is taken, so diff this splice here, once the changes before it have been sent.
+			if !td.forward(pending, changes) {
+				return false
+			}
+			pending = []chan ValueChanged{}
+			if !td.diffSplice(last, current, splice, changes) {
+				return false
+			}
 		}
 	}
+	return td.forward(pending, changes)
+}
+
+// diffSplice diffs the children of |last| and |current| that |splice| covers.
+func (td *topDownDiffer) diffSplice(last orderedSequence, current orderedSequence, splice Splice, changes chan<- ValueChanged) bool {
+	if splice.SpRemoved+splice.SpAdded > topDownDiffMaxSpliceChunks {
+		return orderedSequenceDiffLeftRight(
+			metaSubsequence(last, splice.SpAt, splice.SpRemoved),
+			metaSubsequence(current, splice.SpFrom, splice.SpAdded),
+			changes, td.stop)
+	}
+
+	var lastChild, currentChild orderedSequence
+	functions.All(
+		func() {
+			lastChild = last.getCompositeChildSequence(splice.SpAt, splice.SpRemoved).(orderedSequence)
+		},
+		func() {
+			currentChild = current.getCompositeChildSequence(splice.SpFrom, splice.SpAdded).(orderedSequence)
+		},
+	)
+	return td.diff(lastChild, currentChild, changes)
+}
 
+// forward sends the changes from each of |pending| in turn to |changes|,
+// releasing a token as each is exhausted.
+func (td *topDownDiffer) forward(pending []chan ValueChanged, changes chan<- ValueChanged) bool {
+	for _, spliceChanges := range pending {
+		for c := range spliceChanges {
+			if !sendChange(changes, td.stop, c) {
+				return false
+			}
+		}
+		<-td.tokens
+	}
 	return true
 }
 
+// metaSubsequence returns the |length| items of the meta sequence |seq|
+// from |start|, as a sequence whose cursors load its children as they go.
+func metaSubsequence(seq orderedSequence, start uint64, length uint64) orderedSequence {
+	if length == 0 {
+		return emptySequence{seq.treeLevel()}
+	}
+	ms := seq.(metaSequence)
+	return newMetaSequence(ms.Kind(), ms.treeLevel(), ms.tuples()[start:start+length], ms.vrw)
+}
+
 // Streams the diff from |last| to |current| into |changes|, using a left-right approach.
 // Left-right immediately descends to the first change and starts streaming changes, but compared to top-down it's serial and much slower to calculate the full diff.
 func orderedSequenceDiffLeftRight(last orderedSequence, current orderedSequence, changes chan<- ValueChanged, stopChan <-chan struct{}) bool {
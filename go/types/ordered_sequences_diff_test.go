@@ -228,3 +228,62 @@ func TestOrderedSequenceDiffWithMetaNodeGap(t *testing.T) {
 	runTest(orderedSequenceDiffLeftRight)
 	runTest(orderedSequenceDiffTopDown)
 }
+
+func TestOrderedSequencesDiffTopDownLarge(t *testing.T) {
+	assert := assert.New(t)
+	vs := newTestValueStore()
+
+	const n = 100000
+	kvs := make([]Value, 0, 2*n)
+	changedKvs := make([]Value, 0, 2*n)
+	for i := 0; i < n; i++ {
+		kvs = append(kvs, Number(i), Number(i))
+		changedKvs = append(changedKvs, Number(i), Number(i+1))
+	}
+	m := NewMap(vs, kvs...)
+	// Every leaf differs, so top-down must stream through the biggest splices.
+	allChanged := NewMap(vs, changedKvs...)
+	// A few scattered leaves differ, so top-down diffs them concurrently.
+	me := m.Edit()
+	for i := 0; i < n; i += 997 {
+		me.Set(Number(i), String("changed"))
+	}
+	me.Remove(Number(n / 2))
+	me.Set(Number(n), Number(n))
+	someChanged := me.Map()
+
+	collect := func(df diffFn, last, current Map) []ValueChanged {
+		changes := make(chan ValueChanged, 16)
+		go func() {
+			df(last.seq, current.seq, changes, nil)
+			close(changes)
+		}()
+		cs := []ValueChanged{}
+		for c := range changes {
+			cs = append(cs, c)
+		}
+		return cs
+	}
+
+	for _, pair := range [][2]Map{{m, allChanged}, {m, someChanged}, {someChanged, m}, {NewMap(vs), m}} {
+		expected := collect(orderedSequenceDiffLeftRight, pair[0], pair[1])
+		actual := collect(orderedSequenceDiffTopDown, pair[0], pair[1])
+		assert.Equal(len(expected), len(actual))
+		for i := range expected {
+			if !assert.Equal(expected[i].ChangeType, actual[i].ChangeType) || !assert.True(expected[i].Key.Equals(actual[i].Key)) {
+				break
+			}
+		}
+	}
+
+	// Stopping part way through mustn't leave anything running.
+	changes := make(chan ValueChanged)
+	stopChan := make(chan struct{}, 1)
+	done := make(chan bool)
+	go func() {
+		done <- orderedSequenceDiffTopDown(m.seq, someChanged.seq, changes, stopChan)
+	}()
+	<-changes
+	stopChan <- struct{}{}
+	assert.False(<-done)
+}
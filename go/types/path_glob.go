@@ -0,0 +1,247 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/attic-labs/noms/go/d"
+)
+
+// The wildcards that can appear in a PathGlob.
+const (
+	anyFieldGlob = ".*"
+	anyIndexGlob = "[*]"
+)
+
+// PathGlob is a pattern that matches Paths. It's written like a Path (see
+// ParsePath), except that `.*` matches any field and `[*]` matches any index
+// or key, e.g. `.rows[*].price`. A PathGlob matches the Paths with the same
+// number of parts, each of which it matches. The empty PathGlob matches only
+// the empty Path.
+type PathGlob struct {
+	str string
+	// Each part is either the String() of the PathPart it matches, or one of
+	// the wildcards.
+	parts []string
+}
+
+// ParsePathGlob parses |str| into a PathGlob.
+func ParsePathGlob(str string) (PathGlob, error) {
+	parts := []string{}
+	for rem := str; rem != ""; {
+		var part string
+		switch {
+		case strings.HasPrefix(rem, anyFieldGlob):
+			parts, rem = append(parts, anyFieldGlob), rem[len(anyFieldGlob):]
+			continue
+		case strings.HasPrefix(rem, anyIndexGlob):
+			parts, rem = append(parts, anyIndexGlob), rem[len(anyIndexGlob):]
+			continue
+		case rem[0] == '.':
+			end := strings.IndexAny(rem[1:], ".[")
+			if end < 0 {
+				part, rem = rem, ""
+			} else {
+				part, rem = rem[:end+1], rem[end+1:]
+			}
+		case rem[0] == '[':
+			if len(rem) == 1 {
+				return PathGlob{}, errors.New("Path glob ends in [")
+			}
+			_, _, idxRem, err := ParsePathIndex(rem[1:])
+			if err != nil {
+				return PathGlob{}, err
+			}
+			if !strings.HasPrefix(idxRem, "]") {
+				return PathGlob{}, errors.New("[ is missing closing ]")
+			}
+			end := len(rem) - len(idxRem) + 1
+			part, rem = rem[:end], rem[end:]
+		default:
+			return PathGlob{}, fmt.Errorf("Invalid path glob: %s", str)
+		}
+
+		p, err := ParsePath(part)
+		if err != nil {
+			return PathGlob{}, err
+		}
+		if len(p) != 1 {
+			return PathGlob{}, fmt.Errorf("Invalid path glob: %s", str)
+		}
+		parts = append(parts, p[0].String())
+	}
+	return PathGlob{str, parts}, nil
+}
+
+// MustParsePathGlob parses |str| into a PathGlob, panicking on failure.
+func MustParsePathGlob(str string) PathGlob {
+	g, err := ParsePathGlob(str)
+	d.PanicIfError(err)
+	return g
+}
+
+// String returns the PathGlob as it was parsed.
+func (g PathGlob) String() string {
+	return g.str
+}
+
+// Matches returns true if |g| matches |p|.
+func (g PathGlob) Matches(p Path) bool {
+	return len(p) == len(g.parts) && g.matchesParts(p)
+}
+
+// MatchesAncestor returns true if |g| matches |p| or any Path that |p| is
+// beneath, i.e. if |p| is part of a value at a Path that |g| matches.
+func (g PathGlob) MatchesAncestor(p Path) bool {
+	return len(p) >= len(g.parts) && g.matchesParts(p[:len(g.parts)])
+}
+
+// MayMatchDescendant returns true if |g| could match |p| or any Path beneath
+// |p|, i.e. if the value at |p| might contain a value that |g| matches.
+func (g PathGlob) MayMatchDescendant(p Path) bool {
+	return len(p) <= len(g.parts) && g.matchesParts(p)
+}
+
+// matchesParts returns true if each part of |p| is matched by the part of
+// |g| at the same position.
+func (g PathGlob) matchesParts(p Path) bool {
+	for i, part := range p {
+		switch gp := g.parts[i]; gp {
+		case anyFieldGlob:
+			if _, ok := part.(FieldPath); !ok {
+				return false
+			}
+		case anyIndexGlob:
+			switch part.(type) {
+			case IndexPath, HashIndexPath:
+			default:
+				return false
+			}
+		default:
+			if gp != part.String() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// PathFilter selects Paths using PathGlobs. The zero PathFilter selects every
+// Path.
+type PathFilter struct {
+	// Include, if not empty, limits the selected Paths to those matched by
+	// one of its PathGlobs, those beneath them, and those above them.
+	Include []PathGlob
+	// Exclude deselects the Paths matched by any of its PathGlobs, and those
+	// beneath them.
+	Exclude []PathGlob
+}
+
+// Selects returns true if |f| selects |p|.
+func (f PathFilter) Selects(p Path) bool {
+	for _, g := range f.Exclude {
+		if g.MatchesAncestor(p) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, g := range f.Include {
+		if g.MatchesAncestor(p) || g.MayMatchDescendant(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectsAllBeneath returns true if |f| selects |p| and everything beneath it.
+func (f PathFilter) selectsAllBeneath(p Path) bool {
+	for _, g := range f.Exclude {
+		if g.MayMatchDescendant(p) || g.MatchesAncestor(p) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, g := range f.Include {
+		if g.MatchesAncestor(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Project returns a copy of |v| that contains only what |f| selects. Struct
+// fields, List elements, Map entries and Set elements that aren't selected,
+// and that contain nothing that is, are left out. Refs aren't followed, so
+// they are either kept or left out whole. Project returns nil if nothing in
+// |v| is selected.
+func (f PathFilter) Project(v Value, vrw ValueReadWriter) Value {
+	return f.project(Path{}, v, vrw)
+}
+
+func (f PathFilter) project(p Path, v Value, vrw ValueReadWriter) Value {
+	if !f.Selects(p) {
+		return nil
+	}
+	if f.selectsAllBeneath(p) {
+		return v
+	}
+	keyPart := func(k Value) PathPart {
+		if ValueCanBePathIndex(k) {
+			return NewIndexPath(k)
+		}
+		return NewHashIndexPath(k.Hash())
+	}
+
+	switch v := v.(type) {
+	case Struct:
+		data := StructData{}
+		v.IterFields(func(name string, fv Value) {
+			if pv := f.project(append(p, NewFieldPath(name)), fv, vrw); pv != nil {
+				data[name] = pv
+			}
+		})
+		if len(data) > 0 {
+			return NewStruct(v.Name(), data)
+		}
+	case List:
+		elems := []Value{}
+		v.IterAll(func(ev Value, idx uint64) {
+			if pv := f.project(append(p, NewIndexPath(Number(idx))), ev, vrw); pv != nil {
+				elems = append(elems, pv)
+			}
+		})
+		if len(elems) > 0 {
+			return NewList(vrw, elems...)
+		}
+	case Map:
+		me := NewMap(vrw).Edit()
+		v.IterAll(func(k, mv Value) {
+			if pv := f.project(append(p, keyPart(k)), mv, vrw); pv != nil {
+				me.Set(k, pv)
+			}
+		})
+		if m := me.Map(); !m.Empty() {
+			return m
+		}
+	case Set:
+		se := NewSet(vrw).Edit()
+		v.IterAll(func(ev Value) {
+			if pv := f.project(append(p, keyPart(ev)), ev, vrw); pv != nil {
+				se.Insert(pv)
+			}
+		})
+		if s := se.Set(); !s.Empty() {
+			return s
+		}
+	}
+	return nil
+}
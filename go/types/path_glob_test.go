@@ -0,0 +1,91 @@
+// Copyright 2017 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathGlob(t *testing.T) {
+	assert := assert.New(t)
+
+	g := MustParsePathGlob(`.rows[*].price`)
+	assert.Equal(`.rows[*].price`, g.String())
+	assert.True(g.Matches(MustParsePath(`.rows[0].price`)))
+	assert.True(g.Matches(MustParsePath(`.rows["a"].price`)))
+	assert.True(g.Matches(MustParsePath(`.rows[#01234567890123456789012345678901].price`)))
+	assert.False(g.Matches(MustParsePath(`.rows[0].name`)))
+	assert.False(g.Matches(MustParsePath(`.rows.x.price`)))
+	assert.False(g.Matches(MustParsePath(`.rows[0]`)))
+
+	assert.True(g.MatchesAncestor(MustParsePath(`.rows[0].price`)))
+	assert.True(g.MatchesAncestor(MustParsePath(`.rows[0].price.currency`)))
+	assert.False(g.MatchesAncestor(MustParsePath(`.rows[0]`)))
+
+	assert.True(g.MayMatchDescendant(Path{}))
+	assert.True(g.MayMatchDescendant(MustParsePath(`.rows`)))
+	assert.True(g.MayMatchDescendant(MustParsePath(`.rows[0].price`)))
+	assert.False(g.MayMatchDescendant(MustParsePath(`.cols`)))
+	assert.False(g.MayMatchDescendant(MustParsePath(`.rows[0].price.currency`)))
+
+	g = MustParsePathGlob(`.*.x`)
+	assert.True(g.Matches(MustParsePath(`.a.x`)))
+	assert.False(g.Matches(MustParsePath(`[1].x`)))
+
+	g = MustParsePathGlob("")
+	assert.True(g.Matches(Path{}))
+	assert.True(g.MatchesAncestor(MustParsePath(`.a`)))
+
+	for _, bad := range []string{"[", "[1", "x", ".a[*", "[*]]"} {
+		_, err := ParsePathGlob(bad)
+		assert.Error(err, bad)
+	}
+}
+
+func TestPathFilterProject(t *testing.T) {
+	assert := assert.New(t)
+	vs := newTestValueStore()
+
+	row := func(name string, price float64) Struct {
+		return NewStruct("Row", StructData{"name": String(name), "price": Number(price)})
+	}
+	v := NewStruct("", StructData{
+		"rows":  NewMap(vs, String("a"), row("a", 1), String("b"), row("b", 2)),
+		"title": String("t"),
+	})
+	globs := func(strs ...string) []PathGlob {
+		gs := make([]PathGlob, len(strs))
+		for i, str := range strs {
+			gs[i] = MustParsePathGlob(str)
+		}
+		return gs
+	}
+	price := func(p float64) Struct {
+		return NewStruct("Row", StructData{"price": Number(p)})
+	}
+
+	assert.True(v.Equals(PathFilter{}.Project(v, vs)))
+
+	f := PathFilter{Include: globs(`.rows[*].price`)}
+	assert.True(f.Selects(Path{}))
+	assert.True(f.Selects(MustParsePath(`.rows["a"]`)))
+	assert.False(f.Selects(MustParsePath(`.title`)))
+	expected := NewStruct("", StructData{
+		"rows": NewMap(vs, String("a"), price(1), String("b"), price(2)),
+	})
+	assert.True(expected.Equals(f.Project(v, vs)))
+
+	f = PathFilter{Exclude: globs(`.rows[*].name`, `.title`)}
+	assert.True(expected.Equals(f.Project(v, vs)))
+
+	f = PathFilter{Include: globs(`.rows["a"]`), Exclude: globs(`.rows[*].name`)}
+	expected = NewStruct("", StructData{"rows": NewMap(vs, String("a"), price(1))})
+	assert.True(expected.Equals(f.Project(v, vs)))
+
+	f = PathFilter{Include: globs(`.nothing`)}
+	assert.Nil(f.Project(v, vs))
+}
@@ -69,7 +69,8 @@ func readSetInput(vrw ValueReadWriter, vChan <-chan Value, outChan chan<- Set) {
 
 // Diff computes the diff from |last| to |m| using the top-down algorithm,
 // which completes as fast as possible while taking longer to return early
-// results than left-to-right.
+// results than left-to-right. Subtrees that differ are diffed in parallel,
+// but changes are still sent in key order.
 func (s Set) Diff(last Set, changes chan<- ValueChanged, closeChan <-chan struct{}) {
 	if s.Equals(last) {
 		return
@@ -5,6 +5,7 @@
 package jsontonoms
 
 import (
+	"fmt"
 	"reflect"
 
 	"github.com/attic-labs/noms/go/d"
@@ -92,3 +93,139 @@ func NomsValueFromDecodedJSON(vrw types.ValueReadWriter, o interface{}, useStruc
 func NomsValueUsingNamedStructsFromDecodedJSON(vrw types.ValueReadWriter, o interface{}) types.Value {
 	return nomsValueFromDecodedJSONBase(vrw, o, true, true)
 }
+
+// NomsValueFromDecodedJSONWithType is like NomsValueFromDecodedJSON, except
+// that the Noms Value it returns is of the type |t|, for example a type
+// declared in a Noms schema file. |t| determines whether JSON arrays become
+// Lists or Sets, whether JSON objects become Structs or Maps, and the names of
+// the Structs. Values of type Value are converted as NomsValueFromDecodedJSON
+// does with |useStruct| set.
+//
+// It returns an error if |o| doesn't fit |t|, including if a JSON object has a
+// field that a Struct doesn't, or is missing one that isn't optional.
+func NomsValueFromDecodedJSONWithType(vrw types.ValueReadWriter, o interface{}, t *types.Type) (types.Value, error) {
+	switch t.TargetKind() {
+	case types.ValueKind:
+		if o == nil {
+			break
+		}
+		return nomsValueFromDecodedJSONBase(vrw, o, true, false), nil
+	case types.UnionKind:
+		for _, et := range t.Desc.(types.CompoundDesc).ElemTypes {
+			if v, err := NomsValueFromDecodedJSONWithType(vrw, o, et); err == nil {
+				return v, nil
+			}
+		}
+	case types.BoolKind:
+		if b, ok := o.(bool); ok {
+			return types.Bool(b), nil
+		}
+	case types.NumberKind:
+		if f, ok := o.(float64); ok {
+			return types.Number(f), nil
+		}
+	case types.StringKind:
+		if s, ok := o.(string); ok {
+			return types.String(s), nil
+		}
+	case types.ListKind, types.SetKind:
+		a, ok := o.([]interface{})
+		if !ok {
+			break
+		}
+		elemType := t.Desc.(types.CompoundDesc).ElemTypes[0]
+		items := make([]types.Value, 0, len(a))
+		for _, v := range a {
+			if v == nil {
+				continue
+			}
+			nv, err := NomsValueFromDecodedJSONWithType(vrw, v, elemType)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, nv)
+		}
+		if t.TargetKind() == types.SetKind {
+			return types.NewSet(vrw, items...), nil
+		}
+		return types.NewList(vrw, items...), nil
+	case types.MapKind:
+		m, ok := o.(map[string]interface{})
+		if !ok {
+			break
+		}
+		elemTypes := t.Desc.(types.CompoundDesc).ElemTypes
+		kv := make([]types.Value, 0, len(m)*2)
+		for k, v := range m {
+			if !types.IsValueSubtypeOf(types.String(k), elemTypes[0]) {
+				return nil, fmt.Errorf("Cannot import JSON object key as %s", elemTypes[0].Describe())
+			}
+			if v == nil {
+				continue
+			}
+			nv, err := NomsValueFromDecodedJSONWithType(vrw, v, elemTypes[1])
+			if err != nil {
+				return nil, err
+			}
+			kv = append(kv, types.String(k), nv)
+		}
+		return types.NewMap(vrw, kv...), nil
+	case types.StructKind:
+		m, ok := o.(map[string]interface{})
+		if !ok {
+			break
+		}
+		return structFromDecodedJSON(vrw, m, t)
+	}
+	return nil, fmt.Errorf("Cannot import JSON %s as %s", jsonKindName(o), t.Describe())
+}
+
+func structFromDecodedJSON(vrw types.ValueReadWriter, m map[string]interface{}, t *types.Type) (types.Value, error) {
+	desc := t.Desc.(types.StructDesc)
+	values := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if v != nil {
+			values[types.EscapeStructField(k)] = v
+		}
+	}
+
+	data := make(types.StructData, len(values))
+	var err error
+	desc.IterFields(func(name string, ft *types.Type, optional bool) {
+		if err != nil {
+			return
+		}
+		v, ok := values[name]
+		if !ok {
+			if !optional {
+				err = fmt.Errorf("JSON object is missing field %s of struct %s", name, desc.Name)
+			}
+			return
+		}
+		delete(values, name)
+		data[name], err = NomsValueFromDecodedJSONWithType(vrw, v, ft)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for name := range values {
+		return nil, fmt.Errorf("Struct %s has no field %s", desc.Name, name)
+	}
+	return types.NewStruct(desc.Name, data), nil
+}
+
+func jsonKindName(o interface{}) string {
+	switch o.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "null"
+}
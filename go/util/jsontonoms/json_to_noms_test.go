@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/attic-labs/noms/go/chunks"
+	"github.com/attic-labs/noms/go/nomdl"
 	"github.com/attic-labs/noms/go/types"
 	"github.com/stretchr/testify/suite"
 )
@@ -126,6 +127,58 @@ func (suite *LibTestSuite) TestCompositeTypeWithNamedStruct() {
 	suite.True(tstruct.Equals(o))
 }
 
+func (suite *LibTestSuite) TestNomsValueWithType() {
+	vs := suite.vs
+	typ := nomdl.MustParseType(`Struct Person {
+		name: String,
+		age?: Number,
+		tags: Set<String>,
+		friends: List<Cycle<Person>>,
+		extra: Map<String, Number | Bool>,
+		data: Value,
+	}`)
+
+	v, err := NomsValueFromDecodedJSONWithType(vs, map[string]interface{}{
+		"name": "alice",
+		"tags": []interface{}{"b", "a", "b"},
+		"friends": []interface{}{
+			map[string]interface{}{
+				"name":    "bob",
+				"age":     float64(42),
+				"tags":    []interface{}{},
+				"friends": []interface{}{},
+				"extra":   map[string]interface{}{},
+				"data":    false,
+			},
+		},
+		"extra": map[string]interface{}{"x": float64(1), "y": true},
+		"data":  map[string]interface{}{"z": "z"},
+	}, typ)
+	suite.NoError(err)
+	suite.True(types.NewStruct("Person", types.StructData{
+		"name": types.String("alice"),
+		"tags": types.NewSet(vs, types.String("a"), types.String("b")),
+		"friends": types.NewList(vs, types.NewStruct("Person", types.StructData{
+			"name":    types.String("bob"),
+			"age":     types.Number(42),
+			"tags":    types.NewSet(vs),
+			"friends": types.NewList(vs),
+			"extra":   types.NewMap(vs),
+			"data":    types.Bool(false),
+		})),
+		"extra": types.NewMap(vs, types.String("x"), types.Number(1), types.String("y"), types.Bool(true)),
+		"data":  types.NewStruct("", types.StructData{"z": types.String("z")}),
+	}).Equals(v))
+	suite.True(types.IsValueSubtypeOf(v, typ))
+
+	_, err = NomsValueFromDecodedJSONWithType(vs, map[string]interface{}{"name": "alice"}, typ)
+	suite.EqualError(err, "JSON object is missing field data of struct Person")
+	_, err = NomsValueFromDecodedJSONWithType(vs, map[string]interface{}{"x": "x"}, nomdl.MustParseType("Struct S {}"))
+	suite.EqualError(err, "Struct S has no field x")
+	_, err = NomsValueFromDecodedJSONWithType(vs, []interface{}{"x"}, nomdl.MustParseType("List<Number | Bool>"))
+	suite.EqualError(err, "Cannot import JSON string as Bool | Number")
+}
+
 func (suite *LibTestSuite) TestPanicOnUnsupportedType() {
 	vs := suite.vs
 	suite.Panics(func() { NomsValueFromDecodedJSON(vs, map[int]string{1: "one"}, false) }, "Should panic on map[int]string!")
@@ -15,6 +15,7 @@ import (
 	"github.com/attic-labs/noms/go/config"
 	"github.com/attic-labs/noms/go/d"
 	"github.com/attic-labs/noms/go/datas"
+	"github.com/attic-labs/noms/go/nomdl"
 	"github.com/attic-labs/noms/go/spec"
 	"github.com/attic-labs/noms/go/types"
 	"github.com/attic-labs/noms/go/util/profile"
@@ -39,6 +40,7 @@ func main() {
 	lowercase := flag.Bool("lowercase", false, "convert column names to lowercase (otherwise preserve the case in the resulting struct fields)")
 	name := flag.String("name", "Row", "struct name. The user-visible name to give to the struct type that will hold each row of data.")
 	columnTypes := flag.String("column-types", "", "a comma-separated list of types representing the desired type of each column. if absent all types default to be String")
+	schema := flag.String("schema", "", "a <file>:<type name> naming a struct type declared in a noms schema file, to use as the type of each row instead of -name and -column-types")
 	pathDescription := "noms path to blob to import"
 	path := flag.String("path", "", pathDescription)
 	flag.StringVar(path, "p", "", pathDescription)
@@ -162,7 +164,15 @@ func main() {
 	}
 
 	kinds := []types.NomsKind{}
-	if *columnTypes != "" {
+	if *schema != "" {
+		if *columnTypes != "" {
+			d.CheckErrorNoUsage(fmt.Errorf("Cannot specify both schema and column-types"))
+		}
+		t, err := nomdl.ParseSchemaType(*schema)
+		d.CheckErrorNoUsage(err)
+		*name, kinds, err = csv.KindsFromStructType(t, headers)
+		d.CheckErrorNoUsage(err)
+	} else if *columnTypes != "" {
 		kinds = csv.StringsToKinds(strings.Split(*columnTypes, ","))
 		if len(kinds) != len(uniqueHeaders) {
 			d.CheckErrorNoUsage(fmt.Errorf("Invalid column-types specified, column types do not correspond to number of headers"))
@@ -10,6 +10,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/attic-labs/noms/go/d"
@@ -295,6 +296,41 @@ func (s *testSuite) TestCSVImporterWithExternalHeader() {
 	s.Equal(types.Number(8), st.Get("y"))
 }
 
+func (s *testSuite) TestCSVImporterWithSchema() {
+	input, err := ioutil.TempFile(s.TempDir, "")
+	d.Chk.NoError(err)
+	defer input.Close()
+	defer os.Remove(input.Name())
+
+	_, err = input.WriteString("x,y\n7,8\n")
+	d.Chk.NoError(err)
+
+	schema := filepath.Join(s.TempDir, "point.noms")
+	d.Chk.NoError(ioutil.WriteFile(schema, []byte("type Point = Struct Point { x: String, y: Number }"), 0644))
+
+	setName := "csv"
+	dataspec := spec.CreateValueSpecString("nbs", s.DBDir, setName)
+	stdout, stderr := s.MustRun(main, []string{"--no-progress", "--schema", schema + ":Point", input.Name(), dataspec})
+	s.Equal("", stdout)
+	s.Equal("", stderr)
+
+	db := datas.NewDatabase(nbs.NewLocalStore(s.DBDir, clienttest.DefaultMemTableSize))
+	defer os.RemoveAll(s.DBDir)
+	defer db.Close()
+	ds := db.GetDataset(setName)
+
+	l := ds.HeadValue().(types.List)
+	s.Equal(uint64(1), l.Len())
+	st := l.Get(0).(types.Struct)
+	s.Equal("Point", st.Name())
+	s.Equal(types.String("7"), st.Get("x"))
+	s.Equal(types.Number(8), st.Get("y"))
+
+	_, stderr, exitErr := s.Run(main, []string{"--no-progress", "--schema", schema + ":Point", "--header", "x,z", input.Name(), dataspec})
+	s.Equal("error: Struct Point has no field z for column z\n", stderr)
+	s.Equal(clienttest.ExitError{1}, exitErr)
+}
+
 func (s *testSuite) TestCSVImporterWithInvalidExternalHeader() {
 	input, err := ioutil.TempFile(s.TempDir, "")
 	d.Chk.NoError(err)
@@ -54,6 +54,41 @@ func EscapeStructFieldFromCSV(input string) string {
 	return types.CamelCaseFieldName(input)
 }
 
+// KindsFromStructType returns the name of the struct type |t| and the kind of the field of |t| each of |headers| maps to, so that |t| can be used as the target schema of an import. Each header must map to a Bool, Number or String field, and each field that isn't optional must have a header.
+func KindsFromStructType(t *types.Type, headers []string) (string, KindSlice, error) {
+	desc, ok := t.Desc.(types.StructDesc)
+	if !ok {
+		return "", nil, fmt.Errorf("Schema type %s is not a struct", t.Describe())
+	}
+
+	fields := map[string]types.StructField{}
+	desc.IterFields(func(name string, t *types.Type, optional bool) {
+		fields[name] = types.StructField{Name: name, Type: t, Optional: optional}
+	})
+
+	kinds := make(KindSlice, len(headers))
+	for i, header := range headers {
+		fn := EscapeStructFieldFromCSV(header)
+		f, ok := fields[fn]
+		if !ok {
+			return "", nil, fmt.Errorf("Struct %s has no field %s for column %s", desc.Name, fn, header)
+		}
+		switch k := f.Type.TargetKind(); k {
+		case types.BoolKind, types.NumberKind, types.StringKind:
+			kinds[i] = k
+		default:
+			return "", nil, fmt.Errorf("Field %s of struct %s is a %s, only Bool, Number and String columns can be imported", fn, desc.Name, f.Type.Describe())
+		}
+		delete(fields, fn)
+	}
+	for _, f := range fields {
+		if !f.Optional {
+			return "", nil, fmt.Errorf("Struct %s has no column for field %s", desc.Name, f.Name)
+		}
+	}
+	return desc.Name, kinds, nil
+}
+
 // MakeStructTemplateFromHeaders creates a struct type from the headers using |kinds| as the type of each field. If |kinds| is empty, default to strings.
 func MakeStructTemplateFromHeaders(headers []string, structName string, kinds KindSlice) (temp types.StructTemplate, fieldOrder []int, kindMap []types.NomsKind) {
 	useStringType := len(kinds) == 0
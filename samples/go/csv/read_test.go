@@ -11,6 +11,7 @@ import (
 
 	"github.com/attic-labs/noms/go/chunks"
 	"github.com/attic-labs/noms/go/datas"
+	"github.com/attic-labs/noms/go/nomdl"
 	"github.com/attic-labs/noms/go/types"
 	"github.com/stretchr/testify/assert"
 )
@@ -231,3 +232,27 @@ func TestBooleanStrings(t *testing.T) {
 		assert.True(types.Bool(false).Equals(row.Get("F")))
 	}
 }
+
+func TestKindsFromStructType(t *testing.T) {
+	assert := assert.New(t)
+	typ := nomdl.MustParseType(`Struct Person {
+		name: String,
+		age: Number,
+		isMember: Bool,
+		nickname?: String,
+	}`)
+
+	name, kinds, err := KindsFromStructType(typ, []string{"age", "name", "is member"})
+	assert.NoError(err)
+	assert.Equal("Person", name)
+	assert.Equal(KindSlice{types.NumberKind, types.StringKind, types.BoolKind}, kinds)
+
+	_, _, err = KindsFromStructType(typ, []string{"age", "name"})
+	assert.EqualError(err, "Struct Person has no column for field isMember")
+	_, _, err = KindsFromStructType(typ, []string{"age", "name", "isMember", "height"})
+	assert.EqualError(err, "Struct Person has no field height for column height")
+	_, _, err = KindsFromStructType(nomdl.MustParseType("Struct Row { a: List<Number> }"), []string{"a"})
+	assert.EqualError(err, "Field a of struct Row is a List<Number>, only Bool, Number and String columns can be imported")
+	_, _, err = KindsFromStructType(types.NumberType, []string{"a"})
+	assert.EqualError(err, "Schema type Number is not a struct")
+}
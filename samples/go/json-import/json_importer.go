@@ -18,7 +18,9 @@ import (
 	"github.com/attic-labs/noms/go/config"
 	"github.com/attic-labs/noms/go/d"
 	"github.com/attic-labs/noms/go/datas"
+	"github.com/attic-labs/noms/go/nomdl"
 	"github.com/attic-labs/noms/go/spec"
+	"github.com/attic-labs/noms/go/types"
 	"github.com/attic-labs/noms/go/util/jsontonoms"
 	"github.com/attic-labs/noms/go/util/progressreader"
 	"github.com/attic-labs/noms/go/util/status"
@@ -29,6 +31,7 @@ import (
 
 func main() {
 	performCommit := flag.Bool("commit", true, "commit the data to head of the dataset (otherwise only write the data to the dataset)")
+	schema := flag.String("schema", "", "a <file>:<type name> naming a type declared in a noms schema file, to import the JSON as")
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "usage: %s <url> <dataset>\n", os.Args[0])
 		flag.PrintDefaults()
@@ -42,6 +45,13 @@ func main() {
 		d.CheckError(errors.New("expected url and dataset flags"))
 	}
 
+	var typ *types.Type
+	if *schema != "" {
+		var err error
+		typ, err = nomdl.ParseSchemaType(*schema)
+		d.CheckErrorNoUsage(err)
+	}
+
 	cfg := config.NewResolver()
 	db, ds, err := cfg.GetDataset(flag.Arg(1))
 	d.CheckError(err)
@@ -85,14 +95,22 @@ func main() {
 	}
 	status.Done()
 
+	var value types.Value
+	if typ != nil {
+		value, err = jsontonoms.NomsValueFromDecodedJSONWithType(db, jsonObject, typ)
+		d.CheckErrorNoUsage(err)
+	} else {
+		value = jsontonoms.NomsValueFromDecodedJSON(db, jsonObject, true)
+	}
+
 	if *performCommit {
 		additionalMetaInfo := map[string]string{"url": url}
 		meta, err := spec.CreateCommitMetaStruct(ds.Database(), "", "", additionalMetaInfo, nil)
 		d.CheckErrorNoUsage(err)
-		_, err = db.Commit(ds, jsontonoms.NomsValueFromDecodedJSON(db, jsonObject, true), datas.CommitOptions{Meta: meta})
+		_, err = db.Commit(ds, value, datas.CommitOptions{Meta: meta})
 		d.PanicIfError(err)
 	} else {
-		ref := db.WriteValue(jsontonoms.NomsValueFromDecodedJSON(db, jsonObject, true))
+		ref := db.WriteValue(value)
 		fmt.Fprintf(os.Stdout, "#%s\n", ref.TargetHash().String())
 	}
 }